@@ -0,0 +1,348 @@
+// Package galaxy embeds a Galaxy site in an existing Go application.
+//
+// NewHandler returns an http.Handler serving the site's pages, endpoints,
+// assets and middleware, which the application mounts on its own mux:
+//
+//	site, err := galaxy.NewHandler(nil, galaxy.Options{
+//		FS:     siteFiles,
+//		Prefix: "/marketing",
+//		Locals: func(r *http.Request) map[string]any {
+//			return map[string]any{"db": db}
+//		},
+//	})
+//	mux.Handle("/marketing/", site)
+package galaxy
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/actions"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/endpoints"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware/builtin"
+	"github.com/cameron-webmatter/galaxy/pkg/server"
+)
+
+// Options configures an embedded site.
+type Options struct {
+	// Dir is the project directory, holding galaxy.config.toml, the source
+	// directory and public/. It defaults to the working directory.
+	Dir string
+	// FS holds the project instead of Dir in production mode, such as an
+	// embed.FS. Bundled assets are served from outDir/server when a
+	// `galaxy build` left them there.
+	FS fs.FS
+	// Dev renders pages from Dir as they change, compiling the project's
+	// Go files on demand, like `galaxy dev`.
+	Dev bool
+	// Prefix is the path the site is mounted under, such as "/marketing".
+	// It is stripped from requests and added to root-relative links, asset
+	// URLs and redirects in responses. It defaults to the configured base.
+	Prefix string
+
+	// Locals seeds the Locals of every request, handing the application's
+	// services to pages, endpoints and actions.
+	Locals func(*http.Request) map[string]any
+	// Middleware runs before the configured built-ins and the project's
+	// own middleware.
+	Middleware []middleware.Middleware
+
+	// Endpoints and Actions provide the handlers of the project's endpoint
+	// and actions files, keyed by route pattern. RouteMiddleware provides
+	// _middleware.go files, keyed by their directory relative to pages
+	// ("." for the pages root). In dev mode they take precedence over the
+	// compiled files. In production they are the only source, as the
+	// handler does not compile Go: NewHandler fails when the project has an
+	// endpoint, actions or middleware file they leave out.
+	Endpoints       map[string]*endpoints.LoadedEndpoint
+	Actions         map[string]actions.Set
+	RouteMiddleware map[string][]middleware.Middleware
+}
+
+// Handler serves an embedded site.
+type Handler struct {
+	handler http.Handler
+	close   func()
+}
+
+// NewHandler creates a handler for the site described by cfg and opts. A nil
+// cfg is loaded from galaxy.config.toml in opts.Dir, or defaults when the
+// project comes from opts.FS.
+func NewHandler(cfg *config.Config, opts Options) (*Handler, error) {
+	if opts.Dir == "" {
+		opts.Dir = "."
+	}
+	if opts.Dev && opts.FS != nil {
+		return nil, fmt.Errorf("dev mode serves from Dir, not FS")
+	}
+
+	var err error
+	switch {
+	case cfg != nil:
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("validate config: %w", err)
+		}
+	case opts.FS != nil:
+		cfg = config.DefaultConfig()
+	default:
+		if cfg, err = config.LoadFromDir(opts.Dir); err != nil {
+			return nil, fmt.Errorf("load config: %w", err)
+		}
+	}
+
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = cfg.Base
+	}
+	prefix = strings.TrimRight(prefix, "/")
+
+	builtins, err := builtin.FromConfig(cfg.Middleware)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	mws := append(append([]middleware.Middleware{}, opts.Middleware...), builtins...)
+	if prefix != "" {
+		mws = append(mws, rewritePrefix)
+	}
+
+	h := &Handler{close: func() {}}
+	if opts.Dev {
+		h.handler, h.close, err = newDevHandler(cfg, opts, mws)
+	} else {
+		h.handler, err = newSiteHandler(cfg, opts, mws)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix != "" {
+		h.handler = mount(prefix, h.handler)
+	}
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// Close stops watching the project in dev mode.
+func (h *Handler) Close() error {
+	h.close()
+	return nil
+}
+
+func newDevHandler(cfg *config.Config, opts Options, mws []middleware.Middleware) (http.Handler, func(), error) {
+	rootDir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srcDir := cfg.SrcDir
+	if !filepath.IsAbs(srcDir) {
+		srcDir = filepath.Join(rootDir, srcDir)
+	}
+	pagesDir := filepath.Join(srcDir, "pages")
+	if _, err := os.Stat(pagesDir); err != nil {
+		return nil, nil, fmt.Errorf("pages directory not found: %s", pagesDir)
+	}
+
+	srv := server.NewDevServer(rootDir, pagesDir, filepath.Join(rootDir, "public"), 0, false)
+	srv.Telemetry = cfg.Telemetry
+	srv.WebSocketOrigins = cfg.Server.WebSocketOrigins
+	srv.BuiltinMiddleware = mws
+	srv.Locals = opts.Locals
+	srv.Endpoints = opts.Endpoints
+	srv.Actions = opts.Actions
+	srv.RouteMiddleware = opts.RouteMiddleware
+
+	if err := srv.Router.Discover(); err != nil {
+		return nil, nil, err
+	}
+	srv.Router.Sort()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := srv.Watch(ctx); err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("watch: %w", err)
+	}
+	return srv.Handler(), cancel, nil
+}
+
+type prefixKey struct{}
+
+// mount serves next under prefix. Its writer rewrites the responses that
+// skip the middleware chain, like files; rewritePrefix takes over for pages
+// and endpoints, so their URLs are rewritten before compression.
+func mount(prefix string, next http.Handler) http.Handler {
+	rw := newPrefixRewriter(prefix)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, prefix)
+		if len(p) == len(r.URL.Path) || (p != "" && p[0] != '/') {
+			http.NotFound(w, r)
+			return
+		}
+		if p == "" {
+			p = "/"
+		}
+
+		pw := &prefixWriter{ResponseWriter: w, rw: rw}
+		r2 := r.Clone(context.WithValue(r.Context(), prefixKey{}, pw))
+		r2.URL.Path = p
+		r2.URL.RawPath = ""
+		next.ServeHTTP(pw, r2)
+		pw.finish()
+	})
+}
+
+// rewritePrefix runs innermost of the built-ins: it rewrites page and
+// endpoint responses before Compress encodes them, in place of the writer
+// mount installed.
+func rewritePrefix(ctx *middleware.Context, next func() error) error {
+	outer, ok := ctx.Request.Context().Value(prefixKey{}).(*prefixWriter)
+	if !ok {
+		return next()
+	}
+	outer.passthrough = true
+	pw := &prefixWriter{ResponseWriter: ctx.Response, rw: outer.rw}
+	ctx.Response = pw
+	err := next()
+	pw.finish()
+	return err
+}
+
+// prefixRewriter adds a prefix to root-relative URLs: link, image and form
+// targets in HTML, asset URLs in HTML and JavaScript, and redirects. URLs
+// already under the prefix are left alone.
+type prefixRewriter struct {
+	prefix string
+	regex  *regexp.Regexp
+	// lookahead is how many bytes a match can span: a streamed chunk's last
+	// bytes wait for the next chunk, in case a URL is split between them.
+	lookahead int
+}
+
+func newPrefixRewriter(prefix string) *prefixRewriter {
+	// Group 1 ends an attribute before its URL; group 2 is a URL already
+	// under the prefix; group 3 is the quote before an asset URL.
+	regex := regexp.MustCompile(`(?i)(\s(?:href|src|action|poster|formaction)\s{0,3}=\s{0,3}["'])/(?:(` +
+		regexp.QuoteMeta(strings.TrimPrefix(prefix, "/")) + `[/"'?#])|[^/])|(["'\x60])/(?:_assets/|wasm_exec\.js)`)
+	return &prefixRewriter{prefix: prefix, regex: regex, lookahead: 48 + len(prefix)}
+}
+
+// rewrite rewrites data and returns it, keeping back for the next call the
+// bytes a URL may continue from, unless final.
+func (rw *prefixRewriter) rewrite(data []byte, final bool) (out, rest []byte) {
+	cut := len(data)
+	if !final {
+		cut = max(0, len(data)-rw.lookahead)
+	}
+	last := 0
+	for _, m := range rw.regex.FindAllSubmatchIndex(data, -1) {
+		if m[0] >= cut {
+			break
+		}
+		cut = max(cut, m[1])
+		if m[4] >= 0 {
+			continue
+		}
+		at := m[3]
+		if at < 0 {
+			at = m[7]
+		}
+		out = append(out, data[last:at]...)
+		out = append(out, rw.prefix...)
+		last = at
+	}
+	out = append(out, data[last:cut]...)
+	return out, data[cut:]
+}
+
+// location prefixes a root-relative redirect.
+func (rw *prefixRewriter) location(loc string) string {
+	if !strings.HasPrefix(loc, "/") || strings.HasPrefix(loc, "//") || loc == rw.prefix || strings.HasPrefix(loc, rw.prefix+"/") {
+		return loc
+	}
+	return rw.prefix + loc
+}
+
+// prefixWriter rewrites URLs in HTML and JavaScript responses, and in
+// redirects. Rewriting changes the length, so Content-Length is dropped
+// from those responses. Encoded responses are passed through: rewritePrefix
+// has rewritten them before they were encoded.
+type prefixWriter struct {
+	http.ResponseWriter
+	rw *prefixRewriter
+	// passthrough is set when rewritePrefix rewrites instead.
+	passthrough bool
+	rewrite     bool
+	wroteHeader bool
+	pending     []byte
+}
+
+func (w *prefixWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		h := w.Header()
+		if !w.passthrough {
+			if loc := h.Get("Location"); loc != "" {
+				h.Set("Location", w.rw.location(loc))
+			}
+			ct := h.Get("Content-Type")
+			if h.Get("Content-Encoding") == "" && (strings.HasPrefix(ct, "text/html") || strings.Contains(ct, "javascript")) {
+				w.rewrite = true
+				h.Del("Content-Length")
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *prefixWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.rewrite {
+		return w.ResponseWriter.Write(b)
+	}
+	out, rest := w.rw.rewrite(append(w.pending, b...), false)
+	w.pending = append([]byte(nil), rest...)
+	if _, err := w.ResponseWriter.Write(out); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// finish writes what Write kept back once the handler is done.
+func (w *prefixWriter) finish() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	out, _ := w.rw.rewrite(w.pending, true)
+	w.pending = nil
+	_, err := w.ResponseWriter.Write(out)
+	return err
+}
+
+// Flush sends what Write kept back, so streamed chunks arrive whole.
+func (w *prefixWriter) Flush() {
+	w.finish()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach Hijack on the underlying
+// writer.
+func (w *prefixWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,215 @@
+package galaxy
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/endpoints"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+)
+
+func siteFS() fstest.MapFS {
+	return fstest.MapFS{
+		"src/components/Card.gxc": {Data: []byte(`<div class="card">{title}</div>`)},
+		"src/pages/index.gxc": {Data: []byte(`---
+var user = Locals.user
+---
+<html><head></head><body><Card title={user} /><p>{Locals.trace}</p><script src="/_assets/app.js"></script></body></html>`)},
+		"src/pages/admin/index.gxc":      {Data: []byte(`<p>admin</p>`)},
+		"src/pages/admin/_middleware.go": {Data: []byte(`package admin`)},
+		"src/pages/api/ping.go":          {Data: []byte(`package api`)},
+		"public/robots.txt":              {Data: []byte("User-agent: *")},
+		"dist/server/_assets/app.js":     {Data: []byte(`import "/_assets/chunk.js"`)},
+	}
+}
+
+func TestHandler(t *testing.T) {
+	trace := func(ctx *middleware.Context, next func() error) error {
+		ctx.Set("trace", "host-mw")
+		return next()
+	}
+	deny := func(ctx *middleware.Context, next func() error) error {
+		ctx.Response.WriteHeader(http.StatusForbidden)
+		return nil
+	}
+
+	h, err := NewHandler(nil, Options{
+		FS:     siteFS(),
+		Prefix: "/marketing",
+		Locals: func(r *http.Request) map[string]any {
+			return map[string]any{"user": "ada"}
+		},
+		Middleware: []middleware.Middleware{trace},
+		Endpoints: map[string]*endpoints.LoadedEndpoint{
+			"/api/ping": {Handlers: map[endpoints.HTTPMethod]endpoints.HandlerFunc{
+				endpoints.GET: func(c *endpoints.Context) error {
+					return c.Text(http.StatusOK, "pong "+c.Locals["user"].(string))
+				},
+			}},
+		},
+		RouteMiddleware: map[string][]middleware.Middleware{"admin": {deny}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := get("/marketing/")
+	body := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, body)
+	}
+	if !strings.Contains(body, `<div class="card">ada</div>`) {
+		t.Errorf("Expected component rendered with injected locals, got %s", body)
+	}
+	if !strings.Contains(body, "<p>host-mw</p>") {
+		t.Errorf("Expected host middleware to set locals, got %s", body)
+	}
+	if !strings.Contains(body, `src="/marketing/_assets/app.js"`) {
+		t.Errorf("Expected asset URLs under the prefix, got %s", body)
+	}
+
+	if w := get("/marketing/api/ping"); w.Body.String() != "pong ada" {
+		t.Errorf("Expected host endpoint, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get("/marketing/admin"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected route middleware to run, got %d", w.Code)
+	}
+	if w := get("/marketing/robots.txt"); w.Body.String() != "User-agent: *" {
+		t.Errorf("Expected public file, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get("/marketing/_assets/app.js"); w.Body.String() != `import "/marketing/_assets/chunk.js"` {
+		t.Errorf("Expected built asset with rewritten imports, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get("/marketingx/"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 outside the prefix, got %d", w.Code)
+	}
+}
+
+func TestHandlerRewritesCompressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/pages/index.gxc": {Data: []byte(`<html><body><a href="/about">About</a><a href="/marketing/faq">FAQ</a><a href="//cdn.example.com/x">CDN</a><script src="/_assets/app.js"></script></body></html>`)},
+		"src/pages/api/go.go": {Data: []byte(`package api`)},
+	}
+	cfg := config.DefaultConfig()
+	cfg.Middleware.Compress = config.CompressConfig{Enabled: true, MinSize: 1}
+
+	h, err := NewHandler(cfg, Options{
+		FS:     fsys,
+		Prefix: "/marketing",
+		Endpoints: map[string]*endpoints.LoadedEndpoint{
+			"/api/go": {Handlers: map[endpoints.HTTPMethod]endpoints.HandlerFunc{
+				endpoints.GET: func(c *endpoints.Context) error {
+					return c.Redirect("/about", http.StatusFound)
+				},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	r := httptest.NewRequest("GET", "/marketing/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip response, got %q", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(data)
+	for _, want := range []string{`href="/marketing/about"`, `href="/marketing/faq"`, `href="//cdn.example.com/x"`, `src="/marketing/_assets/app.js"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in the decompressed page, got %s", want, body)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/marketing/api/go", nil))
+	if loc := w.Header().Get("Location"); loc != "/marketing/about" {
+		t.Errorf("Expected redirect under the prefix, got %q", loc)
+	}
+}
+
+func TestPrefixWriterChunks(t *testing.T) {
+	w := httptest.NewRecorder()
+	pw := &prefixWriter{ResponseWriter: w, rw: newPrefixRewriter("/docs")}
+	pw.Header().Set("Content-Type", "text/html")
+	text := strings.Repeat("x", 100)
+	pw.Write([]byte(`<a href="/a">a</a>` + text + `<script src="/_as`))
+	pw.Write([]byte(`sets/app.js"></script>` + text + `<a hr`))
+	pw.Write([]byte(`ef="/b">b</a>`))
+	pw.finish()
+
+	expected := `<a href="/docs/a">a</a>` + text + `<script src="/docs/_assets/app.js"></script>` + text + `<a href="/docs/b">b</a>`
+	if got := w.Body.String(); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestHandlerMissingGoHandlers(t *testing.T) {
+	_, err := NewHandler(nil, Options{FS: siteFS(), Endpoints: map[string]*endpoints.LoadedEndpoint{"/api/ping": {}}})
+	if err == nil || !strings.Contains(err.Error(), "middleware for admin") {
+		t.Errorf("Expected error naming the missing middleware, got %v", err)
+	}
+
+	_, err = NewHandler(nil, Options{FS: siteFS(), RouteMiddleware: map[string][]middleware.Middleware{"admin": nil}})
+	if err == nil || !strings.Contains(err.Error(), "endpoint /api/ping") {
+		t.Errorf("Expected error naming the missing endpoint, got %v", err)
+	}
+}
+
+func TestDevHandler(t *testing.T) {
+	dir := t.TempDir()
+	pages := filepath.Join(dir, "src", "pages")
+	if err := os.MkdirAll(pages, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pages, "index.gxc"), []byte(`<h1>{Locals.user}</h1>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(nil, Options{
+		Dir: dir,
+		Dev: true,
+		Locals: func(r *http.Request) map[string]any {
+			return map[string]any{"user": "grace"}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(w.Body.String(), "<h1>grace</h1>") {
+		t.Errorf("Expected page rendered from Dir with locals, got %d %q", w.Code, w.Body.String())
+	}
+
+	if _, err := NewHandler(nil, Options{Dir: dir, Dev: true, FS: siteFS()}); err == nil {
+		t.Error("Expected error for dev mode with FS")
+	}
+}
@@ -1,10 +1,11 @@
 module github.com/cameron-webmatter/galaxy
 
-go 1.23
+go 1.24
 
 require (
 	github.com/AlecAivazis/survey/v2 v2.3.7
 	github.com/BurntSushi/toml v1.5.0
+	github.com/andybalholm/brotli v1.1.1
 	github.com/fsnotify/fsnotify v1.9.0
 	github.com/spf13/cobra v1.10.1
 	go.lsp.dev/jsonrpc2 v0.10.0
@@ -11,12 +11,28 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/telemetry"
 )
 
 type Compiler struct {
 	TempDir   string
 	CacheDir  string
 	UseTinyGo bool
+	// BuildCache, when set, reuses modules built from the same sources in
+	// earlier builds.
+	BuildCache BuildCache
+
+	// locks holds a mutex per script hash: the same script compiled for
+	// two pages at once shares a build directory.
+	locks sync.Map
+}
+
+// BuildCache runs build to write output in dir unless it can restore it.
+type BuildCache interface {
+	GoBuild(dir, output string, build func() error) (bool, error)
 }
 
 type CompiledModule struct {
@@ -35,6 +51,10 @@ func NewCompiler(tempDir, cacheDir string) *Compiler {
 func (c *Compiler) Compile(script, pagePath string) (*CompiledModule, error) {
 	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(script)))[:8]
 
+	lock, _ := c.locks.LoadOrStore(hash, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
 	cachedWasm := filepath.Join(c.CacheDir, fmt.Sprintf("script-%s.wasm", hash))
 	if _, err := os.Stat(cachedWasm); err == nil {
 		return &CompiledModule{
@@ -43,6 +63,8 @@ func (c *Compiler) Compile(script, pagePath string) (*CompiledModule, error) {
 		}, nil
 	}
 
+	defer telemetry.WasmCompileDuration.Since(time.Now())
+
 	buildDir := filepath.Join(c.TempDir, hash)
 	if err := os.MkdirAll(buildDir, 0755); err != nil {
 		return nil, fmt.Errorf("create build dir: %w", err)
@@ -69,34 +91,21 @@ func (c *Compiler) Compile(script, pagePath string) (*CompiledModule, error) {
 		return nil, fmt.Errorf("write go.mod: %w", err)
 	}
 
-	tidyCmd := exec.Command("go", "mod", "tidy")
-	tidyCmd.Dir = buildDir
-	if output, err := tidyCmd.CombinedOutput(); err != nil {
-		return nil, fmt.Errorf("go mod tidy failed: %s\n%s", err, output)
-	}
-
 	outWasm := filepath.Join(buildDir, "script.wasm")
-	absOutWasm, err := filepath.Abs(outWasm)
-	if err != nil {
-		absOutWasm = outWasm
-	}
-
-	var cmd *exec.Cmd
-	if c.UseTinyGo && isTinyGoAvailable() {
-		cmd = exec.Command("tinygo", "build", "-o", absOutWasm, "-target", "wasm", ".")
-		cmd.Dir = buildDir
-	} else {
-		cmd = exec.Command("go", "build", "-o", absOutWasm, ".")
-		cmd.Dir = buildDir
-		cmd.Env = append(os.Environ(),
-			"GOOS=js",
-			"GOARCH=wasm",
-		)
+	build := func() error {
+		tidyCmd := exec.Command("go", "mod", "tidy")
+		tidyCmd.Dir = buildDir
+		if output, err := tidyCmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("go mod tidy failed: %s\n%s", err, output)
+		}
+		return c.Build(buildDir, outWasm)
 	}
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("compile failed: %s\n%s", err, output)
+	if c.BuildCache != nil {
+		if _, err := c.BuildCache.GoBuild(buildDir, "script.wasm", build); err != nil {
+			return nil, err
+		}
+	} else if err := build(); err != nil {
+		return nil, err
 	}
 
 	if _, err := os.Stat(outWasm); os.IsNotExist(err) {
@@ -105,7 +114,7 @@ func (c *Compiler) Compile(script, pagePath string) (*CompiledModule, error) {
 		for _, e := range entries {
 			files = append(files, e.Name())
 		}
-		return nil, fmt.Errorf("wasm file not generated at %s, build output: %s, files in buildDir: %v", outWasm, output, files)
+		return nil, fmt.Errorf("wasm file not generated at %s, files in buildDir: %v", outWasm, files)
 	}
 
 	if err := os.MkdirAll(c.CacheDir, 0755); err != nil {
@@ -129,6 +138,48 @@ func (c *Compiler) Compile(script, pagePath string) (*CompiledModule, error) {
 	}, nil
 }
 
+// Build compiles the main package in dir to a WASM module at output, with
+// TinyGo when enabled and installed and otherwise the go tool for js/wasm.
+func (c *Compiler) Build(dir, output string) error {
+	absOutput, err := filepath.Abs(output)
+	if err != nil {
+		absOutput = output
+	}
+
+	var cmd *exec.Cmd
+	if c.UseTinyGo && isTinyGoAvailable() {
+		cmd = exec.Command("tinygo", "build", "-o", absOutput, "-target", "wasm", ".")
+		cmd.Dir = dir
+	} else {
+		cmd = exec.Command("go", "build", "-o", absOutput, ".")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GOOS=js",
+			"GOARCH=wasm",
+		)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("compile failed: %s\n%s", err, out)
+	}
+	return nil
+}
+
+// ExecJS returns the wasm_exec.js that runs modules built by the go tool.
+func ExecJS() ([]byte, error) {
+	out, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return nil, fmt.Errorf("go env GOROOT: %w", err)
+	}
+	goRoot := strings.TrimSpace(string(out))
+
+	data, err := os.ReadFile(filepath.Join(goRoot, "lib", "wasm", "wasm_exec.js"))
+	if os.IsNotExist(err) {
+		data, err = os.ReadFile(filepath.Join(goRoot, "misc", "wasm", "wasm_exec.js"))
+	}
+	return data, err
+}
+
 func findModuleRoot() string {
 	exePath, err := os.Executable()
 	if err == nil {
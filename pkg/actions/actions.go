@@ -0,0 +1,257 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cameron-webmatter/galaxy/pkg/endpoints"
+	"github.com/cameron-webmatter/galaxy/pkg/executor"
+	"github.com/cameron-webmatter/galaxy/pkg/telemetry"
+)
+
+// QueryParam selects the action to run on a POST to a page.
+const QueryParam = "_action"
+
+// EnhanceHeader is sent by the client script; such requests get a JSON
+// Result instead of a re-rendered page.
+const EnhanceHeader = "X-Galaxy-Action"
+
+// Func is a form action after it has been adapted with Plain or Typed.
+type Func func(*endpoints.Context) (any, error)
+
+// Set maps action names, as used in templates, to their functions.
+type Set map[string]Func
+
+// Plain adapts func(*endpoints.Context) (Out, error).
+func Plain[Out any](fn func(*endpoints.Context) (Out, error)) Func {
+	return func(ctx *endpoints.Context) (any, error) {
+		return fn(ctx)
+	}
+}
+
+// Typed adapts func(*endpoints.Context, In) (Out, error), binding and
+// validating In from the submitted form first.
+func Typed[In, Out any](fn func(*endpoints.Context, In) (Out, error)) Func {
+	return func(ctx *endpoints.Context) (any, error) {
+		var in In
+		if err := ctx.Bind(&in); err != nil {
+			return nil, err
+		}
+		return fn(ctx, in)
+	}
+}
+
+// Result is exposed to the re-rendered page as Galaxy.ActionResult.
+type Result struct {
+	Action   string            `json:"action,omitempty"`
+	OK       bool              `json:"ok"`
+	Data     any               `json:"data,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+	Values   map[string]string `json:"values,omitempty"`
+	Redirect string            `json:"redirect,omitempty"`
+	Status   int               `json:"-"`
+}
+
+// RedirectError ends an action with a redirect instead of a re-render.
+type RedirectError struct {
+	URL    string
+	Status int
+}
+
+func (e *RedirectError) Error() string {
+	return "redirect to " + e.URL
+}
+
+// Redirect is returned from an action to redirect after a successful post.
+// A zero status means 303 See Other.
+func Redirect(url string, status int) error {
+	if status == 0 {
+		status = http.StatusSeeOther
+	}
+	return &RedirectError{URL: url, Status: status}
+}
+
+// URL returns the form action attribute for the named action.
+func URL(name string) string {
+	return "?" + QueryParam + "=" + url.QueryEscape(name)
+}
+
+// URLs builds the `actions` template variable for the given names.
+func URLs(names ...string) map[string]interface{} {
+	urls := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		urls[name] = URL(name)
+	}
+	return urls
+}
+
+func (s Set) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Name converts an exported Go function name to its template name,
+// e.g. Subscribe -> subscribe.
+func Name(funcName string) string {
+	r, size := utf8.DecodeRuneInString(funcName)
+	return string(unicode.ToLower(r)) + funcName[size:]
+}
+
+// Handle runs the action selected by a POST request. It reports handled when
+// a response has already been written (redirect, JSON for enhanced requests,
+// or an unknown action); otherwise the page should be rendered with result.
+// Non-POST requests return a nil result.
+func Handle(set Set, w http.ResponseWriter, r *http.Request, params map[string]string, locals map[string]any) (*Result, bool) {
+	if r.Method != http.MethodPost || len(set) == 0 {
+		return nil, false
+	}
+
+	name := r.URL.Query().Get(QueryParam)
+	if name == "" {
+		name = r.FormValue(QueryParam)
+	}
+	if name == "" && len(set) == 1 {
+		for only := range set {
+			name = only
+		}
+	}
+
+	fn, ok := set[name]
+	if !ok {
+		http.Error(w, "Unknown action: "+name, http.StatusNotFound)
+		return nil, true
+	}
+
+	data, err := fn(endpoints.NewContext(w, r, params, locals))
+	result := newResult(name, data, err, r)
+
+	enhanced := r.Header.Get(EnhanceHeader) != ""
+	if result.Redirect != "" && !enhanced {
+		http.Redirect(w, r, result.Redirect, result.Status)
+		return result, true
+	}
+
+	if enhanced {
+		w.Header().Set("Content-Type", "application/json")
+		if result.Redirect == "" {
+			w.WriteHeader(result.Status)
+		}
+		json.NewEncoder(w).Encode(result)
+		return result, true
+	}
+
+	return result, false
+}
+
+func newResult(name string, data any, err error, r *http.Request) *Result {
+	result := &Result{Action: name, Status: http.StatusOK}
+	if err == nil {
+		result.OK = true
+		result.Data = data
+		return result
+	}
+
+	var (
+		redirect *RedirectError
+		verrs    endpoints.ValidationErrors
+		berr     *endpoints.BindError
+		herr     *endpoints.HTTPError
+	)
+
+	switch {
+	case errors.As(err, &redirect):
+		result.OK = true
+		result.Data = data
+		result.Redirect = redirect.URL
+		result.Status = redirect.Status
+		return result
+	case errors.As(err, &verrs):
+		result.Status = http.StatusBadRequest
+		result.Error = "validation failed"
+		result.Errors = make(map[string]string, len(verrs))
+		for _, fe := range verrs {
+			if _, exists := result.Errors[fe.Field]; !exists {
+				result.Errors[fe.Field] = fe.Message
+			}
+		}
+	case errors.As(err, &berr):
+		result.Status = http.StatusBadRequest
+		result.Error = berr.Error()
+		result.Errors = map[string]string{berr.Field: "invalid value"}
+	case errors.As(err, &herr):
+		result.Status = herr.Status
+		result.Error = herr.Message
+	default:
+		// Other errors may hold internals, so the form only learns that
+		// something went wrong; return an *endpoints.HTTPError to show a
+		// message.
+		telemetry.Logger(r.Context()).Error("action failed", "action", name, "error", err)
+		result.Status = http.StatusInternalServerError
+		result.Error = http.StatusText(http.StatusInternalServerError)
+	}
+
+	result.Values = submittedValues(r)
+	return result
+}
+
+// csrfField matches builtin.CSRFField.
+const csrfField = "_csrf"
+
+// submittedValues returns the posted fields to refill the form with. The
+// CSRF token and passwords are left out, so they are never echoed back into
+// the page.
+func submittedValues(r *http.Request) map[string]string {
+	if r.PostForm == nil {
+		return nil
+	}
+	values := make(map[string]string, len(r.PostForm))
+	for key, vals := range r.PostForm {
+		if key == QueryParam || key == csrfField || len(vals) == 0 || strings.Contains(strings.ToLower(key), "password") {
+			continue
+		}
+		values[key] = vals[0]
+	}
+	return values
+}
+
+type resultKey struct{}
+
+// WithResult attaches result to the request so compiled page handlers can
+// read it with ResultFrom.
+func WithResult(r *http.Request, result *Result) *http.Request {
+	if result == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), resultKey{}, result))
+}
+
+// ResultFrom returns the action result for r, or an empty Result when no
+// action ran.
+func ResultFrom(r *http.Request) *Result {
+	if result, ok := r.Context().Value(resultKey{}).(*Result); ok {
+		return result
+	}
+	return &Result{}
+}
+
+// Expose makes Galaxy.ActionResult and the `actions` URL map available to a
+// page's frontmatter and template.
+func Expose(ctx *executor.Context, names []string, result *Result) {
+	if result == nil {
+		result = &Result{}
+	}
+	ctx.SetActionResult(result)
+	ctx.Set("actions", URLs(names...))
+}
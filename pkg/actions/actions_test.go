@@ -0,0 +1,222 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/endpoints"
+	"github.com/cameron-webmatter/galaxy/pkg/executor"
+	"github.com/cameron-webmatter/galaxy/pkg/template"
+)
+
+type subscribeInput struct {
+	Email string `form:"email" validate:"required,email"`
+}
+
+var testSet = Set{
+	"subscribe": Typed(func(ctx *endpoints.Context, in subscribeInput) (string, error) {
+		return "subscribed " + in.Email, nil
+	}),
+	"unsubscribe": Plain(func(ctx *endpoints.Context) (any, error) {
+		return nil, Redirect("/goodbye", 0)
+	}),
+	"fail": Plain(func(ctx *endpoints.Context) (any, error) {
+		return nil, endpoints.NewHTTPError(http.StatusBadRequest, "already subscribed")
+	}),
+	"crash": Plain(func(ctx *endpoints.Context) (any, error) {
+		return nil, errors.New("dial db: password authentication failed")
+	}),
+}
+
+func postForm(action string, form url.Values) *http.Request {
+	req := httptest.NewRequest("POST", "/newsletter"+URL(action), strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestHandleSuccess(t *testing.T) {
+	w := httptest.NewRecorder()
+	result, handled := Handle(testSet, w, postForm("subscribe", url.Values{"email": {"ada@example.com"}}), nil, nil)
+
+	if handled {
+		t.Fatal("Expected page to be rendered")
+	}
+	if !result.OK || result.Data != "subscribed ada@example.com" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if result.Status != http.StatusOK {
+		t.Errorf("Expected 200, got %d", result.Status)
+	}
+}
+
+func TestHandleValidationErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	result, handled := Handle(testSet, w, postForm("subscribe", url.Values{"email": {"nope"}}), nil, nil)
+
+	if handled {
+		t.Fatal("Expected page to be rendered")
+	}
+	if result.OK || result.Status != http.StatusBadRequest {
+		t.Errorf("Expected failed result, got %+v", result)
+	}
+	if result.Errors["email"] == "" {
+		t.Errorf("Expected email error, got %v", result.Errors)
+	}
+	if result.Values["email"] != "nope" {
+		t.Errorf("Expected submitted value to be kept, got %v", result.Values)
+	}
+
+	form := url.Values{"email": {"nope"}, "_csrf": {"token"}, "password": {"secret"}, "confirmPassword": {"secret"}}
+	result, _ = Handle(testSet, httptest.NewRecorder(), postForm("subscribe", form), nil, nil)
+	if len(result.Values) != 1 {
+		t.Errorf("Expected the token and passwords left out, got %v", result.Values)
+	}
+}
+
+func TestHandleRedirect(t *testing.T) {
+	w := httptest.NewRecorder()
+	_, handled := Handle(testSet, w, postForm("unsubscribe", nil), nil, nil)
+
+	if !handled {
+		t.Fatal("Expected redirect to be handled")
+	}
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/goodbye" {
+		t.Errorf("Expected 303 to /goodbye, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestHandleEnhancedReturnsJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := postForm("fail", nil)
+	req.Header.Set(EnhanceHeader, "1")
+
+	_, handled := Handle(testSet, w, req, nil, nil)
+	if !handled {
+		t.Fatal("Expected enhanced request to be handled")
+	}
+
+	var result Result
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Expected JSON body: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || result.Error != "already subscribed" {
+		t.Errorf("Unexpected response %d %+v", w.Code, result)
+	}
+}
+
+func TestHandleHidesInternalErrors(t *testing.T) {
+	result, _ := Handle(testSet, httptest.NewRecorder(), postForm("crash", nil), nil, nil)
+
+	if result.Status != http.StatusInternalServerError || result.Error != "Internal Server Error" {
+		t.Errorf("Expected a generic 500, got %d %q", result.Status, result.Error)
+	}
+}
+
+func TestHandleIgnoresGetAndUnknownActions(t *testing.T) {
+	result, handled := Handle(testSet, httptest.NewRecorder(), httptest.NewRequest("GET", "/newsletter", nil), nil, nil)
+	if result != nil || handled {
+		t.Error("Expected GET to skip actions")
+	}
+
+	w := httptest.NewRecorder()
+	_, handled = Handle(testSet, w, postForm("missing", nil), nil, nil)
+	if !handled || w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown action, got %d", w.Code)
+	}
+}
+
+func TestExposeRendersResult(t *testing.T) {
+	ctx := executor.NewContext()
+	Expose(ctx, testSet.Names(), &Result{Action: "subscribe", Errors: map[string]string{"email": "email must be a valid email"}})
+
+	tmpl := `<form method="post" action={actions.subscribe}><span>{Galaxy.ActionResult.Errors.email}</span><i>{Galaxy.ActionResult.Errors.name}</i>` +
+		`<p galaxy:if={Galaxy.ActionResult.OK}>Thanks!</p></form>`
+	html, err := template.NewEngine(ctx).Render(tmpl, nil)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	if !strings.Contains(html, `action="?_action=subscribe"`) {
+		t.Errorf("Expected quoted action URL, got %s", html)
+	}
+	if !strings.Contains(html, "<span>email must be a valid email</span>") {
+		t.Errorf("Expected field error, got %s", html)
+	}
+	if !strings.Contains(html, "<i></i>") {
+		t.Errorf("Expected missing error to render empty, got %s", html)
+	}
+	if strings.Contains(html, "Thanks!") {
+		t.Errorf("Expected success message to be hidden, got %s", html)
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	src := `package pages
+
+import "github.com/cameron-webmatter/galaxy/pkg/endpoints"
+
+func Subscribe(ctx *endpoints.Context, in SubscribeInput) (string, error) { return "", nil }
+
+func SignOut(ctx *endpoints.Context) (any, error) { return nil, nil }
+
+func helper(ctx *endpoints.Context) (any, error) { return nil, nil }
+
+func Format(s string) string { return s }
+`
+	sigs, err := ParseSource("newsletter.actions.go", []byte(src))
+	if err != nil {
+		t.Fatalf("ParseSource failed: %v", err)
+	}
+
+	if len(sigs) != 2 {
+		t.Fatalf("Expected 2 actions, got %+v", sigs)
+	}
+	if sigs[0].Name != "subscribe" || !sigs[0].Typed {
+		t.Errorf("Expected typed subscribe, got %+v", sigs[0])
+	}
+	if sigs[1].Name != "signOut" || sigs[1].Adapter("pages") != "actions.Plain(pages.SignOut)" {
+		t.Errorf("Expected plain signOut, got %+v", sigs[1])
+	}
+}
+
+func TestInjectClient(t *testing.T) {
+	html := `<html><body><form data-enhance></form></body></html>`
+	out := InjectClient(html)
+	if !strings.Contains(out, "__galaxyActions") || !strings.HasSuffix(out, "</body></html>") {
+		t.Errorf("Expected client script before </body>, got %s", out)
+	}
+	if InjectClient(out) != out {
+		t.Error("Expected script to be injected once")
+	}
+	if plain := `<body><form></form></body>`; InjectClient(plain) != plain {
+		t.Error("Expected pages without enhanced forms to be unchanged")
+	}
+}
+
+func TestPackageAlias(t *testing.T) {
+	files := []string{
+		"/site/pages/my-page.actions.go",
+		"/site/pages/[slug].actions.go",
+		"/site/pages/blog/index.actions.go",
+		"/site/pages/api/blog/index.actions.go",
+		"/site/pages/blog/post.actions.go",
+	}
+	ident := regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	seen := make(map[string]string)
+	for _, file := range files {
+		alias := PackageAlias("/site/pages", file)
+		if !ident.MatchString(alias) {
+			t.Errorf("Expected a Go identifier for %s, got %q", file, alias)
+		}
+		if other, ok := seen[alias]; ok {
+			t.Errorf("Expected %s and %s to get different aliases, both got %q", other, file, alias)
+		}
+		seen[alias] = file
+	}
+}
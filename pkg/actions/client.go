@@ -0,0 +1,78 @@
+package actions
+
+import "strings"
+
+// ClientScript submits forms marked with data-enhance through fetch. The
+// server answers with a JSON Result; field errors are written into elements
+// with a matching data-error attribute (data-error="" receives the general
+// error) and a galaxy:action event is dispatched on the form.
+const ClientScript = `(() => {
+  if (window.__galaxyActions) return;
+  window.__galaxyActions = true;
+
+  document.addEventListener('submit', async (event) => {
+    const form = event.target;
+    if (!(form instanceof HTMLFormElement) || !form.hasAttribute('data-enhance')) return;
+    if ((form.getAttribute('method') || 'get').toLowerCase() !== 'post') return;
+    event.preventDefault();
+
+    const submitter = event.submitter;
+    const action = (submitter && submitter.getAttribute('formaction')) || form.getAttribute('action') || '';
+    const body = new FormData(form);
+    if (submitter && submitter.name) body.append(submitter.name, submitter.value);
+
+    form.setAttribute('aria-busy', 'true');
+    try {
+      const res = await fetch(new URL(action, location.href), {
+        method: 'POST',
+        body,
+        headers: { 'X-Galaxy-Action': '1', 'Accept': 'application/json' },
+      });
+      const result = await res.json();
+      if (result.redirect) {
+        location.assign(result.redirect);
+        return;
+      }
+
+      const errors = result.errors || {};
+      form.querySelectorAll('[data-error]').forEach((el) => {
+        const field = el.getAttribute('data-error');
+        el.textContent = field ? errors[field] || '' : result.error || '';
+      });
+      if (result.ok) form.reset();
+
+      form.dispatchEvent(new CustomEvent('galaxy:action', { bubbles: true, detail: result }));
+    } catch (err) {
+      form.removeAttribute('data-enhance');
+      form.requestSubmit(submitter);
+    } finally {
+      form.removeAttribute('aria-busy');
+    }
+  });
+})();`
+
+// InjectClient adds ClientScript to pages containing an enhanced form.
+func InjectClient(html string) string {
+	if !strings.Contains(html, "data-enhance") || strings.Contains(html, "__galaxyActions") {
+		return html
+	}
+
+	tag := "<script>" + ClientScript + "</script>"
+	if strings.Contains(html, "</body>") {
+		return strings.Replace(html, "</body>", tag+"\n</body>", 1)
+	}
+	return html + tag
+}
+
+// StreamingClient returns a chunk transform for streamed pages. It adds the
+// client script before </body> once any chunk has had an enhanced form.
+func StreamingClient() func(string) string {
+	enhanced := false
+	return func(chunk string) string {
+		enhanced = enhanced || strings.Contains(chunk, "data-enhance")
+		if !enhanced || !strings.Contains(chunk, "</body>") {
+			return chunk
+		}
+		return strings.Replace(chunk, "</body>", "<script>"+ClientScript+"</script>\n</body>", 1)
+	}
+}
@@ -0,0 +1,169 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plugin"
+	"strings"
+	"time"
+)
+
+// Compiler builds .actions.go files into plugins for the dev server.
+type Compiler struct {
+	CacheDir   string
+	BaseDir    string
+	ModuleName string
+	cache      map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	set     Set
+	modTime time.Time
+}
+
+func NewCompiler(baseDir, cacheDir string) *Compiler {
+	return &Compiler{
+		CacheDir:   cacheDir,
+		BaseDir:    baseDir,
+		ModuleName: detectModuleName(baseDir),
+		cache:      make(map[string]*cacheEntry),
+	}
+}
+
+func detectModuleName(baseDir string) string {
+	content, err := os.ReadFile(filepath.Join(baseDir, "go.mod"))
+	if err != nil {
+		return filepath.Base(baseDir)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "module"))
+		}
+	}
+
+	return filepath.Base(baseDir)
+}
+
+func (c *Compiler) Load(filePath string) (Set, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry, ok := c.cache[filePath]; ok && entry.modTime.Equal(info.ModTime()) {
+		return entry.set, nil
+	}
+
+	sigs, err := ParseFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("parse actions: %w", err)
+	}
+	if len(sigs) == 0 {
+		return nil, fmt.Errorf("no actions found in %s", filePath)
+	}
+
+	soPath, err := c.compile(filePath, sigs)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := LoadPlugin(soPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache[filePath] = &cacheEntry{set: set, modTime: info.ModTime()}
+	return set, nil
+}
+
+func (c *Compiler) compile(filePath string, sigs []Signature) (string, error) {
+	if err := os.MkdirAll(c.CacheDir, 0755); err != nil {
+		return "", err
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(filePath)))[:8]
+	soPath := filepath.Join(c.CacheDir, fmt.Sprintf("actions-%s.so", hash))
+
+	// Each file gets its own package, so pages in one directory may declare
+	// the same actions.
+	pkgName := PackageAlias(c.BaseDir, filePath)
+	cacheSourceDir := filepath.Join(c.CacheDir, "src", pkgName)
+	if err := os.MkdirAll(cacheSourceDir, 0755); err != nil {
+		return "", err
+	}
+
+	source, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	// Strip build tags so pages hidden from the project build still compile here
+	var lines []string
+	for _, line := range strings.Split(string(source), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "//go:build") && !strings.HasPrefix(trimmed, "// +build") {
+			lines = append(lines, line)
+		}
+	}
+	source = []byte(strings.Join(lines, "\n"))
+
+	if err := os.WriteFile(filepath.Join(cacheSourceDir, filepath.Base(filePath)), source, 0644); err != nil {
+		return "", err
+	}
+
+	importPath := filepath.ToSlash(filepath.Join(c.ModuleName, c.CacheDir, "src", pkgName))
+
+	var entries strings.Builder
+	for _, sig := range sigs {
+		entries.WriteString(fmt.Sprintf("\t%q: %s,\n", sig.Name, sig.Adapter(pkgName)))
+	}
+
+	pluginSrc := fmt.Sprintf(`package main
+
+import (
+	"github.com/cameron-webmatter/galaxy/pkg/actions"
+	%s "%s"
+)
+
+var Actions = actions.Set{
+%s}
+`, pkgName, importPath, entries.String())
+
+	pluginPath := filepath.Join(c.CacheDir, fmt.Sprintf("actions-%s.go", hash))
+	if err := os.WriteFile(pluginPath, []byte(pluginSrc), 0644); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("go", "build", "-buildmode=plugin", "-o", soPath, pluginPath)
+	cmd.Dir = c.BaseDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("compile actions plugin: %w\n%s", err, string(output))
+	}
+
+	return soPath, nil
+}
+
+func LoadPlugin(pluginPath string) (Set, error) {
+	p, err := plugin.Open(pluginPath)
+	if err != nil {
+		return nil, fmt.Errorf("open plugin: %w", err)
+	}
+
+	sym, err := p.Lookup("Actions")
+	if err != nil {
+		return nil, fmt.Errorf("lookup Actions: %w", err)
+	}
+
+	set, ok := sym.(*Set)
+	if !ok {
+		return nil, fmt.Errorf("invalid Actions type: %T", sym)
+	}
+
+	return *set, nil
+}
@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Signature describes an exported action in a .actions.go file. Plain
+// actions have the form func(*endpoints.Context) (Out, error); typed actions
+// take a bound input as well: func(*endpoints.Context, In) (Out, error).
+type Signature struct {
+	Func  string
+	Name  string
+	Typed bool
+}
+
+func ParseFile(filePath string) ([]Signature, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSource(filePath, src)
+}
+
+func ParseSource(filename string, src []byte) ([]Signature, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var sigs []Signature
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !fn.Name.IsExported() || fn.Type.TypeParams != nil {
+			continue
+		}
+
+		params := fn.Type.Params.NumFields()
+		results := fn.Type.Results.NumFields()
+		if results != 2 || (params != 1 && params != 2) {
+			continue
+		}
+
+		sigs = append(sigs, Signature{
+			Func:  fn.Name.Name,
+			Name:  Name(fn.Name.Name),
+			Typed: params == 2,
+		})
+	}
+
+	return sigs, nil
+}
+
+// Names returns the template names of sigs.
+func Names(sigs []Signature) []string {
+	names := make([]string, len(sigs))
+	for i, sig := range sigs {
+		names[i] = sig.Name
+	}
+	return names
+}
+
+// Adapter returns the Go expression wrapping sig for use in an actions.Set,
+// e.g. actions.Typed(pkg.Subscribe).
+func (sig Signature) Adapter(pkg string) string {
+	if sig.Typed {
+		return "actions.Typed(" + pkg + "." + sig.Func + ")"
+	}
+	return "actions.Plain(" + pkg + "." + sig.Func + ")"
+}
+
+var nonIdent = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// PackageAlias names the package the .actions.go file at path is copied
+// into. Pages sharing a directory may declare the same actions, and
+// directories such as blog and api/blog share a name, so the alias is made
+// from the file's path under baseDir and ends in a short hash of it.
+func PackageAlias(baseDir, path string) string {
+	relPath, err := filepath.Rel(baseDir, path)
+	if err != nil {
+		relPath = path
+	}
+	relPath = filepath.ToSlash(relPath)
+	name := nonIdent.ReplaceAllString(strings.TrimSuffix(relPath, ".actions.go"), "_")
+	sum := sha256.Sum256([]byte(relPath))
+	return "actions" + strings.ToLower(strings.Trim(name, "_")) + "_" + hex.EncodeToString(sum[:3])
+}
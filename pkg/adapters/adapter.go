@@ -1,6 +1,13 @@
 package adapters
 
-import "github.com/cameron-webmatter/galaxy/pkg/config"
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
 
 type Adapter interface {
 	Name() string
@@ -8,7 +15,10 @@ type Adapter interface {
 }
 
 type BuildConfig struct {
-	Config    *config.Config
+	Config *config.Config
+	// RootDir is the project directory, where platform config files such
+	// as netlify.toml live.
+	RootDir   string
 	ServerDir string
 	OutDir    string
 	PagesDir  string
@@ -17,7 +27,59 @@ type BuildConfig struct {
 }
 
 type RouteInfo struct {
-	Pattern    string
-	FilePath   string
-	IsEndpoint bool
+	Pattern     string
+	FilePath    string
+	IsEndpoint  bool
+	ActionsFile string
+	// Prerendered pages were rendered to static files at build time and
+	// are not served by the server, unless they are Incremental: served
+	// from the prerendered file and regenerated once their cache TTL ends.
+	Prerendered bool
+	Incremental bool
+}
+
+// DynamicRoutes returns the routes the server handles, in match order.
+func (c *BuildConfig) DynamicRoutes() []RouteInfo {
+	var routes []RouteInfo
+	for _, r := range c.Routes {
+		if !r.Prerendered {
+			routes = append(routes, r)
+		}
+	}
+	return routes
+}
+
+// Compile builds the server generated in serverDir into output, with env
+// added to the go tool's environment, such as GOOS and GOARCH for the
+// platform's runtime.
+func Compile(serverDir, output string, env ...string) error {
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+	output, err := filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("go", "build", "-trimpath", "-o", output, ".")
+	cmd.Dir = serverDir
+	cmd.Env = append(os.Environ(), env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("go build: %w\n%s", err, out)
+	}
+	return nil
+}
+
+// StringOption returns the [adapter.config] setting key, or def when it is
+// not set.
+func StringOption(cfg *config.Config, key, def string) (string, error) {
+	v, ok := cfg.Adapter.Config[key]
+	if !ok {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("adapter.config.%s: expected a string", key)
+	}
+	return s, nil
 }
@@ -0,0 +1,57 @@
+// Package adaptertest writes stand-in server modules for adapter tests, in
+// place of the server a build generates.
+package adaptertest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// PathServer is a server main.go answering every request with "dynamic "
+// and its path.
+const PathServer = `package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/serve"
+)
+
+func main() {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("dynamic " + r.URL.Path))
+	})
+	if err := serve.Run(config.ServerConfig{}, h, nil); err != nil {
+		os.Exit(1)
+	}
+}
+`
+
+// WriteServer writes files, keyed by slash-separated path, into serverDir
+// with a go.mod that builds against this checkout of Galaxy, then tidies it.
+func WriteServer(t testing.TB, serverDir string, files map[string]string) {
+	t.Helper()
+
+	_, file, _, _ := runtime.Caller(0)
+	root := filepath.Join(filepath.Dir(file), "..", "..", "..")
+	files["go.mod"] = "module galaxy-server\n\ngo 1.24\n\nrequire github.com/cameron-webmatter/galaxy v0.0.0\n\nreplace github.com/cameron-webmatter/galaxy => " + root + "\n"
+
+	for name, content := range files {
+		path := filepath.Join(serverDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tidy := exec.Command("go", "mod", "tidy")
+	tidy.Dir = serverDir
+	if out, err := tidy.CombinedOutput(); err != nil {
+		t.Fatalf("go mod tidy failed: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,145 @@
+// Package awslambda deploys Galaxy sites to AWS Lambda: the server is
+// compiled into a custom runtime function that serves API Gateway and
+// function URL events, and static files are staged separately for S3.
+package awslambda
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/codegen"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+// FunctionZip is the deployment package written to OutDir/lambda.
+const FunctionZip = "function.zip"
+
+// bootstrap is the executable the provided.al2023 runtime starts.
+const bootstrap = "bootstrap"
+
+type LambdaAdapter struct{}
+
+func New() *LambdaAdapter {
+	return &LambdaAdapter{}
+}
+
+func init() {
+	adapters.Register(New())
+}
+
+func (a *LambdaAdapter) Name() string {
+	return string(config.AdapterAWSLambda)
+}
+
+func (a *LambdaAdapter) Capabilities() adapters.Capabilities {
+	return adapters.Capabilities{Static: true, SSR: true}
+}
+
+// Build writes the deploy to OutDir/lambda: function.zip for the
+// provided.al2023 runtime and static/ for an S3 bucket. The function
+// serves every route, so static/ only takes load off it.
+func (a *LambdaAdapter) Build(cfg *adapters.BuildConfig) error {
+	arch, err := adapters.StringOption(cfg.Config, "arch", "amd64")
+	if err != nil {
+		return err
+	}
+	if arch != "amd64" && arch != "arm64" {
+		return fmt.Errorf("adapter.config.arch: Lambda runs amd64 or arm64, got %q", arch)
+	}
+
+	outDir := filepath.Join(cfg.OutDir, "lambda")
+	if err := os.RemoveAll(outDir); err != nil {
+		return err
+	}
+	if err := codegen.StageStatic(cfg.OutDir, cfg.ServerDir, filepath.Join(outDir, "static")); err != nil {
+		return fmt.Errorf("stage static files: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "galaxy-lambda-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	binary := filepath.Join(tmpDir, bootstrap)
+	if err := adapters.Compile(cfg.ServerDir, binary, "GOOS=linux", "GOARCH="+arch, "CGO_ENABLED=0"); err != nil {
+		return fmt.Errorf("compile function: %w", err)
+	}
+
+	var site string
+	if cfg.Config.Output.Assets == config.AssetsDisk {
+		site = cfg.ServerDir
+	}
+	if err := writeZip(filepath.Join(outDir, FunctionZip), binary, site); err != nil {
+		return fmt.Errorf("write %s: %w", FunctionZip, err)
+	}
+	return nil
+}
+
+// zipTime is the modification time of every file in the zip, so rebuilds
+// from the same sources produce the same package and code hash.
+var zipTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// writeZip writes the deployment package: the binary as bootstrap and,
+// when serverDir is set, the site files it reads from /var/task.
+func writeZip(path, binary, serverDir string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	if err := addFile(zw, bootstrap, binary, 0755); err != nil {
+		return err
+	}
+	if serverDir != "" {
+		names, err := codegen.SiteEntries(serverDir)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			err := filepath.WalkDir(filepath.Join(serverDir, name), func(p string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(serverDir, p)
+				if err != nil {
+					return err
+				}
+				return addFile(zw, filepath.ToSlash(rel), p, 0644)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func addFile(zw *zip.Writer, name, src string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: zipTime}
+	hdr.SetMode(mode)
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
@@ -0,0 +1,160 @@
+package awslambda
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/adapters/adaptertest"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/lambda"
+	"github.com/cameron-webmatter/galaxy/pkg/lambda/lambdatest"
+)
+
+// echoServer answers each request with its method, URI, X-Test headers,
+// cookies and body, the way the generated server runs under serve.Run.
+const echoServer = `package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/serve"
+)
+
+func main() {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+		fmt.Fprintf(w, "%s %s %s %s %s", r.Method, r.URL.RequestURI(), r.Header.Values("X-Test"), r.Header.Get("Cookie"), body)
+	})
+	if err := serve.Run(config.ServerConfig{}, h, nil); err != nil {
+		os.Exit(1)
+	}
+}
+`
+
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a function")
+	}
+
+	project := t.TempDir()
+	outDir := filepath.Join(project, "dist")
+	serverDir := filepath.Join(outDir, "server")
+	os.MkdirAll(filepath.Join(outDir, "public"), 0755)
+	os.WriteFile(filepath.Join(outDir, "index.html"), []byte("home"), 0644)
+	os.WriteFile(filepath.Join(outDir, "public", "robots.txt"), []byte("robots"), 0644)
+	adaptertest.WriteServer(t, serverDir, map[string]string{
+		"main.go":                 echoServer,
+		"_assets/app-1a2b3c4d.js": "app",
+		"static/index.html":       "home",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Output.Assets = config.AssetsDisk
+	build := &adapters.BuildConfig{
+		Config:    cfg,
+		RootDir:   project,
+		ServerDir: serverDir,
+		OutDir:    outDir,
+		Routes:    []adapters.RouteInfo{{Pattern: "/", Prerendered: true}, {Pattern: "/blog/[slug]"}},
+	}
+	if err := New().Build(build); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	staticDir := filepath.Join(outDir, "lambda", "static")
+	for name, want := range map[string]string{
+		"index.html":              "home",
+		"robots.txt":              "robots",
+		"_assets/app-1a2b3c4d.js": "app",
+	} {
+		data, err := os.ReadFile(filepath.Join(staticDir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("Expected %s to hold %q, got %q (%v)", name, want, data, err)
+		}
+	}
+
+	zr, err := zip.OpenReader(filepath.Join(outDir, "lambda", FunctionZip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+	if f := entries[bootstrap]; f == nil || f.Mode().Perm() != 0755 {
+		t.Fatalf("Expected an executable bootstrap, got %v", entries)
+	}
+	for _, name := range []string{"static/index.html", "_assets/app-1a2b3c4d.js"} {
+		if entries[name] == nil {
+			t.Errorf("Expected %s in the package with disk assets", name)
+		}
+	}
+	if entries["main.go"] != nil || entries["go.mod"] != nil {
+		t.Error("Expected the server's sources to stay out of the package")
+	}
+
+	cfg.Adapter.Config = map[string]interface{}{"arch": "riscv64"}
+	if err := New().Build(build); err == nil || !strings.Contains(err.Error(), "arch") {
+		t.Errorf("Expected an unsupported architecture to fail, got %v", err)
+	}
+
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("function binary targets linux/amd64")
+	}
+	function := filepath.Join(t.TempDir(), bootstrap)
+	in, _ := entries[bootstrap].Open()
+	data, _ := io.ReadAll(in)
+	in.Close()
+	os.WriteFile(function, data, 0755)
+	invokeFunction(t, function)
+}
+
+// invokeFunction runs the function with a function URL event and an API
+// Gateway event.
+func invokeFunction(t *testing.T, function string) {
+	t.Helper()
+
+	responses := lambdatest.Invoke(t, function,
+		`{"version":"2.0","rawPath":"/blog/hello","rawQueryString":"a=1&a=2","cookies":["s=1","t=2"],`+
+			`"headers":{"x-test":"yes"},"body":"cGF5bG9hZA==","isBase64Encoded":true,`+
+			`"requestContext":{"domainName":"abc.lambda-url.us-east-1.on.aws","http":{"method":"POST","sourceIp":"203.0.113.9"}}}`,
+		`{"httpMethod":"GET","path":"/blog/rest","multiValueHeaders":{"X-Test":["one","two"]},"multiValueQueryStringParameters":{"q":["x"]}}`,
+	)
+
+	var v2 lambda.V2Response
+	if err := json.Unmarshal(responses[0], &v2); err != nil {
+		t.Fatalf("Expected a function URL response, got %q", responses[0])
+	}
+	if want := "POST /blog/hello?a=1&a=2 [yes] s=1; t=2 payload"; v2.StatusCode != http.StatusOK || v2.Body != want {
+		t.Errorf("Expected %q, got %+v", want, v2)
+	}
+	if len(v2.Cookies) != 2 {
+		t.Errorf("Expected both cookies, got %v", v2.Cookies)
+	}
+
+	var v1 lambda.Response
+	if err := json.Unmarshal(responses[1], &v1); err != nil {
+		t.Fatalf("Expected an API Gateway response, got %q", responses[1])
+	}
+	if want := "GET /blog/rest?q=x [one two]  "; v1.Body != want {
+		t.Errorf("Expected %q, got %q", want, v1.Body)
+	}
+	if len(v1.MultiValueHeaders["Set-Cookie"]) != 2 {
+		t.Errorf("Expected both cookies, got %v", v1.MultiValueHeaders)
+	}
+}
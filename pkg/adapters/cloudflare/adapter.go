@@ -0,0 +1,270 @@
+// Package cloudflare deploys Galaxy sites to Cloudflare Pages: static files
+// go to the output directory and the server, compiled to WebAssembly, runs
+// in an advanced-mode _worker.js that serves every other route.
+package cloudflare
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/internal/wasm"
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
+	"github.com/cameron-webmatter/galaxy/pkg/codegen"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/serve"
+)
+
+//go:embed shim
+var shim embed.FS
+
+// generatedMarker starts the files the adapter writes. A wrangler.toml
+// without it belongs to the project and is left alone.
+const generatedMarker = "# Generated by galaxy build."
+
+// maxRules is how many rules Pages accepts in _routes.json and _headers.
+const maxRules = 100
+
+// compatibilityDate pins the Workers runtime the shim was written for.
+const compatibilityDate = "2024-09-23"
+
+type CloudflareAdapter struct{}
+
+func New() *CloudflareAdapter {
+	return &CloudflareAdapter{}
+}
+
+func init() {
+	adapters.Register(New())
+}
+
+func (a *CloudflareAdapter) Name() string {
+	return "cloudflare"
+}
+
+func (a *CloudflareAdapter) Capabilities() adapters.Capabilities {
+	return adapters.Capabilities{Static: true, StaticOutput: true, SSR: true, Edge: true}
+}
+
+// Emulate sets the client address header Cloudflare adds to requests.
+func (a *CloudflareAdapter) Emulate(cfg *config.Config, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("CF-Connecting-IP") == "" {
+			r.Header.Set("CF-Connecting-IP", adapters.ClientIP(r))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Routes is _routes.json, which selects the requests that invoke the
+// worker. Exclude wins over Include.
+type Routes struct {
+	Version int      `json:"version"`
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+// Build writes the deploy to OutDir/cloudflare, with the worker under
+// _worker.js/. wrangler.toml in RootDir points Pages at it.
+func (a *CloudflareAdapter) Build(cfg *adapters.BuildConfig) error {
+	outDir := filepath.Join(cfg.OutDir, "cloudflare")
+	if err := os.RemoveAll(outDir); err != nil {
+		return err
+	}
+
+	if err := codegen.StageStatic(cfg.OutDir, cfg.ServerDir, outDir); err != nil {
+		return fmt.Errorf("stage static files: %w", err)
+	}
+
+	routes := cfg.DynamicRoutes()
+	if len(routes) > 0 {
+		if err := writeWorker(cfg.ServerDir, filepath.Join(outDir, "_worker.js")); err != nil {
+			return fmt.Errorf("build worker: %w", err)
+		}
+		data, err := json.MarshalIndent(routesJSON(cfg.Config, routes), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outDir, "_routes.json"), append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+
+	headers, err := headers(outDir)
+	if err != nil {
+		return err
+	}
+	if err := writeRules(filepath.Join(outDir, "_headers"), headers); err != nil {
+		return err
+	}
+	if err := writeRules(filepath.Join(outDir, "_redirects"), generatedMarker+"\n"); err != nil {
+		return err
+	}
+
+	return writeWranglerToml(cfg, outDir)
+}
+
+// writeWorker compiles the server to js/wasm next to the shim that hands
+// it fetch events.
+func writeWorker(serverDir, workerDir string) error {
+	if err := os.MkdirAll(workerDir, 0755); err != nil {
+		return err
+	}
+
+	compiler := &wasm.Compiler{}
+	if err := compiler.Build(serverDir, filepath.Join(workerDir, "galaxy.wasm")); err != nil {
+		return err
+	}
+
+	execJS, err := wasm.ExecJS()
+	if err != nil {
+		return fmt.Errorf("wasm_exec.js: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(workerDir, "wasm_exec.js"), execJS, 0644); err != nil {
+		return err
+	}
+
+	return fs.WalkDir(shim, "shim", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := shim.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(workerDir, d.Name()), data, 0644)
+	})
+}
+
+var (
+	catchAllParam = regexp.MustCompile(`\[\.\.\.\w+\].*`)
+	dynamicParam  = regexp.MustCompile(`\[\w+\]`)
+)
+
+// pagesPath converts a route pattern to a _routes.json rule, where *
+// matches anything, slashes included.
+func pagesPath(pattern string) string {
+	pattern = catchAllParam.ReplaceAllString(pattern, "*")
+	return dynamicParam.ReplaceAllString(pattern, "*")
+}
+
+// routesJSON sends the server's routes to the worker and keeps assets on
+// Pages. Past Pages' rule limit, every request goes to the worker, which
+// falls back to Pages for files it does not serve.
+func routesJSON(cfg *config.Config, routes []adapters.RouteInfo) *Routes {
+	out := &Routes{Version: 1, Exclude: []string{"/_assets/*", "/wasm_exec.js"}}
+
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			out.Include = append(out.Include, path)
+		}
+	}
+	if cfg.Cache.Enabled {
+		add(cache.RevalidatePath)
+	}
+	if cfg.Telemetry.Metrics.Enabled {
+		add(cfg.Telemetry.Metrics.Path)
+	}
+	for _, r := range routes {
+		add(pagesPath(r.Pattern))
+	}
+
+	if len(out.Include)+len(out.Exclude) > maxRules {
+		out.Include = []string{"/*"}
+	}
+	return out
+}
+
+// headers marks hashed assets as immutable, with one rule for the whole
+// directory when every asset is hashed, which keeps under Pages' limit.
+func headers(outDir string) (string, error) {
+	var b strings.Builder
+	b.WriteString(generatedMarker + "\n")
+
+	var hashed []string
+	all := true
+	err := filepath.WalkDir(filepath.Join(outDir, "_assets"), func(path string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return filepath.SkipAll
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if !serve.Hashed(path) {
+			all = false
+			return nil
+		}
+		rel, err := filepath.Rel(outDir, path)
+		if err != nil {
+			return err
+		}
+		hashed = append(hashed, "/"+filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if all && len(hashed) > 0 {
+		hashed = []string{"/_assets/*"}
+	}
+	if len(hashed) > maxRules {
+		hashed = hashed[:maxRules]
+	}
+	for _, path := range hashed {
+		fmt.Fprintf(&b, "%s\n  Cache-Control: public, max-age=31536000, immutable\n", path)
+	}
+	return b.String(), nil
+}
+
+// writeRules writes generated rules to a Pages rules file after those the
+// project keeps in its own copy from public/, which take precedence.
+func writeRules(path, rules string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+		existing = append(existing, '\n')
+	}
+	return os.WriteFile(path, append(existing, rules...), 0644)
+}
+
+// writeWranglerToml points Pages at the output directory, unless the
+// project keeps its own wrangler.toml.
+func writeWranglerToml(cfg *adapters.BuildConfig, outDir string) error {
+	rootDir := cfg.RootDir
+	if rootDir == "" {
+		rootDir = filepath.Dir(cfg.OutDir)
+	}
+	path := filepath.Join(rootDir, "wrangler.toml")
+
+	rel, err := filepath.Rel(rootDir, outDir)
+	if err != nil {
+		rel = outDir
+	}
+	rel = filepath.ToSlash(rel)
+
+	existing, err := os.ReadFile(path)
+	if err == nil && !bytes.HasPrefix(existing, []byte(generatedMarker)) {
+		fmt.Printf("⚠ Keeping existing %s; set pages_build_output_dir = %q in it\n", path, rel)
+		return nil
+	}
+
+	content := fmt.Sprintf(`%s Delete this line to keep your own changes.
+name = %q
+compatibility_date = %q
+pages_build_output_dir = %q
+`, generatedMarker, filepath.Base(rootDir), compatibilityDate, rel)
+	return os.WriteFile(path, []byte(content), 0644)
+}
@@ -0,0 +1,249 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/adapters/adaptertest"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+func TestPagesPath(t *testing.T) {
+	tests := map[string]string{
+		"/":                     "/",
+		"/blog/[slug]":          "/blog/*",
+		"/docs/[...rest]":       "/docs/*",
+		"/[lang]/posts/[id]":    "/*/posts/*",
+		"/files/[...path]/edit": "/files/*",
+	}
+	for pattern, want := range tests {
+		if got := pagesPath(pattern); got != want {
+			t.Errorf("Expected %s for %s, got %s", want, pattern, got)
+		}
+	}
+}
+
+func TestRoutesJSON(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Cache.Enabled = true
+	routes := []adapters.RouteInfo{{Pattern: "/blog/[slug]"}, {Pattern: "/blog/[id]"}, {Pattern: "/api/data", IsEndpoint: true}}
+
+	got := routesJSON(cfg, routes)
+	want := []string{"/_galaxy/revalidate", "/blog/*", "/api/data"}
+	if strings.Join(got.Include, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected include %v, got %v", want, got.Include)
+	}
+	if got.Version != 1 || len(got.Exclude) == 0 || got.Exclude[0] != "/_assets/*" {
+		t.Errorf("Expected assets excluded, got %+v", got)
+	}
+
+	routes = nil
+	for i := 0; i < maxRules; i++ {
+		routes = append(routes, adapters.RouteInfo{Pattern: "/page" + strings.Repeat("x", i)})
+	}
+	if got := routesJSON(cfg, routes); len(got.Include) != 1 || got.Include[0] != "/*" {
+		t.Errorf("Expected a catch-all past the rule limit, got %d rules", len(got.Include))
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	outDir := t.TempDir()
+	os.MkdirAll(filepath.Join(outDir, "_assets"), 0755)
+	os.WriteFile(filepath.Join(outDir, "_assets", "styles-1a2b3c4d.css"), []byte(""), 0644)
+
+	got, err := headers(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "/_assets/*\n  Cache-Control: public, max-age=31536000, immutable") {
+		t.Errorf("Expected one rule for all-hashed assets, got:\n%s", got)
+	}
+
+	os.WriteFile(filepath.Join(outDir, "_assets", "plain.js"), []byte(""), 0644)
+	got, _ = headers(outDir)
+	if !strings.Contains(got, "/_assets/styles-1a2b3c4d.css\n") || strings.Contains(got, "plain.js") || strings.Contains(got, "/_assets/*") {
+		t.Errorf("Expected a rule per hashed asset, got:\n%s", got)
+	}
+}
+
+func TestWriteRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "_redirects")
+	os.WriteFile(path, []byte("/old /new 301"), 0644)
+
+	if err := writeRules(path, generatedMarker+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "/old /new 301\n"+generatedMarker+"\n" {
+		t.Errorf("Expected the project's rules first, got %q", data)
+	}
+}
+
+// echoServer answers each request with its method, URI, X-Test header, the
+// GREETING variable and body, the way the generated server runs under
+// serve.Run.
+const echoServer = `package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/serve"
+)
+
+func main() {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+		fmt.Fprintf(w, "%s %s %s %s %s", r.Method, r.URL.RequestURI(), r.Header.Get("X-Test"), os.Getenv("GREETING"), body)
+	})
+	if err := serve.Run(config.ServerConfig{}, h, nil); err != nil {
+		os.Exit(1)
+	}
+}
+`
+
+// fetchScript stands in for the Workers runtime: it loads the worker the
+// way Pages does and sends it fetch events, with an ASSETS binding that
+// answers for the static files.
+const fetchScript = `
+import { readFileSync } from "node:fs";
+import { createHandler } from "./bridge.js";
+
+const wasm = await WebAssembly.compile(readFileSync(new URL("./galaxy.wasm", import.meta.url)));
+const worker = createHandler(wasm);
+const env = {
+  GREETING: "hello",
+  ASSETS: { fetch: async () => new Response("from pages") },
+};
+
+const results = [];
+for (const request of [
+  new Request("https://site.pages.dev/blog/post?x=1", { method: "POST", body: "payload", headers: { "x-test": "yes" } }),
+  new Request("https://site.pages.dev/robots.txt"),
+]) {
+  const res = await worker.fetch(request, env);
+  results.push({ status: res.status, body: await res.text(), cookies: res.headers.getSetCookie() });
+}
+console.log("RESULT " + JSON.stringify(results));
+process.exit(0);
+`
+
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a worker")
+	}
+
+	project := t.TempDir()
+	outDir := filepath.Join(project, "dist")
+	serverDir := filepath.Join(outDir, "server")
+	for name, content := range map[string]string{
+		"index.html":         "home",
+		"public/robots.txt":  "robots",
+		"public/_redirects":  "/old /new 301\n",
+		"public/_headers":    "/robots.txt\n  X-Robots: yes\n",
+		"about/index.html":   "about",
+		"_build/ignored.txt": "ignored",
+	} {
+		path := filepath.Join(outDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	adaptertest.WriteServer(t, serverDir, map[string]string{
+		"main.go":                 echoServer,
+		"_assets/app-1a2b3c4d.js": "app",
+	})
+
+	build := &adapters.BuildConfig{
+		Config:    config.DefaultConfig(),
+		RootDir:   project,
+		ServerDir: serverDir,
+		OutDir:    outDir,
+		Routes: []adapters.RouteInfo{
+			{Pattern: "/about", Prerendered: true},
+			{Pattern: "/blog/[slug]"},
+			{Pattern: "/", Prerendered: true},
+		},
+	}
+	if err := New().Build(build); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	pagesDir := filepath.Join(outDir, "cloudflare")
+	for name, want := range map[string]string{
+		"index.html":              "home",
+		"about/index.html":        "about",
+		"robots.txt":              "robots",
+		"_assets/app-1a2b3c4d.js": "app",
+		"_redirects":              "/old /new 301\n" + generatedMarker,
+		"_headers":                "X-Robots: yes\n" + generatedMarker,
+		"_routes.json":            `"/blog/*"`,
+		"_worker.js/index.js":     `import wasm from "./galaxy.wasm"`,
+		"_worker.js/bridge.js":    "galaxyFetch",
+		"_worker.js/wasm_exec.js": "class",
+	} {
+		data, err := os.ReadFile(filepath.Join(pagesDir, name))
+		if err != nil || !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s to contain %q, got %q (%v)", name, want, data, err)
+		}
+	}
+	for _, name := range []string{"server", "_build", "main.go"} {
+		if _, err := os.Stat(filepath.Join(pagesDir, name)); err == nil {
+			t.Errorf("Expected %s to stay out of the output", name)
+		}
+	}
+	toml, _ := os.ReadFile(filepath.Join(project, "wrangler.toml"))
+	if !strings.Contains(string(toml), `pages_build_output_dir = "dist/cloudflare"`) {
+		t.Errorf("Unexpected wrangler.toml:\n%s", toml)
+	}
+
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+	workerDir := filepath.Join(pagesDir, "_worker.js")
+	os.WriteFile(filepath.Join(workerDir, "package.json"), []byte(`{"type": "module"}`), 0644)
+	os.WriteFile(filepath.Join(workerDir, "fetch.js"), []byte(fetchScript), 0644)
+
+	cmd := exec.Command(node, "fetch.js")
+	cmd.Dir = workerDir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("worker failed: %v\n%s", err, out)
+	}
+	_, result, ok := strings.Cut(string(out), "RESULT ")
+	if !ok {
+		t.Fatalf("Expected results from the worker, got:\n%s", out)
+	}
+
+	var results []struct {
+		Status  int
+		Body    string
+		Cookies []string
+	}
+	if err := json.Unmarshal([]byte(result), &results); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != 200 || results[0].Body != "POST /blog/post?x=1 yes hello payload" {
+		t.Errorf("Unexpected response: %+v", results[0])
+	}
+	if len(results[0].Cookies) != 2 {
+		t.Errorf("Expected both cookies, got %v", results[0].Cookies)
+	}
+	if results[1].Body != "from pages" {
+		t.Errorf("Expected files the server does not serve to come from Pages, got %+v", results[1])
+	}
+}
@@ -0,0 +1,60 @@
+// Bridges fetch events to the Galaxy server compiled to WebAssembly, which
+// registers globalThis.galaxyFetch when it starts.
+import "./wasm_exec.js";
+
+const nullBodyStatus = [101, 204, 205, 304];
+
+function start(wasm, env) {
+  const go = new Go();
+  go.argv = ["galaxy"];
+  go.env = {};
+  for (const [key, value] of Object.entries(env || {})) {
+    if (typeof value === "string") {
+      go.env[key] = value;
+    }
+  }
+  return WebAssembly.instantiate(wasm, go.importObject).then((instance) => {
+    go.run(instance);
+    if (typeof globalThis.galaxyFetch !== "function") {
+      throw new Error("galaxy: server did not start");
+    }
+  });
+}
+
+export function createHandler(wasm) {
+  let ready;
+
+  return {
+    async fetch(request, env) {
+      ready ??= start(wasm, env).catch((err) => {
+        ready = undefined;
+        throw err;
+      });
+      await ready;
+
+      const hasBody = request.method !== "GET" && request.method !== "HEAD";
+      const res = await globalThis.galaxyFetch({
+        method: request.method,
+        url: request.url,
+        headers: [...request.headers],
+        body: hasBody ? new Uint8Array(await request.arrayBuffer()) : null,
+      });
+
+      // Files the server does not know, such as those added to the
+      // deploy by hand, still come from Pages.
+      if (res.status === 404 && !hasBody && env && env.ASSETS) {
+        const asset = await env.ASSETS.fetch(request);
+        if (asset.status !== 404) {
+          return asset;
+        }
+      }
+
+      const headers = new Headers();
+      for (const [name, value] of res.headers) {
+        headers.append(name, value);
+      }
+      const body = nullBodyStatus.includes(res.status) ? null : res.body;
+      return new Response(body, { status: res.status, headers });
+    },
+  };
+}
@@ -0,0 +1,6 @@
+// Cloudflare Pages worker for a Galaxy server compiled to WebAssembly.
+// Generated by galaxy build.
+import wasm from "./galaxy.wasm";
+import { createHandler } from "./bridge.js";
+
+export default createHandler(wasm);
@@ -0,0 +1,212 @@
+// Package netlify deploys Galaxy sites to Netlify: static files go to the
+// publish directory and the server is compiled into a Go Netlify Function
+// that serves every other route.
+package netlify
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
+	"github.com/cameron-webmatter/galaxy/pkg/codegen"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/serve"
+)
+
+// FunctionName is the name of the Netlify Function serving dynamic routes.
+const FunctionName = "galaxy"
+
+// generatedMarker starts the netlify.toml the adapter writes. A file
+// without it belongs to the project and is left alone.
+const generatedMarker = "# Generated by galaxy build."
+
+type NetlifyAdapter struct{}
+
+func New() *NetlifyAdapter {
+	return &NetlifyAdapter{}
+}
+
+func init() {
+	adapters.Register(New())
+}
+
+func (a *NetlifyAdapter) Name() string {
+	return "netlify"
+}
+
+func (a *NetlifyAdapter) Capabilities() adapters.Capabilities {
+	return adapters.Capabilities{Static: true, StaticOutput: true, SSR: true}
+}
+
+// Emulate sets the client address header Netlify adds to requests.
+func (a *NetlifyAdapter) Emulate(cfg *config.Config, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Nf-Client-Connection-Ip") == "" {
+			r.Header.Set("X-Nf-Client-Connection-Ip", adapters.ClientIP(r))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Build writes the deploy to OutDir/netlify: the publish directory under
+// publish/ with _redirects and _headers, and the function under
+// functions/. netlify.toml in RootDir points Netlify at both.
+func (a *NetlifyAdapter) Build(cfg *adapters.BuildConfig) error {
+	routes := cfg.DynamicRoutes()
+	if len(routes) > 0 && cfg.Config.Output.Assets == config.AssetsDisk {
+		return fmt.Errorf("netlify functions ship as a single binary; set output.assets to embed")
+	}
+
+	outDir := filepath.Join(cfg.OutDir, "netlify")
+	publishDir := filepath.Join(outDir, "publish")
+	functionsDir := filepath.Join(outDir, "functions")
+	if err := os.RemoveAll(outDir); err != nil {
+		return err
+	}
+
+	if err := codegen.StageStatic(cfg.OutDir, cfg.ServerDir, publishDir); err != nil {
+		return fmt.Errorf("stage static files: %w", err)
+	}
+
+	if len(routes) > 0 {
+		if err := adapters.Compile(cfg.ServerDir, filepath.Join(functionsDir, FunctionName), "GOOS=linux", "GOARCH=amd64", "CGO_ENABLED=0"); err != nil {
+			return fmt.Errorf("compile function: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(publishDir, "_redirects"), []byte(redirects(cfg.Config, routes)), 0644); err != nil {
+		return err
+	}
+
+	headers, err := headers(cfg.Config, publishDir)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(publishDir, "_headers"), []byte(headers), 0644); err != nil {
+		return err
+	}
+
+	return writeNetlifyToml(cfg, publishDir, functionsDir)
+}
+
+var (
+	catchAllParam = regexp.MustCompile(`\[\.\.\.\w+\]`)
+	dynamicParam  = regexp.MustCompile(`\[(\w+)\]`)
+)
+
+// netlifyPath converts a route pattern to Netlify's redirect syntax:
+// [slug] becomes :slug and [...rest] a splat.
+func netlifyPath(pattern string) string {
+	pattern = catchAllParam.ReplaceAllString(pattern, "*")
+	return dynamicParam.ReplaceAllString(pattern, ":$1")
+}
+
+// redirects rewrites the routes the server handles to the function, in
+// match order. Netlify serves an existing file before applying a rewrite,
+// so prerendered pages and assets still come from the CDN.
+func redirects(cfg *config.Config, routes []adapters.RouteInfo) string {
+	var b strings.Builder
+	b.WriteString(generatedMarker + "\n")
+	if len(routes) == 0 {
+		return b.String()
+	}
+
+	target := "/.netlify/functions/" + FunctionName
+	var paths []string
+	if cfg.Cache.Enabled {
+		paths = append(paths, cache.RevalidatePath)
+	}
+	if cfg.Telemetry.Metrics.Enabled {
+		paths = append(paths, cfg.Telemetry.Metrics.Path)
+	}
+	for _, r := range routes {
+		paths = append(paths, netlifyPath(r.Pattern))
+	}
+
+	for _, p := range paths {
+		fmt.Fprintf(&b, "%s  %s  200\n", p, target)
+	}
+	return b.String()
+}
+
+// headers marks hashed assets as immutable and gives prerendered pages
+// covered by a cache route rule its TTL on Netlify's CDN.
+func headers(cfg *config.Config, publishDir string) (string, error) {
+	var b strings.Builder
+	b.WriteString(generatedMarker + "\n")
+
+	err := filepath.WalkDir(filepath.Join(publishDir, "_assets"), func(path string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return filepath.SkipAll
+		}
+		if err != nil || d.IsDir() || !serve.Hashed(path) {
+			return err
+		}
+		rel, err := filepath.Rel(publishDir, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "/%s\n  Cache-Control: public, max-age=31536000, immutable\n", filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.Cache.Enabled {
+		for _, rule := range cfg.Cache.Routes {
+			if rule.TTL <= 0 {
+				continue
+			}
+			// Netlify's splat matches any depth, so * and ** both map to it.
+			path := strings.ReplaceAll(rule.Path, "**", "*")
+			fmt.Fprintf(&b, "%s\n  Cache-Control: public, max-age=0, must-revalidate\n", path)
+			fmt.Fprintf(&b, "  Netlify-CDN-Cache-Control: public, s-maxage=%d", rule.TTL)
+			if rule.StaleWhileRevalidate > 0 {
+				fmt.Fprintf(&b, ", stale-while-revalidate=%d", rule.StaleWhileRevalidate)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// writeNetlifyToml points Netlify at the publish and functions
+// directories, unless the project keeps its own netlify.toml.
+func writeNetlifyToml(cfg *adapters.BuildConfig, publishDir, functionsDir string) error {
+	rootDir := cfg.RootDir
+	if rootDir == "" {
+		rootDir = filepath.Dir(cfg.OutDir)
+	}
+	path := filepath.Join(rootDir, "netlify.toml")
+
+	existing, err := os.ReadFile(path)
+	if err == nil && !bytes.HasPrefix(existing, []byte(generatedMarker)) {
+		fmt.Printf("⚠ Keeping existing %s; set publish = %q and functions = %q in it\n", path, relTo(rootDir, publishDir), relTo(rootDir, functionsDir))
+		return nil
+	}
+
+	content := fmt.Sprintf(`%s Delete this line to keep your own changes.
+[build]
+  publish = %q
+
+[functions]
+  directory = %q
+`, generatedMarker, relTo(rootDir, publishDir), relTo(rootDir, functionsDir))
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+func relTo(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
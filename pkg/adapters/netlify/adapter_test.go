@@ -0,0 +1,192 @@
+package netlify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/adapters/adaptertest"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/lambda"
+	"github.com/cameron-webmatter/galaxy/pkg/lambda/lambdatest"
+)
+
+func TestNetlifyPath(t *testing.T) {
+	tests := map[string]string{
+		"/":                  "/",
+		"/blog/[slug]":       "/blog/:slug",
+		"/docs/[...rest]":    "/docs/*",
+		"/[lang]/posts/[id]": "/:lang/posts/:id",
+	}
+	for pattern, want := range tests {
+		if got := netlifyPath(pattern); got != want {
+			t.Errorf("Expected %s for %s, got %s", want, pattern, got)
+		}
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Cache.Enabled = true
+	routes := []adapters.RouteInfo{{Pattern: "/blog/[slug]"}, {Pattern: "/api/[...path]", IsEndpoint: true}}
+
+	got := redirects(cfg, routes)
+	want := generatedMarker + "\n" +
+		"/_galaxy/revalidate  /.netlify/functions/galaxy  200\n" +
+		"/blog/:slug  /.netlify/functions/galaxy  200\n" +
+		"/api/*  /.netlify/functions/galaxy  200\n"
+	if got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	if got := redirects(cfg, nil); got != generatedMarker+"\n" {
+		t.Errorf("Expected no rewrites for a static site, got:\n%s", got)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	publishDir := t.TempDir()
+	os.MkdirAll(filepath.Join(publishDir, "_assets"), 0755)
+	os.WriteFile(filepath.Join(publishDir, "_assets", "styles-1a2b3c4d.css"), []byte("body{}"), 0644)
+	os.WriteFile(filepath.Join(publishDir, "_assets", "plain.js"), []byte(""), 0644)
+
+	cfg := config.DefaultConfig()
+	cfg.Cache.Enabled = true
+	cfg.Cache.Routes = []config.CacheRoute{
+		{Path: "/blog/**", TTL: 60, StaleWhileRevalidate: 300},
+		{Path: "/account", TTL: 0},
+	}
+
+	got, err := headers(cfg, publishDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "/_assets/styles-1a2b3c4d.css\n  Cache-Control: public, max-age=31536000, immutable") {
+		t.Errorf("Expected hashed asset to be immutable, got:\n%s", got)
+	}
+	if strings.Contains(got, "plain.js") {
+		t.Errorf("Expected unhashed asset to be left alone, got:\n%s", got)
+	}
+	if !strings.Contains(got, "/blog/*\n  Cache-Control: public, max-age=0, must-revalidate\n  Netlify-CDN-Cache-Control: public, s-maxage=60, stale-while-revalidate=300") {
+		t.Errorf("Expected CDN caching for /blog, got:\n%s", got)
+	}
+	if strings.Contains(got, "/account") {
+		t.Errorf("Expected no headers for uncached route, got:\n%s", got)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a function")
+	}
+
+	project := t.TempDir()
+	outDir := filepath.Join(project, "dist")
+	serverDir := filepath.Join(outDir, "server")
+	os.MkdirAll(filepath.Join(outDir, "public"), 0755)
+	os.MkdirAll(filepath.Join(outDir, "about"), 0755)
+	os.WriteFile(filepath.Join(outDir, "index.html"), []byte("home"), 0644)
+	os.WriteFile(filepath.Join(outDir, "about", "index.html"), []byte("about"), 0644)
+	os.WriteFile(filepath.Join(outDir, "public", "robots.txt"), []byte("robots"), 0644)
+	adaptertest.WriteServer(t, serverDir, map[string]string{
+		"main.go":                 adaptertest.PathServer,
+		"_assets/app-1a2b3c4d.js": "app",
+	})
+
+	build := &adapters.BuildConfig{
+		Config:    config.DefaultConfig(),
+		RootDir:   project,
+		ServerDir: serverDir,
+		OutDir:    outDir,
+		Routes: []adapters.RouteInfo{
+			{Pattern: "/about", Prerendered: true},
+			{Pattern: "/blog/[slug]"},
+			{Pattern: "/", Prerendered: true},
+		},
+	}
+	if err := New().Build(build); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	publishDir := filepath.Join(outDir, "netlify", "publish")
+	for name, want := range map[string]string{
+		"index.html":              "home",
+		"about/index.html":        "about",
+		"robots.txt":              "robots",
+		"_assets/app-1a2b3c4d.js": "app",
+	} {
+		data, err := os.ReadFile(filepath.Join(publishDir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("Expected %s to hold %q, got %q (%v)", name, want, data, err)
+		}
+	}
+	for _, name := range []string{"server", "main.go", "netlify"} {
+		if _, err := os.Stat(filepath.Join(publishDir, name)); err == nil {
+			t.Errorf("Expected %s to stay out of the publish directory", name)
+		}
+	}
+
+	redirects, _ := os.ReadFile(filepath.Join(publishDir, "_redirects"))
+	if !strings.Contains(string(redirects), "/blog/:slug  /.netlify/functions/galaxy  200") || strings.Contains(string(redirects), "/about") {
+		t.Errorf("Unexpected _redirects:\n%s", redirects)
+	}
+
+	toml, _ := os.ReadFile(filepath.Join(project, "netlify.toml"))
+	if !strings.Contains(string(toml), `publish = "dist/netlify/publish"`) || !strings.Contains(string(toml), `directory = "dist/netlify/functions"`) {
+		t.Errorf("Unexpected netlify.toml:\n%s", toml)
+	}
+
+	function := filepath.Join(outDir, "netlify", "functions", FunctionName)
+	if _, err := os.Stat(function); err != nil {
+		t.Fatalf("Expected the function binary: %v", err)
+	}
+
+	// A project's own netlify.toml survives a rebuild.
+	os.WriteFile(filepath.Join(project, "netlify.toml"), []byte("[build]\n  command = \"make\"\n"), 0644)
+	if err := New().Build(build); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if toml, _ := os.ReadFile(filepath.Join(project, "netlify.toml")); !strings.Contains(string(toml), "make") {
+		t.Errorf("Expected the project's netlify.toml to be kept, got:\n%s", toml)
+	}
+
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("function binary targets linux/amd64")
+	}
+	invokeFunction(t, function)
+}
+
+// invokeFunction runs the function the way Netlify invokes it.
+func invokeFunction(t *testing.T, function string) {
+	t.Helper()
+
+	responses := lambdatest.Invoke(t, function, `{"httpMethod":"GET","path":"/blog/hello","headers":{"Host":"site.netlify.app"}}`)
+
+	var res lambda.Response
+	if err := json.Unmarshal(responses[0], &res); err != nil {
+		t.Fatalf("Expected a Lambda response, got %q", responses[0])
+	}
+	if res.StatusCode != http.StatusOK || res.Body != "dynamic /blog/hello" {
+		t.Errorf("Unexpected response: %+v", res)
+	}
+}
+
+func TestEmulate(t *testing.T) {
+	var got string
+	h := New().Emulate(config.DefaultConfig(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Nf-Client-Connection-Ip")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.9:4242"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got != "203.0.113.9" {
+		t.Errorf("Expected the client address header, got %q", got)
+	}
+}
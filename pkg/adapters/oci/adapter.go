@@ -0,0 +1,327 @@
+// Package oci packages Galaxy sites as container images: the server,
+// compiled for Linux, goes into a minimal non-root image written as an OCI
+// image layout tarball, without a Docker daemon.
+package oci
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/codegen"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/serve"
+)
+
+// ImageFile is the name of the tarball written to OutDir/oci.
+const ImageFile = "image.tar"
+
+// appDir holds the server and, with output.assets = "disk", its files.
+const appDir = "app"
+
+// uid is the user the server runs as. It is numeric so Kubernetes can
+// verify runAsNonRoot.
+const uid = 65532
+
+// caBundles are where the build machine may keep the CA certificates the
+// server needs for outgoing HTTPS requests, as crypto/x509 looks them up.
+var caBundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+type OCIAdapter struct{}
+
+func New() *OCIAdapter {
+	return &OCIAdapter{}
+}
+
+func init() {
+	adapters.Register(New())
+}
+
+func (a *OCIAdapter) Name() string {
+	return string(config.AdapterOCI)
+}
+
+func (a *OCIAdapter) Capabilities() adapters.Capabilities {
+	return adapters.Capabilities{Static: true, SSR: true, Streaming: true}
+}
+
+// options are the adapter's [adapter.config] settings.
+type options struct {
+	// Image is the reference the image is tagged with. It defaults to the
+	// project directory's name, and the tag to latest.
+	Image string
+	// Arch is the GOARCH the server is compiled for, amd64 by default.
+	Arch string
+	// Labels are added to the image's, overriding them.
+	Labels map[string]string
+	// Healthcheck is the path the image's healthcheck requests, / by
+	// default. "none" leaves the healthcheck out.
+	Healthcheck string
+}
+
+var invalidRepoChars = regexp.MustCompile(`[^a-z0-9._/-]+`)
+
+// parseOptions reads the adapter's settings from cfg, for a project in
+// rootDir.
+func parseOptions(cfg *config.Config, rootDir string) (*options, error) {
+	opts := &options{Arch: "amd64", Healthcheck: "/", Labels: make(map[string]string)}
+
+	var err error
+	if opts.Image, err = adapters.StringOption(cfg, "image", ""); err != nil {
+		return nil, err
+	}
+	if opts.Arch, err = adapters.StringOption(cfg, "arch", opts.Arch); err != nil {
+		return nil, err
+	}
+	if opts.Healthcheck, err = adapters.StringOption(cfg, "healthcheck", opts.Healthcheck); err != nil {
+		return nil, err
+	}
+	if opts.Healthcheck != "none" && !strings.HasPrefix(opts.Healthcheck, "/") {
+		return nil, fmt.Errorf("adapter.config.healthcheck: expected a path starting with /, got %q", opts.Healthcheck)
+	}
+
+	if labels, ok := cfg.Adapter.Config["labels"]; ok {
+		m, ok := labels.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("adapter.config.labels: expected a table")
+		}
+		for k, v := range m {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("adapter.config.labels.%s: expected a string", k)
+			}
+			opts.Labels[k] = s
+		}
+	}
+
+	if opts.Image == "" {
+		name := invalidRepoChars.ReplaceAllString(strings.ToLower(filepath.Base(rootDir)), "-")
+		opts.Image = strings.Trim(name, "-._")
+		if opts.Image == "" {
+			opts.Image = "galaxy"
+		}
+	}
+	if !strings.Contains(path.Base(opts.Image), ":") {
+		opts.Image += ":latest"
+	}
+	return opts, nil
+}
+
+// splitRef splits an image reference into its repository and tag.
+func splitRef(ref string) (string, string) {
+	i := strings.LastIndex(ref, ":")
+	return ref[:i], ref[i+1:]
+}
+
+// Build writes OutDir/oci/image.tar, which docker load reads and registry
+// tools such as skopeo or crane can push.
+func (a *OCIAdapter) Build(cfg *adapters.BuildConfig) error {
+	rootDir := cfg.RootDir
+	if rootDir == "" {
+		rootDir = filepath.Dir(cfg.OutDir)
+	}
+	opts, err := parseOptions(cfg.Config, rootDir)
+	if err != nil {
+		return err
+	}
+
+	outDir := filepath.Join(cfg.OutDir, "oci")
+	if err := os.RemoveAll(outDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp("", "galaxy-oci-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	binary := filepath.Join(tmpDir, "server")
+	if err := adapters.Compile(cfg.ServerDir, binary, "GOOS=linux", "GOARCH="+opts.Arch, "CGO_ENABLED=0"); err != nil {
+		return fmt.Errorf("compile server: %w", err)
+	}
+
+	created := createdTime()
+	layerPath := filepath.Join(tmpDir, "layer.tar.gz")
+	layerDesc, diffID, err := writeRootFS(cfg, binary, layerPath, created)
+	if err != nil {
+		return fmt.Errorf("write layer: %w", err)
+	}
+
+	image := imageConfig(cfg.Config, opts, created)
+	image.RootFS.DiffIDs = []string{diffID}
+	if err := writeLayout(filepath.Join(outDir, ImageFile), opts.Image, image, layerPath, layerDesc); err != nil {
+		return fmt.Errorf("write image: %w", err)
+	}
+	return nil
+}
+
+// createdTime is when the image was built, or SOURCE_DATE_EPOCH for
+// reproducible images.
+func createdTime() time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(epoch, 0).UTC()
+	}
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// writeRootFS writes the image's only layer: the server under /app, a
+// passwd entry for its user, a CA bundle and a writable /tmp. Everything
+// is owned by root, so the server cannot modify itself.
+func writeRootFS(cfg *adapters.BuildConfig, binary, layerPath string, modTime time.Time) (Descriptor, string, error) {
+	f, err := os.Create(layerPath)
+	if err != nil {
+		return Descriptor{}, "", err
+	}
+	defer f.Close()
+	l := newLayer(f, modTime)
+
+	passwd := fmt.Sprintf("root:x:0:0:root:/root:/sbin/nologin\ngalaxy:x:%d:%d:galaxy:/%s:/sbin/nologin\n", uid, uid, appDir)
+	group := fmt.Sprintf("root:x:0:\ngalaxy:x:%d:\n", uid)
+	if err := l.file("etc/passwd", 0644, int64(len(passwd)), strings.NewReader(passwd)); err != nil {
+		return Descriptor{}, "", err
+	}
+	if err := l.file("etc/group", 0644, int64(len(group)), strings.NewReader(group)); err != nil {
+		return Descriptor{}, "", err
+	}
+	if bundle := caBundle(); bundle != "" {
+		if err := addFile(l, "etc/ssl/certs/ca-certificates.crt", bundle, 0644); err != nil {
+			return Descriptor{}, "", err
+		}
+	}
+	if err := l.dir("tmp", 01777); err != nil {
+		return Descriptor{}, "", err
+	}
+
+	if err := l.dir(appDir, 0755); err != nil {
+		return Descriptor{}, "", err
+	}
+	if err := addFile(l, path.Join(appDir, "server"), binary, 0755); err != nil {
+		return Descriptor{}, "", err
+	}
+	if cfg.Config.Output.Assets == config.AssetsDisk {
+		if err := addSite(l, cfg.ServerDir); err != nil {
+			return Descriptor{}, "", err
+		}
+	}
+
+	desc, diffID, err := l.close()
+	if err != nil {
+		return Descriptor{}, "", err
+	}
+	return desc, diffID, f.Close()
+}
+
+// caBundle returns the first of caBundles the build machine has, or "".
+func caBundle() string {
+	for _, bundle := range caBundles {
+		if info, err := os.Stat(bundle); err == nil && info.Mode().IsRegular() {
+			return bundle
+		}
+	}
+	return ""
+}
+
+func addFile(l *layer, name, src string, mode int64) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return l.file(name, mode, info.Size(), f)
+}
+
+// addSite adds the files the server reads next to its binary.
+func addSite(l *layer, serverDir string) error {
+	names, err := codegen.SiteEntries(serverDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		root := filepath.Join(serverDir, name)
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(serverDir, p)
+			if err != nil {
+				return err
+			}
+			target := path.Join(appDir, filepath.ToSlash(rel))
+			if d.IsDir() {
+				return l.dir(target, 0755)
+			}
+			return addFile(l, target, p, 0644)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// imageConfig runs the server as the galaxy user on all interfaces, with
+// cfg's port exposed and a healthcheck that runs the binary's probe.
+func imageConfig(cfg *config.Config, opts *options, created time.Time) *Image {
+	port := strconv.Itoa(cfg.Server.Port)
+	server := "/" + path.Join(appDir, "server")
+	repo, _ := splitRef(opts.Image)
+
+	labels := map[string]string{
+		"org.opencontainers.image.created": created.Format(time.RFC3339),
+		"org.opencontainers.image.title":   path.Base(repo),
+	}
+	if cfg.Site != "" {
+		labels["org.opencontainers.image.url"] = cfg.Site
+	}
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+
+	image := &Image{
+		Created:      created,
+		Architecture: opts.Arch,
+		OS:           "linux",
+		Config: ImageConfig{
+			User:         fmt.Sprintf("%d:%d", uid, uid),
+			ExposedPorts: map[string]struct{}{port + "/tcp": {}},
+			Env:          []string{"HOST=0.0.0.0", "PORT=" + port},
+			Entrypoint:   []string{server},
+			WorkingDir:   "/" + appDir,
+			Labels:       labels,
+			StopSignal:   "SIGTERM",
+		},
+		RootFS:  RootFS{Type: "layers"},
+		History: []History{{Created: created, CreatedBy: "galaxy build"}},
+	}
+	if opts.Healthcheck != "none" {
+		image.Config.Healthcheck = &Healthcheck{
+			Test:        []string{"CMD", server, serve.HealthcheckCommand, opts.Healthcheck},
+			Interval:    30 * time.Second,
+			Timeout:     5 * time.Second,
+			StartPeriod: 5 * time.Second,
+			Retries:     3,
+		}
+	}
+	return image
+}
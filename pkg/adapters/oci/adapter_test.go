@@ -0,0 +1,238 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/adapters/adaptertest"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+func TestParseOptions(t *testing.T) {
+	cfg := config.DefaultConfig()
+	opts, err := parseOptions(cfg, "/work/My Docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Image != "my-docs:latest" || opts.Arch != "amd64" || opts.Healthcheck != "/" {
+		t.Errorf("Unexpected defaults: %+v", opts)
+	}
+
+	cfg.Adapter.Config = map[string]interface{}{
+		"image":       "registry.example.com:5000/team/docs",
+		"arch":        "arm64",
+		"healthcheck": "/healthz",
+		"labels":      map[string]interface{}{"team": "docs"},
+	}
+	opts, err = parseOptions(cfg, "/work/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Image != "registry.example.com:5000/team/docs:latest" {
+		t.Errorf("Expected the latest tag after the registry port, got %s", opts.Image)
+	}
+	if opts.Arch != "arm64" || opts.Healthcheck != "/healthz" || opts.Labels["team"] != "docs" {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	cfg.Adapter.Config = map[string]interface{}{"labels": map[string]interface{}{"build": 42}}
+	if _, err := parseOptions(cfg, "/work/docs"); err == nil || !strings.Contains(err.Error(), "labels.build") {
+		t.Errorf("Expected an error naming the label, got %v", err)
+	}
+	cfg.Adapter.Config = map[string]interface{}{"healthcheck": "healthz"}
+	if _, err := parseOptions(cfg, "/work/docs"); err == nil {
+		t.Error("Expected a relative healthcheck path to fail")
+	}
+}
+
+// readTar returns the regular files in a tar stream and the headers of
+// all its entries.
+// fileServer is a minimal server with a healthcheck, serving a prerendered
+// page it reads from disk.
+const fileServer = `package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/serve"
+)
+
+func main() {
+	cfg := config.ServerConfig{Port: 4322}
+	serve.HandleHealthcheck(cfg)
+	if err := serve.Run(cfg, http.FileServer(http.Dir("static")), nil); err != nil {
+		os.Exit(1)
+	}
+}
+`
+
+func readTar(t *testing.T, r io.Reader) (map[string][]byte, map[string]*tar.Header) {
+	t.Helper()
+	files := make(map[string][]byte)
+	headers := make(map[string]*tar.Header)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers[hdr.Name] = hdr
+		if hdr.Typeflag == tar.TypeReg {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[hdr.Name] = data
+		}
+	}
+	return files, headers
+}
+
+func blob(t *testing.T, files map[string][]byte, desc Descriptor) []byte {
+	t.Helper()
+	data, ok := files[blobPath(desc)]
+	if !ok {
+		t.Fatalf("Expected blob %s in the layout", desc.Digest)
+	}
+	sum := sha256.Sum256(data)
+	if "sha256:"+hex.EncodeToString(sum[:]) != desc.Digest || int64(len(data)) != desc.Size {
+		t.Errorf("Expected blob %s to match its descriptor", desc.Digest)
+	}
+	return data
+}
+
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a server")
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	project := filepath.Join(t.TempDir(), "docs")
+	outDir := filepath.Join(project, "dist")
+	serverDir := filepath.Join(outDir, "server")
+	adaptertest.WriteServer(t, serverDir, map[string]string{
+		"main.go":           fileServer,
+		"static/index.html": "home",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Output.Assets = config.AssetsDisk
+	cfg.Server.Port = 8080
+	cfg.Adapter.Config = map[string]interface{}{
+		"labels": map[string]interface{}{"org.opencontainers.image.source": "https://example.com/docs"},
+	}
+	build := &adapters.BuildConfig{Config: cfg, RootDir: project, ServerDir: serverDir, OutDir: outDir}
+	if err := New().Build(build); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "oci", ImageFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := readTar(t, bytes.NewReader(data))
+	if string(files["oci-layout"]) != `{"imageLayoutVersion":"1.0.0"}` {
+		t.Errorf("Unexpected oci-layout: %s", files["oci-layout"])
+	}
+
+	var index Index
+	if err := json.Unmarshal(files["index.json"], &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != 1 || index.Manifests[0].Annotations["io.containerd.image.name"] != "docs:latest" {
+		t.Fatalf("Unexpected index: %+v", index)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(blob(t, files, index.Manifests[0]), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	var image Image
+	if err := json.Unmarshal(blob(t, files, manifest.Config), &image); err != nil {
+		t.Fatal(err)
+	}
+
+	if image.OS != "linux" || image.Architecture != "amd64" || image.Created.Unix() != 1700000000 {
+		t.Errorf("Unexpected platform or date: %s/%s %v", image.OS, image.Architecture, image.Created)
+	}
+	c := image.Config
+	if c.User != "65532:65532" || strings.Join(c.Entrypoint, " ") != "/app/server" {
+		t.Errorf("Expected the server to run as a non-root user, got %q %v", c.User, c.Entrypoint)
+	}
+	if _, ok := c.ExposedPorts["8080/tcp"]; !ok || strings.Join(c.Env, " ") != "HOST=0.0.0.0 PORT=8080" {
+		t.Errorf("Expected port 8080 on all interfaces, got %v %v", c.ExposedPorts, c.Env)
+	}
+	if c.Healthcheck == nil || strings.Join(c.Healthcheck.Test, " ") != "CMD /app/server healthcheck /" {
+		t.Errorf("Unexpected healthcheck: %+v", c.Healthcheck)
+	}
+	if c.Labels["org.opencontainers.image.source"] != "https://example.com/docs" || c.Labels["org.opencontainers.image.title"] != "docs" {
+		t.Errorf("Unexpected labels: %v", c.Labels)
+	}
+
+	var docker []dockerManifest
+	if err := json.Unmarshal(files["manifest.json"], &docker); err != nil {
+		t.Fatal(err)
+	}
+	if len(docker) != 1 || docker[0].RepoTags[0] != "docs:latest" || docker[0].Config != blobPath(manifest.Config) {
+		t.Errorf("Unexpected manifest.json: %+v", docker)
+	}
+
+	if len(manifest.Layers) != 1 {
+		t.Fatalf("Expected one layer, got %d", len(manifest.Layers))
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(blob(t, files, manifest.Layers[0])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layerData, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(layerData)
+	if image.RootFS.DiffIDs[0] != "sha256:"+hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected the diff ID to be the digest of the uncompressed layer")
+	}
+
+	layerFiles, headers := readTar(t, bytes.NewReader(layerData))
+	if hdr := headers["app/server"]; hdr == nil || hdr.Mode != 0755 || hdr.Uid != 0 {
+		t.Errorf("Expected an executable server owned by root, got %+v", hdr)
+	}
+	if string(layerFiles["app/static/index.html"]) != "home" {
+		t.Errorf("Expected the site's files next to the server with disk assets")
+	}
+	for _, name := range []string{"app/main.go", "app/go.mod"} {
+		if _, ok := headers[name]; ok {
+			t.Errorf("Expected %s to stay out of the image", name)
+		}
+	}
+	if !strings.Contains(string(layerFiles["etc/passwd"]), "galaxy:x:65532:65532") {
+		t.Errorf("Unexpected /etc/passwd: %s", layerFiles["etc/passwd"])
+	}
+	if hdr := headers["tmp/"]; hdr == nil || hdr.Mode != 01777 {
+		t.Errorf("Expected a world-writable /tmp, got %+v", hdr)
+	}
+
+	again := filepath.Join(t.TempDir(), ImageFile)
+	os.Rename(filepath.Join(outDir, "oci", ImageFile), again)
+	if err := New().Build(build); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, _ := os.ReadFile(filepath.Join(outDir, "oci", ImageFile))
+	if first, _ := os.ReadFile(again); !bytes.Equal(first, rebuilt) {
+		t.Error("Expected identical images with SOURCE_DATE_EPOCH set")
+	}
+}
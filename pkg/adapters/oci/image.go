@@ -0,0 +1,296 @@
+package oci
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	mediaTypeIndex    = "application/vnd.oci.image.index.v1+json"
+	mediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeConfig   = "application/vnd.oci.image.config.v1+json"
+	mediaTypeLayer    = "application/vnd.oci.image.layer.v1.tar+gzip"
+)
+
+// Descriptor points at a blob of the image layout by digest.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Index is the layout's index.json.
+type Index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+// Manifest lists the image's config and layers.
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// Image is the image config blob.
+type Image struct {
+	Created      time.Time   `json:"created"`
+	Architecture string      `json:"architecture"`
+	OS           string      `json:"os"`
+	Config       ImageConfig `json:"config"`
+	RootFS       RootFS      `json:"rootfs"`
+	History      []History   `json:"history"`
+}
+
+// ImageConfig is how a container runs the image.
+type ImageConfig struct {
+	User         string              `json:"User,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	StopSignal   string              `json:"StopSignal,omitempty"`
+	// Healthcheck is Docker's addition to the config. Other runtimes,
+	// Kubernetes included, ignore it.
+	Healthcheck *Healthcheck `json:"Healthcheck,omitempty"`
+}
+
+// Healthcheck is Docker's HEALTHCHECK, with durations in nanoseconds.
+type Healthcheck struct {
+	Test        []string      `json:"Test"`
+	Interval    time.Duration `json:"Interval,omitempty"`
+	Timeout     time.Duration `json:"Timeout,omitempty"`
+	StartPeriod time.Duration `json:"StartPeriod,omitempty"`
+	Retries     int           `json:"Retries,omitempty"`
+}
+
+type RootFS struct {
+	Type    string   `json:"type"`
+	DiffIDs []string `json:"diff_ids"`
+}
+
+type History struct {
+	Created   time.Time `json:"created"`
+	CreatedBy string    `json:"created_by"`
+}
+
+// dockerManifest is an entry of manifest.json, which lets docker load
+// read the layout on versions without OCI support.
+type dockerManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// layer writes a filesystem layer as a gzipped tar, tracking the digests
+// of both the compressed blob and the tar itself.
+type layer struct {
+	tw      *tar.Writer
+	gz      *gzip.Writer
+	digest  hash.Hash
+	diffID  hash.Hash
+	size    *countWriter
+	modTime time.Time
+	dirs    map[string]bool
+}
+
+func newLayer(w io.Writer, modTime time.Time) *layer {
+	l := &layer{
+		digest:  sha256.New(),
+		diffID:  sha256.New(),
+		size:    &countWriter{w: w},
+		modTime: modTime,
+		dirs:    make(map[string]bool),
+	}
+	l.gz = gzip.NewWriter(io.MultiWriter(l.size, l.digest))
+	l.tw = tar.NewWriter(io.MultiWriter(l.gz, l.diffID))
+	return l
+}
+
+// dir adds the directory name and any missing parents, owned by root.
+func (l *layer) dir(name string, mode int64) error {
+	name = strings.Trim(path.Clean(name), "/")
+	if name == "." || l.dirs[name] {
+		return nil
+	}
+	if err := l.dir(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	l.dirs[name] = true
+	return l.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     mode,
+		ModTime:  l.modTime,
+		Format:   tar.FormatPAX,
+	})
+}
+
+// file adds a file owned by root with size bytes read from r.
+func (l *layer) file(name string, mode int64, size int64, r io.Reader) error {
+	name = strings.Trim(path.Clean(name), "/")
+	if err := l.dir(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	err := l.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     mode,
+		Size:     size,
+		ModTime:  l.modTime,
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(l.tw, r)
+	return err
+}
+
+// close finishes the layer and returns its descriptor and diff ID.
+func (l *layer) close() (Descriptor, string, error) {
+	if err := l.tw.Close(); err != nil {
+		return Descriptor{}, "", err
+	}
+	if err := l.gz.Close(); err != nil {
+		return Descriptor{}, "", err
+	}
+	desc := Descriptor{
+		MediaType: mediaTypeLayer,
+		Digest:    "sha256:" + hex.EncodeToString(l.digest.Sum(nil)),
+		Size:      l.size.n,
+	}
+	return desc, "sha256:" + hex.EncodeToString(l.diffID.Sum(nil)), nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeLayout writes an OCI image layout tarball to path holding one
+// image tagged ref, with config and the layer blob at layerPath.
+func writeLayout(path, ref string, config *Image, layerPath string, layerDesc Descriptor) error {
+	configData, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	configDesc := describe(mediaTypeConfig, configData)
+
+	manifestData, err := json.Marshal(&Manifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeManifest,
+		Config:        configDesc,
+		Layers:        []Descriptor{layerDesc},
+	})
+	if err != nil {
+		return err
+	}
+	manifestDesc := describe(mediaTypeManifest, manifestData)
+	_, tag := splitRef(ref)
+	manifestDesc.Annotations = map[string]string{
+		"io.containerd.image.name":          ref,
+		"org.opencontainers.image.ref.name": tag,
+	}
+
+	indexData, err := json.Marshal(&Index{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeIndex,
+		Manifests:     []Descriptor{manifestDesc},
+	})
+	if err != nil {
+		return err
+	}
+
+	dockerData, err := json.Marshal([]dockerManifest{{
+		Config:   blobPath(configDesc),
+		RepoTags: []string{ref},
+		Layers:   []string{blobPath(layerDesc)},
+	}})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+
+	for _, dir := range []string{"blobs/", "blobs/sha256/"} {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir, Mode: 0755, ModTime: config.Created}); err != nil {
+			return err
+		}
+	}
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(data)), ModTime: config.Created}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := add("oci-layout", []byte(`{"imageLayoutVersion":"1.0.0"}`)); err != nil {
+		return err
+	}
+	if err := add(blobPath(configDesc), configData); err != nil {
+		return err
+	}
+	if err := add(blobPath(manifestDesc), manifestData); err != nil {
+		return err
+	}
+
+	layerFile, err := os.Open(layerPath)
+	if err != nil {
+		return err
+	}
+	defer layerFile.Close()
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: blobPath(layerDesc), Mode: 0644, Size: layerDesc.Size, ModTime: config.Created}); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, layerFile); err != nil {
+		return fmt.Errorf("copy layer: %w", err)
+	}
+
+	if err := add("index.json", indexData); err != nil {
+		return err
+	}
+	if err := add("manifest.json", dockerData); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func describe(mediaType string, data []byte) Descriptor {
+	sum := sha256.Sum256(data)
+	return Descriptor{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(data))}
+}
+
+func blobPath(desc Descriptor) string {
+	return "blobs/sha256/" + strings.TrimPrefix(desc.Digest, "sha256:")
+}
@@ -0,0 +1,149 @@
+package adapters
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+// Capabilities describes what an adapter's platform can serve.
+type Capabilities struct {
+	// Static serves prerendered pages and assets.
+	Static bool
+	// StaticOutput deploys static builds, which have no server to run.
+	StaticOutput bool
+	// SSR renders pages and runs endpoints on request.
+	SSR bool
+	// Streaming sends responses as they render rather than buffering them.
+	Streaming bool
+	// Edge runs the server without a file system, so assets are embedded.
+	Edge bool
+}
+
+// Capable is implemented by adapters that declare their capabilities.
+// Adapters without it are taken to serve static, static-only and SSR output.
+type Capable interface {
+	Capabilities() Capabilities
+}
+
+// RouteTransformer is implemented by adapters that adjust the route table
+// before Build, such as to add platform routes or drop unsupported ones.
+// Only the routes Build sees change: the built site and server keep the
+// routes discovered from src/pages, so a route added here must be served
+// by the platform itself.
+type RouteTransformer interface {
+	TransformRoutes(routes []RouteInfo) ([]RouteInfo, error)
+}
+
+// DevEmulator is implemented by adapters that emulate their platform in
+// the dev server, such as by setting the request headers it adds.
+type DevEmulator interface {
+	Emulate(cfg *config.Config, next http.Handler) http.Handler
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[config.AdapterName]Adapter)
+)
+
+// Register makes a available under its Name to the [adapter] name setting.
+// It is meant to be called from init and panics if the name is taken.
+func Register(a Adapter) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name := config.AdapterName(a.Name())
+	if _, dup := registry[name]; dup {
+		panic("adapters: Register called twice for adapter " + string(name))
+	}
+	registry[name] = a
+}
+
+// Lookup returns the adapter registered under name.
+func Lookup(name config.AdapterName) (Adapter, error) {
+	registryMu.RLock()
+	a, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		names := make([]string, 0)
+		for _, n := range Names() {
+			names = append(names, string(n))
+		}
+		return nil, fmt.Errorf("unknown adapter: %s (registered: %s)", name, strings.Join(names, ", "))
+	}
+	return a, nil
+}
+
+// Names returns the registered adapter names, sorted.
+func Names() []config.AdapterName {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]config.AdapterName, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// CapabilitiesOf returns the capabilities a declares.
+func CapabilitiesOf(a Adapter) Capabilities {
+	if c, ok := a.(Capable); ok {
+		return c.Capabilities()
+	}
+	return Capabilities{Static: true, StaticOutput: true, SSR: true}
+}
+
+// Check reports whether a can deploy the output cfg configures.
+func Check(a Adapter, cfg *config.Config) error {
+	caps := CapabilitiesOf(a)
+	if (cfg.IsSSR() || cfg.IsHybrid()) && !caps.SSR {
+		return fmt.Errorf("adapter %s does not support server rendering", a.Name())
+	}
+	if (cfg.IsStatic() || cfg.IsHybrid()) && !caps.Static {
+		return fmt.Errorf("adapter %s does not serve prerendered pages", a.Name())
+	}
+	if cfg.IsStatic() && !caps.StaticOutput {
+		return fmt.Errorf("adapter %s deploys a server, which static builds do not have; set output.type to server or hybrid", a.Name())
+	}
+	if caps.Edge && !cfg.IsStatic() && cfg.Output.Assets == config.AssetsDisk {
+		return fmt.Errorf("adapter %s runs without a file system; set output.assets to embed", a.Name())
+	}
+	return nil
+}
+
+// standalone is the server binary the build itself produces in
+// OutDir/server, which leaves nothing to package.
+type standalone struct{}
+
+func (standalone) Name() string {
+	return string(config.AdapterStandalone)
+}
+
+func (standalone) Build(cfg *BuildConfig) error {
+	return nil
+}
+
+func (standalone) Capabilities() Capabilities {
+	return Capabilities{Static: true, StaticOutput: true, SSR: true, Streaming: true}
+}
+
+func init() {
+	Register(standalone{})
+}
+
+// ClientIP returns the address of the client that sent r, for adapters
+// emulating the headers their platform sets.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
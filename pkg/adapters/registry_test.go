@@ -0,0 +1,107 @@
+package adapters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+type edgeAdapter struct{}
+
+func (edgeAdapter) Name() string                 { return "test-edge" }
+func (edgeAdapter) Build(cfg *BuildConfig) error { return nil }
+func (edgeAdapter) Capabilities() Capabilities {
+	return Capabilities{SSR: true, Edge: true}
+}
+
+type serverAdapter struct{}
+
+func (serverAdapter) Name() string                 { return "test-server" }
+func (serverAdapter) Build(cfg *BuildConfig) error { return nil }
+func (serverAdapter) Capabilities() Capabilities {
+	return Capabilities{Static: true, SSR: true}
+}
+
+type plainAdapter struct{}
+
+func (plainAdapter) Name() string                 { return "test-plain" }
+func (plainAdapter) Build(cfg *BuildConfig) error { return nil }
+
+func TestRegistry(t *testing.T) {
+	Register(edgeAdapter{})
+
+	a, err := Lookup("test-edge")
+	if err != nil || a.Name() != "test-edge" {
+		t.Fatalf("Expected the registered adapter, got %v, %v", a, err)
+	}
+	if _, err := Lookup(config.AdapterStandalone); err != nil {
+		t.Errorf("Expected standalone to be built in, got %v", err)
+	}
+
+	_, err = Lookup("kubernetes")
+	if err == nil || !strings.Contains(err.Error(), "unknown adapter: kubernetes") || !strings.Contains(err.Error(), "test-edge") {
+		t.Errorf("Expected an error listing the registered adapters, got %v", err)
+	}
+
+	names := Names()
+	for i := 1; i < len(names); i++ {
+		if names[i-1] > names[i] {
+			t.Errorf("Expected sorted names, got %v", names)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a name twice to panic")
+		}
+	}()
+	Register(edgeAdapter{})
+}
+
+func TestCheck(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Output.Type = config.OutputHybrid
+
+	if err := Check(edgeAdapter{}, cfg); err == nil || !strings.Contains(err.Error(), "prerendered") {
+		t.Errorf("Expected hybrid output to need static support, got %v", err)
+	}
+
+	cfg.Output.Type = config.OutputServer
+	if err := Check(edgeAdapter{}, cfg); err != nil {
+		t.Errorf("Expected server output to pass, got %v", err)
+	}
+	cfg.Output.Assets = config.AssetsDisk
+	if err := Check(edgeAdapter{}, cfg); err == nil || !strings.Contains(err.Error(), "embed") {
+		t.Errorf("Expected edge adapters to need embedded assets, got %v", err)
+	}
+
+	cfg.Output.Type = config.OutputStatic
+	if err := Check(edgeAdapter{}, cfg); err == nil || !strings.Contains(err.Error(), "prerendered") {
+		t.Errorf("Expected static output to need static support, got %v", err)
+	}
+	if err := Check(serverAdapter{}, cfg); err == nil || !strings.Contains(err.Error(), "static builds") {
+		t.Errorf("Expected static output to need a serverless deploy, got %v", err)
+	}
+
+	if caps := CapabilitiesOf(plainAdapter{}); !caps.Static || !caps.StaticOutput || !caps.SSR || caps.Streaming || caps.Edge {
+		t.Errorf("Expected static and SSR by default, got %+v", caps)
+	}
+	if err := Check(plainAdapter{}, cfg); err != nil {
+		t.Errorf("Expected the default capabilities to pass, got %v", err)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.9:4242"
+	if ip := ClientIP(r); ip != "203.0.113.9" {
+		t.Errorf("Expected 203.0.113.9, got %s", ip)
+	}
+	r.RemoteAddr = "203.0.113.9"
+	if ip := ClientIP(r); ip != "203.0.113.9" {
+		t.Errorf("Expected 203.0.113.9, got %s", ip)
+	}
+}
@@ -1,14 +1,24 @@
 package standalone
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
 
+	"github.com/cameron-webmatter/galaxy/pkg/actions"
 	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
+	"github.com/cameron-webmatter/galaxy/pkg/codegen"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/endpoints"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+	"github.com/cameron-webmatter/galaxy/pkg/router"
 )
 
 type StandaloneAdapter struct{}
@@ -43,12 +53,45 @@ func (a *StandaloneAdapter) generateMain(cfg *adapters.BuildConfig) error {
 	if err := a.copyProjectFiles(cfg); err != nil {
 		return fmt.Errorf("copy project files: %w", err)
 	}
+	if err := codegen.StageSite(cfg.OutDir, cfg.ServerDir); err != nil {
+		return fmt.Errorf("stage site files: %w", err)
+	}
+	var embed []string
+	if cfg.Config.Output.Assets != config.AssetsDisk {
+		var err error
+		if embed, err = codegen.EmbedPatterns(cfg.ServerDir); err != nil {
+			return fmt.Errorf("embed files: %w", err)
+		}
+	}
 
 	endpoints := a.buildEndpointData(cfg)
 	imports := a.buildEndpointImports(cfg)
+	actionSets, actionImports, err := a.buildActionData(cfg)
+	if err != nil {
+		return fmt.Errorf("copy actions: %w", err)
+	}
+	imports = append(imports, actionImports...)
 	hasMiddleware := a.checkMiddleware(cfg)
 	hasSequence := a.checkSequence(cfg)
+	dirMiddleware, err := a.copyDirMiddleware(cfg)
+	if err != nil {
+		return fmt.Errorf("copy directory middleware: %w", err)
+	}
+	builtinConfig := ""
+	if cfg.Config.Middleware.Enabled() {
+		builtinConfig = fmt.Sprintf("%#v", cfg.Config.Middleware)
+	}
 	hasLifecycle := a.checkLifecycle(cfg)
+	cacheConfig := ""
+	var incremental []string
+	if cfg.Config.Cache.Enabled {
+		cacheConfig = fmt.Sprintf("%#v", cfg.Config.Cache)
+		for _, r := range cfg.Routes {
+			if !r.IsEndpoint && !strings.ContainsAny(r.Pattern, "[{") && cache.Incremental(cfg.Config.Cache, r.Pattern, r.FilePath) {
+				incremental = append(incremental, r.Pattern)
+			}
+		}
+	}
 
 	tmpl := template.Must(template.New("main").Parse(mainTemplate))
 
@@ -69,17 +112,22 @@ func (a *StandaloneAdapter) generateMain(cfg *adapters.BuildConfig) error {
 	}
 
 	data := map[string]interface{}{
-		"Port":            cfg.Config.Server.Port,
-		"Host":            cfg.Config.Server.Host,
-		"PublicDir":       filepath.Join(cfg.OutDir, "public"),
-		"StaticDir":       cfg.OutDir,
-		"PagesDir":        cfg.PagesDir,
+		"ServerConfig":    fmt.Sprintf("%#v", cfg.Config.Server),
+		"TelemetryConfig": fmt.Sprintf("%#v", cfg.Config.Telemetry),
+		"Embed":           strings.Join(embed, " "),
 		"Routes":          routes,
 		"Endpoints":       endpoints,
+		"Actions":         actionSets,
 		"EndpointImports": imports,
 		"HasMiddleware":   hasMiddleware,
 		"HasSequence":     hasSequence,
+		"DirMiddleware":   dirMiddleware,
+		"BuiltinConfig":   builtinConfig,
 		"HasLifecycle":    hasLifecycle,
+		"CacheConfig":     cacheConfig,
+		"Incremental":     fmt.Sprintf("%#v", incremental),
+		"Streaming":       cfg.Config.Output.Streaming,
+		"WSOrigins":       fmt.Sprintf("%#v", cfg.Config.Server.WebSocketOrigins),
 	}
 
 	return tmpl.Execute(f, data)
@@ -93,14 +141,15 @@ func (a *StandaloneAdapter) buildEndpointData(cfg *adapters.BuildConfig) []map[s
 			continue
 		}
 
-		pkgName := a.getPackageName(route.FilePath)
-		methods := a.detectMethods(route.FilePath, pkgName)
+		pkgName := a.getPackageName(cfg.PagesDir, route.FilePath)
+		methods, webSocket := a.detectMethods(route.FilePath, pkgName)
 
-		if len(methods) > 0 {
+		if len(methods) > 0 || webSocket {
 			endpoints = append(endpoints, map[string]interface{}{
-				"Pattern": route.Pattern,
-				"Methods": methods,
-				"Package": pkgName,
+				"Pattern":   route.Pattern,
+				"Methods":   methods,
+				"Package":   pkgName,
+				"WebSocket": webSocket,
 			})
 		}
 	}
@@ -108,27 +157,79 @@ func (a *StandaloneAdapter) buildEndpointData(cfg *adapters.BuildConfig) []map[s
 	return endpoints
 }
 
-func (a *StandaloneAdapter) detectMethods(filePath, pkgName string) []map[string]string {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil
-	}
+// buildActionData copies each page's .actions.go into its own package
+// under server/actions, as the codegen builder does, and returns the action
+// sets and the imports they need.
+func (a *StandaloneAdapter) buildActionData(cfg *adapters.BuildConfig) ([]map[string]interface{}, []map[string]string, error) {
+	sets := []map[string]interface{}{}
+	imports := []map[string]string{}
 
-	src := string(content)
-	methods := []map[string]string{}
+	for _, route := range cfg.Routes {
+		if route.ActionsFile == "" {
+			continue
+		}
+
+		sigs, err := actions.ParseFile(route.ActionsFile)
+		if err != nil || len(sigs) == 0 {
+			continue
+		}
 
-	httpMethods := []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "ALL"}
-	for _, method := range httpMethods {
-		pattern := fmt.Sprintf("func %s(", method)
-		if contains(src, pattern) {
-			methods = append(methods, map[string]string{
-				"Method":  method,
-				"Package": pkgName,
+		pkgName := actions.PackageAlias(cfg.PagesDir, route.ActionsFile)
+		data, err := os.ReadFile(route.ActionsFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		destDir := filepath.Join(cfg.ServerDir, "actions", pkgName)
+		if err := os.MkdirAll(destDir, 0755); err != nil {
+			return nil, nil, err
+		}
+		content := regexp.MustCompile(`(?m)^package\s+\w+`).ReplaceAllString(string(data), "package "+pkgName)
+		if err := os.WriteFile(filepath.Join(destDir, "actions.go"), []byte(content), 0644); err != nil {
+			return nil, nil, err
+		}
+		imports = append(imports, map[string]string{
+			"Alias": pkgName,
+			"Path":  "galaxy-server/actions/" + pkgName,
+		})
+
+		entries := []map[string]string{}
+		for _, sig := range sigs {
+			entries = append(entries, map[string]string{
+				"Name":    sig.Name,
+				"Adapter": sig.Adapter(pkgName),
 			})
 		}
+
+		sets = append(sets, map[string]interface{}{
+			"Pattern": route.Pattern,
+			"Entries": entries,
+		})
 	}
 
-	return methods
+	return sets, imports, nil
+}
+
+func (a *StandaloneAdapter) detectMethods(filePath, pkgName string) ([]map[string]interface{}, bool) {
+	sigs, err := endpoints.ParseSignatures(filePath)
+	if err != nil {
+		return nil, false
+	}
+
+	methods := []map[string]interface{}{}
+	webSocket := false
+	for _, sig := range sigs {
+		if sig.Method == endpoints.WS {
+			webSocket = true
+			continue
+		}
+		methods = append(methods, map[string]interface{}{
+			"Method":  string(sig.Method),
+			"Package": pkgName,
+			"Typed":   sig.Typed,
+		})
+	}
+
+	return methods, webSocket
 }
 
 func contains(s, substr string) bool {
@@ -148,14 +249,15 @@ func (a *StandaloneAdapter) buildEndpointImports(cfg *adapters.BuildConfig) []ma
 		if !route.IsEndpoint {
 			continue
 		}
+		filePath := route.FilePath
 
-		pkgName := a.getPackageName(route.FilePath)
+		pkgName := a.getPackageName(cfg.PagesDir, filePath)
 		if seen[pkgName] {
 			continue
 		}
 		seen[pkgName] = true
 
-		relPath, _ := filepath.Rel(cfg.PagesDir, filepath.Dir(route.FilePath))
+		relPath, _ := filepath.Rel(cfg.PagesDir, filepath.Dir(filePath))
 		importPath := filepath.Join("galaxy-server/pages", relPath)
 
 		imports = append(imports, map[string]string{
@@ -167,13 +269,18 @@ func (a *StandaloneAdapter) buildEndpointImports(cfg *adapters.BuildConfig) []ma
 	return imports
 }
 
-func (a *StandaloneAdapter) getPackageName(filePath string) string {
-	dir := filepath.Dir(filePath)
-	base := filepath.Base(dir)
-	if base == "api" || base == "." {
-		return "api"
-	}
-	return base
+// getPackageName returns the alias of the pages package holding the
+// endpoint at filePath. It is made from the directory's path, since
+// directories such as blog and api/blog share a name.
+func (a *StandaloneAdapter) getPackageName(pagesDir, filePath string) string {
+	relPath, _ := filepath.Rel(pagesDir, filepath.Dir(filePath))
+	relPath = filepath.ToSlash(relPath)
+	if relPath == "." {
+		return "pagesroot"
+	}
+	name := regexp.MustCompile(`[^A-Za-z0-9]+`).ReplaceAllString(relPath, "_")
+	sum := sha256.Sum256([]byte(relPath))
+	return "pages" + strings.ToLower(strings.Trim(name, "_")) + "_" + hex.EncodeToString(sum[:3])
 }
 
 func (a *StandaloneAdapter) checkMiddleware(cfg *adapters.BuildConfig) bool {
@@ -193,6 +300,48 @@ func (a *StandaloneAdapter) checkSequence(cfg *adapters.BuildConfig) bool {
 	return contains(string(content), "func Sequence()")
 }
 
+// copyDirMiddleware gives every _middleware.go under pages its own package,
+// since the go tool ignores "_" files. The copy under pages stays so the
+// runtime router still attaches it to routes.
+func (a *StandaloneAdapter) copyDirMiddleware(cfg *adapters.BuildConfig) ([]map[string]interface{}, error) {
+	dirs := []map[string]interface{}{}
+
+	err := filepath.Walk(cfg.PagesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Name() != router.MiddlewareFile {
+			return err
+		}
+
+		relPath, _ := filepath.Rel(cfg.PagesDir, filepath.Dir(path))
+		alias := codegen.MiddlewareAlias(cfg.PagesDir, path)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		destDir := filepath.Join(cfg.ServerDir, "middleware", alias)
+		if err := os.MkdirAll(destDir, 0755); err != nil {
+			return err
+		}
+
+		content := regexp.MustCompile(`(?m)^package\s+\w+`).ReplaceAllString(string(data), "package "+alias)
+		if err := os.WriteFile(filepath.Join(destDir, "middleware.go"), []byte(content), 0644); err != nil {
+			return err
+		}
+
+		_, hasSequence := middleware.Detect(path)
+		dirs = append(dirs, map[string]interface{}{
+			"Dir":      filepath.ToSlash(relPath),
+			"Alias":    alias,
+			"Path":     "galaxy-server/middleware/" + alias,
+			"Sequence": hasSequence,
+		})
+		return nil
+	})
+
+	return dirs, err
+}
+
 func (a *StandaloneAdapter) checkLifecycle(cfg *adapters.BuildConfig) bool {
 	projectDir := filepath.Dir(cfg.PagesDir)
 	lifecyclePath := filepath.Join(projectDir, "src", "lifecycle.go")
@@ -258,7 +407,8 @@ func (a *StandaloneAdapter) copyProjectFiles(cfg *adapters.BuildConfig) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
+		// Actions files get their own packages from buildActionData.
+		if info.IsDir() || strings.HasSuffix(path, ".actions.go") {
 			return nil
 		}
 
@@ -292,7 +442,7 @@ func (a *StandaloneAdapter) generateGoMod(cfg *adapters.BuildConfig) error {
 
 	content := fmt.Sprintf(`module galaxy-server
 
-go 1.21
+go 1.24
 
 replace github.com/cameron-webmatter/galaxy => %s
 
@@ -341,29 +491,34 @@ func (a *StandaloneAdapter) compile(cfg *adapters.BuildConfig) error {
 const mainTemplate = `package main
 
 import (
+	{{if .Embed}}
+	"embed"
+	{{else}}
+	"os"
+	{{end}}
 	"fmt"
+	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
-	"os"
-	{{if .HasLifecycle}}
-	"os/signal"
-	{{end}}
 	"path/filepath"
 	"strings"
-	{{if .HasLifecycle}}
-	"syscall"
-	{{end}}
 
+	"github.com/cameron-webmatter/galaxy/pkg/actions"
+	"github.com/cameron-webmatter/galaxy/pkg/auth"
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
 	"github.com/cameron-webmatter/galaxy/pkg/compiler"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
 	"github.com/cameron-webmatter/galaxy/pkg/endpoints"
 	"github.com/cameron-webmatter/galaxy/pkg/executor"
-	{{if .HasLifecycle}}
 	"github.com/cameron-webmatter/galaxy/pkg/lifecycle"
-	{{end}}
 	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware/builtin"
 	"github.com/cameron-webmatter/galaxy/pkg/parser"
 	"github.com/cameron-webmatter/galaxy/pkg/router"
+	"github.com/cameron-webmatter/galaxy/pkg/serve"
 	"github.com/cameron-webmatter/galaxy/pkg/ssr"
+	"github.com/cameron-webmatter/galaxy/pkg/telemetry"
 	"github.com/cameron-webmatter/galaxy/pkg/template"
 	"github.com/cameron-webmatter/galaxy/pkg/wasm"
 
@@ -373,22 +528,53 @@ import (
 	{{if .HasMiddleware}}
 	usermw "galaxy-server/src"
 	{{end}}
+	{{range .DirMiddleware}}
+	{{.Alias}} "{{.Path}}"
+	{{end}}
 	{{if .HasLifecycle}}
 	userlc "galaxy-server/src"
 	{{end}}
 )
 
+{{if .Embed}}
+//go:embed {{.Embed}}
+var files embed.FS
+{{end}}
+
 var (
 	rt           *router.Router
 	comp         *compiler.ComponentCompiler
-	baseDir      string
+	site         fs.FS
 	pagesDir     = "pages"
 	wasmManifest *wasm.WasmManifest
 	endpointHandlers = map[string]map[string]endpoints.HandlerFunc{
 		{{range .Endpoints}}
 		"{{.Pattern}}": {
 			{{range .Methods}}
-			"{{.Method}}": {{.Package}}.{{.Method}},
+			"{{.Method}}": {{if .Typed}}endpoints.Typed({{.Package}}.{{.Method}}){{else}}{{.Package}}.{{.Method}}{{end}},
+			{{end}}
+		},
+		{{end}}
+	}
+	wsHandlers = map[string]endpoints.WSHandlerFunc{
+		{{range .Endpoints}}{{if .WebSocket}}
+		"{{.Pattern}}": {{.Package}}.WS,
+		{{end}}{{end}}
+	}
+	{{if .HasMiddleware}}
+	globalMiddleware = {{if .HasSequence}}usermw.Sequence(){{else}}[]middleware.Middleware{usermw.OnRequest}{{end}}
+	{{end}}
+	builtinMiddleware []middleware.Middleware
+	dirMiddleware = map[string][]middleware.Middleware{
+		{{range .DirMiddleware}}
+		"{{.Dir}}": {{if .Sequence}}{{.Alias}}.Sequence(){{else}}{ {{.Alias}}.OnRequest }{{end}},
+		{{end}}
+	}
+	actionSets = map[string]actions.Set{
+		{{range .Actions}}
+		"{{.Pattern}}": {
+			{{range .Entries}}
+			"{{.Name}}": {{.Adapter}},
 			{{end}}
 		},
 		{{end}}
@@ -396,131 +582,154 @@ var (
 )
 
 func main() {
-	exePath, err := os.Executable()
+	telemetryConfig := {{.TelemetryConfig}}
+	stopTelemetry, err := telemetry.Setup(telemetryConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	{{if .Embed}}
+	site = files
+	{{else}}
+	dir, err := serve.ExecutableDir()
 	if err != nil {
 		log.Fatal(err)
 	}
-	baseDir = filepath.Dir(exePath)
-	comp = compiler.NewComponentCompiler(baseDir)
+	site = os.DirFS(dir)
+	{{end}}
+	comp = compiler.NewComponentCompilerFS(site, ".")
+
+	{{if .BuiltinConfig}}
+	mw, err := builtin.FromConfig({{.BuiltinConfig}})
+	if err != nil {
+		log.Fatalf("Middleware config: %v", err)
+	}
+	builtinMiddleware = mw
+	{{end}}
 
-	rt = router.NewRouter(filepath.Join(baseDir, pagesDir))
+	rt = router.NewRouter(pagesDir)
+	rt.FS = site
 	if err := rt.Discover(); err != nil {
 		log.Fatalf("Route discovery failed: %v", err)
 	}
 	rt.Sort()
 
-	manifestPath := filepath.Join(baseDir, "_assets", "wasm-manifest.json")
-	wasmManifest, _ = wasm.LoadManifest(manifestPath)
+	wasmManifest, _ = wasm.LoadManifestFS(site, "_assets/wasm-manifest.json")
 
+	var lc *lifecycle.Lifecycle
 	{{if .HasLifecycle}}
-	lc := lifecycle.NewLifecycle()
+	lc = lifecycle.NewLifecycle()
 	lc.Register(userlc.Lifecycle())
 	
 	if err := lc.ExecuteStartup(); err != nil {
 		log.Fatalf("Startup failed: %v", err)
 	}
-	
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-sigChan
-		log.Println("Shutting down gracefully...")
-		if err := lc.ExecuteShutdown(); err != nil {
-			log.Printf("Shutdown error: %v", err)
-		}
-		os.Exit(0)
-	}()
 	{{end}}
 
-	http.HandleFunc("/", handleRequest)
-
-	addr := "{{.Host}}:{{.Port}}"
-	log.Printf("🚀 Server running at http://%s\n", addr)
-	
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	var handler http.Handler = serve.Site(site, http.HandlerFunc(handleRequest), {{.Incremental}}...)
+	{{if .CacheConfig}}
+	cacheConfig := {{.CacheConfig}}
+	pageCache := cache.FromConfig(cacheConfig)
+	{{if .Embed}}
+	staticFS, err := fs.Sub(site, "static")
+	if err != nil {
 		log.Fatal(err)
 	}
-}
-
-func handleRequest(w http.ResponseWriter, r *http.Request) {
-	if strings.HasPrefix(r.URL.Path, "/_assets/") {
-		assetsPath := filepath.Join(baseDir, r.URL.Path)
-		http.ServeFile(w, r, assetsPath)
-		return
-	}
-
-	if r.URL.Path == "/wasm_exec.js" {
-		wasmExecPath := filepath.Join(baseDir, "wasm_exec.js")
-		http.ServeFile(w, r, wasmExecPath)
-		return
+	if err := pageCache.LoadStaticFS(staticFS, {{.Incremental}}); err != nil {
+		log.Fatalf("Cache: %v", err)
 	}
-
-	if filepath.Ext(r.URL.Path) != "" {
-		http.ServeFile(w, r, filepath.Join("{{.PublicDir}}", r.URL.Path))
-		return
+	{{else}}
+	if err := pageCache.LoadStatic(filepath.Join(dir, "static"), {{.Incremental}}); err != nil {
+		log.Fatalf("Cache: %v", err)
 	}
+	{{end}}
+	http.Handle(cache.RevalidatePath, pageCache.RevalidateHandler(cache.Secret(cacheConfig)))
+	handler = pageCache.Handler(handler)
+	{{end}}
+	http.Handle("/", handler)
 
-	staticPath := filepath.Join("{{.StaticDir}}", r.URL.Path)
-	if r.URL.Path == "/" {
-		staticPath = filepath.Join("{{.StaticDir}}", "index.html")
-	} else {
-		staticPath = filepath.Join("{{.StaticDir}}", r.URL.Path, "index.html")
-	}
-	
-	if _, err := os.Stat(staticPath); err == nil {
-		http.ServeFile(w, r, staticPath)
-		return
+	err = serve.Run({{.ServerConfig}}, telemetry.Handler(telemetryConfig, http.DefaultServeMux), lc)
+	stopTelemetry()
+	if err != nil {
+		log.Fatal(err)
 	}
+}
 
+func handleRequest(w http.ResponseWriter, r *http.Request) {
 	route, params := rt.Match(r.URL.Path)
 	if route == nil {
 		http.NotFound(w, r)
 		return
 	}
+	telemetry.SetRoute(r, route.Pattern)
 
 	mwCtx := middleware.NewContext(w, r)
 	mwCtx.Params = params
 
-	{{if .HasMiddleware}}
-	{{if .HasSequence}}
-	chain := middleware.NewChain()
-	for _, mw := range usermw.Sequence() {
-		chain.Use(mw)
+	chain, err := middlewareFor(route)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	if err := chain.Execute(mwCtx, func(ctx *middleware.Context) error {
+	if chain.Len() == 0 {
 		if route.IsEndpoint {
 			handleEndpoint(route.Pattern, mwCtx)
-		} else {
-			handlePage(route.FilePath, mwCtx)
+			return
 		}
-		return nil
-	}); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handlePage(route, mwCtx)
 		return
 	}
-	{{else}}
-	if err := usermw.OnRequest(mwCtx, func() error {
+
+	if err := chain.Execute(mwCtx, func(ctx *middleware.Context) error {
 		if route.IsEndpoint {
-			handleEndpoint(route.Pattern, mwCtx)
+			handleEndpoint(route.Pattern, ctx)
 		} else {
-			handlePage(route.FilePath, mwCtx)
+			handlePage(route, ctx)
 		}
 		return nil
 	}); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
 	}
+}
+
+// middlewareFor composes the configured built-ins, src/middleware.go, the
+// route's directory middleware and its auth guard, outermost first.
+func middlewareFor(route *router.Route) (*middleware.Chain, error) {
+	chain := middleware.NewChain().Use(builtinMiddleware...)
+	{{if .HasMiddleware}}
+	chain.Use(globalMiddleware...)
 	{{end}}
-	{{else}}
-	if route.IsEndpoint {
-		handleEndpoint(route.Pattern, mwCtx)
-		return
+	for _, file := range route.Middleware {
+		relPath, err := filepath.Rel(rt.PagesDir, filepath.Dir(file))
+		if err != nil {
+			continue
+		}
+		chain.Use(dirMiddleware[filepath.ToSlash(relPath)]...)
 	}
-	handlePage(route.FilePath, mwCtx)
-	{{end}}
+
+	rule, err := auth.FileRuleFS(site, route.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		chain.Use(auth.Guard(*rule))
+	}
+	return chain, nil
 }
 
 func handleEndpoint(pattern string, mwCtx *middleware.Context) {
+	spanCtx, span := telemetry.Start(mwCtx.Request.Context(), "endpoint", slog.String("http.route", pattern))
+	defer span.End()
+	mwCtx.Request = mwCtx.Request.WithContext(spanCtx)
+
+	if ws, ok := wsHandlers[pattern]; ok && endpoints.IsWebSocketUpgrade(mwCtx.Request) {
+		mwCtx.Request = mwCtx.Request.WithContext(endpoints.WithWebSocketOrigins(spanCtx, {{.WSOrigins}}))
+		if err := endpoints.ServeWebSocket(ws, mwCtx.Response, mwCtx.Request, mwCtx.Params, mwCtx.Locals); err != nil {
+			endpoints.WriteError(mwCtx.Response, err)
+		}
+		return
+	}
+
 	ep, ok := endpointHandlers[pattern]
 	if !ok {
 		http.Error(mwCtx.Response, "Endpoint not found", http.StatusNotFound)
@@ -539,26 +748,28 @@ func handleEndpoint(pattern string, mwCtx *middleware.Context) {
 
 	ctx := endpoints.NewContext(mwCtx.Response, mwCtx.Request, mwCtx.Params, mwCtx.Locals)
 	if err := handler(ctx); err != nil {
-		http.Error(mwCtx.Response, err.Error(), http.StatusInternalServerError)
+		span.RecordError(err)
+		endpoints.WriteError(mwCtx.Response, err)
 	}
 }
 
-func handlePage(filePath string, mwCtx *middleware.Context) {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		http.Error(mwCtx.Response, err.Error(), http.StatusInternalServerError)
+func handlePage(route *router.Route, mwCtx *middleware.Context) {
+	filePath := route.FilePath
+
+	actionSet, hasActions := actionSets[route.Pattern]
+	actionResult, handled := actions.Handle(actionSet, mwCtx.Response, mwCtx.Request, mwCtx.Params, mwCtx.Locals)
+	if handled {
 		return
 	}
 
-	parsed, err := parser.Parse(string(content))
+	// Parsed pages are shared between requests; everything a render
+	// changes lives in the request below.
+	parsed, err := comp.Load(filePath)
 	if err != nil {
 		http.Error(mwCtx.Response, fmt.Sprintf("Parse error: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	resolver := comp.Resolver
-	resolver.SetCurrentFile(filePath)
-
 	imports := make([]compiler.Import, len(parsed.Imports))
 	for i, imp := range parsed.Imports {
 		imports[i] = compiler.Import{
@@ -567,7 +778,7 @@ func handlePage(filePath string, mwCtx *middleware.Context) {
 			IsComponent: imp.IsComponent,
 		}
 	}
-	resolver.ParseImports(imports)
+	renderReq := comp.NewRequest(filePath, imports)
 
 	ctx := executor.NewContext()
 
@@ -576,64 +787,128 @@ func handlePage(filePath string, mwCtx *middleware.Context) {
 	ctx.SetLocals(mwCtx.Locals)
 
 	ctx.SetParams(mwCtx.Params)
+	ctx.SetSession(mwCtx.Session(), mwCtx.Cookies())
+	ctx.SetCSRFToken(builtin.CSRFToken(mwCtx.Request))
+	ctx.SetCache(cache.PolicyFrom(mwCtx.Request))
 
 	for k, v := range mwCtx.Params {
 		ctx.Set(k, v)
 	}
 
+	if hasActions {
+		actions.Expose(ctx, actionSet.Names(), actionResult)
+	}
+
 	if parsed.Frontmatter != "" {
-		if err := ctx.Execute(parsed.Frontmatter); err != nil {
+		_, span := telemetry.Start(mwCtx.Request.Context(), "frontmatter", slog.String("http.route", route.Pattern))
+		err := ctx.Execute(parsed.Frontmatter)
+		span.RecordError(err)
+		span.End()
+		if err != nil {
 			http.Error(mwCtx.Response, fmt.Sprintf("Execution error: %v", err), http.StatusInternalServerError)
 			return
 		}
 	}
 
+	response := ctx.GetResponse()
+	response.ApplyHeaders(mwCtx.Response.Header())
+
 	if ctx.ShouldRedirect {
 		http.Redirect(mwCtx.Response, mwCtx.Request, ctx.RedirectURL, ctx.RedirectStatus)
 		return
 	}
 
-	comp.CollectedStyles = nil
-	processedTemplate := comp.ProcessComponentTags(parsed.Template, ctx)
+	if ctx.NotFound {
+		http.NotFound(mwCtx.Response, mwCtx.Request)
+		return
+	}
+
+	if mwCtx.Response.Header().Get("Content-Type") == "" {
+		mwCtx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	status := http.StatusOK
+	if actionResult != nil {
+		status = actionResult.Status
+	}
+
+	renderCtx, renderDone := telemetry.Render(mwCtx.Request.Context(), route.Pattern)
+	renderReq.Context = renderCtx
+
+	if {{.Streaming}} || compiler.HasIslands(parsed.Template) {
+		mwCtx.Response.WriteHeader(response.StatusOr(status))
+		actionsClient := actions.StreamingClient()
+		err := renderReq.Stream(mwCtx.Response, parsed.Template, ctx, compiler.StreamOptions{
+			Transform: func(chunk string) string {
+				chunk = injectPageAssets(chunk, filePath, parsed.Styles)
+				if hasActions {
+					chunk = actionsClient(chunk)
+				}
+				return chunk
+			},
+		})
+		renderDone(err)
+		if err != nil {
+			telemetry.Logger(renderCtx).Error("stream failed", "route", route.Pattern, "error", err)
+		}
+		return
+	}
+
+	processedTemplate := renderReq.ProcessComponentTags(parsed.Template, ctx)
 
 	engine := template.NewEngine(ctx)
+	engine.Page = true
 	rendered, err := engine.Render(processedTemplate, nil)
+	renderDone(err)
 	if err != nil {
 		http.Error(mwCtx.Response, fmt.Sprintf("Render error: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	allStyles := append(parsed.Styles, comp.CollectedStyles...)
-	if len(allStyles) > 0 {
+	styles := append(append([]parser.Style{}, parsed.Styles...), renderReq.Styles...)
+	rendered = injectPageAssets(rendered, filePath, styles)
+
+	if hasActions {
+		rendered = actions.InjectClient(rendered)
+	}
+
+	mwCtx.Response.WriteHeader(response.StatusOr(status))
+	mwCtx.Response.Write([]byte(rendered))
+}
+
+// injectPageAssets adds styles before </head> and the page's WASM and JS
+// scripts before </body>.
+func injectPageAssets(rendered, filePath string, styles []parser.Style) string {
+	if len(styles) > 0 && strings.Contains(rendered, "</head>") {
 		var styleContent string
-		for _, style := range allStyles {
+		for _, style := range styles {
 			styleContent += style.Content + "\n"
 		}
 		styleTag := "<style>" + styleContent + "</style>"
 		rendered = strings.Replace(rendered, "</head>", styleTag+"\n</head>", 1)
 	}
 
-	if wasmManifest != nil {
-		pageAssets, ok := wasmManifest.Assets[filePath]
-		if ok && len(pageAssets.WasmModules) > 0 {
-			wasmExecTag := "<script src=\"/wasm_exec.js\"></script>"
-			rendered = strings.Replace(rendered, "</body>", wasmExecTag+"\n</body>", 1)
+	if wasmManifest == nil || !strings.Contains(rendered, "</body>") {
+		return rendered
+	}
 
-			for _, mod := range pageAssets.WasmModules {
-				loaderTag := fmt.Sprintf("<script src=\"%s\"></script>", mod.LoaderPath)
-				rendered = strings.Replace(rendered, "</body>", loaderTag+"\n</body>", 1)
-			}
+	pageAssets, ok := wasmManifest.Assets[filePath]
+	if ok && len(pageAssets.WasmModules) > 0 {
+		wasmExecTag := "<script src=\"/wasm_exec.js\"></script>"
+		rendered = strings.Replace(rendered, "</body>", wasmExecTag+"\n</body>", 1)
+
+		for _, mod := range pageAssets.WasmModules {
+			loaderTag := fmt.Sprintf("<script src=\"%s\"></script>", mod.LoaderPath)
+			rendered = strings.Replace(rendered, "</body>", loaderTag+"\n</body>", 1)
 		}
+	}
 
-		if len(pageAssets.JSScripts) > 0 {
-			for _, jsPath := range pageAssets.JSScripts {
-				jsTag := fmt.Sprintf("<script type=\"module\" src=\"%s\"></script>", jsPath)
-				rendered = strings.Replace(rendered, "</body>", jsTag+"\n</body>", 1)
-			}
+	if len(pageAssets.JSScripts) > 0 {
+		for _, jsPath := range pageAssets.JSScripts {
+			jsTag := fmt.Sprintf("<script type=\"module\" src=\"%s\"></script>", jsPath)
+			rendered = strings.Replace(rendered, "</body>", jsTag+"\n</body>", 1)
 		}
 	}
 
-	mwCtx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
-	mwCtx.Response.Write([]byte(rendered))
+	return rendered
 }
 `
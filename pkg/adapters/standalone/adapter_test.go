@@ -0,0 +1,318 @@
+package standalone
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+// buildServer writes files into a project, generates its server for the
+// pages under routes and builds it with the given go build flags.
+func buildServer(t *testing.T, files map[string]string, routes map[string]string, flags ...string) (serverDir string, port int) {
+	t.Helper()
+
+	project := t.TempDir()
+	pagesDir := filepath.Join(project, "src", "pages")
+	for name, content := range files {
+		path := filepath.Join(project, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port = listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Server.Host = "127.0.0.1"
+	cfg.Server.Port = port
+	serverDir = filepath.Join(project, "dist", "server")
+	os.MkdirAll(serverDir, 0755)
+	build := &adapters.BuildConfig{
+		Config:    cfg,
+		ServerDir: serverDir,
+		OutDir:    filepath.Join(project, "dist"),
+		PagesDir:  pagesDir,
+	}
+	for pattern, page := range routes {
+		route := adapters.RouteInfo{Pattern: pattern, FilePath: filepath.Join(pagesDir, page), IsEndpoint: filepath.Ext(page) == ".go"}
+		if actionsFile := strings.TrimSuffix(route.FilePath, ".gxc") + ".actions.go"; !route.IsEndpoint {
+			if _, err := os.Stat(actionsFile); err == nil {
+				route.ActionsFile = actionsFile
+			}
+		}
+		build.Routes = append(build.Routes, route)
+	}
+
+	a := New()
+	if err := a.generateMain(build); err != nil {
+		t.Fatalf("generateMain failed: %v", err)
+	}
+	if err := a.generateGoMod(build); err != nil {
+		t.Fatalf("generateGoMod failed: %v", err)
+	}
+	for _, args := range [][]string{{"mod", "tidy"}, append(append([]string{"build"}, flags...), "-o", "server", ".")} {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = serverDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s failed: %v\n%s", args[0], err, out)
+		}
+	}
+	return serverDir, port
+}
+
+// lockedBuffer collects a process's output, which os/exec copies in from
+// its own goroutine while the test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startServer runs binary and waits until readyPath answers.
+func startServer(t *testing.T, binary string, port int, readyPath string) (base string, stderr *lockedBuffer) {
+	t.Helper()
+
+	stderr = &lockedBuffer{}
+	server := exec.Command(binary)
+	server.Dir = t.TempDir()
+	server.Stderr = stderr
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Process.Kill() })
+
+	base = fmt.Sprintf("http://127.0.0.1:%d", port)
+	for i := 0; ; i++ {
+		if resp, err := http.Get(base + readyPath); err == nil {
+			resp.Body.Close()
+			return base, stderr
+		}
+		if i == 100 {
+			t.Fatalf("Server did not start:\n%s", stderr.String())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// TestServerConcurrentRequests builds the generated server with the race
+// detector and renders two pages whose Card components differ under load.
+func TestServerConcurrentRequests(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a server binary")
+	}
+
+	serverDir, port := buildServer(t, map[string]string{
+		"src/components/Badge.gxc":  "---\nshown := slug\n---\n<span class=\"badge\">{shown}</span>\n<style>.badge { color: blue }</style>",
+		"src/pages/blog/Card.gxc":   "<article>blog <Badge slug={slug} /></article>\n<style>.blog-card { margin: 1px }</style>",
+		"src/pages/shop/Card.gxc":   "<section>shop <Badge slug={slug} /></section>\n<style>.shop-card { margin: 2px }</style>",
+		"src/pages/blog/[slug].gxc": "<html><head></head><body><h1>{slug}</h1><Card slug={slug} /></body></html>",
+		"src/pages/shop/[slug].gxc": "<html><head></head><body><h1>{slug}</h1><Card slug={slug} /></body></html>",
+	}, map[string]string{
+		"/blog/{slug}": "blog/[slug].gxc",
+		"/shop/{slug}": "shop/[slug].gxc",
+	}, "-race")
+	base, stderr := startServer(t, filepath.Join(serverDir, "server"), port, "/blog/ready")
+
+	var wg sync.WaitGroup
+	for w := 0; w < 20; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				kind, other := "blog", "shop"
+				if (w+i)%2 == 1 {
+					kind, other = other, kind
+				}
+				slug := fmt.Sprintf("w%d-%d", w, i)
+
+				resp, err := http.Get(base + "/" + kind + "/" + slug)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				html := string(body)
+
+				if strings.Count(html, slug) != 2 {
+					t.Errorf("Expected %s twice in /%s, got %q", slug, kind, html)
+				}
+				if !strings.Contains(html, "."+kind+"-card") || strings.Contains(html, other) {
+					t.Errorf("Expected only %s content and styles, got %q", kind, html)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if strings.Contains(stderr.String(), "DATA RACE") {
+		t.Errorf("Expected no data races, got:\n%s", stderr.String())
+	}
+}
+
+// TestServerActionPackages builds pages whose actions share names, in one
+// directory and in directories of the same name, next to endpoints in
+// directories of the same name.
+func TestServerActionPackages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a server binary")
+	}
+
+	action := func(result string) string {
+		return "package blog\n\nimport \"github.com/cameron-webmatter/galaxy/pkg/endpoints\"\n\nfunc Subscribe(c *endpoints.Context) (string, error) {\n\treturn \"" + result + "\", nil\n}\n"
+	}
+	endpoint := func(result string) string {
+		return "package blog\n\nimport \"github.com/cameron-webmatter/galaxy/pkg/endpoints\"\n\nfunc GET(c *endpoints.Context) error {\n\treturn c.Text(200, \"" + result + "\")\n}\n"
+	}
+	serverDir, port := buildServer(t, map[string]string{
+		"src/pages/blog/a.gxc":            "<h1>a</h1>",
+		"src/pages/blog/a.actions.go":     action("blog a"),
+		"src/pages/blog/b.gxc":            "<h1>b</h1>",
+		"src/pages/blog/b.actions.go":     action("blog b"),
+		"src/pages/blog/feed.go":          endpoint("blog feed"),
+		"src/pages/api/blog/c.gxc":        "<h1>c</h1>",
+		"src/pages/api/blog/c.actions.go": action("api c"),
+		"src/pages/api/blog/feed.go":      endpoint("api feed"),
+	}, map[string]string{
+		"/blog/a":        "blog/a.gxc",
+		"/blog/b":        "blog/b.gxc",
+		"/blog/feed":     "blog/feed.go",
+		"/api/blog/c":    "api/blog/c.gxc",
+		"/api/blog/feed": "api/blog/feed.go",
+	})
+	base, _ := startServer(t, filepath.Join(serverDir, "server"), port, "/blog/a")
+
+	for path, want := range map[string]string{"/blog/a": "blog a", "/blog/b": "blog b", "/api/blog/c": "api c"} {
+		req, _ := http.NewRequest(http.MethodPost, base+path+"?_action=subscribe", nil)
+		req.Header.Set("X-Galaxy-Action", "1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), `"data":"`+want+`"`) {
+			t.Errorf("Expected %s to run its own action, got %d %s", path, resp.StatusCode, body)
+		}
+	}
+
+	for path, want := range map[string]string{"/blog/feed": "blog feed", "/api/blog/feed": "api feed"} {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != want {
+			t.Errorf("Expected %s to answer %q, got %q", path, want, body)
+		}
+	}
+}
+
+// TestServerEmbedsFiles moves the built binary away from its build
+// directory and serves pages, components, prerendered and public files and
+// hashed assets from the binary alone.
+func TestServerEmbedsFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a server binary")
+	}
+
+	serverDir, port := buildServer(t, map[string]string{
+		"src/components/Card.gxc":                 "<p class=\"card\">{title}</p>",
+		"src/pages/index.gxc":                     "---\ntitle := \"home\"\n---\n<html><body><Card title={title} /></body></html>",
+		"dist/public/robots.txt":                  "User-agent: *",
+		"dist/about/index.html":                   "<h1>prerendered</h1>",
+		"dist/server/_assets/styles-1a2b3c4d.css": "body { margin: 0 }",
+	}, map[string]string{"/": "index.gxc"})
+
+	binary := filepath.Join(t.TempDir(), "server")
+	data, err := os.ReadFile(filepath.Join(serverDir, "server"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(binary, data, 0755)
+	os.RemoveAll(filepath.Dir(filepath.Dir(serverDir)))
+
+	base, _ := startServer(t, binary, port, "/")
+
+	get := func(path string) (*http.Response, string) {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	if _, body := get("/"); !strings.Contains(body, `<p class="card">home</p>`) {
+		t.Errorf("Expected page with component, got %q", body)
+	}
+	if _, body := get("/about"); body != "<h1>prerendered</h1>" {
+		t.Errorf("Expected prerendered page, got %q", body)
+	}
+	if resp, body := get("/robots.txt"); body != "User-agent: *" || resp.Header.Get("ETag") == "" {
+		t.Errorf("Expected public file with ETag, got %q %v", body, resp.Header)
+	}
+	resp, body := get("/_assets/styles-1a2b3c4d.css")
+	if body != "body { margin: 0 }" {
+		t.Errorf("Expected asset, got %q", body)
+	}
+	if got := resp.Header.Get("Cache-Control"); !strings.Contains(got, "immutable") {
+		t.Errorf("Expected immutable hashed asset, got %q", got)
+	}
+}
+
+func TestDirMiddlewareAliasesUnique(t *testing.T) {
+	pagesDir := t.TempDir()
+	for _, dir := range []string{"a-b", "a_b", "a/b"} {
+		path := filepath.Join(pagesDir, filepath.FromSlash(dir), "_middleware.go")
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("package mw\n"), 0644)
+	}
+
+	dirs, err := New().copyDirMiddleware(&adapters.BuildConfig{PagesDir: pagesDir, ServerDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliases := make(map[string]string)
+	for _, d := range dirs {
+		alias := d["Alias"].(string)
+		if other, ok := aliases[alias]; ok {
+			t.Errorf("Expected %s and %s to get different packages, both got %s", other, d["Dir"], alias)
+		}
+		aliases[alias] = d["Dir"].(string)
+	}
+	if len(dirs) != 3 {
+		t.Errorf("Expected 3 middleware packages, got %d", len(dirs))
+	}
+}
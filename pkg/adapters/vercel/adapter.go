@@ -0,0 +1,319 @@
+// Package vercel deploys Galaxy sites to Vercel through the Build Output
+// API (v3): static files and prerendered pages go to .vercel/output/static
+// and the server is compiled into a function serving every other route.
+package vercel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
+	"github.com/cameron-webmatter/galaxy/pkg/codegen"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+// FunctionName is the name of the function serving dynamic routes.
+const FunctionName = "galaxy"
+
+type VercelAdapter struct{}
+
+func New() *VercelAdapter {
+	return &VercelAdapter{}
+}
+
+func init() {
+	adapters.Register(New())
+}
+
+func (a *VercelAdapter) Name() string {
+	return "vercel"
+}
+
+func (a *VercelAdapter) Capabilities() adapters.Capabilities {
+	return adapters.Capabilities{Static: true, StaticOutput: true, SSR: true}
+}
+
+// Emulate sets the client address headers Vercel adds to requests.
+func (a *VercelAdapter) Emulate(cfg *config.Config, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Real-Ip") == "" {
+			ip := adapters.ClientIP(r)
+			r.Header.Set("X-Real-Ip", ip)
+			r.Header.Set("X-Forwarded-For", ip)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Config is .vercel/output/config.json.
+type Config struct {
+	Version   int                 `json:"version"`
+	Routes    []Route             `json:"routes"`
+	Overrides map[string]Override `json:"overrides,omitempty"`
+}
+
+// Route is an entry of Config.Routes. An entry with only Handle set starts
+// a routing phase instead.
+type Route struct {
+	Src      string            `json:"src,omitempty"`
+	Dest     string            `json:"dest,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Continue bool              `json:"continue,omitempty"`
+	Handle   string            `json:"handle,omitempty"`
+}
+
+// Override serves a static file at another path.
+type Override struct {
+	Path string `json:"path"`
+}
+
+// FunctionConfig is the .vc-config.json of a function.
+type FunctionConfig struct {
+	Runtime      string `json:"runtime"`
+	Handler      string `json:"handler"`
+	Architecture string `json:"architecture"`
+}
+
+// PrerenderConfig is the .prerender-config.json that makes a function
+// serve a prerendered page and regenerate it once it expires.
+type PrerenderConfig struct {
+	Expiration int    `json:"expiration"`
+	Fallback   string `json:"fallback"`
+}
+
+// Build writes the deploy to RootDir/.vercel/output, where the Vercel CLI
+// picks it up with `vercel deploy --prebuilt`.
+func (a *VercelAdapter) Build(cfg *adapters.BuildConfig) error {
+	routes := cfg.DynamicRoutes()
+	incremental := incrementalPages(cfg)
+	hasFunction := len(routes) > 0 || len(incremental) > 0
+	if hasFunction && cfg.Config.Output.Assets == config.AssetsDisk {
+		return fmt.Errorf("vercel functions ship as a single binary; set output.assets to embed")
+	}
+
+	rootDir := cfg.RootDir
+	if rootDir == "" {
+		rootDir = filepath.Dir(cfg.OutDir)
+	}
+	outDir := filepath.Join(rootDir, ".vercel", "output")
+	staticDir := filepath.Join(outDir, "static")
+	functionsDir := filepath.Join(outDir, "functions")
+	if err := os.RemoveAll(outDir); err != nil {
+		return err
+	}
+
+	if err := codegen.StageStatic(cfg.OutDir, cfg.ServerDir, staticDir); err != nil {
+		return fmt.Errorf("stage static files: %w", err)
+	}
+
+	if hasFunction {
+		funcDir := filepath.Join(functionsDir, FunctionName+".func")
+		if err := adapters.Compile(cfg.ServerDir, filepath.Join(funcDir, "bootstrap"), "GOOS=linux", "GOARCH=amd64", "CGO_ENABLED=0"); err != nil {
+			return fmt.Errorf("compile function: %w", err)
+		}
+		if err := writeJSON(filepath.Join(funcDir, ".vc-config.json"), FunctionConfig{
+			Runtime:      "provided.al2023",
+			Handler:      "bootstrap",
+			Architecture: "x86_64",
+		}); err != nil {
+			return err
+		}
+	}
+
+	for path, ttl := range incremental {
+		if err := writePrerender(staticDir, functionsDir, path, ttl); err != nil {
+			return fmt.Errorf("prerender %s: %w", path, err)
+		}
+	}
+
+	vc, err := buildConfig(cfg.Config, staticDir, routes, hasFunction)
+	if err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(outDir, "config.json"), vc)
+}
+
+// incrementalPages returns the TTL of each incremental page with a fixed
+// path that a cache route rule gives a TTL. Vercel serves these from the
+// prerendered file and regenerates them through the function once stale.
+func incrementalPages(cfg *adapters.BuildConfig) map[string]int {
+	pages := make(map[string]int)
+	for _, r := range cfg.Routes {
+		if !r.Incremental || strings.Contains(r.Pattern, "[") {
+			continue
+		}
+		if ttl := cache.PolicyFor(cfg.Config.Cache.Routes, r.Pattern).TTL; ttl > 0 {
+			pages[r.Pattern] = ttl
+		}
+	}
+	return pages
+}
+
+// writePrerender moves the prerendered page for path out of the static
+// files, where it would shadow the function, and makes it the fallback of
+// a prerender function linked to the server function.
+func writePrerender(staticDir, functionsDir, path string, ttl int) error {
+	name := strings.Trim(path, "/")
+	if name == "" {
+		name = "index"
+	}
+	base := filepath.Join(functionsDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return err
+	}
+
+	page := filepath.Join(staticDir, filepath.FromSlash(strings.Trim(path, "/")), "index.html")
+	fallback := base + ".prerender-fallback.html"
+	if err := os.Rename(page, fallback); err != nil {
+		return err
+	}
+
+	target, err := filepath.Rel(filepath.Dir(base), filepath.Join(functionsDir, FunctionName+".func"))
+	if err != nil {
+		return err
+	}
+	if err := os.Symlink(target, base+".func"); err != nil {
+		return err
+	}
+	return writeJSON(base+".prerender-config.json", PrerenderConfig{
+		Expiration: ttl,
+		Fallback:   filepath.Base(fallback),
+	})
+}
+
+// buildConfig routes hashed assets and cache route rules to their headers,
+// then, after static files, the server's routes to the function.
+func buildConfig(cfg *config.Config, staticDir string, routes []adapters.RouteInfo, hasFunction bool) (*Config, error) {
+	out := &Config{Version: 3}
+
+	out.Routes = append(out.Routes, Route{
+		Src:      `^/_assets/(?:.*/)?[^/]*[-.][0-9a-f]{8,}[-.][^/]*$`,
+		Headers:  map[string]string{"Cache-Control": "public, max-age=31536000, immutable"},
+		Continue: true,
+	})
+	if cfg.Cache.Enabled {
+		for _, rule := range cfg.Cache.Routes {
+			if rule.TTL <= 0 {
+				continue
+			}
+			cdn := fmt.Sprintf("max-age=%d", rule.TTL)
+			if rule.StaleWhileRevalidate > 0 {
+				cdn += fmt.Sprintf(", stale-while-revalidate=%d", rule.StaleWhileRevalidate)
+			}
+			out.Routes = append(out.Routes, Route{
+				Src: ruleSrc(rule.Path),
+				Headers: map[string]string{
+					"Cache-Control":            "public, max-age=0, must-revalidate",
+					"Vercel-CDN-Cache-Control": cdn,
+				},
+				Continue: true,
+			})
+		}
+	}
+
+	out.Routes = append(out.Routes, Route{Handle: "filesystem"})
+
+	dest := "/" + FunctionName
+	if hasFunction {
+		if cfg.Cache.Enabled {
+			out.Routes = append(out.Routes, Route{Src: "^" + regexp.QuoteMeta(cache.RevalidatePath) + "$", Dest: dest})
+		}
+		if cfg.Telemetry.Metrics.Enabled {
+			out.Routes = append(out.Routes, Route{Src: "^" + regexp.QuoteMeta(cfg.Telemetry.Metrics.Path) + "$", Dest: dest})
+		}
+	}
+	for _, r := range routes {
+		out.Routes = append(out.Routes, Route{Src: routeSrc(r.Pattern), Dest: dest})
+	}
+
+	overrides, err := pageOverrides(staticDir)
+	if err != nil {
+		return nil, err
+	}
+	out.Overrides = overrides
+	return out, nil
+}
+
+var routeParam = regexp.MustCompile(`\[(\.\.\.)?(\w+)\]`)
+
+// routeSrc converts a route pattern to the regular expression the router
+// matches it with.
+func routeSrc(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, m := range routeParam.FindAllStringSubmatchIndex(pattern, -1) {
+		b.WriteString(regexp.QuoteMeta(pattern[last:m[0]]))
+		name := pattern[m[4]:m[5]]
+		if m[2] >= 0 {
+			fmt.Fprintf(&b, "(?<%s>.*)", name)
+		} else {
+			fmt.Fprintf(&b, "(?<%s>[^/]+)", name)
+		}
+		last = m[1]
+	}
+	b.WriteString(regexp.QuoteMeta(pattern[last:]))
+	b.WriteString("$")
+	return b.String()
+}
+
+// ruleSrc converts a cache route rule path, where * matches one segment and
+// ** any number, to a regular expression.
+func ruleSrc(path string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, seg := range strings.Split(strings.Trim(path, "/"), "/") {
+		switch seg {
+		case "":
+		case "**":
+			b.WriteString("(?:/.*)?")
+		case "*":
+			b.WriteString("/[^/]+")
+		default:
+			b.WriteString("/" + regexp.QuoteMeta(seg))
+		}
+	}
+	b.WriteString("/?$")
+	return b.String()
+}
+
+// pageOverrides serves each prerendered about/index.html at /about.
+func pageOverrides(staticDir string) (map[string]Override, error) {
+	overrides := make(map[string]Override)
+	err := filepath.WalkDir(staticDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != "index.html" {
+			return err
+		}
+		rel, err := filepath.Rel(staticDir, path)
+		if err != nil || rel == "index.html" {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		overrides[rel] = Override{Path: strings.TrimSuffix(rel, "/index.html")}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+	return overrides, nil
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
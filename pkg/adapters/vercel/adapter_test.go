@@ -0,0 +1,190 @@
+package vercel
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/adapters/adaptertest"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/lambda"
+	"github.com/cameron-webmatter/galaxy/pkg/lambda/lambdatest"
+)
+
+func TestRouteSrc(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{"/", []string{"/"}, []string{"/a"}},
+		{"/blog/[slug]", []string{"/blog/hello"}, []string{"/blog", "/blog/a/b"}},
+		{"/docs/[...rest]", []string{"/docs/", "/docs/a/b/c"}, []string{"/doc"}},
+		{"/api/v1.0/[id]", []string{"/api/v1.0/7"}, []string{"/api/v1x0/7"}},
+	}
+	for _, tt := range tests {
+		re := regexp.MustCompile(routeSrc(tt.pattern))
+		for _, path := range tt.match {
+			if !re.MatchString(path) {
+				t.Errorf("Expected %s (%s) to match %s", tt.pattern, re, path)
+			}
+		}
+		for _, path := range tt.noMatch {
+			if re.MatchString(path) {
+				t.Errorf("Expected %s (%s) not to match %s", tt.pattern, re, path)
+			}
+		}
+	}
+
+	if got := routeSrc("/blog/[slug]"); got != "^/blog/(?<slug>[^/]+)$" {
+		t.Errorf("Expected a named group, got %s", got)
+	}
+}
+
+func TestRuleSrc(t *testing.T) {
+	tests := map[string][]string{
+		"/blog/**": {"/blog", "/blog/a", "/blog/a/b"},
+		"/blog/*":  {"/blog/a", "/blog/a/"},
+		"/":        {"/"},
+	}
+	for path, matches := range tests {
+		re := regexp.MustCompile(ruleSrc(path))
+		for _, m := range matches {
+			if !re.MatchString(m) {
+				t.Errorf("Expected %s (%s) to match %s", path, re, m)
+			}
+		}
+	}
+	if regexp.MustCompile(ruleSrc("/blog/*")).MatchString("/blog/a/b") {
+		t.Error("Expected * to match a single segment")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a function")
+	}
+
+	project := t.TempDir()
+	outDir := filepath.Join(project, "dist")
+	serverDir := filepath.Join(outDir, "server")
+	for name, content := range map[string]string{
+		"index.html":          "home",
+		"about/index.html":    "about",
+		"pricing/index.html":  "pricing",
+		"public/robots.txt":   "robots",
+		"server/wasm_exec.js": "wasm",
+	} {
+		path := filepath.Join(outDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	adaptertest.WriteServer(t, serverDir, map[string]string{
+		"main.go":                 adaptertest.PathServer,
+		"_assets/app-1a2b3c4d.js": "app",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Cache.Enabled = true
+	cfg.Cache.Routes = []config.CacheRoute{
+		{Path: "/pricing", TTL: 600},
+		{Path: "/blog/*", TTL: 60, StaleWhileRevalidate: 30},
+	}
+	build := &adapters.BuildConfig{
+		Config:    cfg,
+		RootDir:   project,
+		ServerDir: serverDir,
+		OutDir:    outDir,
+		Routes: []adapters.RouteInfo{
+			{Pattern: "/about", Prerendered: true},
+			{Pattern: "/pricing", Prerendered: true, Incremental: true},
+			{Pattern: "/blog/[slug]"},
+			{Pattern: "/api/[...path]", IsEndpoint: true},
+			{Pattern: "/", Prerendered: true},
+		},
+	}
+	if err := New().Build(build); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	output := filepath.Join(project, ".vercel", "output")
+	for name, want := range map[string]string{
+		"static/index.html":                         "home",
+		"static/about/index.html":                   "about",
+		"static/robots.txt":                         "robots",
+		"static/wasm_exec.js":                       "wasm",
+		"static/_assets/app-1a2b3c4d.js":            "app",
+		"functions/pricing.prerender-fallback.html": "pricing",
+		"functions/pricing.prerender-config.json":   `"expiration": 600`,
+		"functions/galaxy.func/.vc-config.json":     `"handler": "bootstrap"`,
+		"functions/pricing.func/.vc-config.json":    `"runtime": "provided.al2023"`,
+		"config.json":                               `"version": 3`,
+	} {
+		data, err := os.ReadFile(filepath.Join(output, name))
+		if err != nil || !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s to contain %q, got %q (%v)", name, want, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(output, "static", "pricing", "index.html")); err == nil {
+		t.Error("Expected the incremental page to move out of the static files")
+	}
+	if _, err := os.Stat(filepath.Join(output, "static", "server")); err == nil {
+		t.Error("Expected the server to stay out of the static files")
+	}
+
+	var vc Config
+	data, _ := os.ReadFile(filepath.Join(output, "config.json"))
+	if err := json.Unmarshal(data, &vc); err != nil {
+		t.Fatal(err)
+	}
+	var phases, dests []string
+	for _, r := range vc.Routes {
+		if r.Handle != "" {
+			phases = append(phases, r.Handle)
+		}
+		if r.Dest != "" {
+			dests = append(dests, r.Src)
+		}
+		if r.Src == ruleSrc("/blog/*") && r.Headers["Vercel-CDN-Cache-Control"] != "max-age=60, stale-while-revalidate=30" {
+			t.Errorf("Expected CDN caching for /blog/*, got %v", r.Headers)
+		}
+	}
+	if len(phases) != 1 || phases[0] != "filesystem" {
+		t.Errorf("Expected a filesystem phase, got %v", phases)
+	}
+	want := []string{"^/_galaxy/revalidate$", "^/blog/(?<slug>[^/]+)$", "^/api/(?<path>.*)$"}
+	if strings.Join(dests, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected function routes %v, got %v", want, dests)
+	}
+	if vc.Overrides["about/index.html"].Path != "about" {
+		t.Errorf("Expected /about to serve about/index.html, got %v", vc.Overrides)
+	}
+
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("function binary targets linux/amd64")
+	}
+	invokeFunction(t, filepath.Join(output, "functions", "galaxy.func", "bootstrap"))
+}
+
+// invokeFunction runs the function the way Vercel invokes it.
+func invokeFunction(t *testing.T, function string) {
+	t.Helper()
+
+	req, _ := json.Marshal(lambda.VercelRequest{Method: "GET", Path: "/blog/hello", Host: "site.vercel.app"})
+	event, _ := json.Marshal(lambda.VercelEvent{Action: "Invoke", Body: string(req)})
+	responses := lambdatest.Invoke(t, function, string(event))
+
+	var res lambda.VercelResponse
+	if err := json.Unmarshal(responses[0], &res); err != nil {
+		t.Fatalf("Expected a Vercel response, got %q", responses[0])
+	}
+	if res.StatusCode != http.StatusOK || res.Body != "dynamic /blog/hello" {
+		t.Errorf("Unexpected response: %+v", res)
+	}
+}
@@ -0,0 +1,132 @@
+// Package auth guards pages and endpoints by role. Guards run as middleware,
+// so protected routes are rejected before frontmatter or handlers execute.
+//
+// The current user is read from Locals (set by your own middleware) and then
+// from the session: "user" marks the request as signed in and "roles" lists
+// its roles.
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+	"github.com/cameron-webmatter/galaxy/pkg/session"
+)
+
+const (
+	UserKey  = "user"
+	RolesKey = "roles"
+)
+
+// Rule describes who may access a route.
+type Rule struct {
+	// Roles lists accepted roles; any one is enough. Empty allows every
+	// signed-in user.
+	Roles []string
+	// Redirect sends anonymous visitors to this URL, with the requested
+	// path in a "next" query parameter. Empty responds 401.
+	Redirect string
+}
+
+// Require allows signed-in users holding any of roles.
+func Require(roles ...string) middleware.Middleware {
+	return Guard(Rule{Roles: roles})
+}
+
+// Guard rejects requests that do not satisfy rule: anonymous visitors are
+// redirected or get 401, and signed-in users without a matching role get
+// 403.
+func Guard(rule Rule) middleware.Middleware {
+	return func(ctx *middleware.Context, next func() error) error {
+		user, roles := Identify(ctx)
+
+		if user == nil {
+			if rule.Redirect != "" {
+				http.Redirect(ctx.Response, ctx.Request, loginURL(rule.Redirect, ctx.Request), http.StatusSeeOther)
+				return nil
+			}
+			http.Error(ctx.Response, "Unauthorized", http.StatusUnauthorized)
+			return nil
+		}
+
+		if !hasAnyRole(roles, rule.Roles) {
+			http.Error(ctx.Response, "Forbidden", http.StatusForbidden)
+			return nil
+		}
+
+		// Guarded pages differ per visitor, so they are never shared.
+		cache.PolicyFrom(ctx.Request).Private()
+		return next()
+	}
+}
+
+// Identify returns the signed-in user and their roles, or a nil user.
+func Identify(ctx *middleware.Context) (user any, roles []string) {
+	if user = ctx.Get(UserKey); user != nil {
+		roles = toStrings(ctx.Get(RolesKey))
+		if roles == nil {
+			if m, ok := user.(map[string]any); ok {
+				roles = toStrings(m[RolesKey])
+			}
+		}
+		return user, roles
+	}
+
+	sess, err := session.Load(ctx.Request)
+	if err != nil || sess == nil {
+		return nil, nil
+	}
+	if user = sess.Get(UserKey); user != nil {
+		roles = toStrings(sess.Get(RolesKey))
+	}
+	return user, roles
+}
+
+func hasAnyRole(have, want []string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, w := range want {
+		for _, h := range have {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toStrings(v any) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []any:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	case string:
+		var out []string
+		for _, role := range strings.Split(v, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				out = append(out, role)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func loginURL(redirect string, r *http.Request) string {
+	sep := "?"
+	if strings.Contains(redirect, "?") {
+		sep = "&"
+	}
+	return redirect + sep + "next=" + url.QueryEscape(r.URL.RequestURI())
+}
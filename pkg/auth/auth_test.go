@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+	"github.com/cameron-webmatter/galaxy/pkg/session"
+)
+
+func serve(rule Rule, locals map[string]any, req *http.Request) (*httptest.ResponseRecorder, bool) {
+	w := httptest.NewRecorder()
+	ctx := middleware.NewContext(w, req)
+	for k, v := range locals {
+		ctx.Set(k, v)
+	}
+
+	reached := false
+	middleware.NewChain().Use(Guard(rule)).Execute(ctx, func(ctx *middleware.Context) error {
+		reached = true
+		return nil
+	})
+	return w, reached
+}
+
+func TestGuardAnonymous(t *testing.T) {
+	w, reached := serve(Rule{}, nil, httptest.NewRequest("GET", "/admin", nil))
+	if reached || w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d (reached=%v)", w.Code, reached)
+	}
+
+	w, _ = serve(Rule{Redirect: "/login"}, nil, httptest.NewRequest("GET", "/admin?tab=1", nil))
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected 303, got %d", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "/login?next=%2Fadmin%3Ftab%3D1" {
+		t.Errorf("Expected login redirect with next, got %s", loc)
+	}
+}
+
+func TestGuardRoles(t *testing.T) {
+	rule := Rule{Roles: []string{"admin", "editor"}}
+
+	w, reached := serve(rule, map[string]any{"user": "ada", "roles": []string{"viewer"}}, httptest.NewRequest("GET", "/", nil))
+	if reached || w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403, got %d", w.Code)
+	}
+
+	_, reached = serve(rule, map[string]any{"user": "ada", "roles": "viewer, editor"}, httptest.NewRequest("GET", "/", nil))
+	if !reached {
+		t.Error("Expected editor to be allowed")
+	}
+
+	user := map[string]any{"name": "ada", "roles": []any{"admin"}}
+	_, reached = serve(rule, map[string]any{"user": user}, httptest.NewRequest("GET", "/", nil))
+	if !reached {
+		t.Error("Expected roles read from the user map")
+	}
+
+	_, reached = serve(Rule{}, map[string]any{"user": "ada"}, httptest.NewRequest("GET", "/", nil))
+	if !reached {
+		t.Error("Expected any signed-in user to pass a rule without roles")
+	}
+}
+
+func TestGuardSession(t *testing.T) {
+	codec, _ := session.NewCodec(false, "0123456789abcdef0123456789abcdef")
+	store := session.NewMemoryStore(codec, session.Options{})
+
+	req := session.WithStore(httptest.NewRequest("GET", "/", nil), store)
+	sess, _ := session.Load(req)
+	sess.Set("user", "ada")
+	sess.Set("roles", []any{"admin"})
+
+	if _, reached := serve(Rule{Roles: []string{"admin"}}, nil, req); !reached {
+		t.Error("Expected session user to be allowed")
+	}
+}
+
+func TestParse(t *testing.T) {
+	rule, err := Parse("---\n//galaxy:auth admin,editor owner redirect=/login\ntitle := \"x\"\n---")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	expected := &Rule{Roles: []string{"admin", "editor", "owner"}, Redirect: "/login"}
+	if !reflect.DeepEqual(rule, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, rule)
+	}
+
+	if rule, _ := Parse("//galaxy:auth"); rule == nil || len(rule.Roles) != 0 {
+		t.Errorf("Expected rule without roles, got %+v", rule)
+	}
+	if rule, _ := Parse("//galaxy:authors"); rule != nil {
+		t.Error("Expected no rule for a different directive")
+	}
+	if _, err := Parse("//galaxy:auth timeout=5"); err == nil {
+		t.Error("Expected error for unknown option")
+	}
+	if rule, _ := Parse("---\ntitle := \"Auth\"\n---\n<pre>\n//galaxy:auth admin\n</pre>"); rule != nil {
+		t.Errorf("Expected markup after the frontmatter to be ignored, got %+v", rule)
+	}
+}
+
+func TestFileRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.gxc")
+	os.WriteFile(path, []byte("---\n//galaxy:auth admin\n---\n<h1>Admin</h1>"), 0644)
+
+	rule, err := FileRule(path)
+	if err != nil {
+		t.Fatalf("FileRule failed: %v", err)
+	}
+	if rule == nil || rule.Roles[0] != "admin" {
+		t.Errorf("Expected admin rule, got %+v", rule)
+	}
+
+	plain := filepath.Join(t.TempDir(), "index.gxc")
+	os.WriteFile(plain, []byte("<h1>Home</h1>"), 0644)
+	if rule, _ := FileRule(plain); rule != nil {
+		t.Errorf("Expected no rule, got %+v", rule)
+	}
+
+	docs := filepath.Join(t.TempDir(), "docs.gxc")
+	os.WriteFile(docs, []byte("<pre>\n//galaxy:auth admin\n</pre>"), 0644)
+	if rule, _ := FileRule(docs); rule != nil {
+		t.Errorf("Expected no rule from markup, got %+v", rule)
+	}
+
+	endpoint := filepath.Join(t.TempDir(), "admin.go")
+	os.WriteFile(endpoint, []byte("package api\n\n//galaxy:auth admin\n"), 0644)
+	if rule, _ := FileRule(endpoint); rule == nil || rule.Roles[0] != "admin" {
+		t.Errorf("Expected admin rule from an endpoint, got %+v", rule)
+	}
+}
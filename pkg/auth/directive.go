@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Directive declares a guard in a page's frontmatter or an endpoint file:
+//
+//	//galaxy:auth admin editor redirect=/login
+//
+// Bare words are roles; with none, any signed-in user is allowed.
+const Directive = "//galaxy:auth"
+
+// frontmatterRegex matches a page's frontmatter, as the parser does.
+var frontmatterRegex = regexp.MustCompile(`(?s)^---\n(.*?)\n---`)
+
+// Parse returns the rule declared in src, a page or a Go file, if any. In a
+// page only the frontmatter is searched, so markup can show the directive.
+func Parse(src string) (*Rule, error) {
+	if m := frontmatterRegex.FindStringSubmatch(src); m != nil {
+		src = m[1]
+	}
+
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line != Directive && !strings.HasPrefix(line, Directive+" ") {
+			continue
+		}
+
+		rule := &Rule{}
+		for _, field := range strings.Fields(strings.TrimPrefix(line, Directive)) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				for _, role := range strings.Split(field, ",") {
+					if role != "" {
+						rule.Roles = append(rule.Roles, role)
+					}
+				}
+				continue
+			}
+
+			switch key {
+			case "redirect":
+				rule.Redirect = value
+			case "roles":
+				rule.Roles = append(rule.Roles, strings.Split(value, ",")...)
+			default:
+				return nil, fmt.Errorf("%s: unknown option %q", Directive, key)
+			}
+		}
+		return rule, nil
+	}
+	return nil, nil
+}
+
+type cachedRule struct {
+	modTime time.Time
+	rule    *Rule
+}
+
+var (
+	ruleCacheMu sync.Mutex
+	ruleCache   = make(map[string]cachedRule)
+)
+
+// FileRule returns the rule declared in a page or endpoint file. Results are
+// cached until the file changes.
+func FileRule(path string) (*Rule, error) {
+	return fileRule(path, os.Stat, os.ReadFile)
+}
+
+// FileRuleFS is FileRule for a file in fsys, such as a page embedded into a
+// built server.
+func FileRuleFS(fsys fs.FS, path string) (*Rule, error) {
+	stat := func(name string) (fs.FileInfo, error) { return fs.Stat(fsys, name) }
+	read := func(name string) ([]byte, error) { return fs.ReadFile(fsys, name) }
+	return fileRule(path, stat, read)
+}
+
+func fileRule(path string, stat func(string) (fs.FileInfo, error), read func(string) ([]byte, error)) (*Rule, error) {
+	info, err := stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleCacheMu.Lock()
+	cached, ok := ruleCache[path]
+	ruleCacheMu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.rule, nil
+	}
+
+	content, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	src := string(content)
+	if !strings.HasSuffix(path, ".go") && !frontmatterRegex.MatchString(src) {
+		// A page without frontmatter is all markup.
+		src = ""
+	}
+	rule, err := Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	ruleCacheMu.Lock()
+	ruleCache[path] = cachedRule{modTime: info.ModTime(), rule: rule}
+	ruleCacheMu.Unlock()
+	return rule, nil
+}
@@ -0,0 +1,68 @@
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	_ "github.com/cameron-webmatter/galaxy/pkg/adapters/awslambda"
+	_ "github.com/cameron-webmatter/galaxy/pkg/adapters/cloudflare"
+	_ "github.com/cameron-webmatter/galaxy/pkg/adapters/netlify"
+	_ "github.com/cameron-webmatter/galaxy/pkg/adapters/oci"
+	_ "github.com/cameron-webmatter/galaxy/pkg/adapters/vercel"
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+)
+
+// resolveAdapter looks up the configured adapter and checks it can deploy
+// the build, before any time is spent compiling.
+func resolveAdapter(cfg *config.Config) (adapters.Adapter, error) {
+	adapter, err := adapters.Lookup(cfg.Adapter.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := adapters.Check(adapter, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Output.Streaming && !adapters.CapabilitiesOf(adapter).Streaming {
+		fmt.Printf("⚠ The %s adapter buffers responses; output.streaming has no effect there\n", adapter.Name())
+	}
+	return adapter, nil
+}
+
+// adapt runs adapter over a finished build of any output type. prerendered
+// holds the routes rendered to static files; static builds have no server,
+// so none of their pages are incremental.
+func adapt(adapter adapters.Adapter, cfg *config.Config, rootDir, pagesDir, outDir, publicDir string, routes []*router.Route, prerendered map[*router.Route]bool) error {
+	var err error
+	build := &adapters.BuildConfig{
+		Config:    cfg,
+		RootDir:   rootDir,
+		ServerDir: filepath.Join(outDir, "server"),
+		OutDir:    outDir,
+		PagesDir:  pagesDir,
+		PublicDir: publicDir,
+	}
+	for _, r := range routes {
+		build.Routes = append(build.Routes, adapters.RouteInfo{
+			Pattern:     r.Pattern,
+			FilePath:    r.FilePath,
+			IsEndpoint:  r.IsEndpoint,
+			ActionsFile: r.ActionsFile,
+			Prerendered: prerendered[r],
+			Incremental: prerendered[r] && !cfg.IsStatic() && cache.Incremental(cfg.Cache, r.Pattern, r.FilePath),
+		})
+	}
+
+	if t, ok := adapter.(adapters.RouteTransformer); ok {
+		if build.Routes, err = t.TransformRoutes(build.Routes); err != nil {
+			return fmt.Errorf("%s adapter: transform routes: %w", adapter.Name(), err)
+		}
+	}
+
+	if err := adapter.Build(build); err != nil {
+		return fmt.Errorf("%s adapter: %w", adapter.Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+)
+
+// clusterAdapter stands in for a third-party adapter: it adds a health
+// check route and records what it was asked to build.
+type clusterAdapter struct {
+	built *adapters.BuildConfig
+}
+
+func (a *clusterAdapter) Name() string { return "test-cluster" }
+
+func (a *clusterAdapter) Build(cfg *adapters.BuildConfig) error {
+	a.built = cfg
+	return nil
+}
+
+func (a *clusterAdapter) TransformRoutes(routes []adapters.RouteInfo) ([]adapters.RouteInfo, error) {
+	return append(routes, adapters.RouteInfo{Pattern: "/healthz", IsEndpoint: true}), nil
+}
+
+func TestAdaptThirdParty(t *testing.T) {
+	cluster := &clusterAdapter{}
+	adapters.Register(cluster)
+
+	cfg := config.DefaultConfig()
+	cfg.Output.Type = config.OutputHybrid
+	cfg.Adapter.Name = "test-cluster"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected any adapter name to validate, got %v", err)
+	}
+
+	adapter, err := resolveAdapter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := &router.Route{Pattern: "/"}
+	post := &router.Route{Pattern: "/blog/[slug]"}
+	if err := adapt(adapter, cfg, "/project", "/project/src/pages", "/project/dist", "/project/public", []*router.Route{home, post}, map[*router.Route]bool{home: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	built := cluster.built
+	if built == nil || built.ServerDir != "/project/dist/server" || built.RootDir != "/project" {
+		t.Fatalf("Unexpected build config: %+v", built)
+	}
+	var patterns []string
+	for _, r := range built.DynamicRoutes() {
+		patterns = append(patterns, r.Pattern)
+	}
+	if strings.Join(patterns, " ") != "/blog/[slug] /healthz" {
+		t.Errorf("Expected the transformed dynamic routes, got %v", patterns)
+	}
+
+	cfg.Adapter.Name = "kubernetes"
+	if _, err := resolveAdapter(cfg); err == nil || !strings.Contains(err.Error(), "unknown adapter") {
+		t.Errorf("Expected unknown adapters to fail the build, got %v", err)
+	}
+}
+
+func TestAdaptStatic(t *testing.T) {
+	cluster := &clusterAdapter{}
+	cfg := config.DefaultConfig()
+	cfg.Cache.Enabled = true
+	cfg.Cache.Routes = []config.CacheRoute{{Path: "/", TTL: 60}}
+
+	home := &router.Route{Pattern: "/"}
+	if err := adapt(cluster, cfg, "/project", "/project/src/pages", "/project/dist", "/project/public", []*router.Route{home}, map[*router.Route]bool{home: true}); err != nil {
+		t.Fatal(err)
+	}
+	if r := cluster.built.Routes[0]; !r.Prerendered || r.Incremental {
+		t.Errorf("Expected a prerendered page that is never regenerated, got %+v", r)
+	}
+
+	cfg.Adapter.Name = config.AdapterOCI
+	if _, err := resolveAdapter(cfg); err == nil || !strings.Contains(err.Error(), "static builds") {
+		t.Errorf("Expected a server-only adapter to fail a static build, got %v", err)
+	}
+}
@@ -6,13 +6,20 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/cameron-webmatter/galaxy/pkg/buildcache"
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
 	"github.com/cameron-webmatter/galaxy/pkg/codegen"
 	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/linkcheck"
+	"github.com/cameron-webmatter/galaxy/pkg/report"
 	"github.com/cameron-webmatter/galaxy/pkg/router"
 )
 
 type HybridBuilder struct {
-	Config     *config.Config
+	Config *config.Config
+	// RootDir is the project directory; it defaults to the parent of
+	// SrcDir.
+	RootDir    string
 	SrcDir     string
 	PagesDir   string
 	OutDir     string
@@ -20,6 +27,22 @@ type HybridBuilder struct {
 	Router     *router.Router
 	SSGBuilder *SSGBuilder
 	SSRBuilder *SSRBuilder
+	// BuildCache, when set, skips pages and compiles unchanged since the
+	// last build.
+	BuildCache *buildcache.Cache
+	// Concurrency is how many pages render or bundle at once; zero uses
+	// every CPU.
+	Concurrency int
+	// Measure collects Report during Build.
+	Measure bool
+	// Report describes the routes built and what each loads, when Measure
+	// is set.
+	Report *report.Report
+	// CheckLinks checks the links of the pages built into LinkIssues;
+	// CheckExternalLinks also requests those to other sites.
+	CheckLinks         bool
+	CheckExternalLinks bool
+	LinkIssues         []linkcheck.Issue
 }
 
 func NewHybridBuilder(cfg *config.Config, srcDir, pagesDir, outDir, publicDir string) *HybridBuilder {
@@ -36,6 +59,14 @@ func NewHybridBuilder(cfg *config.Config, srcDir, pagesDir, outDir, publicDir st
 }
 
 func (b *HybridBuilder) Build() error {
+	adapter, err := resolveAdapter(b.Config)
+	if err != nil {
+		return err
+	}
+	b.SSGBuilder.BuildCache = b.BuildCache
+	b.SSRBuilder.BuildCache = b.BuildCache
+	b.SSRBuilder.Concurrency = b.Concurrency
+
 	if err := b.Router.Discover(); err != nil {
 		return fmt.Errorf("route discovery: %w", err)
 	}
@@ -51,15 +82,24 @@ func (b *HybridBuilder) Build() error {
 
 	staticRoutes := []*router.Route{}
 	dynamicRoutes := []*router.Route{}
+	var incremental []string
 
 	for _, route := range b.Router.Routes {
-		if b.shouldPrerender(route) {
-			staticRoutes = append(staticRoutes, route)
-		} else {
+		if !b.shouldPrerender(route) {
+			dynamicRoutes = append(dynamicRoutes, route)
+			continue
+		}
+		staticRoutes = append(staticRoutes, route)
+
+		// Cached pages are also compiled into the server, which serves the
+		// prerendered HTML and regenerates it once stale.
+		if cache.Incremental(b.Config.Cache, route.Pattern, route.FilePath) {
 			dynamicRoutes = append(dynamicRoutes, route)
+			incremental = append(incremental, route.Pattern)
 		}
 	}
 
+	var pages []report.Page
 	if len(staticRoutes) > 0 {
 		moduleName, err := detectModuleName()
 		if err != nil {
@@ -67,9 +107,17 @@ func (b *HybridBuilder) Build() error {
 		}
 
 		ssgCodegen := codegen.NewSSGCodegenBuilder(staticRoutes, b.PagesDir, b.OutDir, moduleName)
+		ssgCodegen.Concurrency = b.Concurrency
+		b.SSGBuilder.useBuildCache(ssgCodegen)
 		if err := ssgCodegen.Build(); err != nil {
 			return fmt.Errorf("ssg codegen: %w", err)
 		}
+		pages = prerenderedPages(ssgCodegen, staticRoutes)
+	}
+
+	// Public files are copied first so the server can embed them.
+	if err := b.SSGBuilder.copyPublicAssets(); err != nil {
+		return fmt.Errorf("copy assets: %w", err)
 	}
 
 	if len(dynamicRoutes) > 0 {
@@ -79,6 +127,7 @@ func (b *HybridBuilder) Build() error {
 		}
 
 		b.SSRBuilder.Router.Routes = dynamicRoutes
+		b.SSRBuilder.useBuildCache()
 
 		if err := b.SSRBuilder.precompileWasmScripts(); err != nil {
 			return fmt.Errorf("precompile wasm: %w", err)
@@ -92,16 +141,50 @@ func (b *HybridBuilder) Build() error {
 			return fmt.Errorf("copy wasm exec: %w", err)
 		}
 
-		if err := b.generateServerForDynamicRoutes(serverDir, dynamicRoutes); err != nil {
+		if err := b.generateServerForDynamicRoutes(serverDir, dynamicRoutes, incremental); err != nil {
 			return fmt.Errorf("generate server: %w", err)
 		}
 	}
 
-	if err := b.SSGBuilder.copyPublicAssets(); err != nil {
-		return fmt.Errorf("copy assets: %w", err)
+	b.SSRBuilder.RootDir = b.RootDir
+	prerendered := make(map[*router.Route]bool, len(staticRoutes))
+	for _, route := range staticRoutes {
+		prerendered[route] = true
+	}
+
+	if b.Measure {
+		// Incremental pages are served by the server but prerendered, so
+		// they are reported once, as static. Routes keep the router's order.
+		byPattern := make(map[string]report.Page, len(b.Router.Routes))
+		for _, p := range serverPages(dynamicRoutes) {
+			byPattern[p.Pattern] = p
+		}
+		for _, p := range pages {
+			byPattern[p.Pattern] = p
+		}
+		var ordered []report.Page
+		for _, route := range b.Router.Routes {
+			if p, ok := byPattern[route.Pattern]; ok {
+				ordered = append(ordered, p)
+			}
+		}
+		if b.Report, err = collectReport(b.Config, b.PagesDir, b.OutDir, ordered); err != nil {
+			return err
+		}
+	}
+	if b.CheckLinks {
+		var served []*router.Route
+		for _, route := range dynamicRoutes {
+			if !prerendered[route] {
+				served = append(served, route)
+			}
+		}
+		if b.LinkIssues, err = checkLinks(b.Config, b.SSRBuilder.rootDir(), b.SrcDir, b.PagesDir, b.OutDir, pages, served, b.CheckExternalLinks); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return adapt(adapter, b.Config, b.SSRBuilder.rootDir(), b.PagesDir, b.OutDir, b.PublicDir, b.Router.Routes, prerendered)
 }
 
 func (b *HybridBuilder) shouldPrerender(route *router.Route) bool {
@@ -122,12 +205,20 @@ func (b *HybridBuilder) shouldPrerender(route *router.Route) bool {
 	return !strings.Contains(src, "prerender = false") && !strings.Contains(src, "prerender=false")
 }
 
-func (b *HybridBuilder) generateServerForDynamicRoutes(serverDir string, routes []*router.Route) error {
+func (b *HybridBuilder) generateServerForDynamicRoutes(serverDir string, routes []*router.Route, incremental []string) error {
 	moduleName, err := detectModuleName()
 	if err != nil {
 		moduleName = "generated-hybrid"
 	}
 
 	codegenBuilder := codegen.NewCodegenBuilder(routes, b.PagesDir, b.OutDir, moduleName)
+	codegenBuilder.Middleware = b.Config.Middleware
+	codegenBuilder.Cache = b.Config.Cache
+	codegenBuilder.Server = b.Config.Server
+	codegenBuilder.Telemetry = b.Config.Telemetry
+	codegenBuilder.Streaming = b.Config.Output.Streaming
+	codegenBuilder.Assets = b.Config.Output.Assets
+	codegenBuilder.Incremental = incremental
+	codegenBuilder.BuildCache = b.BuildCache
 	return codegenBuilder.Build()
 }
@@ -0,0 +1,71 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cameron-webmatter/galaxy/pkg/buildcache"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/linkcheck"
+	"github.com/cameron-webmatter/galaxy/pkg/report"
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+)
+
+// checkLinks checks the links of the pages built into outDir: prerendered
+// pages as written, and server pages with static routes as the server in
+// outDir renders them. served are the routes the server serves. Like
+// collectReport, it runs before the adapter moves the output.
+func checkLinks(cfg *config.Config, rootDir, srcDir, pagesDir, outDir string, prerendered []report.Page, served []*router.Route, external bool) ([]linkcheck.Issue, error) {
+	var docs []linkcheck.Document
+	for _, p := range prerendered {
+		html, err := os.ReadFile(p.HTML)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, linkcheck.Document{Pattern: p.Pattern, Source: p.Source, HTML: html})
+	}
+
+	var pages []linkcheck.Document
+	for _, route := range served {
+		if route.Type == router.RouteStatic && !route.IsEndpoint {
+			pages = append(pages, linkcheck.Document{Pattern: route.Pattern, Source: route.FilePath})
+		}
+	}
+	rendered, err := linkcheck.Render(filepath.Join(outDir, "server", "server"), pages)
+	if err != nil {
+		return nil, fmt.Errorf("check links: %w", err)
+	}
+	docs = append(docs, rendered...)
+
+	var serverRouter *router.Router
+	if len(served) > 0 {
+		serverRouter = router.NewRouter(pagesDir)
+		serverRouter.Routes = served
+	}
+	graph := buildcache.NewGraph(rootDir, srcDir)
+	checker := &linkcheck.Checker{
+		Router:   serverRouter,
+		Base:     cfg.Base,
+		Dirs:     []string{outDir, filepath.Join(outDir, "public"), filepath.Join(outDir, "server"), filepath.Join(outDir, "server", "public")},
+		RootDir:  rootDir,
+		Sources:  func(page string) []string { return templates(graph, page) },
+		External: external,
+	}
+	return checker.Check(docs), nil
+}
+
+// templates returns the .gxc files page is built from.
+func templates(graph *buildcache.Graph, page string) []string {
+	files, _, err := graph.Deps(page)
+	if err != nil {
+		return nil
+	}
+	var gxc []string
+	for _, f := range files {
+		if filepath.Ext(f) == ".gxc" && f != page {
+			gxc = append(gxc, f)
+		}
+	}
+	return gxc
+}
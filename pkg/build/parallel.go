@@ -0,0 +1,40 @@
+package build
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// workers returns how many jobs run at once for a concurrency setting;
+// zero or less uses every CPU.
+func workers(concurrency int) int {
+	if concurrency > 0 {
+		return concurrency
+	}
+	return runtime.NumCPU()
+}
+
+// forEach calls fn for each index below n on up to concurrency goroutines.
+// Every call runs even when some fail; their errors are joined in index
+// order, so they read the same on every run.
+func forEach(n, concurrency int, fn func(i int) error) error {
+	errs := make([]error, n)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers(concurrency), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return errors.Join(errs...)
+}
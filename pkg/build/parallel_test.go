@@ -0,0 +1,28 @@
+package build
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEach(t *testing.T) {
+	var calls atomic.Int32
+	err := forEach(20, 4, func(i int) error {
+		calls.Add(1)
+		if i%7 == 3 {
+			return fmt.Errorf("page %d", i)
+		}
+		return nil
+	})
+	if calls.Load() != 20 {
+		t.Errorf("Expected every call to run despite failures, got %d", calls.Load())
+	}
+	if err == nil || err.Error() != "page 3\npage 10\npage 17" {
+		t.Errorf("Expected the errors in index order, got %v", err)
+	}
+
+	if err := forEach(0, 0, func(int) error { return nil }); err != nil {
+		t.Errorf("Expected no error without jobs, got %v", err)
+	}
+}
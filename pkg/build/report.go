@@ -0,0 +1,58 @@
+package build
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cameron-webmatter/galaxy/pkg/codegen"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/report"
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+)
+
+// prerenderedPages lists the pages gen wrote, with their render times.
+// Pages that render a 404 are left out; they were not written.
+func prerenderedPages(gen *codegen.SSGCodegenBuilder, routes []*router.Route) []report.Page {
+	var pages []report.Page
+	for _, route := range routes {
+		if route.IsEndpoint {
+			continue
+		}
+		html := gen.OutputPath(route.Pattern)
+		if _, err := os.Stat(html); err != nil {
+			continue
+		}
+		pages = append(pages, report.Page{
+			Pattern:    route.Pattern,
+			Mode:       report.ModeStatic,
+			Source:     route.FilePath,
+			HTML:       html,
+			RenderTime: gen.RenderTimes[route.Pattern],
+			Cached:     gen.Restored[route.Pattern],
+		})
+	}
+	return pages
+}
+
+// serverPages lists routes the server renders on request.
+func serverPages(routes []*router.Route) []report.Page {
+	pages := make([]report.Page, 0, len(routes))
+	for _, route := range routes {
+		mode := report.ModeSSR
+		if route.IsEndpoint {
+			mode = report.ModeEndpoint
+		}
+		pages = append(pages, report.Page{Pattern: route.Pattern, Mode: mode, Source: route.FilePath})
+	}
+	return pages
+}
+
+// collectReport measures pages in outDir. Builders call it before the
+// adapter runs, since adapters may move the output.
+func collectReport(cfg *config.Config, pagesDir, outDir string, pages []report.Page) (*report.Report, error) {
+	r, err := report.Collect(outDir, pagesDir, cfg.Base, pages)
+	if err != nil {
+		return nil, fmt.Errorf("build report: %w", err)
+	}
+	return r, nil
+}
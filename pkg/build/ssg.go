@@ -8,13 +8,17 @@ import (
 	"strings"
 
 	"github.com/cameron-webmatter/galaxy/internal/assets"
+	"github.com/cameron-webmatter/galaxy/pkg/buildcache"
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
 	"github.com/cameron-webmatter/galaxy/pkg/codegen"
 	"github.com/cameron-webmatter/galaxy/pkg/compiler"
 	"github.com/cameron-webmatter/galaxy/pkg/config"
 	"github.com/cameron-webmatter/galaxy/pkg/executor"
+	"github.com/cameron-webmatter/galaxy/pkg/linkcheck"
 	"github.com/cameron-webmatter/galaxy/pkg/parser"
 	"github.com/cameron-webmatter/galaxy/pkg/plugins"
 	"github.com/cameron-webmatter/galaxy/pkg/plugins/tailwind"
+	"github.com/cameron-webmatter/galaxy/pkg/report"
 	"github.com/cameron-webmatter/galaxy/pkg/router"
 	"github.com/cameron-webmatter/galaxy/pkg/template"
 )
@@ -29,6 +33,21 @@ type SSGBuilder struct {
 	Bundler       *assets.Bundler
 	Compiler      *compiler.ComponentCompiler
 	PluginManager *plugins.Manager
+	// BuildCache, when set, skips pages and compiles unchanged since the
+	// last build.
+	BuildCache *buildcache.Cache
+	// Concurrency is how many pages render at once; zero uses every CPU.
+	Concurrency int
+	// Measure collects Report during Build.
+	Measure bool
+	// Report describes the routes built and what each loads, when Measure
+	// is set.
+	Report *report.Report
+	// CheckLinks checks the links of the pages built into LinkIssues;
+	// CheckExternalLinks also requests those to other sites.
+	CheckLinks         bool
+	CheckExternalLinks bool
+	LinkIssues         []linkcheck.Issue
 }
 
 func NewSSGBuilder(cfg *config.Config, srcDir, pagesDir, outDir, publicDir string) *SSGBuilder {
@@ -54,6 +73,11 @@ func NewSSGBuilder(cfg *config.Config, srcDir, pagesDir, outDir, publicDir strin
 }
 
 func (b *SSGBuilder) Build() error {
+	adapter, err := resolveAdapter(b.Config)
+	if err != nil {
+		return err
+	}
+
 	baseDir := b.SrcDir
 	if err := b.PluginManager.Load(baseDir, b.OutDir); err != nil {
 		return fmt.Errorf("load plugins: %w", err)
@@ -90,6 +114,8 @@ func (b *SSGBuilder) Build() error {
 	}
 
 	codegenBuilder := codegen.NewSSGCodegenBuilder(b.Router.Routes, b.PagesDir, b.OutDir, moduleName)
+	codegenBuilder.Concurrency = b.Concurrency
+	b.useBuildCache(codegenBuilder)
 	if err := codegenBuilder.Build(); err != nil {
 		return fmt.Errorf("codegen build: %w", err)
 	}
@@ -98,6 +124,32 @@ func (b *SSGBuilder) Build() error {
 		return fmt.Errorf("copy assets: %w", err)
 	}
 
+	pages := prerenderedPages(codegenBuilder, b.Router.Routes)
+	if b.Measure {
+		if b.Report, err = collectReport(b.Config, b.PagesDir, b.OutDir, pages); err != nil {
+			return err
+		}
+	}
+	if b.CheckLinks {
+		if b.LinkIssues, err = checkLinks(b.Config, filepath.Dir(b.SrcDir), b.SrcDir, b.PagesDir, b.OutDir, pages, nil, b.CheckExternalLinks); err != nil {
+			return err
+		}
+	}
+
+	// Every page is prerendered and endpoints are not served, so the
+	// adapter deploys the files alone.
+	var routes []*router.Route
+	prerendered := make(map[*router.Route]bool)
+	for _, route := range b.Router.Routes {
+		if !route.IsEndpoint {
+			routes = append(routes, route)
+			prerendered[route] = true
+		}
+	}
+	if err := adapt(adapter, b.Config, filepath.Dir(b.SrcDir), b.PagesDir, b.OutDir, b.PublicDir, routes, prerendered); err != nil {
+		return err
+	}
+
 	if err := b.PluginManager.BuildEnd(buildCtx); err != nil {
 		return fmt.Errorf("plugin BuildEnd: %w", err)
 	}
@@ -105,6 +157,15 @@ func (b *SSGBuilder) Build() error {
 	return nil
 }
 
+// useBuildCache hands the build cache, if any, to the page generator.
+func (b *SSGBuilder) useBuildCache(gen *codegen.SSGCodegenBuilder) {
+	if b.BuildCache == nil {
+		return
+	}
+	gen.BuildCache = b.BuildCache
+	gen.Graph = buildcache.NewGraph(b.BuildCache.RootDir, b.SrcDir)
+}
+
 func (b *SSGBuilder) buildStaticRoute(route *router.Route) error {
 	content, err := os.ReadFile(route.FilePath)
 	if err != nil {
@@ -130,12 +191,18 @@ func (b *SSGBuilder) buildStaticRoute(route *router.Route) error {
 	resolver.ParseImports(imports)
 
 	ctx := executor.NewContext()
+	ctx.SetCache(cache.PolicyFrom(nil))
 	if comp.Frontmatter != "" {
 		if err := ctx.Execute(comp.Frontmatter); err != nil {
 			return err
 		}
 	}
 
+	if ctx.NotFound {
+		fmt.Printf("  ⊘ %s (404, skipped)\n", route.Pattern)
+		return nil
+	}
+
 	b.Compiler.CollectedStyles = nil
 	processedTemplate := b.Compiler.ProcessComponentTags(comp.Template, ctx)
 
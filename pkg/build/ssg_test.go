@@ -0,0 +1,187 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/buildcache"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+func TestSSGBuildSkipsNotFound(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	distDir := filepath.Join(tmpDir, "dist")
+	pagesDir := filepath.Join(srcDir, "pages")
+	publicDir := filepath.Join(srcDir, "public")
+	os.MkdirAll(pagesDir, 0755)
+	os.MkdirAll(publicDir, 0755)
+
+	draft := `---
+var draft = true
+if draft {
+	Galaxy.NotFound()
+}
+---
+<h1>Draft</h1>
+`
+	published := `---
+var title = "Published"
+---
+<h1>{title}</h1>
+`
+	os.WriteFile(filepath.Join(pagesDir, "draft.gxc"), []byte(draft), 0644)
+	os.WriteFile(filepath.Join(pagesDir, "published.gxc"), []byte(published), 0644)
+
+	builder := NewSSGBuilder(config.DefaultConfig(), srcDir, pagesDir, distDir, publicDir)
+	if err := builder.Build(); err != nil {
+		t.Fatalf("SSG Build failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(distDir, "draft", "index.html")); !os.IsNotExist(err) {
+		t.Error("Expected draft page to be skipped")
+	}
+	if _, err := os.Stat(filepath.Join(distDir, "published", "index.html")); err != nil {
+		t.Errorf("Expected published page to be written: %v", err)
+	}
+}
+
+func TestSSGBuildCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	distDir := filepath.Join(tmpDir, "dist")
+	pagesDir := filepath.Join(srcDir, "pages")
+	publicDir := filepath.Join(srcDir, "public")
+	os.MkdirAll(pagesDir, 0755)
+	os.MkdirAll(publicDir, 0755)
+
+	titleFile := filepath.Join(tmpDir, "data", "title.txt")
+	os.MkdirAll(filepath.Dir(titleFile), 0755)
+	os.WriteFile(titleFile, []byte("First"), 0644)
+	page := `---
+import "os"
+
+data, _ := os.ReadFile("` + filepath.ToSlash(titleFile) + `")
+var title = string(data)
+---
+<h1>{title}</h1>
+`
+	os.WriteFile(filepath.Join(pagesDir, "index.gxc"), []byte(page), 0644)
+	os.WriteFile(filepath.Join(pagesDir, "draft.gxc"), []byte("---\nGalaxy.NotFound()\n---\n<h1>Draft</h1>\n"), 0644)
+
+	build := func() string {
+		t.Helper()
+		cache, err := buildcache.Open(tmpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		builder := NewSSGBuilder(config.DefaultConfig(), srcDir, pagesDir, distDir, publicDir)
+		builder.BuildCache = cache
+		if err := builder.Build(); err != nil {
+			t.Fatalf("SSG Build failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(distDir, "draft", "index.html")); !os.IsNotExist(err) {
+			t.Error("Expected draft page to stay skipped")
+		}
+		html, _ := os.ReadFile(filepath.Join(distDir, "index.html"))
+		return string(html)
+	}
+
+	if html := build(); !strings.Contains(html, "First") {
+		t.Fatalf("Expected the page to render its data, got %q", html)
+	}
+	if html := build(); !strings.Contains(html, "First") {
+		t.Errorf("Expected the cached page to be restored, got %q", html)
+	}
+	if _, err := os.Stat(filepath.Join(distDir, "_build", "generator")); !os.IsNotExist(err) {
+		t.Error("Expected no generator to be built when every page is cached")
+	}
+
+	os.WriteFile(titleFile, []byte("Second"), 0644)
+	if html := build(); !strings.Contains(html, "Second") {
+		t.Errorf("Expected a data change to render the page again, got %q", html)
+	}
+}
+
+func TestSSGBuildReportsFailedPages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	distDir := filepath.Join(tmpDir, "dist")
+	pagesDir := filepath.Join(srcDir, "pages")
+	publicDir := filepath.Join(srcDir, "public")
+	os.MkdirAll(pagesDir, 0755)
+	os.MkdirAll(publicDir, 0755)
+
+	broken := "---\nvar items []string\nvar first = items[0]\n---\n<h1>{first}</h1>\n"
+	for _, name := range []string{"a", "b", "c", "d"} {
+		os.WriteFile(filepath.Join(pagesDir, name+".gxc"), []byte("<h1>"+name+"</h1>\n"), 0644)
+	}
+	os.WriteFile(filepath.Join(pagesDir, "broken.gxc"), []byte(broken), 0644)
+	os.WriteFile(filepath.Join(pagesDir, "worse.gxc"), []byte(broken), 0644)
+
+	builder := NewSSGBuilder(config.DefaultConfig(), srcDir, pagesDir, distDir, publicDir)
+	builder.Concurrency = 3
+	err := builder.Build()
+	if err == nil {
+		t.Fatal("Expected failing pages to fail the build")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "✗ /broken: panic") || !strings.Contains(msg, "✗ /worse: panic") || !strings.Contains(msg, "2 of 6 pages failed") {
+		t.Errorf("Expected both failures reported, got %v", err)
+	}
+	if strings.Index(msg, "/broken") > strings.Index(msg, "/worse") {
+		t.Errorf("Expected failures in route order, got %v", err)
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if _, err := os.Stat(filepath.Join(distDir, name, "index.html")); err != nil {
+			t.Errorf("Expected %s to render despite the failures: %v", name, err)
+		}
+	}
+}
+
+func TestSSGBuildRunsAdapter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	distDir := filepath.Join(tmpDir, "dist")
+	pagesDir := filepath.Join(srcDir, "pages")
+	publicDir := filepath.Join(srcDir, "public")
+	os.MkdirAll(pagesDir, 0755)
+	os.MkdirAll(publicDir, 0755)
+	os.WriteFile(filepath.Join(pagesDir, "index.gxc"), []byte("<h1>Home</h1>\n"), 0644)
+	os.WriteFile(filepath.Join(publicDir, "robots.txt"), []byte("User-agent: *\n"), 0644)
+
+	cfg := config.DefaultConfig()
+	cfg.Adapter.Name = config.AdapterNetlify
+	builder := NewSSGBuilder(cfg, srcDir, pagesDir, distDir, publicDir)
+	if err := builder.Build(); err != nil {
+		t.Fatalf("SSG Build failed: %v", err)
+	}
+
+	publishDir := filepath.Join(distDir, "netlify", "publish")
+	for _, name := range []string{"index.html", "robots.txt", "_redirects", "_headers"} {
+		if _, err := os.Stat(filepath.Join(publishDir, name)); err != nil {
+			t.Errorf("Expected %s in the publish directory: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(distDir, "netlify", "functions")); !os.IsNotExist(err) {
+		t.Error("Expected no function for a static build")
+	}
+}
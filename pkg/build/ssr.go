@@ -8,17 +8,23 @@ import (
 	"strings"
 
 	"github.com/cameron-webmatter/galaxy/internal/assets"
+	"github.com/cameron-webmatter/galaxy/pkg/buildcache"
 	"github.com/cameron-webmatter/galaxy/pkg/codegen"
 	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/linkcheck"
 	"github.com/cameron-webmatter/galaxy/pkg/parser"
 	"github.com/cameron-webmatter/galaxy/pkg/plugins"
 	"github.com/cameron-webmatter/galaxy/pkg/plugins/tailwind"
+	"github.com/cameron-webmatter/galaxy/pkg/report"
 	"github.com/cameron-webmatter/galaxy/pkg/router"
 	"github.com/cameron-webmatter/galaxy/pkg/wasm"
 )
 
 type SSRBuilder struct {
-	Config        *config.Config
+	Config *config.Config
+	// RootDir is the project directory; it defaults to the parent of
+	// SrcDir.
+	RootDir       string
 	SrcDir        string
 	PagesDir      string
 	OutDir        string
@@ -26,6 +32,22 @@ type SSRBuilder struct {
 	Router        *router.Router
 	PluginManager *plugins.Manager
 	Bundler       *assets.Bundler
+	// BuildCache, when set, reuses the server and WASM modules compiled
+	// from unchanged sources.
+	BuildCache *buildcache.Cache
+	// Concurrency is how many pages are bundled at once; zero uses every
+	// CPU.
+	Concurrency int
+	// Measure collects Report during Build.
+	Measure bool
+	// Report describes the routes built and what each loads, when Measure
+	// is set.
+	Report *report.Report
+	// CheckLinks checks the links of the pages built into LinkIssues;
+	// CheckExternalLinks also requests those to other sites.
+	CheckLinks         bool
+	CheckExternalLinks bool
+	LinkIssues         []linkcheck.Issue
 }
 
 func NewSSRBuilder(cfg *config.Config, srcDir, pagesDir, outDir, publicDir string) *SSRBuilder {
@@ -48,6 +70,11 @@ func NewSSRBuilder(cfg *config.Config, srcDir, pagesDir, outDir, publicDir strin
 }
 
 func (b *SSRBuilder) Build() error {
+	adapter, err := resolveAdapter(b.Config)
+	if err != nil {
+		return err
+	}
+
 	baseDir := b.SrcDir
 	if err := b.PluginManager.Load(baseDir, b.OutDir); err != nil {
 		return fmt.Errorf("load plugins: %w", err)
@@ -83,6 +110,7 @@ func (b *SSRBuilder) Build() error {
 		return fmt.Errorf("create server dir: %w", err)
 	}
 
+	b.useBuildCache()
 	if err := b.precompileWasmScripts(); err != nil {
 		return fmt.Errorf("precompile wasm: %w", err)
 	}
@@ -95,18 +123,34 @@ func (b *SSRBuilder) Build() error {
 		return fmt.Errorf("copy wasm exec: %w", err)
 	}
 
-	if err := b.generateServerCode(serverDir); err != nil {
-		return fmt.Errorf("generate server: %w", err)
-	}
-
+	// Public files are copied first so the server can embed them.
 	if err := b.copyPublicAssets(); err != nil {
 		return fmt.Errorf("copy assets: %w", err)
 	}
 
+	if err := b.generateServerCode(serverDir); err != nil {
+		return fmt.Errorf("generate server: %w", err)
+	}
+
 	if err := b.compileServer(serverDir); err != nil {
 		return fmt.Errorf("compile server: %w", err)
 	}
 
+	if b.Measure {
+		if b.Report, err = collectReport(b.Config, b.PagesDir, b.OutDir, serverPages(b.Router.Routes)); err != nil {
+			return err
+		}
+	}
+	if b.CheckLinks {
+		if b.LinkIssues, err = checkLinks(b.Config, b.rootDir(), b.SrcDir, b.PagesDir, b.OutDir, nil, b.Router.Routes, b.CheckExternalLinks); err != nil {
+			return err
+		}
+	}
+
+	if err := adapt(adapter, b.Config, b.rootDir(), b.PagesDir, b.OutDir, b.PublicDir, b.Router.Routes, nil); err != nil {
+		return err
+	}
+
 	if err := b.PluginManager.BuildEnd(buildCtx); err != nil {
 		return fmt.Errorf("plugin BuildEnd: %w", err)
 	}
@@ -114,6 +158,20 @@ func (b *SSRBuilder) Build() error {
 	return nil
 }
 
+// useBuildCache hands the build cache, if any, to the WASM compiler.
+func (b *SSRBuilder) useBuildCache() {
+	if b.BuildCache != nil {
+		b.Bundler.WasmCompiler.BuildCache = b.BuildCache
+	}
+}
+
+func (b *SSRBuilder) rootDir() string {
+	if b.RootDir != "" {
+		return b.RootDir
+	}
+	return filepath.Dir(b.SrcDir)
+}
+
 func (b *SSRBuilder) generateServerCode(serverDir string) error {
 	moduleName, err := detectModuleName()
 	if err != nil {
@@ -121,6 +179,13 @@ func (b *SSRBuilder) generateServerCode(serverDir string) error {
 	}
 
 	codegenBuilder := codegen.NewCodegenBuilder(b.Router.Routes, b.PagesDir, b.OutDir, moduleName)
+	codegenBuilder.Middleware = b.Config.Middleware
+	codegenBuilder.Cache = b.Config.Cache
+	codegenBuilder.Server = b.Config.Server
+	codegenBuilder.Telemetry = b.Config.Telemetry
+	codegenBuilder.Streaming = b.Config.Output.Streaming
+	codegenBuilder.Assets = b.Config.Output.Assets
+	codegenBuilder.BuildCache = b.BuildCache
 	return codegenBuilder.Build()
 }
 
@@ -184,36 +249,42 @@ func detectModuleName() (string, error) {
 	return "", fmt.Errorf("module name not found")
 }
 
+// precompileWasmScripts bundles each page's scripts, compiling Go scripts
+// to WASM, with pages handled in parallel, and writes the manifest the
+// server injects them from.
 func (b *SSRBuilder) precompileWasmScripts() error {
 	manifest := wasm.NewManifest()
 
-	for _, route := range b.Router.Routes {
+	routes := b.Router.Routes
+	assets := make([]*wasm.WasmPageAssets, len(routes))
+	err := forEach(len(routes), b.Concurrency, func(i int) error {
+		route := routes[i]
 		if route.IsEndpoint {
-			continue
+			return nil
 		}
 
 		content, err := os.ReadFile(route.FilePath)
 		if err != nil {
-			continue
+			return nil
 		}
 
 		comp, err := parser.Parse(string(content))
 		if err != nil {
-			continue
+			return nil
 		}
 
 		wasmAssets, err := b.Bundler.BundleWasmScripts(comp, route.FilePath)
 		if err != nil {
-			return err
+			return fmt.Errorf("%s: %w", route.FilePath, err)
 		}
 
 		jsPath, err := b.Bundler.BundleScripts(comp, route.FilePath)
 		if err != nil {
-			return err
+			return fmt.Errorf("%s: %w", route.FilePath, err)
 		}
 
 		if len(wasmAssets) == 0 && jsPath == "" {
-			continue
+			return nil
 		}
 
 		pageAssets := wasm.WasmPageAssets{}
@@ -228,13 +299,23 @@ func (b *SSRBuilder) precompileWasmScripts() error {
 		if jsPath != "" {
 			pageAssets.JSScripts = append(pageAssets.JSScripts, jsPath)
 		}
+		assets[i] = &pageAssets
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
+	for i, route := range routes {
+		if assets[i] == nil {
+			continue
+		}
 		relPath, err := filepath.Rel(b.PagesDir, route.FilePath)
 		if err != nil {
 			relPath = route.FilePath
 		}
 		manifestKey := "pages/" + relPath
-		manifest.Assets[manifestKey] = pageAssets
+		manifest.Assets[manifestKey] = *assets[i]
 	}
 
 	manifestPath := filepath.Join(b.OutDir, "server", "_assets", "wasm-manifest.json")
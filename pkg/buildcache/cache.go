@@ -0,0 +1,163 @@
+// Package buildcache keeps build results in .galaxy/cache so unchanged work
+// is skipped on the next build: prerendered pages, keyed by the files each
+// page depends on, and compiled Go binaries, keyed by their sources.
+//
+// Keys hash file contents and project-relative names, never timestamps or
+// absolute paths, so a cache restored on another machine, as in CI, is
+// reused when the sources match and ignored when they don't.
+package buildcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Dir is the cache directory, relative to the project root.
+const Dir = ".galaxy/cache"
+
+// version is bumped when the layout or the keys change, so older caches are
+// left alone rather than misread.
+const version = "v1"
+
+type Cache struct {
+	// Root is the versioned directory entries are stored in.
+	Root string
+	// RootDir is the project directory; files are named relative to it in
+	// keys.
+	RootDir string
+
+	mu         sync.Mutex
+	goEnv      string
+	frameworks map[string]string
+}
+
+// Open returns the cache of the project in rootDir, creating it if needed.
+func Open(rootDir string) (*Cache, error) {
+	root := filepath.Join(rootDir, Dir, version)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("create build cache: %w", err)
+	}
+	return &Cache{Root: root, RootDir: rootDir, frameworks: make(map[string]string)}, nil
+}
+
+// Clear removes the cache of the project in rootDir.
+func Clear(rootDir string) error {
+	return os.RemoveAll(filepath.Join(rootDir, Dir))
+}
+
+// Key hashes parts into a cache key.
+func Key(parts ...string) string {
+	h := sha256.New()
+	io.WriteString(h, version)
+	for _, p := range parts {
+		fmt.Fprintf(h, "\x00%d:%s", len(p), p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashFiles hashes the names and contents of files. Names inside the
+// project are taken relative to it, so the hash is the same in any checkout.
+func (c *Cache) HashFiles(files []string) (string, error) {
+	return hashFiles(c.RootDir, files)
+}
+
+// hashFiles hashes files, naming those under root relative to it.
+func hashFiles(root string, files []string) (string, error) {
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, file := range sorted {
+		name := file
+		if rel, err := filepath.Rel(root, file); err == nil && filepath.IsLocal(rel) {
+			name = filepath.ToSlash(rel)
+		}
+		if err := hashFile(h, name, file); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(h io.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(h, "%s\x00%d\x00", name, info.Size())
+	_, err = io.Copy(h, f)
+	return err
+}
+
+func (c *Cache) path(kind, key string) string {
+	return filepath.Join(c.Root, kind, key[:2], key)
+}
+
+// Get returns the entry of kind stored under key.
+func (c *Cache) Get(kind, key string) ([]byte, bool) {
+	data, err := os.ReadFile(c.path(kind, key))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores data under key. Entries are renamed into place, so concurrent
+// builds sharing the cache never see a partial one.
+func (c *Cache) Put(kind, key string, data []byte) error {
+	path := c.path(kind, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// walkFiles returns the regular files under dir, skipping directories for
+// which skip reports true.
+func walkFiles(dir string, skip func(name string) bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && skip != nil && skip(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return files, err
+}
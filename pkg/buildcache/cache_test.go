@@ -0,0 +1,106 @@
+package buildcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHashFiles(t *testing.T) {
+	var sums []string
+	for i := 0; i < 2; i++ {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"src/pages/index.gxc": "<h1>Home</h1>"})
+		c, err := Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := c.HashFiles([]string{filepath.Join(dir, "src/pages/index.gxc")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sums = append(sums, sum)
+	}
+	if sums[0] != sums[1] {
+		t.Error("Expected the same files to hash the same in another checkout")
+	}
+}
+
+func TestPutGet(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := Key("page", "/")
+	if _, ok := c.Get("pages", key); ok {
+		t.Fatal("Expected an empty cache to miss")
+	}
+	if err := c.Put("pages", key, []byte("<h1>Home</h1>")); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := c.Get("pages", key); !ok || string(data) != "<h1>Home</h1>" {
+		t.Errorf("Expected the stored page, got %q", data)
+	}
+	if Key("page", "/a", "b") == Key("page", "/ab") {
+		t.Error("Expected keys to keep their parts apart")
+	}
+}
+
+func TestGoBuild(t *testing.T) {
+	project := t.TempDir()
+	c, err := Open(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builds := 0
+	build := func(dir string) func() error {
+		return func() error {
+			builds++
+			writeFiles(t, dir, map[string]string{"server": "binary", "go.sum": "sums"})
+			return nil
+		}
+	}
+	run := func(main string) string {
+		t.Helper()
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"main.go": main, "go.mod": "module site\n"})
+		if _, err := c.GoBuild(dir, "server", build(dir)); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, "go.sum"))
+		return string(data)
+	}
+
+	run("package main")
+	if sum := run("package main"); builds != 1 || sum != "sums" {
+		t.Errorf("Expected the second build to be restored with its go.sum, got %d builds and %q", builds, sum)
+	}
+	run("package main // changed")
+	if builds != 2 {
+		t.Errorf("Expected changed sources to build again, got %d builds", builds)
+	}
+}
+
+func TestReplaceTarget(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"go.mod": "module site\n\nreplace github.com/cameron-webmatter/galaxy => ../galaxy\n"})
+	if got := replaceTarget(filepath.Join(dir, "go.mod")); got != "../galaxy" {
+		t.Errorf("Expected ../galaxy, got %q", got)
+	}
+	writeFiles(t, dir, map[string]string{"go.mod": "module site\n\nreplace github.com/cameron-webmatter/galaxy => github.com/fork/galaxy v1.0.0\n"})
+	if got := replaceTarget(filepath.Join(dir, "go.mod")); got != "" {
+		t.Errorf("Expected no local directory for a module replacement, got %q", got)
+	}
+}
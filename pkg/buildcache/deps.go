@@ -0,0 +1,154 @@
+package buildcache
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cameron-webmatter/galaxy/pkg/compiler"
+	"github.com/cameron-webmatter/galaxy/pkg/parser"
+)
+
+var (
+	stringLiteralRegex = regexp.MustCompile("\"(?:[^\"\\\\\\n]|\\\\.)*\"|`[^`]*`")
+	// volatileRegex matches frontmatter whose output depends on more than
+	// files: the clock, the environment, randomness or the network.
+	volatileRegex = regexp.MustCompile(`\btime\.(Now|Since|Until)\(|\bos\.(Getenv|LookupEnv|Environ)\(|"math/rand(/v2)?"|"crypto/rand"|"net/http"|"net"|"os/exec"`)
+)
+
+// Graph finds the files a page's output depends on.
+type Graph struct {
+	RootDir string
+	SrcDir  string
+
+	resolver *compiler.ComponentResolver
+	mu       sync.Mutex
+	nodes    map[string]*node
+}
+
+// node holds what one page or component depends on directly.
+type node struct {
+	components []string
+	data       []string
+	volatile   bool
+}
+
+func NewGraph(rootDir, srcDir string) *Graph {
+	return &Graph{
+		RootDir:  rootDir,
+		SrcDir:   srcDir,
+		resolver: compiler.NewComponentResolver(srcDir, nil),
+		nodes:    make(map[string]*node),
+	}
+}
+
+// Deps returns the page and every file its output depends on, sorted: the
+// components its template uses, transitively, the files named by string
+// literals in their frontmatter, which is how pages load content and data,
+// and the project config. A directory named that way, like a content
+// collection, counts with all its files.
+//
+// volatile reports frontmatter that reads the clock, the environment,
+// random numbers or the network, whose output no set of files determines.
+func (g *Graph) Deps(page string) (files []string, volatile bool, err error) {
+	seen := map[string]bool{}
+	queue := []string{page}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+
+		n, err := g.node(file)
+		if err != nil {
+			return nil, false, err
+		}
+		volatile = volatile || n.volatile
+		queue = append(queue, n.components...)
+		for _, d := range n.data {
+			seen[d] = true
+		}
+	}
+
+	if config := filepath.Join(g.RootDir, "galaxy.config.toml"); fileExists(config) {
+		seen[config] = true
+	}
+	for file := range seen {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, volatile, nil
+}
+
+func (g *Graph) node(file string) (*node, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n, ok := g.nodes[file]; ok {
+		return n, nil
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	comp, err := parser.Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	n := &node{volatile: volatileRegex.MatchString(comp.Frontmatter)}
+	for _, name := range g.resolver.ExtractComponentRefs(comp.Template) {
+		if path, ok := g.resolver.ComponentIndex[name]; ok {
+			n.components = append(n.components, path)
+		}
+	}
+	n.data = g.dataFiles(comp.Frontmatter, filepath.Dir(file))
+	g.nodes[file] = n
+	return n, nil
+}
+
+// dataFiles returns the files named by string literals in frontmatter,
+// relative to the file's directory, the source directory or the project.
+func (g *Graph) dataFiles(frontmatter, dir string) []string {
+	var files []string
+	for _, lit := range stringLiteralRegex.FindAllString(frontmatter, -1) {
+		name, err := strconv.Unquote(lit)
+		if err != nil || name == "" || strings.ContainsAny(name, "\n*?") || len(name) > 512 || strings.Trim(name, "./") == "" {
+			continue
+		}
+		bases := []string{dir, g.SrcDir, g.RootDir}
+		if filepath.IsAbs(name) {
+			bases = []string{""}
+		}
+		for _, base := range bases {
+			path := filepath.Join(base, name)
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() {
+				// A separator like "/", say, rather than a content directory.
+				if rel, err := filepath.Rel(path, g.SrcDir); err == nil && filepath.IsLocal(rel) {
+					break
+				}
+				dirFiles, _ := walkFiles(path, func(name string) bool { return strings.HasPrefix(name, ".") })
+				files = append(files, dirFiles...)
+			} else {
+				files = append(files, path)
+			}
+			break
+		}
+	}
+	return files
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,47 @@
+package buildcache
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeps(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	writeFiles(t, root, map[string]string{
+		"galaxy.config.toml":        "[output]\ntype = \"static\"\n",
+		"data/authors.json":         "[]",
+		"content/blog/first.md":     "# First",
+		"content/blog/second.md":    "# Second",
+		"src/components/Layout.gxc": "<html><Nav /><slot /></html>",
+		"src/components/Nav.gxc":    "---\nlinks, _ := os.ReadFile(\"data/authors.json\")\n---\n<nav></nav>",
+		"src/components/Unused.gxc": "<p></p>",
+		"src/pages/index.gxc":       "---\nposts := loadAll(\"content/blog\")\nparts := strings.Split(slug, \"/\")\n---\n<Layout><h1>Home</h1></Layout>",
+		"src/pages/now.gxc":         "---\nimport \"time\"\n\nnow := time.Now()\n---\n<p>{now}</p>",
+		"src/pages/blog/[slug].gxc": "<Layout></Layout>",
+	})
+
+	g := NewGraph(root, src)
+	files, volatile, err := g.Deps(filepath.Join(src, "pages", "index.gxc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volatile {
+		t.Error("Expected a page built from files not to be volatile")
+	}
+	var names []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(root, f)
+		names = append(names, filepath.ToSlash(rel))
+	}
+	want := "content/blog/first.md content/blog/second.md data/authors.json galaxy.config.toml " +
+		"src/components/Layout.gxc src/components/Nav.gxc src/pages/index.gxc"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	if _, volatile, _ := g.Deps(filepath.Join(src, "pages", "now.gxc")); !volatile {
+		t.Error("Expected a page reading the clock to be volatile")
+	}
+}
@@ -0,0 +1,209 @@
+package buildcache
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// galaxyModule is the module generated code replaces with a local checkout.
+const galaxyModule = "github.com/cameron-webmatter/galaxy"
+
+// restored are the files a cached Go build puts back besides the binary;
+// go mod tidy writes them, and later steps read them.
+var restored = []string{"go.mod", "go.sum"}
+
+// GoBuild writes output, a binary built from the module in dir, by calling
+// build, which tidies the module and compiles it. When the same sources
+// were built before, the binary and the tidied go.mod and go.sum are
+// restored from the cache instead; hit reports whether they were.
+func (c *Cache) GoBuild(dir, output string, build func() error) (hit bool, err error) {
+	key, err := c.goBuildKey(dir, output)
+	if err != nil {
+		return false, fmt.Errorf("hash %s: %w", dir, err)
+	}
+
+	entry := filepath.Join(c.Root, "go", key[:2], key)
+	if restoreBuild(entry, dir, output) == nil {
+		return true, nil
+	}
+
+	if err := build(); err != nil {
+		return false, err
+	}
+	return false, c.storeBuild(entry, dir, output)
+}
+
+// goBuildKey hashes everything that goes into the binary: the files in dir,
+// which include embedded ones, the toolchain, and the Galaxy sources the
+// module is built against.
+func (c *Cache) goBuildKey(dir, output string) (string, error) {
+	files, err := walkFiles(dir, nil)
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(dir, output)
+	inputs := files[:0]
+	for _, f := range files {
+		if f != out {
+			inputs = append(inputs, f)
+		}
+	}
+
+	sources, err := hashFiles(dir, inputs)
+	if err != nil {
+		return "", err
+	}
+	env, err := c.toolchain()
+	if err != nil {
+		return "", err
+	}
+	framework, err := c.Framework(dir)
+	if err != nil {
+		return "", err
+	}
+	return Key("go", output, sources, env, framework), nil
+}
+
+// toolchain describes the go command builds run with.
+func (c *Cache) toolchain() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.goEnv != "" {
+		return c.goEnv, nil
+	}
+	out, err := exec.Command("go", "env", "GOVERSION", "GOOS", "GOARCH", "CGO_ENABLED", "GOFLAGS", "GOAMD64", "GOARM64").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env: %w", err)
+	}
+	c.goEnv = string(out)
+	return c.goEnv, nil
+}
+
+// Framework fingerprints the Galaxy sources the module in dir replaces
+// Galaxy with. It is empty when Galaxy is not replaced with a local
+// directory, since go.sum then pins it.
+func (c *Cache) Framework(dir string) (string, error) {
+	target := replaceTarget(filepath.Join(dir, "go.mod"))
+	if target == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fp, ok := c.frameworks[target]; ok {
+		return fp, nil
+	}
+	files, err := walkFiles(target, func(name string) bool {
+		switch name {
+		case "examples", "testdata", "dist", "node_modules":
+			return true
+		}
+		return strings.HasPrefix(name, ".")
+	})
+	if err != nil {
+		return "", err
+	}
+	var sources []string
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		sources = append(sources, f)
+	}
+	fp, err := hashFiles(target, sources)
+	if err != nil {
+		return "", err
+	}
+	c.frameworks[target] = fp
+	return fp, nil
+}
+
+// replaceTarget returns the local directory go.mod replaces Galaxy with.
+func replaceTarget(goMod string) string {
+	data, err := os.ReadFile(goMod)
+	if err != nil {
+		return ""
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(sc.Text()), "replace"))
+		from, to, ok := strings.Cut(line, "=>")
+		if !ok || strings.TrimSpace(from) != galaxyModule {
+			continue
+		}
+		if fields := strings.Fields(to); len(fields) == 1 {
+			if t := fields[0]; strings.HasPrefix(t, ".") || filepath.IsAbs(t) {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+func restoreBuild(entry, dir, output string) error {
+	if _, err := os.Stat(filepath.Join(entry, output)); err != nil {
+		return err
+	}
+	for _, name := range append([]string{output}, restored...) {
+		mode := os.FileMode(0644)
+		if name == output {
+			mode = 0755
+		}
+		if err := copyFile(filepath.Join(entry, name), filepath.Join(dir, name), mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeBuild copies the build into a temporary directory next to entry and
+// renames it into place.
+func (c *Cache) storeBuild(entry, dir, output string) error {
+	if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(entry), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, name := range append([]string{output}, restored...) {
+		src := filepath.Join(dir, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) && name != output {
+			// A module without dependencies has no go.sum; an empty one
+			// restores the same build.
+			if err := os.WriteFile(filepath.Join(tmp, name), nil, 0644); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := copyFile(src, filepath.Join(tmp, name), 0644); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, entry); err != nil && !os.IsExist(err) {
+		if _, statErr := os.Stat(filepath.Join(entry, output)); statErr == nil {
+			// Another build stored the same entry first.
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func copyFile(src, dest string, mode os.FileMode) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, mode)
+}
@@ -0,0 +1,442 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/telemetry"
+)
+
+// StatusHeader reports HIT, STALE or MISS on cacheable responses.
+const StatusHeader = "X-Galaxy-Cache"
+
+// maxBodySize caps the responses kept; larger ones are streamed through
+// uncached.
+const maxBodySize = 8 << 20
+
+// perRequestHeaders describe one response rather than the page, such as
+// its request ID and rate limit, and are never stored.
+var perRequestHeaders = []string{StatusHeader, "Age", "Date", "Retry-After", telemetry.RequestIDHeader}
+
+// staticGrace keeps prerendered pages loaded by LoadStatic servable while
+// they regenerate, however old the files are.
+const staticGrace = 365 * 24 * time.Hour
+
+// Cache serves cached responses in front of an SSR handler. Hits are
+// answered before any middleware runs, so pages behind auth guards or
+// reading the session should vary on the session cookie or call
+// Galaxy.Cache.Private().
+type Cache struct {
+	store Store
+	rules []config.CacheRoute
+
+	// StaticDir, when set, receives regenerated HTML for pages prerendered
+	// into it, so restarts pick up the latest version.
+	StaticDir string
+
+	now func() time.Time
+
+	mu      sync.Mutex
+	vary    map[string][]string
+	pending map[string]bool
+}
+
+func New(store Store, rules []config.CacheRoute) *Cache {
+	return &Cache{
+		store:   store,
+		rules:   rules,
+		now:     time.Now,
+		vary:    make(map[string][]string),
+		pending: make(map[string]bool),
+	}
+}
+
+// FromConfig creates a cache backed by a MemoryStore.
+func FromConfig(cfg config.CacheConfig) *Cache {
+	return New(NewMemoryStore(cfg.MaxEntries), cfg.Routes)
+}
+
+// Handler caches the responses of next according to each request's Policy.
+// Fresh entries are served as they are; stale ones are served while next
+// renders a replacement in the background.
+func (c *Cache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cacheableRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := c.key(r, c.varyFor(r.URL.Path))
+		if e, ok := c.store.Get(key); ok {
+			now := c.now()
+			if e.fresh(now) {
+				serve(w, r, e, "HIT", now)
+				return
+			}
+			if e.usable(now) {
+				serve(w, r, e, "STALE", now)
+				c.revalidate(key, r, next)
+				return
+			}
+		}
+
+		r, policy := c.attach(r)
+		rec := &recorder{w: w, policy: policy}
+		next.ServeHTTP(rec, r)
+		c.save(r, policy, rec)
+	})
+}
+
+// Purge removes every cached variant of path.
+func (c *Cache) Purge(path string) int {
+	return c.store.PurgePath(path)
+}
+
+// PurgeTag removes every cached response tagged tag.
+func (c *Cache) PurgeTag(tag string) int {
+	return c.store.PurgeTag(tag)
+}
+
+func cacheableRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	return r.Header.Get("Upgrade") == "" && r.Header.Get("Authorization") == ""
+}
+
+func (c *Cache) attach(r *http.Request) (*http.Request, *Policy) {
+	policy := policyFor(c.rules, r.URL.Path)
+	return withPolicy(r, policy), policy
+}
+
+// varyFor returns the variant keys last seen for path, falling back to its
+// route rule before anything has been stored.
+func (c *Cache) varyFor(path string) []string {
+	c.mu.Lock()
+	vary, ok := c.vary[path]
+	c.mu.Unlock()
+	if ok {
+		return vary
+	}
+	return normalizeVary(policyFor(c.rules, path).Vary)
+}
+
+func (c *Cache) key(r *http.Request, vary []string) string {
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	if r.URL.RawQuery != "" {
+		b.WriteString("?")
+		b.WriteString(r.URL.RawQuery)
+	}
+	for _, k := range vary {
+		b.WriteString("\x00")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(varyValue(r, k))
+	}
+	return b.String()
+}
+
+// save stores a recorded response if its policy and the response allow it.
+func (c *Cache) save(r *http.Request, policy *Policy, rec *recorder) bool {
+	if r.Method != http.MethodGet || !policy.cacheable() || rec.skip {
+		return false
+	}
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status != http.StatusOK {
+		return false
+	}
+
+	header := rec.Header().Clone()
+	// Responses that set cookies or embed a CSRF token belong to one visitor.
+	if len(header.Values("Set-Cookie")) > 0 || bytes.Contains(rec.body.Bytes(), []byte(`name="_csrf"`)) {
+		return false
+	}
+	vary := normalizeVary(append(append([]string(nil), policy.Vary...), header.Values("Vary")...))
+	for _, k := range vary {
+		if k == "*" {
+			return false
+		}
+	}
+	for _, k := range perRequestHeaders {
+		header.Del(k)
+	}
+	for k := range header {
+		if strings.HasPrefix(k, "X-Ratelimit-") {
+			header.Del(k)
+		}
+	}
+	if header.Get("Content-Type") == "" && rec.body.Len() > 0 {
+		header.Set("Content-Type", http.DetectContentType(rec.body.Bytes()))
+	}
+
+	c.mu.Lock()
+	c.vary[r.URL.Path] = vary
+	c.mu.Unlock()
+
+	e := &Entry{
+		Path:                 r.URL.Path,
+		Status:               status,
+		Header:               header,
+		Body:                 bytes.Clone(rec.body.Bytes()),
+		Tags:                 policy.Tags,
+		Created:              c.now(),
+		TTL:                  time.Duration(policy.TTL) * time.Second,
+		StaleWhileRevalidate: time.Duration(policy.StaleWhileRevalidate) * time.Second,
+	}
+	c.store.Set(c.key(r, vary), e)
+
+	if r.URL.RawQuery == "" && len(vary) == 0 {
+		c.writeStatic(e)
+	}
+	return true
+}
+
+// revalidate renders key again in the background, once at a time. The
+// request keeps only the cookies and headers the variant depends on.
+func (c *Cache) revalidate(key string, r *http.Request, next http.Handler) {
+	c.mu.Lock()
+	if c.pending[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.pending[key] = true
+	c.mu.Unlock()
+
+	req := backgroundRequest(r, c.varyFor(r.URL.Path))
+
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				slog.Error("cache revalidation panicked", "path", r.URL.Path, "error", err)
+			}
+			c.mu.Lock()
+			delete(c.pending, key)
+			c.mu.Unlock()
+		}()
+
+		req, policy := c.attach(req)
+		rec := &recorder{header: make(http.Header), policy: policy}
+		next.ServeHTTP(rec, req)
+		if !c.save(req, policy, rec) {
+			c.store.Delete(key)
+		}
+	}()
+}
+
+type revalidatingKey struct{}
+
+// Revalidating reports whether r is a background render replacing a stale
+// entry, which must not be answered from prerendered files.
+func Revalidating(r *http.Request) bool {
+	return r.Context().Value(revalidatingKey{}) != nil
+}
+
+func backgroundRequest(r *http.Request, vary []string) *http.Request {
+	req := r.Clone(context.WithValue(context.WithoutCancel(r.Context()), revalidatingKey{}, true))
+	req.Method = http.MethodGet
+	req.Header.Del("Cookie")
+	req.Header.Del("Authorization")
+	for _, k := range vary {
+		if name, ok := strings.CutPrefix(k, "cookie:"); ok {
+			if cookie, err := r.Cookie(name); err == nil {
+				req.AddCookie(cookie)
+			}
+		}
+	}
+	return req
+}
+
+func serve(w http.ResponseWriter, r *http.Request, e *Entry, state string, now time.Time) {
+	// Headers already set, such as the request ID, belong to this request.
+	h := w.Header()
+	for k, v := range e.Header {
+		if _, ok := h[k]; !ok {
+			h[k] = append([]string(nil), v...)
+		}
+	}
+	h.Set(StatusHeader, state)
+	telemetry.CacheRequests.Inc(strings.ToLower(state))
+	h.Set("Age", strconv.Itoa(int(now.Sub(e.Created).Seconds())))
+	w.WriteHeader(e.Status)
+	if r.Method != http.MethodHead {
+		w.Write(e.Body)
+	}
+}
+
+// Incremental reports whether the prerendered page at path, built from
+// file, is served through the cache and regenerated: a route rule gives it
+// a TTL or its source sets Galaxy.Cache.
+func Incremental(cfg config.CacheConfig, path, file string) bool {
+	if !cfg.Enabled {
+		return false
+	}
+	if policyFor(cfg.Routes, path).TTL > 0 {
+		return true
+	}
+	content, err := os.ReadFile(file)
+	return err == nil && strings.Contains(string(content), "Galaxy.Cache")
+}
+
+// LoadStatic seeds the cache with pages prerendered into dir, so hybrid
+// builds serve them straight away and regenerate them once their TTL has
+// passed. Paths without a prerendered file are skipped.
+func (c *Cache) LoadStatic(dir string, paths []string) error {
+	if err := c.LoadStaticFS(os.DirFS(dir), paths); err != nil {
+		return err
+	}
+	c.StaticDir = dir
+	return nil
+}
+
+// LoadStaticFS is LoadStatic for pages in fsys, such as those embedded into
+// a built server. Regenerated pages are not written back.
+func (c *Cache) LoadStaticFS(fsys fs.FS, paths []string) error {
+	for _, path := range paths {
+		file := filepath.ToSlash(staticFile(".", path))
+		info, err := fs.Stat(fsys, file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		// Embedded files have no modification time; treat them as built now.
+		created := info.ModTime()
+		if created.IsZero() {
+			created = c.now()
+		}
+
+		policy := policyFor(c.rules, path)
+		r, _ := http.NewRequest(http.MethodGet, path, nil)
+		c.store.Set(c.key(r, c.varyFor(path)), &Entry{
+			Path:                 path,
+			Status:               http.StatusOK,
+			Header:               http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+			Body:                 body,
+			Tags:                 policy.Tags,
+			Created:              created,
+			TTL:                  time.Duration(policy.TTL) * time.Second,
+			StaleWhileRevalidate: staticGrace,
+		})
+	}
+	return nil
+}
+
+// writeStatic replaces a prerendered page with its regenerated HTML. Only
+// files that already exist are updated.
+func (c *Cache) writeStatic(e *Entry) {
+	if c.StaticDir == "" || !strings.HasPrefix(e.Header.Get("Content-Type"), "text/html") {
+		return
+	}
+	file := staticFile(c.StaticDir, e.Path)
+	if _, err := os.Stat(file); err != nil {
+		return
+	}
+
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, e.Body, 0644); err != nil {
+		slog.Error("cache: write static page", "path", e.Path, "error", err)
+		return
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		slog.Error("cache: write static page", "path", e.Path, "error", err)
+	}
+}
+
+func staticFile(dir, path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return filepath.Join(dir, "index.html")
+	}
+	return filepath.Join(dir, filepath.FromSlash(path), "index.html")
+}
+
+// recorder passes a response through to the client while keeping a copy.
+// Background renders have no client and only record.
+type recorder struct {
+	w      http.ResponseWriter
+	header http.Header
+	policy *Policy
+
+	status int
+	body   bytes.Buffer
+	// skip stops the copy once the response turns out uncacheable or
+	// outgrows maxBodySize.
+	skip bool
+}
+
+func (rec *recorder) Header() http.Header {
+	if rec.w != nil {
+		return rec.w.Header()
+	}
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(code int) {
+	if rec.status != 0 {
+		return
+	}
+	rec.status = code
+	// The page has set its policy by the time it writes, so responses that
+	// will not be stored go straight through without a copy.
+	rec.skip = !rec.policy.cacheable() || code != http.StatusOK || len(rec.Header().Values("Set-Cookie")) > 0
+	if rec.w == nil {
+		return
+	}
+	if rec.policy.cacheable() {
+		rec.w.Header().Set(StatusHeader, "MISS")
+		telemetry.CacheRequests.Inc("miss")
+	}
+	rec.w.WriteHeader(code)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.skip {
+		if rec.body.Len()+len(b) > maxBodySize {
+			rec.skip = true
+			rec.body = bytes.Buffer{}
+		} else {
+			rec.body.Write(b)
+		}
+	}
+	if rec.w == nil {
+		return len(b), nil
+	}
+	return rec.w.Write(b)
+}
+
+func (rec *recorder) Flush() {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.w != nil {
+		http.NewResponseController(rec.w).Flush()
+	}
+}
+
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.w
+}
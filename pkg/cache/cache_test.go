@@ -0,0 +1,351 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+func counter(configure func(w http.ResponseWriter, r *http.Request)) (http.Handler, *int32) {
+	var renders int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&renders, 1)
+		if configure != nil {
+			configure(w, r)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "render %d", n)
+	}), &renders
+}
+
+func get(h http.Handler, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestHitAndMiss(t *testing.T) {
+	next, renders := counter(nil)
+	c := New(NewMemoryStore(0), []config.CacheRoute{{Path: "/blog/**", TTL: 60}})
+	h := c.Handler(next)
+
+	w := get(h, "/blog/post")
+	if w.Header().Get(StatusHeader) != "MISS" || w.Body.String() != "render 1" {
+		t.Errorf("Expected MISS render 1, got %s %q", w.Header().Get(StatusHeader), w.Body.String())
+	}
+	w = get(h, "/blog/post")
+	if w.Header().Get(StatusHeader) != "HIT" || w.Body.String() != "render 1" {
+		t.Errorf("Expected HIT render 1, got %s %q", w.Header().Get(StatusHeader), w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("Expected cached headers, got %v", w.Header())
+	}
+
+	get(h, "/about")
+	if w := get(h, "/about"); w.Header().Get(StatusHeader) != "" {
+		t.Errorf("Expected uncached route, got %s", w.Header().Get(StatusHeader))
+	}
+	if *renders != 3 {
+		t.Errorf("Expected 3 renders, got %d", *renders)
+	}
+}
+
+func TestPerRequestHeadersNotStored(t *testing.T) {
+	next, _ := counter(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "10")
+		w.Header().Set("X-RateLimit-Remaining", "9")
+		w.Header().Set("Content-Language", "en")
+	})
+	cached := New(NewMemoryStore(0), []config.CacheRoute{{Path: "/**", TTL: 60}}).Handler(next)
+	// Stands in for telemetry.Handler, which sets the request ID ahead of
+	// the cache.
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", r.Header.Get("X-Test-ID"))
+		cached.ServeHTTP(w, r)
+	})
+
+	for _, id := range []string{"first", "second"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Test-ID", id)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if got := w.Header().Get("X-Request-ID"); got != id {
+			t.Errorf("Expected request ID %s, got %s", id, got)
+		}
+		if id == "second" {
+			if w.Header().Get(StatusHeader) != "HIT" {
+				t.Fatalf("Expected HIT, got %s", w.Header().Get(StatusHeader))
+			}
+			if w.Header().Get("X-RateLimit-Remaining") != "" || w.Header().Get("X-RateLimit-Limit") != "" {
+				t.Errorf("Expected no stored rate limit headers, got %v", w.Header())
+			}
+			if w.Header().Get("Content-Language") != "en" {
+				t.Errorf("Expected page headers to be kept, got %v", w.Header())
+			}
+		}
+	}
+}
+
+func TestFrontmatterPolicy(t *testing.T) {
+	next, _ := counter(func(w http.ResponseWriter, r *http.Request) {
+		policy := PolicyFrom(r)
+		policy.TTL = 60
+		policy.Tag("posts")
+	})
+	c := New(NewMemoryStore(0), nil)
+	h := c.Handler(next)
+
+	get(h, "/")
+	if w := get(h, "/"); w.Header().Get(StatusHeader) != "HIT" {
+		t.Errorf("Expected HIT, got %s", w.Header().Get(StatusHeader))
+	}
+	if n := c.PurgeTag("posts"); n != 1 {
+		t.Errorf("Expected 1 purged, got %d", n)
+	}
+	if w := get(h, "/"); w.Header().Get(StatusHeader) != "MISS" {
+		t.Errorf("Expected MISS after purge, got %s", w.Header().Get(StatusHeader))
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	next, renders := counter(nil)
+	c := New(NewMemoryStore(0), []config.CacheRoute{{Path: "/", TTL: 10, StaleWhileRevalidate: 60}})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	h := c.Handler(next)
+
+	get(h, "/")
+	now = now.Add(30 * time.Second)
+
+	w := get(h, "/")
+	if w.Header().Get(StatusHeader) != "STALE" || w.Body.String() != "render 1" {
+		t.Errorf("Expected STALE render 1, got %s %q", w.Header().Get(StatusHeader), w.Body.String())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(renders) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	w = get(h, "/")
+	if w.Header().Get(StatusHeader) != "HIT" || w.Body.String() != "render 2" {
+		t.Errorf("Expected HIT render 2, got %s %q", w.Header().Get(StatusHeader), w.Body.String())
+	}
+
+	now = now.Add(time.Hour)
+	if w := get(h, "/"); w.Header().Get(StatusHeader) != "MISS" {
+		t.Errorf("Expected MISS once past the stale window, got %s", w.Header().Get(StatusHeader))
+	}
+}
+
+func TestVary(t *testing.T) {
+	next, renders := counter(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+	})
+	c := New(NewMemoryStore(0), []config.CacheRoute{{Path: "/", TTL: 60, Vary: []string{"cookie:theme"}}})
+	h := c.Handler(next)
+
+	dark := &http.Cookie{Name: "theme", Value: "dark"}
+	light := &http.Cookie{Name: "theme", Value: "light"}
+
+	get(h, "/", dark)
+	get(h, "/", light)
+	if w := get(h, "/", dark); w.Body.String() != "render 1" {
+		t.Errorf("Expected dark variant, got %q", w.Body.String())
+	}
+	if w := get(h, "/", light); w.Body.String() != "render 2" {
+		t.Errorf("Expected light variant, got %q", w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(dark)
+	req.Header.Set("Accept-Language", "fr")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if *renders != 3 {
+		t.Errorf("Expected response Vary header to add a variant, got %d renders", *renders)
+	}
+}
+
+func TestNotStored(t *testing.T) {
+	cases := map[string]func(w http.ResponseWriter, r *http.Request){
+		"cookie":  func(w http.ResponseWriter, r *http.Request) { http.SetCookie(w, &http.Cookie{Name: "a", Value: "b"}) },
+		"private": func(w http.ResponseWriter, r *http.Request) { PolicyFrom(r).Private() },
+		"status":  func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+		"csrf": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`<form method="post"><input type="hidden" name="_csrf" value="x"></form>`))
+		},
+	}
+
+	for name, configure := range cases {
+		next, renders := counter(configure)
+		h := New(NewMemoryStore(0), []config.CacheRoute{{Path: "/**", TTL: 60}}).Handler(next)
+		get(h, "/")
+		get(h, "/")
+		if *renders != 2 {
+			t.Errorf("%s: expected response not to be cached, got %d renders", name, *renders)
+		}
+	}
+
+	next, renders := counter(nil)
+	h := New(NewMemoryStore(0), []config.CacheRoute{{Path: "/**", TTL: 60}}).Handler(next)
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer x")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if *renders != 2 {
+		t.Errorf("Expected authorized requests to bypass the cache, got %d renders", *renders)
+	}
+}
+
+func TestRecorderSkipsUncacheable(t *testing.T) {
+	cases := map[string]struct {
+		policy *Policy
+		status int
+	}{
+		"no policy": {&Policy{}, http.StatusOK},
+		"private":   {&Policy{TTL: 60, private: true}, http.StatusOK},
+		"status":    {&Policy{TTL: 60}, http.StatusNotFound},
+	}
+	for name, c := range cases {
+		w := httptest.NewRecorder()
+		rec := &recorder{w: w, policy: c.policy}
+		rec.WriteHeader(c.status)
+		rec.Write([]byte("large download"))
+		if rec.body.Len() != 0 || !rec.skip {
+			t.Errorf("%s: expected the response to pass through uncopied, kept %q", name, rec.body.String())
+		}
+		if w.Body.String() != "large download" {
+			t.Errorf("%s: expected the client to get the body, got %q", name, w.Body.String())
+		}
+	}
+
+	rec := &recorder{w: httptest.NewRecorder(), policy: &Policy{TTL: 60}}
+	rec.Write([]byte("page"))
+	if rec.skip || rec.body.String() != "page" {
+		t.Errorf("Expected a cacheable response to be copied, got %q", rec.body.String())
+	}
+}
+
+func TestRevalidateHandler(t *testing.T) {
+	next, _ := counter(nil)
+	c := New(NewMemoryStore(0), []config.CacheRoute{{Path: "/blog/**", TTL: 60, Tags: []string{"blog"}}})
+	h := c.Handler(next)
+	get(h, "/blog/a")
+	get(h, "/blog/a?page=2")
+	get(h, "/blog/b")
+
+	revalidate := c.RevalidateHandler("s3cret")
+
+	req := httptest.NewRequest("POST", RevalidatePath+"?path=/blog/a", nil)
+	w := httptest.NewRecorder()
+	revalidate.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", RevalidatePath+"?path=/blog/a", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w = httptest.NewRecorder()
+	revalidate.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"purged":2}` {
+		t.Errorf("Expected 2 purged, got %d %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", RevalidatePath, strings.NewReader("tag=blog"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w = httptest.NewRecorder()
+	revalidate.ServeHTTP(w, req)
+	if strings.TrimSpace(w.Body.String()) != `{"purged":1}` {
+		t.Errorf("Expected 1 purged by tag, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	c.RevalidateHandler("").ServeHTTP(w, httptest.NewRequest("POST", RevalidatePath, nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected endpoint disabled without a secret, got %d", w.Code)
+	}
+}
+
+func TestLoadStatic(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "about"), 0755)
+	file := filepath.Join(dir, "about", "index.html")
+	os.WriteFile(file, []byte("prerendered"), 0644)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(file, old, old)
+
+	next, renders := counter(nil)
+	c := New(NewMemoryStore(0), []config.CacheRoute{{Path: "/about", TTL: 60}})
+	if err := c.LoadStatic(dir, []string{"/about", "/missing"}); err != nil {
+		t.Fatalf("LoadStatic failed: %v", err)
+	}
+	h := c.Handler(next)
+
+	w := get(h, "/about")
+	if w.Header().Get(StatusHeader) != "STALE" || w.Body.String() != "prerendered" {
+		t.Errorf("Expected stale prerendered page, got %s %q", w.Header().Get(StatusHeader), w.Body.String())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := os.ReadFile(file); string(data) == "render 1" {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "render 1" {
+		t.Errorf("Expected regenerated page written back, got %q", data)
+	}
+	if *renders != 1 {
+		t.Errorf("Expected 1 render, got %d", *renders)
+	}
+}
+
+func TestLoadStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{"about/index.html": {Data: []byte("embedded")}}
+
+	next, renders := counter(nil)
+	c := New(NewMemoryStore(0), []config.CacheRoute{{Path: "/about", TTL: 60}})
+	if err := c.LoadStaticFS(fsys, []string{"/about"}); err != nil {
+		t.Fatalf("LoadStaticFS failed: %v", err)
+	}
+
+	w := get(c.Handler(next), "/about")
+	if w.Header().Get(StatusHeader) != "HIT" || w.Body.String() != "embedded" {
+		t.Errorf("Expected embedded page fresh from startup, got %s %q", w.Header().Get(StatusHeader), w.Body.String())
+	}
+	if *renders != 0 {
+		t.Errorf("Expected no render, got %d", *renders)
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	s := NewMemoryStore(2)
+	s.Set("a", &Entry{Path: "/a"})
+	s.Set("b", &Entry{Path: "/b"})
+	s.Get("a")
+	s.Set("c", &Entry{Path: "/c"})
+
+	if _, ok := s.Get("b"); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, ok := s.Get("a"); !ok {
+		t.Error("Expected recently used entry to be kept")
+	}
+}
@@ -0,0 +1,126 @@
+// Package cache caches rendered SSR responses. Route rules and page
+// frontmatter declare how long a response stays fresh, how long it may be
+// served stale while a new one renders in the background, which request
+// headers and cookies select between variants, and tags used to purge it.
+package cache
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+)
+
+// Policy controls caching of one response. Route rules seed it and
+// frontmatter adjusts it through Galaxy.Cache while the page renders.
+type Policy struct {
+	// TTL is how many seconds the response stays fresh. Zero disables
+	// caching.
+	TTL int
+	// StaleWhileRevalidate is how many seconds past TTL the response is
+	// still served while a fresh one renders in the background.
+	StaleWhileRevalidate int
+	// Vary lists request headers, or cookies as "cookie:<name>", that select
+	// between cached variants.
+	Vary []string
+	Tags []string
+
+	private bool
+}
+
+// Tag adds a tag the response can be purged by.
+func (p *Policy) Tag(tag string) {
+	p.Tags = append(p.Tags, tag)
+}
+
+// VaryBy caches a variant per value of a request header, or of a cookie
+// given as "cookie:<name>".
+func (p *Policy) VaryBy(key string) {
+	p.Vary = append(p.Vary, key)
+}
+
+// Private keeps the response out of the cache, whatever the TTL.
+func (p *Policy) Private() {
+	p.private = true
+}
+
+func (p *Policy) cacheable() bool {
+	return p != nil && p.TTL > 0 && !p.private
+}
+
+type contextKey struct{}
+
+func withPolicy(r *http.Request, p *Policy) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, p))
+}
+
+// PolicyFrom returns the policy of the request being rendered. Outside a
+// cached handler it returns a detached policy that has no effect.
+func PolicyFrom(r *http.Request) *Policy {
+	if r != nil {
+		if p, ok := r.Context().Value(contextKey{}).(*Policy); ok {
+			return p
+		}
+	}
+	return &Policy{}
+}
+
+// PolicyFor returns the policy the route rules give path before any page
+// adjusts it, for adapters that hand caching to a platform's CDN.
+func PolicyFor(rules []config.CacheRoute, path string) *Policy {
+	return policyFor(rules, path)
+}
+
+// policyFor seeds a policy from the first route rule matching path.
+func policyFor(rules []config.CacheRoute, path string) *Policy {
+	for _, rule := range rules {
+		if middleware.MatchPath(rule.Path, path) {
+			return &Policy{
+				TTL:                  rule.TTL,
+				StaleWhileRevalidate: rule.StaleWhileRevalidate,
+				Vary:                 append([]string(nil), rule.Vary...),
+				Tags:                 append([]string(nil), rule.Tags...),
+			}
+		}
+	}
+	return &Policy{}
+}
+
+// normalizeVary canonicalizes header names, drops duplicates and sorts, so
+// the same variants always produce the same key.
+func normalizeVary(keys []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, key := range keys {
+		for _, k := range strings.Split(key, ",") {
+			k = strings.TrimSpace(k)
+			if k == "" {
+				continue
+			}
+			if name, ok := strings.CutPrefix(k, "cookie:"); ok {
+				k = "cookie:" + name
+			} else {
+				k = http.CanonicalHeaderKey(k)
+			}
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, k)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func varyValue(r *http.Request, key string) string {
+	if name, ok := strings.CutPrefix(key, "cookie:"); ok {
+		if c, err := r.Cookie(name); err == nil {
+			return c.Value
+		}
+		return ""
+	}
+	return strings.Join(r.Header.Values(key), ",")
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+// RevalidatePath is where generated servers mount RevalidateHandler.
+const RevalidatePath = "/_galaxy/revalidate"
+
+// Secret returns the revalidate secret, preferring the
+// GALAXY_REVALIDATE_SECRET environment variable.
+func Secret(cfg config.CacheConfig) string {
+	if secret := os.Getenv("GALAXY_REVALIDATE_SECRET"); secret != "" {
+		return secret
+	}
+	return cfg.RevalidateSecret
+}
+
+// RevalidateHandler purges cached responses on POST requests carrying
+// "Authorization: Bearer <secret>". Each "path" and "tag" query or form
+// value is purged; the response reports how many entries were removed.
+// Without a secret every request is refused.
+func (c *Cache) RevalidateHandler(secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		paths, tags := r.Form["path"], r.Form["tag"]
+		if len(paths) == 0 && len(tags) == 0 {
+			http.Error(w, "path or tag is required", http.StatusBadRequest)
+			return
+		}
+
+		purged := 0
+		for _, path := range paths {
+			purged += c.Purge(path)
+		}
+		for _, tag := range tags {
+			purged += c.PurgeTag(tag)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "{\"purged\":%d}\n", purged)
+	})
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Entry is a cached response.
+type Entry struct {
+	Path                 string
+	Status               int
+	Header               http.Header
+	Body                 []byte
+	Tags                 []string
+	Created              time.Time
+	TTL                  time.Duration
+	StaleWhileRevalidate time.Duration
+}
+
+func (e *Entry) fresh(now time.Time) bool {
+	return now.Sub(e.Created) < e.TTL
+}
+
+func (e *Entry) usable(now time.Time) bool {
+	return now.Sub(e.Created) < e.TTL+e.StaleWhileRevalidate
+}
+
+// Store holds cached responses. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, e *Entry)
+	Delete(key string)
+	// PurgePath removes every variant cached for a URL path and returns how
+	// many were removed.
+	PurgePath(path string) int
+	// PurgeTag removes every entry carrying tag and returns how many were
+	// removed.
+	PurgeTag(tag string) int
+}
+
+// MemoryStore keeps entries in memory, evicting the least recently used
+// once it holds MaxEntries.
+type MemoryStore struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+}
+
+// NewMemoryStore creates a store holding at most maxEntries entries, or
+// 1000 when maxEntries is zero.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (s *MemoryStore) Get(key string) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(el)
+	return el.Value.(*memoryItem).entry, true
+}
+
+func (s *MemoryStore) Set(key string, e *Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*memoryItem).entry = e
+		s.lru.MoveToFront(el)
+		return
+	}
+	s.entries[key] = s.lru.PushFront(&memoryItem{key: key, entry: e})
+
+	for s.lru.Len() > s.maxEntries {
+		s.remove(s.lru.Back())
+	}
+}
+
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+}
+
+func (s *MemoryStore) PurgePath(path string) int {
+	return s.purge(func(e *Entry) bool { return e.Path == path })
+}
+
+func (s *MemoryStore) PurgeTag(tag string) int {
+	return s.purge(func(e *Entry) bool {
+		for _, t := range e.Tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (s *MemoryStore) purge(match func(*Entry) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, el := range s.entries {
+		if match(el.Value.(*memoryItem).entry) {
+			s.remove(el)
+			n++
+		}
+	}
+	return n
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*memoryItem).key)
+}
@@ -1,13 +1,17 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/cameron-webmatter/galaxy/pkg/build"
+	"github.com/cameron-webmatter/galaxy/pkg/buildcache"
 	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/linkcheck"
+	"github.com/cameron-webmatter/galaxy/pkg/report"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +19,14 @@ var (
 	buildOutDir string
 	buildMode   string
 	buildForce  bool
+
+	buildConcurrency int
+
+	buildReport     string
+	buildReportFile string
+
+	buildCheckLinks    bool
+	buildExternalLinks bool
 )
 
 var buildCmd = &cobra.Command{
@@ -29,6 +41,11 @@ func init() {
 	buildCmd.Flags().StringVar(&buildOutDir, "outDir", "./dist", "output directory")
 	buildCmd.Flags().StringVar(&buildMode, "mode", "production", "build mode")
 	buildCmd.Flags().BoolVar(&buildForce, "force", false, "clear cache and rebuild")
+	buildCmd.Flags().IntVar(&buildConcurrency, "concurrency", 0, "pages built at once (default: number of CPUs)")
+	buildCmd.Flags().StringVar(&buildReport, "report", "", "report route sizes and render times: table or json")
+	buildCmd.Flags().StringVar(&buildReportFile, "report-file", ".galaxy/report.json", "where --report=json writes the report")
+	buildCmd.Flags().BoolVar(&buildCheckLinks, "check-links", false, "fail on broken links and missing assets")
+	buildCmd.Flags().BoolVar(&buildExternalLinks, "external-links", false, "with --check-links, also request links to other sites")
 }
 
 func runBuild(cmd *cobra.Command, args []string) error {
@@ -48,6 +65,15 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("load config: %w", err)
 	}
 
+	if buildReport != "" && buildReport != "table" && buildReport != "json" {
+		return fmt.Errorf("invalid --report %q (must be table or json)", buildReport)
+	}
+	budgets, err := report.ParseBudgets(cfg.Budgets)
+	if err != nil {
+		return err
+	}
+	measure := buildReport != "" || budgets.Enabled()
+
 	srcDir := cfg.SrcDir
 	if !filepath.IsAbs(srcDir) {
 		srcDir = filepath.Join(cwd, srcDir)
@@ -87,17 +113,52 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
+	if buildForce {
+		if err := buildcache.Clear(cwd); err != nil {
+			return fmt.Errorf("clear build cache: %w", err)
+		}
+	}
+	buildCache, err := buildcache.Open(cwd)
+	if err != nil {
+		return err
+	}
+
 	var buildErr error
+	var rep *report.Report
+	var linkIssues []linkcheck.Issue
 
 	if cfg.IsStatic() {
 		builder := build.NewSSGBuilder(cfg, srcDir, pagesDir, outDir, publicDir)
+		builder.BuildCache = buildCache
+		builder.Concurrency = buildConcurrency
+		builder.Measure = measure
+		builder.CheckLinks = buildCheckLinks
+		builder.CheckExternalLinks = buildExternalLinks
 		buildErr = builder.Build()
+		rep = builder.Report
+		linkIssues = builder.LinkIssues
 	} else if cfg.IsHybrid() {
 		builder := build.NewHybridBuilder(cfg, srcDir, pagesDir, outDir, publicDir)
+		builder.RootDir = cwd
+		builder.BuildCache = buildCache
+		builder.Concurrency = buildConcurrency
+		builder.Measure = measure
+		builder.CheckLinks = buildCheckLinks
+		builder.CheckExternalLinks = buildExternalLinks
 		buildErr = builder.Build()
+		rep = builder.Report
+		linkIssues = builder.LinkIssues
 	} else if cfg.IsSSR() {
 		builder := build.NewSSRBuilder(cfg, srcDir, pagesDir, outDir, publicDir)
+		builder.RootDir = cwd
+		builder.BuildCache = buildCache
+		builder.Concurrency = buildConcurrency
+		builder.Measure = measure
+		builder.CheckLinks = buildCheckLinks
+		builder.CheckExternalLinks = buildExternalLinks
 		buildErr = builder.Build()
+		rep = builder.Report
+		linkIssues = builder.LinkIssues
 	} else {
 		return fmt.Errorf("unsupported output type: %s", cfg.Output.Type)
 	}
@@ -106,6 +167,14 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("build failed: %w", buildErr)
 	}
 
+	var reportErr error
+	if rep != nil {
+		reportErr = writeReport(rep, budgets, cwd)
+	}
+	if err := errors.Join(reportErr, printLinkIssues(linkIssues)); err != nil {
+		return err
+	}
+
 	duration := time.Since(start)
 
 	if !silent {
@@ -115,3 +184,56 @@ func runBuild(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// writeReport checks rep against budgets and writes it as --report asks,
+// failing the build when a budget is exceeded.
+func writeReport(rep *report.Report, budgets report.Budgets, cwd string) error {
+	rep.Violations = rep.Check(budgets)
+
+	switch buildReport {
+	case "table":
+		fmt.Println()
+		if err := rep.WriteTable(os.Stdout); err != nil {
+			return err
+		}
+	case "json":
+		path := buildReportFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cwd, path)
+		}
+		if err := rep.Save(path); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+		if !silent {
+			fmt.Printf("\n📊 Report: %s\n", path)
+		}
+	}
+
+	if len(rep.Violations) == 0 {
+		return nil
+	}
+	fmt.Fprintln(os.Stderr)
+	for _, v := range rep.Violations {
+		fmt.Fprintf(os.Stderr, "  ✗ %s\n", v)
+	}
+	if len(rep.Violations) == 1 {
+		return fmt.Errorf("1 budget exceeded")
+	}
+	return fmt.Errorf("%d budgets exceeded", len(rep.Violations))
+}
+
+// printLinkIssues lists broken links and fails the build when there are
+// any.
+func printLinkIssues(issues []linkcheck.Issue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	fmt.Fprintln(os.Stderr)
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "  ✗ %s\n", issue)
+	}
+	if len(issues) == 1 {
+		return fmt.Errorf("1 broken link")
+	}
+	return fmt.Errorf("%d broken links", len(issues))
+}
@@ -6,12 +6,15 @@ import (
 	"path/filepath"
 
 	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/linkcheck"
 	"github.com/cameron-webmatter/galaxy/pkg/parser"
+	"github.com/cameron-webmatter/galaxy/pkg/router"
 	"github.com/spf13/cobra"
 )
 
 var (
-	checkWatch bool
+	checkWatch         bool
+	checkExternalLinks bool
 )
 
 var checkCmd = &cobra.Command{
@@ -24,6 +27,7 @@ var checkCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(checkCmd)
 	checkCmd.Flags().BoolVar(&checkWatch, "watch", false, "watch for changes")
+	checkCmd.Flags().BoolVar(&checkExternalLinks, "external-links", false, "also request links to other sites")
 }
 
 func runCheck(cmd *cobra.Command, args []string) error {
@@ -66,6 +70,10 @@ func runCheck(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if err = checkLinks(cwd, cfg, srcDir, pagesDir, &errors); err != nil {
+		return err
+	}
+
 	if !silent {
 		fmt.Printf("\n")
 		if errors > 0 {
@@ -108,3 +116,57 @@ func checkDirectory(dir string, errors, warnings *int) error {
 		return nil
 	})
 }
+
+// checkLinks reports links written in templates that point to no page or
+// public file. `galaxy build --check-links` also checks the links built by
+// expressions, anchors and bundled assets.
+func checkLinks(cwd string, cfg *config.Config, srcDir, pagesDir string, errors *int) error {
+	r := router.NewRouter(pagesDir)
+	if err := r.Discover(); err != nil {
+		return fmt.Errorf("route discovery: %w", err)
+	}
+	r.Sort()
+	patterns := make(map[string]string, len(r.Routes))
+	for _, route := range r.Routes {
+		patterns[route.FilePath] = route.Pattern
+	}
+
+	var docs []linkcheck.Document
+	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".gxc" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		comp, err := parser.Parse(string(content))
+		if err != nil {
+			// Already reported by checkDirectory.
+			return nil
+		}
+		docs = append(docs, linkcheck.Document{Pattern: patterns[path], Source: path, HTML: []byte(comp.Template), Template: true})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	checker := &linkcheck.Checker{
+		Router:   r,
+		Base:     cfg.Base,
+		Dirs:     []string{filepath.Join(cwd, "public")},
+		RootDir:  cwd,
+		External: checkExternalLinks,
+	}
+	for _, issue := range checker.Check(docs) {
+		*errors++
+		if !silent {
+			fmt.Printf("❌ %s\n", issue)
+		}
+	}
+	return nil
+}
@@ -1,17 +1,19 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"runtime"
-	"strings"
 	"syscall"
 
-	"github.com/fsnotify/fsnotify"
+	"github.com/cameron-webmatter/galaxy/pkg/adapters"
 	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware/builtin"
 	"github.com/cameron-webmatter/galaxy/pkg/server"
 	"github.com/spf13/cobra"
 )
@@ -66,69 +68,29 @@ func runDev(cmd *cobra.Command, args []string) error {
 	}
 
 	srv := server.NewDevServer(cwd, pagesDir, publicDir, devPort, devVerbose)
-
-	watcher, err := fsnotify.NewWatcher()
+	srv.Telemetry = cfg.Telemetry
+	srv.WebSocketOrigins = cfg.Server.WebSocketOrigins
+	srv.BuiltinMiddleware, err = builtin.FromConfig(cfg.Middleware)
 	if err != nil {
-		return err
+		return fmt.Errorf("load config: %w", err)
 	}
-	defer watcher.Close()
 
-	if err := watcher.Add(srcDir); err != nil {
-		return err
+	if cfg.IsSSR() || cfg.IsHybrid() {
+		adapter, err := adapters.Lookup(cfg.Adapter.Name)
+		if err != nil {
+			return err
+		}
+		if e, ok := adapter.(adapters.DevEmulator); ok {
+			srv.Emulate = func(next http.Handler) http.Handler {
+				return e.Emulate(cfg, next)
+			}
+		}
 	}
-	if err := addRecursive(watcher, srcDir); err != nil {
+
+	if err := srv.Watch(context.Background()); err != nil {
 		return err
 	}
 
-	go func() {
-		for {
-			select {
-			case event := <-watcher.Events:
-				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove) != 0 {
-					if !verbose && !silent {
-						fmt.Printf("🔄 Change detected: %s\n", filepath.Base(event.Name))
-					}
-
-					if event.Op&fsnotify.Create != 0 {
-						info, err := os.Stat(event.Name)
-						if err == nil && info.IsDir() && isUnderDir(event.Name, srcDir) {
-							if err := watcher.Add(event.Name); err == nil {
-								if err := addRecursive(watcher, event.Name); err != nil && !silent {
-									fmt.Printf("⚠ Failed to watch new directory: %v\n", err)
-								}
-							}
-						}
-					}
-
-					if filepath.Ext(event.Name) == ".gxc" && isUnderDir(event.Name, srcDir) {
-						srv.Compiler.ClearCache()
-
-						if isUnderDir(event.Name, pagesDir) && event.Op&(fsnotify.Create|fsnotify.Remove) != 0 {
-							if err := srv.ReloadRoutes(); err != nil && !silent {
-								fmt.Printf("⚠ Failed to reload routes: %v\n", err)
-							}
-						}
-					}
-
-					if filepath.Base(event.Name) == "middleware.go" && isUnderDir(event.Name, srcDir) {
-						if !verbose && !silent {
-							fmt.Printf("🔄 Reloading middleware...\n")
-						}
-						if err := srv.ReloadMiddleware(); err != nil && !silent {
-							fmt.Printf("⚠ Middleware reload failed: %v\n", err)
-						} else if !verbose && !silent {
-							fmt.Printf("✅ Middleware reloaded\n")
-						}
-					}
-				}
-			case err := <-watcher.Errors:
-				if !silent {
-					fmt.Printf("⚠ Watcher error: %v\n", err)
-				}
-			}
-		}
-	}()
-
 	go handleInput(srv)
 
 	sigChan := make(chan os.Signal, 1)
@@ -157,18 +119,6 @@ func runDev(cmd *cobra.Command, args []string) error {
 	return srv.Start()
 }
 
-func addRecursive(watcher *fsnotify.Watcher, dir string) error {
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			return watcher.Add(path)
-		}
-		return nil
-	})
-}
-
 func handleInput(srv *server.DevServer) {
 	buf := make([]byte, 1)
 	for {
@@ -215,11 +165,3 @@ func clearConsole() {
 		cmd.Run()
 	}
 }
-
-func isUnderDir(path, dir string) bool {
-	rel, err := filepath.Rel(dir, path)
-	if err != nil {
-		return false
-	}
-	return !strings.HasPrefix(rel, "..") && rel != "."
-}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/openapi"
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+	"github.com/spf13/cobra"
+)
+
+var (
+	openapiOut     string
+	openapiTitle   string
+	openapiVersion string
+)
+
+var openapiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Generate an OpenAPI document for API endpoints",
+	Long:  `Generate an OpenAPI 3 document from the Go endpoints in pages/api`,
+	RunE:  runOpenAPI,
+}
+
+func init() {
+	rootCmd.AddCommand(openapiCmd)
+	openapiCmd.Flags().StringVarP(&openapiOut, "out", "o", "", "output file (default: stdout)")
+	openapiCmd.Flags().StringVar(&openapiTitle, "title", "Galaxy API", "API title")
+	openapiCmd.Flags().StringVar(&openapiVersion, "api-version", "1.0.0", "API version")
+}
+
+func runOpenAPI(cmd *cobra.Command, args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	if rootDir != "" {
+		cwd = rootDir
+	}
+
+	cfg, err := config.LoadFromDir(cwd)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	srcDir := cfg.SrcDir
+	if !filepath.IsAbs(srcDir) {
+		srcDir = filepath.Join(cwd, srcDir)
+	}
+
+	pagesDir := filepath.Join(srcDir, "pages")
+	if _, err := os.Stat(pagesDir); os.IsNotExist(err) {
+		return fmt.Errorf("pages directory not found: %s", pagesDir)
+	}
+
+	rt := router.NewRouter(pagesDir)
+	if err := rt.Discover(); err != nil {
+		return fmt.Errorf("route discovery: %w", err)
+	}
+	rt.Sort()
+
+	var apiRoutes []*router.Route
+	for _, route := range rt.Routes {
+		if route.IsEndpoint && (route.Pattern == "/api" || strings.HasPrefix(route.Pattern, "/api/")) {
+			apiRoutes = append(apiRoutes, route)
+		}
+	}
+
+	gen := openapi.NewGenerator(openapiTitle, openapiVersion)
+	doc, err := gen.Generate(apiRoutes)
+	if err != nil {
+		return fmt.Errorf("generate openapi: %w", err)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if openapiOut == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	outPath := openapiOut
+	if !filepath.IsAbs(outPath) {
+		outPath = filepath.Join(cwd, outPath)
+	}
+	if err := os.WriteFile(outPath, data, 0644); err != nil {
+		return err
+	}
+
+	if !silent {
+		fmt.Printf("✅ OpenAPI document written to %s (%d paths)\n", outPath, len(doc.Paths))
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/cameron-webmatter/galaxy/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Work with build reports",
+	Long:  `Work with the reports written by galaxy build --report=json`,
+}
+
+var reportDiffCmd = &cobra.Command{
+	Use:   "diff <base.json> <head.json>",
+	Short: "Compare two build reports",
+	Long:  `Print, as Markdown for a pull request comment, the routes whose sizes changed between two build reports`,
+	Args:  cobra.ExactArgs(2),
+	RunE:  runReportDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportDiffCmd)
+}
+
+func runReportDiff(cmd *cobra.Command, args []string) error {
+	base, err := report.Load(args[0])
+	if err != nil {
+		return err
+	}
+	head, err := report.Load(args[1])
+	if err != nil {
+		return err
+	}
+	return report.WriteDiff(os.Stdout, base, head)
+}
@@ -1,6 +1,8 @@
 package codegen
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
@@ -8,6 +10,11 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/cameron-webmatter/galaxy/pkg/auth"
+	"github.com/cameron-webmatter/galaxy/pkg/buildcache"
+	"github.com/cameron-webmatter/galaxy/pkg/compiler"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
 	"github.com/cameron-webmatter/galaxy/pkg/parser"
 	"github.com/cameron-webmatter/galaxy/pkg/router"
 )
@@ -18,6 +25,20 @@ type CodegenBuilder struct {
 	OutDir         string
 	ModuleName     string
 	MiddlewarePath string
+	Middleware     config.MiddlewareConfig
+	Cache          config.CacheConfig
+	Server         config.ServerConfig
+	Telemetry      config.TelemetryConfig
+	// Streaming streams every page; pages with server islands always stream.
+	Streaming bool
+	// Incremental lists paths prerendered into OutDir that the server
+	// regenerates through the response cache.
+	Incremental []string
+	// Assets selects whether the server embeds its files or reads them
+	// from disk; empty embeds.
+	Assets config.AssetsMode
+	// BuildCache, when set, reuses a server compiled from the same sources.
+	BuildCache *buildcache.Cache
 }
 
 func NewCodegenBuilder(routes []*router.Route, pagesDir, outDir, moduleName string) *CodegenBuilder {
@@ -42,6 +63,7 @@ func (b *CodegenBuilder) Build() error {
 
 	var handlers []*GeneratedHandler
 	var nonEndpointRoutes []*router.Route
+	streaming := false
 
 	for _, route := range b.Routes {
 		if route.IsEndpoint {
@@ -59,23 +81,89 @@ func (b *CodegenBuilder) Build() error {
 		}
 
 		gen := NewHandlerGenerator(comp, route, b.ModuleName, b.PagesDir)
+		gen.Streaming = b.Streaming || compiler.HasIslands(comp.Template)
+		streaming = streaming || gen.Streaming
 		handler, err := gen.Generate()
 		if err != nil {
 			return fmt.Errorf("generate handler for %s: %w", route.Pattern, err)
 		}
 
+		if route.ActionsFile != "" {
+			if err := b.copyActions(serverDir, route, handler); err != nil {
+				return fmt.Errorf("copy actions for %s: %w", route.Pattern, err)
+			}
+		}
+
 		handlers = append(handlers, handler)
 		nonEndpointRoutes = append(nonEndpointRoutes, route)
 	}
 
+	if streaming {
+		if err := b.copyComponents(serverDir); err != nil {
+			return fmt.Errorf("copy components: %w", err)
+		}
+	}
+
+	if err := StageSite(b.OutDir, serverDir); err != nil {
+		return fmt.Errorf("stage site files: %w", err)
+	}
+
 	manifestPath := filepath.Join(serverDir, "_assets", "wasm-manifest.json")
 	hasMiddleware := false
 	if _, err := os.Stat(b.MiddlewarePath); err == nil {
 		hasMiddleware = true
 	}
 
+	dirMiddleware, err := b.copyDirMiddleware(serverDir, nonEndpointRoutes)
+	if err != nil {
+		return fmt.Errorf("copy directory middleware: %w", err)
+	}
+
+	lifecyclePath := filepath.Join(filepath.Dir(b.PagesDir), "lifecycle.go")
+	hasLifecycle := false
+	if _, err := os.Stat(lifecyclePath); err == nil {
+		hasLifecycle = true
+		if err := copyToMain(lifecyclePath, filepath.Join(serverDir, "lifecycle.go")); err != nil {
+			return fmt.Errorf("copy lifecycle: %w", err)
+		}
+	}
+
 	mainGen := NewMainGenerator(handlers, nonEndpointRoutes, b.ModuleName, manifestPath)
+	server := b.Server
+	if server.Port == 0 {
+		server.Port = 4322
+	}
+	mainGen.ServerConfig = fmt.Sprintf("%#v", server)
+	mainGen.TelemetryConfig = fmt.Sprintf("%#v", b.Telemetry)
+	mainGen.HasLifecycle = hasLifecycle
+	mainGen.DiskAssets = b.Assets == config.AssetsDisk
+	if !mainGen.DiskAssets {
+		if mainGen.Embed, err = EmbedPatterns(serverDir); err != nil {
+			return fmt.Errorf("embed files: %w", err)
+		}
+	}
 	mainGen.HasMiddleware = hasMiddleware
+	if hasMiddleware {
+		_, mainGen.HasSequence = middleware.Detect(b.MiddlewarePath)
+	}
+	mainGen.DirMiddleware = dirMiddleware
+	mainGen.Guards = make(map[string]string)
+	for _, route := range nonEndpointRoutes {
+		rule, err := auth.FileRule(route.FilePath)
+		if err != nil {
+			return fmt.Errorf("auth directive: %w", err)
+		}
+		if rule != nil {
+			mainGen.Guards[route.FilePath] = fmt.Sprintf("%#v", *rule)
+		}
+	}
+	if b.Middleware.Enabled() {
+		mainGen.BuiltinConfig = fmt.Sprintf("%#v", b.Middleware)
+	}
+	if b.Cache.Enabled {
+		mainGen.CacheConfig = fmt.Sprintf("%#v", b.Cache)
+		mainGen.Incremental = b.Incremental
+	}
 	mainGo := mainGen.Generate()
 
 	if err := os.WriteFile(filepath.Join(serverDir, "main.go"), []byte(mainGo), 0644); err != nil {
@@ -112,7 +200,12 @@ func (b *CodegenBuilder) copyMiddleware(serverDir string) error {
 		return nil
 	}
 
-	data, err := os.ReadFile(b.MiddlewarePath)
+	return copyToMain(b.MiddlewarePath, filepath.Join(serverDir, "middleware.go"))
+}
+
+// copyToMain copies a Go file from src into the generated main package.
+func copyToMain(src, dest string) error {
+	data, err := os.ReadFile(src)
 	if err != nil {
 		return err
 	}
@@ -120,8 +213,119 @@ func (b *CodegenBuilder) copyMiddleware(serverDir string) error {
 	content := string(data)
 	content = regexp.MustCompile(`(?m)^package\s+\w+`).ReplaceAllString(content, "package main")
 
-	destPath := filepath.Join(serverDir, "middleware.go")
-	return os.WriteFile(destPath, []byte(content), 0644)
+	return os.WriteFile(dest, []byte(content), 0644)
+}
+
+// copyComponents copies the .gxc components outside pages into
+// server/components, where streamed pages render them at request time.
+func (b *CodegenBuilder) copyComponents(serverDir string) error {
+	srcDir := filepath.Dir(b.PagesDir)
+	destDir := filepath.Join(serverDir, "components")
+
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path == b.PagesDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".gxc" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(destDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(dest, data, 0644)
+	})
+}
+
+// copyDirMiddleware copies each _middleware.go used by the routes into its
+// own package under server/middleware; the go tool skips "_" files in place.
+func (b *CodegenBuilder) copyDirMiddleware(serverDir string, routes []*router.Route) (map[string]*MiddlewarePackage, error) {
+	packages := make(map[string]*MiddlewarePackage)
+
+	for _, route := range routes {
+		for _, file := range route.Middleware {
+			if _, ok := packages[file]; ok {
+				continue
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+
+			alias := MiddlewareAlias(b.PagesDir, file)
+			destDir := filepath.Join(serverDir, "middleware", alias)
+			if err := os.MkdirAll(destDir, 0755); err != nil {
+				return nil, err
+			}
+
+			content := regexp.MustCompile(`(?m)^package\s+\w+`).ReplaceAllString(string(data), "package "+alias)
+			if err := os.WriteFile(filepath.Join(destDir, "middleware.go"), []byte(content), 0644); err != nil {
+				return nil, err
+			}
+
+			_, hasSequence := middleware.Detect(file)
+			packages[file] = &MiddlewarePackage{
+				Alias:    alias,
+				Import:   b.ModuleName + "/middleware/" + alias,
+				Sequence: hasSequence,
+			}
+		}
+	}
+
+	return packages, nil
+}
+
+// MiddlewareAlias names the package the _middleware.go file is copied to.
+// Directories such as a-b, a_b and a/b read alike once made an identifier,
+// so the name ends in a short hash of the directory's path.
+func MiddlewareAlias(pagesDir, file string) string {
+	relPath, _ := filepath.Rel(pagesDir, filepath.Dir(file))
+	if relPath == "." {
+		return "mwroot"
+	}
+	relPath = filepath.ToSlash(relPath)
+	name := regexp.MustCompile(`[^A-Za-z0-9]+`).ReplaceAllString(relPath, "_")
+	sum := sha256.Sum256([]byte(relPath))
+	return "mw" + strings.ToLower(strings.Trim(name, "_")) + "_" + hex.EncodeToString(sum[:3])
+}
+
+// copyActions copies a page's .actions.go into its own package under
+// server/actions so action names cannot collide between pages.
+func (b *CodegenBuilder) copyActions(serverDir string, route *router.Route, handler *GeneratedHandler) error {
+	data, err := os.ReadFile(route.ActionsFile)
+	if err != nil {
+		return err
+	}
+
+	alias := actionsAlias(handler)
+	destDir := filepath.Join(serverDir, "actions", alias)
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+
+	content := regexp.MustCompile(`(?m)^package\s+\w+`).ReplaceAllString(string(data), "package "+alias)
+	if err := os.WriteFile(filepath.Join(destDir, "actions.go"), []byte(content), 0644); err != nil {
+		return err
+	}
+
+	handler.ActionsImport = b.ModuleName + "/actions/" + alias
+	return nil
 }
 
 func (b *CodegenBuilder) generateGoMod(serverDir string) error {
@@ -132,7 +336,7 @@ func (b *CodegenBuilder) generateGoMod(serverDir string) error {
 
 	goMod := fmt.Sprintf(`module %s
 
-go 1.23
+go 1.24
 
 replace github.com/cameron-webmatter/galaxy => %s
 
@@ -164,6 +368,14 @@ func findGalaxyRoot() (string, error) {
 }
 
 func (b *CodegenBuilder) compile(serverDir string) error {
+	if b.BuildCache != nil {
+		_, err := b.BuildCache.GoBuild(serverDir, "server", func() error { return b.goBuild(serverDir) })
+		return err
+	}
+	return b.goBuild(serverDir)
+}
+
+func (b *CodegenBuilder) goBuild(serverDir string) error {
 	tidyCmd := exec.Command("go", "mod", "tidy")
 	tidyCmd.Dir = serverDir
 	if output, err := tidyCmd.CombinedOutput(); err != nil {
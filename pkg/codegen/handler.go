@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/cameron-webmatter/galaxy/pkg/actions"
 	"github.com/cameron-webmatter/galaxy/pkg/executor"
 	"github.com/cameron-webmatter/galaxy/pkg/parser"
 	"github.com/cameron-webmatter/galaxy/pkg/router"
@@ -31,7 +32,34 @@ func (g *HandlerGenerator) Generate() (*GeneratedHandler, error) {
 		FunctionName: funcName,
 	}
 
-	handler.Code = g.generateHandlerFunc(funcName, code, imports)
+	if g.Route.ActionsFile != "" {
+		sigs, err := actions.ParseFile(g.Route.ActionsFile)
+		if err != nil {
+			return nil, fmt.Errorf("parse actions: %w", err)
+		}
+		handler.Actions = sigs
+		handler.Imports = append(handler.Imports, `"github.com/cameron-webmatter/galaxy/pkg/actions"`)
+	}
+
+	if g.usesSession() {
+		handler.Imports = append(handler.Imports, `"github.com/cameron-webmatter/galaxy/pkg/session"`)
+	}
+	if g.usesCache() {
+		handler.Imports = append(handler.Imports, `"github.com/cameron-webmatter/galaxy/pkg/cache"`)
+	}
+	handler.Imports = append(handler.Imports,
+		`"log/slog"`,
+		`"github.com/cameron-webmatter/galaxy/pkg/executor"`,
+		`"github.com/cameron-webmatter/galaxy/pkg/middleware/builtin"`,
+		`"github.com/cameron-webmatter/galaxy/pkg/telemetry"`,
+	)
+	if g.Streaming {
+		handler.Imports = append(handler.Imports, fmt.Sprintf("%q", g.ModuleName+"/runtime"))
+	} else {
+		handler.Imports = append(handler.Imports, `"fmt"`, `"github.com/cameron-webmatter/galaxy/pkg/template"`)
+	}
+
+	handler.Code = g.generateHandlerFunc(funcName, code, handler.Actions)
 
 	return handler, nil
 }
@@ -76,7 +104,19 @@ func (g *HandlerGenerator) transformCode(code string) string {
 	}
 
 	code = regexp.MustCompile(`Galaxy\.[Rr]edirect\(([^,]+),\s*(\d+)\)`).ReplaceAllString(code,
-		"http.Redirect(w, r, $1, $2); return")
+		"galaxyResponse.ApplyHeaders(w.Header()); http.Redirect(w, r, $1, $2); return")
+
+	code = regexp.MustCompile(`Galaxy\.NotFound\(\)`).ReplaceAllString(code,
+		"galaxyResponse.ApplyHeaders(w.Header()); http.NotFound(w, r); return")
+	code = regexp.MustCompile(`Galaxy\.Response\b`).ReplaceAllString(code, "galaxyResponse")
+
+	if g.Route.ActionsFile != "" {
+		code = regexp.MustCompile(`Galaxy\.ActionResult\b`).ReplaceAllString(code, "actionResult")
+	}
+
+	code = regexp.MustCompile(`Galaxy\.Session\b`).ReplaceAllString(code, "galaxySession")
+	code = regexp.MustCompile(`Galaxy\.Cookies\b`).ReplaceAllString(code, "galaxyCookies")
+	code = regexp.MustCompile(`Galaxy\.Cache\b`).ReplaceAllString(code, "galaxyCache")
 
 	code = regexp.MustCompile(`Galaxy\.Locals\.(\w+)`).ReplaceAllString(code, "locals[\"$1\"]")
 
@@ -85,6 +125,15 @@ func (g *HandlerGenerator) transformCode(code string) string {
 	return code
 }
 
+func (g *HandlerGenerator) usesSession() bool {
+	source := g.Component.Frontmatter + g.Component.Template
+	return strings.Contains(source, "Galaxy.Session") || strings.Contains(source, "Galaxy.Cookies")
+}
+
+func (g *HandlerGenerator) usesCache() bool {
+	return strings.Contains(g.Component.Frontmatter+g.Component.Template, "Galaxy.Cache")
+}
+
 func (g *HandlerGenerator) functionName() string {
 	name := strings.ReplaceAll(g.Route.Pattern, "/", "_")
 	name = strings.ReplaceAll(name, "{", "")
@@ -102,16 +151,66 @@ func (g *HandlerGenerator) functionName() string {
 	return "Handle" + toPascalCase(name)
 }
 
-func (g *HandlerGenerator) generateHandlerFunc(funcName, frontmatterCode string, imports []string) string {
+func (g *HandlerGenerator) generateHandlerFunc(funcName, frontmatterCode string, sigs []actions.Signature) string {
 	template := escapeTemplate(g.Component.Template)
 	paramExtraction := g.generateParamExtraction()
 
+	setup := "galaxyResponse := executor.NewResponse()\n\t_ = galaxyResponse\n\t"
+	expose := "ctx.SetCSRFToken(builtin.CSRFToken(r))\n\t"
+	status := "galaxyResponse.Status"
+	finish := ""
+	if g.usesSession() {
+		setup += "galaxySession := session.FromRequest(r)\n\tgalaxyCookies := session.NewCookies(w, r)\n\t_, _ = galaxySession, galaxyCookies\n\t"
+		expose += "ctx.SetSession(galaxySession, galaxyCookies)\n\t"
+	}
+	if g.usesCache() {
+		setup += "galaxyCache := cache.PolicyFrom(r)\n\t_ = galaxyCache\n\t"
+		expose += "ctx.SetCache(galaxyCache)\n\t"
+	}
+	if g.Route.ActionsFile != "" {
+		setup += "actionResult := actions.ResultFrom(r)\n\t_ = actionResult"
+		expose += fmt.Sprintf("actions.Expose(ctx, %#v, actionResult)", actions.Names(sigs))
+		status = "galaxyResponse.StatusOr(actionResult.Status)"
+		finish = "html = actions.InjectClient(html)\n\t"
+	}
+	writeHeader := "galaxyResponse.ApplyHeaders(w.Header())\n\tif status := " + status + "; status != 0 {\n\t\tw.WriteHeader(status)\n\t}"
+
+	render := fmt.Sprintf(`// Use Galaxy template engine for full directive support (galaxy:for, galaxy:if, etc.)
+	_, galaxyRenderDone := telemetry.Render(r.Context(), %q)
+	engine := template.NewEngine(ctx)
+	engine.Page = true
+	html, err := engine.Render(template%s, nil)
+	galaxyRenderDone(err)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Template render error: %%v", err), http.StatusInternalServerError)
+		return
+	}
+	%s%s
+	w.Write([]byte(html))`, g.Route.Pattern, funcName, finish, writeHeader)
+
+	if g.Streaming {
+		transforms := ""
+		if g.Route.ActionsFile != "" {
+			transforms = ", actions.StreamingClient()"
+		}
+		render = fmt.Sprintf(`if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	%s
+	galaxyRenderCtx, galaxyRenderDone := telemetry.Render(r.Context(), %q)
+	galaxyRenderDone(runtime.Stream(w, galaxyRenderCtx, ctx, %q, template%s%s))`, writeHeader, g.Route.Pattern, g.getRoutePath(), funcName, transforms)
+	}
+
 	return fmt.Sprintf(`func %s(w http.ResponseWriter, r *http.Request, params map[string]string, locals map[string]interface{}) {
 	%s
 	_ = locals
+	%s
 	
+	_, galaxySpan := telemetry.Start(r.Context(), "frontmatter", slog.String("http.route", %q))
+	defer galaxySpan.End()
 	%s
 	%s
+	galaxySpan.End()
 	
 	// Create executor context for template engine
 	ctx := executor.NewContext()
@@ -121,21 +220,15 @@ func (g *HandlerGenerator) generateHandlerFunc(funcName, frontmatterCode string,
 	for k, v := range locals {
 		ctx.Set(k, v)
 	}
+	%s
 	
 	%s
 	
-	// Use Galaxy template engine for full directive support (galaxy:for, galaxy:if, etc.)
-	engine := template.NewEngine(ctx)
-	html, err := engine.Render(template%s, nil)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Template render error: %%v", err), http.StatusInternalServerError)
-		return
-	}
-	w.Write([]byte(html))
+	%s
 }
 
 const template%s = %s
-`, funcName, paramExtraction, frontmatterCode, g.generateUseStatements(), g.generateVarAssignments(), funcName, funcName, template)
+`, funcName, paramExtraction, setup, g.Route.Pattern, frontmatterCode, g.generateUseStatements(), expose, g.generateVarAssignments(), render, funcName, template)
 }
 
 func (g *HandlerGenerator) getRoutePath() string {
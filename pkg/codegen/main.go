@@ -3,6 +3,7 @@ package codegen
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/cameron-webmatter/galaxy/pkg/router"
@@ -23,6 +24,15 @@ func (g *MainGenerator) Generate() string {
 	handlerFunctions := g.generateHandlerFunctions()
 	helpers := g.generateHelpers()
 
+	serverConfig := g.ServerConfig
+	if serverConfig == "" {
+		serverConfig = "config.ServerConfig{Port: 4322}"
+	}
+	telemetryConfig := g.TelemetryConfig
+	if telemetryConfig == "" {
+		telemetryConfig = "config.TelemetryConfig{}"
+	}
+
 	regexpImport := ""
 	for _, route := range g.Routes {
 		if hasParams(route.Pattern) {
@@ -39,23 +49,29 @@ import (
 	%s
 	"strings"
 	%s
-	"%s/runtime"
 )
-
+%s
 func main() {
-	log.Println("Starting server...")
+	serverConfig := %s
+	serve.HandleHealthcheck(serverConfig)
+	telemetryConfig := %s
+	stopTelemetry, err := telemetry.Setup(telemetryConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	%s
+	runtime.Load(site)
 	%s
-	
-	http.Handle("/_assets/", http.StripPrefix("/_assets/", http.FileServer(http.Dir("_assets"))))
-	http.Handle("/wasm_exec.js", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "wasm_exec.js")
-	}))
 	
 	%s
 	
-	addr := ":4322"
-	log.Printf("🚀 Server running at http://localhost%%s\n", addr)
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	var handler http.Handler = serve.Site(site, http.DefaultServeMux%s)
+	%s
+	%s
+	handler = telemetry.Handler(telemetryConfig, handler)
+	err = serve.Run(serverConfig, handler, lc)
+	stopTelemetry()
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -63,7 +79,52 @@ func main() {
 %s
 
 %s
-`, regexpImport, imports, g.ModuleName, g.generateMiddlewareSetup(), routeRegistrations, helpers, handlerFunctions)
+`, regexpImport, imports, g.generateEmbed(), serverConfig, telemetryConfig, g.generateSiteSetup(), g.generateMiddlewareSetup(), routeRegistrations, g.dynamicPaths(), g.generateCacheSetup(), g.generateLifecycleSetup(), helpers, handlerFunctions)
+}
+
+// generateEmbed declares the files compiled into the binary.
+func (g *MainGenerator) generateEmbed() string {
+	if g.DiskAssets {
+		return ""
+	}
+	if len(g.Embed) == 0 {
+		return "\nvar files embed.FS\n"
+	}
+	return fmt.Sprintf("\n//go:embed %s\nvar files embed.FS\n", strings.Join(g.Embed, " "))
+}
+
+// generateSiteSetup opens the server's files: embedded ones, or those next
+// to the binary with DiskAssets.
+func (g *MainGenerator) generateSiteSetup() string {
+	if !g.DiskAssets {
+		return "site := files"
+	}
+	return `dir, err := serve.ExecutableDir()
+	if err != nil {
+		log.Fatal(err)
+	}
+	site := os.DirFS(dir)`
+}
+
+// dynamicPaths passes the incremental pages to serve.Site, which leaves
+// them to the cache instead of serving their prerendered files.
+func (g *MainGenerator) dynamicPaths() string {
+	if g.CacheConfig == "" || len(g.Incremental) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %#v...", g.Incremental)
+}
+
+func (g *MainGenerator) generateLifecycleSetup() string {
+	if !g.HasLifecycle {
+		return "var lc *lifecycle.Lifecycle"
+	}
+
+	return `lc := lifecycle.NewLifecycle()
+	lc.Register(Lifecycle())
+	if err := lc.ExecuteStartup(); err != nil {
+		log.Fatalf("Startup failed: %v", err)
+	}`
 }
 
 func (g *MainGenerator) generateHelpers() string {
@@ -90,43 +151,20 @@ func (g *MainGenerator) generateHelpers() string {
 	return params
 }`
 
-	if g.HasMiddleware {
+	if g.usesMiddleware() {
 		helpers += `
 
-type MiddlewareChain struct {
-	middlewares []MiddlewareFunc
-}
-
-type MiddlewareFunc func(w http.ResponseWriter, r *http.Request, locals map[string]interface{}, next func())
+func runChain(chain *middleware.Chain, w http.ResponseWriter, r *http.Request, params map[string]string, handler func(http.ResponseWriter, *http.Request, map[string]string, map[string]interface{})) {
+	ctx := middleware.NewContext(w, r)
+	ctx.Params = params
 
-func NewMiddlewareChain() *MiddlewareChain {
-	return &MiddlewareChain{
-		middlewares: make([]MiddlewareFunc, 0),
+	if err := chain.Execute(ctx, func(ctx *middleware.Context) error {
+		handler(ctx.Response, ctx.Request, ctx.Params, ctx.Locals)
+		return nil
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
-}
-
-func (c *MiddlewareChain) Use(mw MiddlewareFunc) {
-	c.middlewares = append(c.middlewares, mw)
-}
-
-func (c *MiddlewareChain) Execute(w http.ResponseWriter, r *http.Request, handler func(http.ResponseWriter, *http.Request, map[string]interface{})) {
-	locals := make(map[string]interface{})
-	
-	var runMiddleware func(int)
-	runMiddleware = func(index int) {
-		if index >= len(c.middlewares) {
-			handler(w, r, locals)
-			return
-		}
-		
-		c.middlewares[index](w, r, locals, func() {
-			runMiddleware(index + 1)
-		})
-	}
-	
-	runMiddleware(0)
-}
-`
+}`
 	}
 
 	return helpers
@@ -139,12 +177,51 @@ func (g *MainGenerator) collectImports() string {
 		for _, imp := range handler.Imports {
 			importMap[imp] = true
 		}
+		if handler.ActionsImport != "" {
+			importMap[fmt.Sprintf("%s %q", actionsAlias(handler), handler.ActionsImport)] = true
+		}
+	}
+
+	importMap[`"github.com/cameron-webmatter/galaxy/pkg/config"`] = true
+	importMap[`"github.com/cameron-webmatter/galaxy/pkg/lifecycle"`] = true
+	importMap[`"github.com/cameron-webmatter/galaxy/pkg/serve"`] = true
+	importMap[`"github.com/cameron-webmatter/galaxy/pkg/telemetry"`] = true
+	importMap[fmt.Sprintf("%q", g.ModuleName+"/runtime")] = true
+	if g.DiskAssets {
+		importMap[`"os"`] = true
+		if g.CacheConfig != "" {
+			importMap[`"path/filepath"`] = true
+		}
+	} else {
+		importMap[`"embed"`] = true
+		if g.CacheConfig != "" {
+			importMap[`"io/fs"`] = true
+		}
+	}
+	if g.usesMiddleware() {
+		importMap[`"github.com/cameron-webmatter/galaxy/pkg/middleware"`] = true
+	}
+	if g.BuiltinConfig != "" {
+		importMap[`"github.com/cameron-webmatter/galaxy/pkg/middleware/builtin"`] = true
+	}
+	if g.CacheConfig != "" {
+		importMap[`"github.com/cameron-webmatter/galaxy/pkg/cache"`] = true
+	}
+	for _, route := range g.Routes {
+		if g.Guards[route.FilePath] != "" {
+			importMap[`"github.com/cameron-webmatter/galaxy/pkg/auth"`] = true
+		}
+	}
+	for _, pkg := range g.usedDirMiddleware() {
+		importMap[fmt.Sprintf("%s %q", pkg.Alias, pkg.Import)] = true
 	}
 
 	var imports []string
 	for imp := range importMap {
 		imports = append(imports, "\t"+imp)
 	}
+	// Sorted, so the same pages generate the same code.
+	sort.Strings(imports)
 
 	if len(imports) == 0 {
 		return ""
@@ -161,38 +238,22 @@ func (g *MainGenerator) generateRouteRegistrations() string {
 	for i, handler := range g.Handlers {
 		route := g.Routes[i]
 		pattern := route.Pattern
+		call := g.handlerCall(i, handler)
 
 		if hasParams(pattern) {
 			matcher := generateMatcher(pattern)
 			extractor := generateParamExtractor(pattern)
 
-			if g.HasMiddleware {
-				dynamicRoutes = append(dynamicRoutes,
-					fmt.Sprintf("\t\tif %s {\n\t\t\tparams := %s\n\t\t\tchain.Execute(w, r, func(w http.ResponseWriter, r *http.Request, locals map[string]interface{}) {\n\t\t\t\t%s(w, r, params, locals)\n\t\t\t})\n\t\t\treturn\n\t\t}",
-						matcher, extractor, handler.FunctionName))
-			} else {
-				dynamicRoutes = append(dynamicRoutes,
-					fmt.Sprintf("\t\tif %s {\n\t\t\tparams := %s\n\t\t\t%s(w, r, params, make(map[string]interface{}))\n\t\t\treturn\n\t\t}",
-						matcher, extractor, handler.FunctionName))
-			}
+			dynamicRoutes = append(dynamicRoutes,
+				fmt.Sprintf("\t\tif %s {\n\t\t\ttelemetry.SetRoute(r, %q)\n\t\t\tparams := %s\n\t\t\t%s\n\t\t\treturn\n\t\t}",
+					matcher, pattern, extractor, call))
 		} else if pattern == "/" {
-			if g.HasMiddleware {
-				indexHandler = fmt.Sprintf("\t\tif r.URL.Path == \"/\" {\n\t\t\tparams := make(map[string]string)\n\t\t\tchain.Execute(w, r, func(w http.ResponseWriter, r *http.Request, locals map[string]interface{}) {\n\t\t\t\t%s(w, r, params, locals)\n\t\t\t})\n\t\t\treturn\n\t\t}",
-					handler.FunctionName)
-			} else {
-				indexHandler = fmt.Sprintf("\t\tif r.URL.Path == \"/\" {\n\t\t\tparams := make(map[string]string)\n\t\t\t%s(w, r, params, make(map[string]interface{}))\n\t\t\treturn\n\t\t}",
-					handler.FunctionName)
-			}
+			indexHandler = fmt.Sprintf("\t\tif r.URL.Path == \"/\" {\n\t\t\ttelemetry.SetRoute(r, \"/\")\n\t\t\tparams := make(map[string]string)\n\t\t\t%s\n\t\t\treturn\n\t\t}",
+				call)
 		} else {
-			if g.HasMiddleware {
-				staticRoutes = append(staticRoutes,
-					fmt.Sprintf("\thttp.HandleFunc(%q, func(w http.ResponseWriter, r *http.Request) {\n\t\tparams := make(map[string]string)\n\t\tchain.Execute(w, r, func(w http.ResponseWriter, r *http.Request, locals map[string]interface{}) {\n\t\t\t%s(w, r, params, locals)\n\t\t})\n\t})",
-						pattern, handler.FunctionName))
-			} else {
-				staticRoutes = append(staticRoutes,
-					fmt.Sprintf("\thttp.HandleFunc(%q, func(w http.ResponseWriter, r *http.Request) {\n\t\tparams := make(map[string]string)\n\t\t%s(w, r, params, make(map[string]interface{}))\n\t})",
-						pattern, handler.FunctionName))
-			}
+			staticRoutes = append(staticRoutes,
+				fmt.Sprintf("\thttp.HandleFunc(%q, func(w http.ResponseWriter, r *http.Request) {\n\t\ttelemetry.SetRoute(r, %q)\n\t\tparams := make(map[string]string)\n\t\t%s\n\t})",
+					pattern, pattern, call))
 		}
 	}
 
@@ -229,13 +290,129 @@ func generateParamExtractor(pattern string) string {
 	return "extractParams(r.URL.Path, \"" + pattern + "\")"
 }
 
+// handlerCall invokes a route's entry point, through its middleware chain
+// when it has one.
+func (g *MainGenerator) handlerCall(i int, handler *GeneratedHandler) string {
+	if g.routeHasMiddleware(g.Routes[i]) {
+		return fmt.Sprintf("runChain(chain%d, w, r, params, %s)", i, entryPoint(handler))
+	}
+	return fmt.Sprintf("%s(w, r, params, make(map[string]interface{}))", entryPoint(handler))
+}
+
+func (g *MainGenerator) routeHasMiddleware(route *router.Route) bool {
+	return g.BuiltinConfig != "" || g.HasMiddleware || len(route.Middleware) > 0 || g.Guards[route.FilePath] != ""
+}
+
+func (g *MainGenerator) usesMiddleware() bool {
+	for _, route := range g.Routes {
+		if g.routeHasMiddleware(route) {
+			return true
+		}
+	}
+	return false
+}
+
+// usedDirMiddleware returns the directory middleware packages referenced by
+// the generated routes, in a stable order.
+func (g *MainGenerator) usedDirMiddleware() []*MiddlewarePackage {
+	var used []*MiddlewarePackage
+	seen := make(map[string]bool)
+	for _, route := range g.Routes {
+		for _, file := range route.Middleware {
+			pkg, ok := g.DirMiddleware[file]
+			if !ok || seen[file] {
+				continue
+			}
+			seen[file] = true
+			used = append(used, pkg)
+		}
+	}
+	return used
+}
+
+// generateMiddlewareSetup instantiates each middleware file once and builds
+// one chain per route: configured built-ins, src/middleware.go, then the
+// route's directory middleware from the outermost directory inwards and its
+// auth guard.
 func (g *MainGenerator) generateMiddlewareSetup() string {
-	if !g.HasMiddleware {
+	if !g.usesMiddleware() {
 		return ""
 	}
 
-	return `chain := NewMiddlewareChain()
-	`
+	var lines []string
+	if g.BuiltinConfig != "" {
+		lines = append(lines,
+			fmt.Sprintf("builtinMiddleware, err := builtin.FromConfig(%s)", g.BuiltinConfig),
+			"if err != nil {\n\t\tlog.Fatal(err)\n\t}")
+	}
+	if g.HasMiddleware {
+		if g.HasSequence {
+			lines = append(lines, "globalMiddleware := Sequence()")
+		} else {
+			lines = append(lines, "globalMiddleware := []middleware.Middleware{OnRequest}")
+		}
+	}
+	for _, pkg := range g.usedDirMiddleware() {
+		if pkg.Sequence {
+			lines = append(lines, fmt.Sprintf("%sMiddleware := %s.Sequence()", pkg.Alias, pkg.Alias))
+		} else {
+			lines = append(lines, fmt.Sprintf("%sMiddleware := []middleware.Middleware{%s.OnRequest}", pkg.Alias, pkg.Alias))
+		}
+	}
+
+	for i, route := range g.Routes {
+		if !g.routeHasMiddleware(route) {
+			continue
+		}
+
+		chain := "middleware.NewChain()"
+		if g.BuiltinConfig != "" {
+			chain += ".Use(builtinMiddleware...)"
+		}
+		if g.HasMiddleware {
+			chain += ".Use(globalMiddleware...)"
+		}
+		for _, file := range route.Middleware {
+			if pkg, ok := g.DirMiddleware[file]; ok {
+				chain += fmt.Sprintf(".Use(%sMiddleware...)", pkg.Alias)
+			}
+		}
+		if rule := g.Guards[route.FilePath]; rule != "" {
+			chain += fmt.Sprintf(".Use(auth.Guard(%s))", rule)
+		}
+		lines = append(lines, fmt.Sprintf("chain%d := %s", i, chain))
+	}
+
+	return strings.Join(lines, "\n\t") + "\n\t"
+}
+
+// generateCacheSetup puts the response cache in front of every route and
+// mounts its revalidate endpoint, seeded with the incremental pages
+// prerendered into static/.
+func (g *MainGenerator) generateCacheSetup() string {
+	if g.CacheConfig == "" {
+		return ""
+	}
+
+	load := fmt.Sprintf(`staticFS, err := fs.Sub(site, "static")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := pageCache.LoadStaticFS(staticFS, %#v); err != nil {
+		log.Fatal(err)
+	}`, g.Incremental)
+	if g.DiskAssets {
+		load = fmt.Sprintf(`if err := pageCache.LoadStatic(filepath.Join(dir, "static"), %#v); err != nil {
+		log.Fatal(err)
+	}`, g.Incremental)
+	}
+
+	return fmt.Sprintf(`cacheConfig := %s
+	pageCache := cache.FromConfig(cacheConfig)
+	%s
+	http.Handle(cache.RevalidatePath, pageCache.RevalidateHandler(cache.Secret(cacheConfig)))
+	handler = pageCache.Handler(handler)
+	`, g.CacheConfig, load)
 }
 
 func (g *MainGenerator) generateHandlerFunctions() string {
@@ -243,7 +420,44 @@ func (g *MainGenerator) generateHandlerFunctions() string {
 
 	for _, handler := range g.Handlers {
 		functions = append(functions, handler.Code)
+		if handler.ActionsImport != "" {
+			functions = append(functions, generateActionsWrapper(handler))
+		}
 	}
 
 	return strings.Join(functions, "\n\n")
 }
+
+// entryPoint is the function registered for a route: the page handler itself,
+// or a wrapper that runs form actions first.
+func entryPoint(handler *GeneratedHandler) string {
+	if handler.ActionsImport != "" {
+		return handler.FunctionName + "Actions"
+	}
+	return handler.FunctionName
+}
+
+func actionsAlias(handler *GeneratedHandler) string {
+	return "actions" + strings.ToLower(strings.TrimPrefix(handler.FunctionName, "Handle"))
+}
+
+func generateActionsWrapper(handler *GeneratedHandler) string {
+	alias := actionsAlias(handler)
+
+	var entries []string
+	for _, sig := range handler.Actions {
+		entries = append(entries, fmt.Sprintf("\t%q: %s,", sig.Name, sig.Adapter(alias)))
+	}
+
+	return fmt.Sprintf(`var %sSet = actions.Set{
+%s
+}
+
+func %s(w http.ResponseWriter, r *http.Request, params map[string]string, locals map[string]interface{}) {
+	result, handled := actions.Handle(%sSet, w, r, params, locals)
+	if handled {
+		return
+	}
+	%s(w, actions.WithResult(r, result), params, locals)
+}`, alias, strings.Join(entries, "\n"), entryPoint(handler), alias, handler.FunctionName)
+}
@@ -6,7 +6,10 @@ func (g *MainGenerator) GenerateRuntime() string {
 	return fmt.Sprintf(`package runtime
 
 import (
+	"context"
 	"encoding/json"
+	"io"
+	"io/fs"
 	"os"
 	"strings"
 	
@@ -20,7 +23,7 @@ var comp *compiler.ComponentCompiler
 var wasmManifest *wasm.WasmManifest
 
 func init() {
-	comp = compiler.NewComponentCompiler(".")
+	comp = compiler.NewComponentCompiler("components")
 	loadWasmManifest()
 }
 
@@ -33,6 +36,15 @@ func loadWasmManifest() {
 	json.Unmarshal(data, wasmManifest)
 }
 
+// Load reads components and the WASM manifest from the server's files,
+// embedded into the binary or on disk.
+func Load(fsys fs.FS) {
+	comp = compiler.NewComponentCompilerFS(fsys, "components")
+	if manifest, err := wasm.LoadManifestFS(fsys, "_assets/wasm-manifest.json"); err == nil {
+		wasmManifest = manifest
+	}
+}
+
 type RenderContext struct {
 	*executor.Context
 	RoutePath string
@@ -45,9 +57,10 @@ func NewRenderContext() *RenderContext {
 }
 
 func RenderTemplate(ctx *RenderContext, templateHTML string) string {
-	processed := comp.ProcessComponentTags(templateHTML, ctx.Context)
+	processed := comp.NewRequest("", nil).ProcessComponentTags(templateHTML, ctx.Context)
 	
 	engine := template.NewEngine(ctx.Context)
+	engine.Page = true
 	rendered, _ := engine.Render(processed, nil)
 	
 	rendered = injectWasmScripts(rendered, ctx.RoutePath)
@@ -55,6 +68,23 @@ func RenderTemplate(ctx *RenderContext, templateHTML string) string {
 	return rendered
 }
 
+// Stream renders a page and its components to w, flushing as it goes.
+// transforms run on every chunk after the page's scripts are added. Component
+// trace spans are children of the span in reqCtx.
+func Stream(w io.Writer, reqCtx context.Context, ctx *executor.Context, routePath, templateHTML string, transforms ...func(string) string) error {
+	req := comp.NewRequest("", nil)
+	req.Context = reqCtx
+	return req.Stream(w, templateHTML, ctx, compiler.StreamOptions{
+		Transform: func(html string) string {
+			html = injectWasmScripts(html, routePath)
+			for _, transform := range transforms {
+				html = transform(html)
+			}
+			return html
+		},
+	})
+}
+
 func injectWasmScripts(html, routePath string) string {
 	if wasmManifest == nil {
 		return html
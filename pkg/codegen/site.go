@@ -0,0 +1,169 @@
+package codegen
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StageSite copies the public files and prerendered pages under outDir into
+// serverDir/public and serverDir/static, where built servers serve them
+// from.
+func StageSite(outDir, serverDir string) error {
+	publicDir := filepath.Join(outDir, "public")
+	if err := copyTree(publicDir, filepath.Join(serverDir, "public"), nil); err != nil {
+		return err
+	}
+
+	skip := map[string]bool{
+		filepath.Clean(serverDir):        true,
+		publicDir:                        true,
+		filepath.Join(outDir, "_assets"): true,
+		filepath.Join(outDir, "_build"):  true,
+	}
+	return copyTree(outDir, filepath.Join(serverDir, "static"), func(path string) bool {
+		return skip[path]
+	})
+}
+
+// copyTree copies the files under src to dest, leaving out the
+// directories skip reports. A missing src copies nothing.
+func copyTree(src, dest string, skip func(path string) bool) error {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if skip != nil && skip(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
+
+// serverSources are the entries of a server directory that are compiled
+// rather than read at runtime.
+var serverSources = map[string]bool{
+	"go.mod":     true,
+	"go.sum":     true,
+	"server":     true,
+	"runtime":    true,
+	"middleware": true,
+	"actions":    true,
+}
+
+// SiteEntries returns the names of the entries in serverDir that a built
+// server reads at runtime: pages, components, assets, public files and
+// prerendered pages.
+func SiteEntries(serverDir string) ([]string, error) {
+	entries, err := os.ReadDir(serverDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if serverSources[name] || strings.HasSuffix(name, ".go") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// EmbedPatterns returns go:embed patterns for the entries SiteEntries
+// lists.
+func EmbedPatterns(serverDir string) ([]string, error) {
+	names, err := SiteEntries(serverDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, name := range names {
+		path := filepath.Join(serverDir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			patterns = append(patterns, name)
+			continue
+		}
+		// go:embed rejects directories without files.
+		if hasFiles(path) {
+			patterns = append(patterns, "all:"+name)
+		}
+	}
+	return patterns, nil
+}
+
+func hasFiles(dir string) bool {
+	found := false
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			found = true
+			return filepath.SkipAll
+		}
+		return err
+	})
+	return found
+}
+
+// StageStatic copies the files a CDN serves for a built site into dest:
+// public files and prerendered pages at its root, plus the bundled assets
+// and wasm_exec.js staged in serverDir.
+func StageStatic(outDir, serverDir, dest string) error {
+	outDir, serverDir, dest = filepath.Clean(outDir), filepath.Clean(serverDir), filepath.Clean(dest)
+
+	publicDir := filepath.Join(outDir, "public")
+	if err := copyTree(publicDir, dest, nil); err != nil {
+		return err
+	}
+
+	skip := map[string]bool{
+		serverDir:                       true,
+		publicDir:                       true,
+		dest:                            true,
+		filepath.Join(outDir, "_build"): true,
+	}
+	// The adapter's own output, which holds dest, is left out too.
+	inDest := func(path string) bool {
+		return path != outDir && strings.HasPrefix(dest, path+string(filepath.Separator))
+	}
+	if err := copyTree(outDir, dest, func(path string) bool { return skip[path] || inDest(path) }); err != nil {
+		return err
+	}
+
+	if err := copyTree(filepath.Join(serverDir, "_assets"), filepath.Join(dest, "_assets"), nil); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(serverDir, "wasm_exec.js"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dest, "wasm_exec.js"), data, 0644)
+}
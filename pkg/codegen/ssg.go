@@ -1,12 +1,17 @@
 package codegen
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/cameron-webmatter/galaxy/pkg/buildcache"
 	"github.com/cameron-webmatter/galaxy/pkg/parser"
 	"github.com/cameron-webmatter/galaxy/pkg/router"
 )
@@ -16,6 +21,18 @@ type SSGCodegenBuilder struct {
 	PagesDir   string
 	OutDir     string
 	ModuleName string
+	// BuildCache, when set, restores pages whose dependencies, found by
+	// Graph, are unchanged since they were cached, and reuses the generator.
+	BuildCache *buildcache.Cache
+	Graph      *buildcache.Graph
+	// Concurrency is how many pages render at once; zero uses every CPU.
+	Concurrency int
+
+	// RenderTimes holds, after Build, how long each page took to render.
+	// Pages restored from the cache, listed in Restored, keep the time of
+	// the render they were cached from.
+	RenderTimes map[string]time.Duration
+	Restored    map[string]bool
 }
 
 func NewSSGCodegenBuilder(routes []*router.Route, pagesDir, outDir, moduleName string) *SSGCodegenBuilder {
@@ -28,6 +45,9 @@ func NewSSGCodegenBuilder(routes []*router.Route, pagesDir, outDir, moduleName s
 }
 
 func (b *SSGCodegenBuilder) Build() error {
+	b.RenderTimes = make(map[string]time.Duration)
+	b.Restored = make(map[string]bool)
+
 	buildDir := filepath.Join(b.OutDir, "_build")
 	if err := os.MkdirAll(buildDir, 0755); err != nil {
 		return err
@@ -83,28 +103,128 @@ func (b *SSGCodegenBuilder) Build() error {
 		return err
 	}
 
+	var pages []cachedPage
+	if b.BuildCache != nil {
+		var err error
+		if pages, err = b.restorePages(buildDir, handlers, nonEndpointRoutes, runtime); err != nil {
+			return err
+		}
+		if len(pages) == 0 {
+			return nil
+		}
+	}
+
 	if err := b.compile(buildDir); err != nil {
 		return err
 	}
 
-	if err := b.execute(buildDir); err != nil {
+	if err := b.execute(buildDir, pages); err != nil {
 		return err
 	}
 
+	return b.storePages(pages)
+}
+
+// cachedPage is a page the generator renders, with the key its output is
+// cached under; volatile pages have none.
+type cachedPage struct {
+	pattern string
+	outPath string
+	key     string
+}
+
+// restorePages writes the cached output of every page whose key is cached
+// and returns the pages left to render.
+func (b *SSGCodegenBuilder) restorePages(buildDir string, handlers []*GeneratedHandler, routes []*router.Route, runtime string) ([]cachedPage, error) {
+	framework, err := b.BuildCache.Framework(buildDir)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint galaxy: %w", err)
+	}
+
+	var pending []cachedPage
+	restored := 0
+	for i, route := range routes {
+		page := cachedPage{pattern: route.Pattern, outPath: b.OutputPath(route.Pattern)}
+
+		deps, volatile, err := b.Graph.Deps(route.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("dependencies of %s: %w", route.FilePath, err)
+		}
+		if volatile {
+			pending = append(pending, page)
+			continue
+		}
+		sum, err := b.BuildCache.HashFiles(deps)
+		if err != nil {
+			return nil, fmt.Errorf("hash %s: %w", route.FilePath, err)
+		}
+		page.key = buildcache.Key("page", route.Pattern, handlers[i].Code, runtime, framework, sum)
+
+		if _, ok := b.BuildCache.Get("notfound", page.key); ok {
+			b.Restored[route.Pattern] = true
+			restored++
+			continue
+		}
+		html, ok := b.BuildCache.Get("pages", page.key)
+		if !ok {
+			pending = append(pending, page)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(page.outPath), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(page.outPath, html, 0644); err != nil {
+			return nil, err
+		}
+		if data, ok := b.BuildCache.Get("rendertime", page.key); ok {
+			if ns, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+				b.RenderTimes[route.Pattern] = time.Duration(ns)
+			}
+		}
+		b.Restored[route.Pattern] = true
+		restored++
+	}
+
+	if restored > 0 {
+		fmt.Printf("  ↺ %d of %d pages unchanged, restored from cache\n", restored, len(routes))
+	}
+	return pending, nil
+}
+
+// storePages caches the output the generator wrote for pages, or that it
+// skipped them as not found.
+func (b *SSGCodegenBuilder) storePages(pages []cachedPage) error {
+	for _, page := range pages {
+		if page.key == "" {
+			continue
+		}
+		html, err := os.ReadFile(page.outPath)
+		if os.IsNotExist(err) {
+			err = b.BuildCache.Put("notfound", page.key, nil)
+		} else if err == nil {
+			err = b.BuildCache.Put("pages", page.key, html)
+		}
+		if d, ok := b.RenderTimes[page.pattern]; ok && err == nil {
+			err = b.BuildCache.Put("rendertime", page.key, []byte(strconv.FormatInt(int64(d), 10)))
+		}
+		if err != nil {
+			return fmt.Errorf("cache %s: %w", page.pattern, err)
+		}
+	}
 	return nil
 }
 
 func (b *SSGCodegenBuilder) generateMain(handlers []*GeneratedHandler, routes []*router.Route, manifestPath string) string {
 	var handlerFuncs []string
-	var renderCalls []string
+	var pageEntries []string
 
 	for i, handler := range handlers {
 		route := routes[i]
 		handlerFuncs = append(handlerFuncs, handler.Code)
 
-		outPath := b.getOutputPath(route.Pattern)
-		renderCalls = append(renderCalls,
-			fmt.Sprintf("\trenderPage(%q, %q, %s)",
+		outPath := b.OutputPath(route.Pattern)
+		pageEntries = append(pageEntries,
+			fmt.Sprintf("\t{%q, %q, %s},",
 				route.Pattern, outPath, handler.FunctionName))
 	}
 
@@ -113,60 +233,180 @@ func (b *SSGCodegenBuilder) generateMain(handlers []*GeneratedHandler, routes []
 	return fmt.Sprintf(`package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"sync"
+	"time"
 	%s
-	"%s/runtime"
+	_ "%s/runtime"
 )
 
+type page struct {
+	pattern string
+	outPath string
+	handler func(http.ResponseWriter, *http.Request, map[string]string, map[string]interface{})
+}
+
+var pages = []page{
+%s
+}
+
 func main() {
+	pagesFile := flag.String("pages", "", "render only the patterns listed in this file")
+	concurrency := flag.Int("concurrency", goruntime.NumCPU(), "pages rendered at once")
+	timingsFile := flag.String("timings", "", "write each page's render time, in nanoseconds, to this file as JSON")
+	flag.Parse()
+
+	var only map[string]bool
+	if *pagesFile != "" {
+		data, err := os.ReadFile(*pagesFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		only = make(map[string]bool)
+		for _, pattern := range strings.Split(string(data), "\n") {
+			only[pattern] = true
+		}
+	}
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+
 	fmt.Println("Pre-rendering pages...")
-	
-%s
-	
+
+	// Pages render on a pool of workers; results are printed in route
+	// order once all are done, so the output doesn't depend on timing.
+	lines := make([]string, len(pages))
+	errs := make([]error, len(pages))
+	durations := make([]time.Duration, len(pages))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < *concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				start := time.Now()
+				lines[i], errs[i] = renderPage(pages[i])
+				durations[i] = time.Since(start)
+			}
+		}()
+	}
+	for i, p := range pages {
+		if only == nil || only[p.pattern] {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if *timingsFile != "" {
+		timings := make(map[string]int64)
+		for i, p := range pages {
+			if durations[i] > 0 {
+				timings[p.pattern] = int64(durations[i])
+			}
+		}
+		data, _ := json.Marshal(timings)
+		if err := os.WriteFile(*timingsFile, data, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	failed := 0
+	for i, line := range lines {
+		if errs[i] != nil {
+			failed++
+			fmt.Printf("  ✗ %%s: %%v\n", pages[i].pattern, errs[i])
+		} else if line != "" {
+			fmt.Println(line)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("✗ %%d of %%d pages failed\n", failed, len(pages))
+		os.Exit(1)
+	}
 	fmt.Println("✓ Done")
 }
 
-func renderPage(pattern, outPath string, handler func(http.ResponseWriter, *http.Request, map[string]string)) {
-	w := &responseWriter{body: make([]byte, 0)}
-	r := &http.Request{}
+// renderPage renders p to its output file and returns the line to report.
+// A panic or a server error fails the page rather than the build.
+func renderPage(p page) (line string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %%v", r)
+		}
+	}()
+
+	w := &responseWriter{header: make(http.Header)}
+	r, err := http.NewRequest("GET", p.pattern, nil)
+	if err != nil {
+		return "", err
+	}
 	params := make(map[string]string)
-	
-	handler(w, r, params)
-	
-	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
-		panic(err)
+
+	p.handler(w, r, params, make(map[string]interface{}))
+
+	if w.status == http.StatusNotFound {
+		return fmt.Sprintf("  ⊘ %%s (404, skipped)", p.pattern), nil
 	}
-	
-	if err := os.WriteFile(outPath, w.body, 0644); err != nil {
-		panic(err)
+	if w.status >= 500 {
+		return "", fmt.Errorf("status %%d: %%s", w.status, strings.TrimSpace(string(w.body)))
 	}
-	
-	fmt.Printf("  ✓ %%s -> %%s\n", pattern, outPath)
+
+	if err := os.MkdirAll(filepath.Dir(p.outPath), 0755); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(p.outPath, w.body, 0644); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("  ✓ %%s -> %%s", p.pattern, p.outPath), nil
 }
 
 type responseWriter struct {
-	body []byte
+	header http.Header
+	status int
+	body   []byte
 }
 
-func (w *responseWriter) Header() http.Header { return http.Header{} }
+func (w *responseWriter) Header() http.Header { return w.header }
 func (w *responseWriter) Write(b []byte) (int, error) {
 	w.body = append(w.body, b...)
 	return len(b), nil
 }
-func (w *responseWriter) WriteHeader(statusCode int) {}
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
 
 %s
-`, imports, b.ModuleName, strings.Join(renderCalls, "\n"), strings.Join(handlerFuncs, "\n\n"))
+`, imports, b.ModuleName, strings.Join(pageEntries, "\n"), strings.Join(handlerFuncs, "\n\n"))
 }
 
 func (b *SSGCodegenBuilder) collectImports(handlers []*GeneratedHandler) string {
+	// Imported by the generated main itself.
+	fixed := map[string]bool{
+		`"encoding/json"`: true, `"flag"`: true, `"fmt"`: true, `"net/http"`: true, `"os"`: true,
+		`"path/filepath"`: true, `"strings"`: true, `"sync"`: true, `"time"`: true,
+	}
+
 	importMap := make(map[string]bool)
 	for _, handler := range handlers {
 		for _, imp := range handler.Imports {
-			importMap[imp] = true
+			if !fixed[imp] {
+				importMap[imp] = true
+			}
 		}
 	}
 
@@ -174,6 +414,8 @@ func (b *SSGCodegenBuilder) collectImports(handlers []*GeneratedHandler) string
 	for imp := range importMap {
 		imports = append(imports, "\t"+imp)
 	}
+	// Sorted, so the same pages generate the same code.
+	sort.Strings(imports)
 
 	if len(imports) == 0 {
 		return ""
@@ -182,7 +424,8 @@ func (b *SSGCodegenBuilder) collectImports(handlers []*GeneratedHandler) string
 	return strings.Join(imports, "\n")
 }
 
-func (b *SSGCodegenBuilder) getOutputPath(pattern string) string {
+// OutputPath returns the file the page at pattern is written to.
+func (b *SSGCodegenBuilder) OutputPath(pattern string) string {
 	if pattern == "/" {
 		return filepath.Join(b.OutDir, "index.html")
 	}
@@ -204,7 +447,7 @@ func (b *SSGCodegenBuilder) generateGoMod(buildDir string) error {
 
 	goMod := fmt.Sprintf(`module %s
 
-go 1.23
+go 1.24
 
 replace github.com/cameron-webmatter/galaxy => %s
 
@@ -215,6 +458,14 @@ require github.com/cameron-webmatter/galaxy v0.0.0
 }
 
 func (b *SSGCodegenBuilder) compile(buildDir string) error {
+	if b.BuildCache != nil {
+		_, err := b.BuildCache.GoBuild(buildDir, "generator", func() error { return b.goBuild(buildDir) })
+		return err
+	}
+	return b.goBuild(buildDir)
+}
+
+func (b *SSGCodegenBuilder) goBuild(buildDir string) error {
 	tidyCmd := exec.Command("go", "mod", "tidy")
 	tidyCmd.Dir = buildDir
 	if output, err := tidyCmd.CombinedOutput(); err != nil {
@@ -230,13 +481,41 @@ func (b *SSGCodegenBuilder) compile(buildDir string) error {
 	return nil
 }
 
-func (b *SSGCodegenBuilder) execute(buildDir string) error {
+// execute runs the generator, limited to pages when the cache is in use,
+// and records how long each page took.
+func (b *SSGCodegenBuilder) execute(buildDir string, pages []cachedPage) error {
 	cmd := exec.Command("./generator")
+	if b.BuildCache != nil {
+		patterns := make([]string, len(pages))
+		for i, page := range pages {
+			patterns[i] = page.pattern
+		}
+		if err := os.WriteFile(filepath.Join(buildDir, "pages.txt"), []byte(strings.Join(patterns, "\n")), 0644); err != nil {
+			return err
+		}
+		cmd.Args = append(cmd.Args, "-pages", "pages.txt")
+	}
+	if b.Concurrency > 0 {
+		cmd.Args = append(cmd.Args, "-concurrency", strconv.Itoa(b.Concurrency))
+	}
+	cmd.Args = append(cmd.Args, "-timings", "timings.json")
 	cmd.Dir = buildDir
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("execute: %w\n%s", err, output)
 	}
 	fmt.Print(string(output))
+
+	data, err := os.ReadFile(filepath.Join(buildDir, "timings.json"))
+	if err != nil {
+		return fmt.Errorf("read render times: %w", err)
+	}
+	var timings map[string]int64
+	if err := json.Unmarshal(data, &timings); err != nil {
+		return fmt.Errorf("read render times: %w", err)
+	}
+	for pattern, ns := range timings {
+		b.RenderTimes[pattern] = time.Duration(ns)
+	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package codegen
 
 import (
+	"github.com/cameron-webmatter/galaxy/pkg/actions"
 	"github.com/cameron-webmatter/galaxy/pkg/parser"
 	"github.com/cameron-webmatter/galaxy/pkg/router"
 )
@@ -10,6 +11,9 @@ type HandlerGenerator struct {
 	Route      *router.Route
 	ModuleName string
 	BaseDir    string
+	// Streaming renders the page and its components through the generated
+	// runtime package, flushing as it goes.
+	Streaming bool
 }
 
 type GeneratedHandler struct {
@@ -17,6 +21,10 @@ type GeneratedHandler struct {
 	Imports      []string
 	FunctionName string
 	Code         string
+	Actions      []actions.Signature
+	// ActionsImport is the import path of the copied .actions.go package;
+	// set by the builder when the route has form actions.
+	ActionsImport string
 }
 
 type MainGenerator struct {
@@ -25,4 +33,39 @@ type MainGenerator struct {
 	ModuleName    string
 	ManifestPath  string
 	HasMiddleware bool
+	// HasSequence reports whether src/middleware.go declares Sequence.
+	HasSequence bool
+	// DirMiddleware maps a _middleware.go path to its copied package.
+	DirMiddleware map[string]*MiddlewarePackage
+	// BuiltinConfig is a config.MiddlewareConfig literal for the built-in
+	// middleware, empty when none are enabled.
+	BuiltinConfig string
+	// Guards maps a page path to the auth.Rule literal declared by its
+	// //galaxy:auth directive.
+	Guards map[string]string
+	// CacheConfig is a config.CacheConfig literal for the response cache,
+	// empty when caching is disabled.
+	CacheConfig string
+	// Incremental lists the paths prerendered into the output directory
+	// that the cache serves and regenerates.
+	Incremental []string
+	// ServerConfig is a config.ServerConfig literal; empty serves on :4322.
+	ServerConfig string
+	// TelemetryConfig is a config.TelemetryConfig literal; empty disables
+	// request logs, metrics and tracing.
+	TelemetryConfig string
+	// HasLifecycle reports whether src/lifecycle.go was copied into main.
+	HasLifecycle bool
+	// Embed lists the go:embed patterns of the files compiled into the
+	// server.
+	Embed []string
+	// DiskAssets reads the files next to the binary instead of embedding
+	// them.
+	DiskAssets bool
+}
+
+type MiddlewarePackage struct {
+	Alias    string
+	Import   string
+	Sequence bool
 }
@@ -1,15 +1,15 @@
 package compiler
 
 import (
-	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"regexp"
-	"strings"
+	"sync"
 
 	"github.com/cameron-webmatter/galaxy/internal/assets"
 	"github.com/cameron-webmatter/galaxy/pkg/executor"
 	"github.com/cameron-webmatter/galaxy/pkg/parser"
-	tmpl "github.com/cameron-webmatter/galaxy/pkg/template"
 )
 
 type ComponentCompiler struct {
@@ -18,6 +18,10 @@ type ComponentCompiler struct {
 	Bundler         *assets.Bundler
 	Resolver        *ComponentResolver
 	CollectedStyles []parser.Style
+	// FS, when set, holds the components instead of the disk.
+	FS fs.FS
+
+	mu sync.RWMutex
 }
 
 func NewComponentCompiler(baseDir string) *ComponentCompiler {
@@ -29,138 +33,89 @@ func NewComponentCompiler(baseDir string) *ComponentCompiler {
 	}
 }
 
+// NewComponentCompilerFS compiles components read from fsys, such as the
+// files embedded into a built server.
+func NewComponentCompilerFS(fsys fs.FS, baseDir string) *ComponentCompiler {
+	return &ComponentCompiler{
+		BaseDir:  baseDir,
+		Cache:    make(map[string]*parser.Component),
+		Bundler:  assets.NewBundler(".galaxy"),
+		Resolver: NewComponentResolverFS(fsys, baseDir, nil),
+		FS:       fsys,
+	}
+}
+
 func (c *ComponentCompiler) SetResolver(resolver *ComponentResolver) {
 	c.Resolver = resolver
 }
 
 func (c *ComponentCompiler) ClearCache() {
+	c.mu.Lock()
 	c.Cache = make(map[string]*parser.Component)
+	c.mu.Unlock()
 	c.CollectedStyles = nil
 }
 
+// Compile renders the component at filePath, resolving nested components
+// against the resolver's current file and collecting styles into
+// CollectedStyles. Concurrent renders should use NewRequest instead.
 func (c *ComponentCompiler) Compile(filePath string, props map[string]interface{}, slots map[string]string) (string, error) {
-	comp, err := c.loadComponent(filePath)
-	if err != nil {
-		return "", err
-	}
-
-	copiedStyles := make([]parser.Style, len(comp.Styles))
-	copy(copiedStyles, comp.Styles)
-	c.CollectedStyles = append(c.CollectedStyles, copiedStyles...)
-
-	ctx := executor.NewContext()
-	for k, v := range props {
-		ctx.SetProp(k, v)
-		ctx.Set(k, v)
-	}
-
-	if comp.Frontmatter != "" {
-		if err := ctx.Execute(comp.Frontmatter); err != nil {
-			return "", err
-		}
-	}
-
-	processedTemplate := c.ProcessComponentTags(comp.Template, ctx)
+	req := c.sharedRequest()
+	rendered, err := req.Compile(filePath, props, slots)
+	c.CollectedStyles = append(c.CollectedStyles, req.Styles...)
+	return rendered, err
+}
 
-	engine := tmpl.NewEngine(ctx)
-	rendered, err := engine.Render(processedTemplate, &tmpl.RenderOptions{
-		Props: props,
-		Slots: slots,
-	})
-	if err != nil {
-		return "", err
-	}
+// ProcessComponentTags renders the component tags in template like Compile.
+func (c *ComponentCompiler) ProcessComponentTags(template string, ctx *executor.Context) string {
+	req := c.sharedRequest()
+	result := req.ProcessComponentTags(template, ctx)
+	c.CollectedStyles = append(c.CollectedStyles, req.Styles...)
+	return result
+}
 
-	return rendered, nil
+// Load returns the parsed component at filePath, parsing it once. The
+// result is shared between requests and must not be modified.
+func (c *ComponentCompiler) Load(filePath string) (*parser.Component, error) {
+	return c.loadComponent(filePath)
 }
 
 func (c *ComponentCompiler) loadComponent(filePath string) (*parser.Component, error) {
-	if comp, ok := c.Cache[filePath]; ok {
+	c.mu.RLock()
+	comp, ok := c.Cache[filePath]
+	c.mu.RUnlock()
+	if ok {
 		return comp, nil
 	}
 
-	content, err := os.ReadFile(filePath)
+	content, err := c.readFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	comp, err := parser.Parse(string(content))
+	comp, err = parser.Parse(string(content))
 	if err != nil {
 		return nil, err
 	}
 
+	c.mu.Lock()
 	c.Cache[filePath] = comp
+	c.mu.Unlock()
 	return comp, nil
 }
 
+func (c *ComponentCompiler) readFile(filePath string) ([]byte, error) {
+	if c.FS != nil {
+		return fs.ReadFile(c.FS, filepath.ToSlash(filePath))
+	}
+	return os.ReadFile(filePath)
+}
+
 var (
-	componentOpenCloseRegex = regexp.MustCompile(`(?s)<([A-Z]\w+)([^>]*)>(.*?)</([A-Z]\w+)>`)
+	componentOpenCloseRegex = regexp.MustCompile(`(?s)<([A-Z]\w+)((?:[^>]*[^/>])?)>(.*?)</([A-Z]\w+)>`)
 	componentSelfCloseRegex = regexp.MustCompile(`<([A-Z]\w+)([^/>]*)/?>`)
 )
 
-func (c *ComponentCompiler) ProcessComponentTags(template string, ctx *executor.Context) string {
-	result := componentOpenCloseRegex.ReplaceAllStringFunc(template, func(match string) string {
-		matches := componentOpenCloseRegex.FindStringSubmatch(match)
-
-		componentName := matches[1]
-		attrs := matches[2]
-		content := matches[3]
-		closingTag := matches[4]
-
-		if componentName != closingTag {
-			return match
-		}
-
-		componentPath, err := c.Resolver.Resolve(componentName)
-		if err != nil {
-			return fmt.Sprintf("<!-- Component resolution error: %v -->", err)
-		}
-
-		props := c.parseAttributes(attrs, ctx)
-
-		slots := make(map[string]string)
-		trimmedContent := strings.TrimSpace(content)
-		if trimmedContent != "" {
-			slotEngine := tmpl.NewEngine(ctx)
-			renderedSlot, err := slotEngine.Render(trimmedContent, nil)
-			if err != nil {
-				return fmt.Sprintf("<!-- Error rendering slot: %v -->", err)
-			}
-			slots["default"] = renderedSlot
-		}
-
-		rendered, err := c.Compile(componentPath, props, slots)
-		if err != nil {
-			return fmt.Sprintf("<!-- Error rendering %s: %v -->", componentName, err)
-		}
-
-		return rendered
-	})
-
-	result = componentSelfCloseRegex.ReplaceAllStringFunc(result, func(match string) string {
-		matches := componentSelfCloseRegex.FindStringSubmatch(match)
-
-		componentName := matches[1]
-		attrs := matches[2]
-
-		componentPath, err := c.Resolver.Resolve(componentName)
-		if err != nil {
-			return fmt.Sprintf("<!-- Component resolution error: %v -->", err)
-		}
-
-		props := c.parseAttributes(attrs, ctx)
-
-		rendered, err := c.Compile(componentPath, props, make(map[string]string))
-		if err != nil {
-			return fmt.Sprintf("<!-- Error rendering %s: %v -->", componentName, err)
-		}
-
-		return rendered
-	})
-
-	return result
-}
-
 func (c *ComponentCompiler) parseAttributes(attrs string, ctx *executor.Context) map[string]interface{} {
 	props := make(map[string]interface{})
 
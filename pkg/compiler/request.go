@@ -0,0 +1,166 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/executor"
+	"github.com/cameron-webmatter/galaxy/pkg/parser"
+	"github.com/cameron-webmatter/galaxy/pkg/telemetry"
+	tmpl "github.com/cameron-webmatter/galaxy/pkg/template"
+)
+
+// Request is the state of a single page render. Parsed components and the
+// component index are shared through the compiler; the page being rendered,
+// its component imports and the styles its components collect live here, so
+// one compiler can serve concurrent requests.
+type Request struct {
+	compiler *ComponentCompiler
+	resolve  func(name string) (string, error)
+
+	// Styles holds the styles of every component rendered so far.
+	Styles []parser.Style
+
+	// Context parents the trace spans of rendered components. It may be nil.
+	Context context.Context
+}
+
+// NewRequest starts a render of the page at file, resolving the component
+// imports of its frontmatter relative to it.
+func (c *ComponentCompiler) NewRequest(file string, imports []Import) *Request {
+	explicit := make(map[string]string)
+	for _, imp := range imports {
+		if imp.IsComponent {
+			explicit[imp.Alias] = imp.Path
+		}
+	}
+
+	return &Request{
+		compiler: c,
+		resolve: func(name string) (string, error) {
+			return c.Resolver.ResolveFrom(name, file, explicit)
+		},
+	}
+}
+
+// sharedRequest renders through the resolver's current file and imports,
+// for callers that render one page at a time.
+func (c *ComponentCompiler) sharedRequest() *Request {
+	return &Request{compiler: c, resolve: c.Resolver.Resolve}
+}
+
+// Compile renders the component at filePath with props and slots.
+func (r *Request) Compile(filePath string, props map[string]interface{}, slots map[string]string) (rendered string, err error) {
+	spanCtx, span := telemetry.Start(r.Context, "component", slog.String("component", filepath.Base(filePath)))
+	if span != nil {
+		parent := r.Context
+		r.Context = spanCtx
+		defer func() {
+			r.Context = parent
+			span.RecordError(err)
+			span.End()
+		}()
+	}
+
+	comp, err := r.compiler.loadComponent(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	copiedStyles := make([]parser.Style, len(comp.Styles))
+	copy(copiedStyles, comp.Styles)
+	r.Styles = append(r.Styles, copiedStyles...)
+
+	ctx := executor.NewContext()
+	for k, v := range props {
+		ctx.SetProp(k, v)
+		ctx.Set(k, v)
+	}
+
+	if comp.Frontmatter != "" {
+		if err := ctx.Execute(comp.Frontmatter); err != nil {
+			return "", err
+		}
+	}
+
+	processedTemplate := r.ProcessComponentTags(comp.Template, ctx)
+
+	engine := tmpl.NewEngine(ctx)
+	rendered, err = engine.Render(processedTemplate, &tmpl.RenderOptions{
+		Props: props,
+		Slots: slots,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return rendered, nil
+}
+
+// ProcessComponentTags replaces the component tags in template with the
+// rendered components.
+func (r *Request) ProcessComponentTags(template string, ctx *executor.Context) string {
+	result := componentOpenCloseRegex.ReplaceAllStringFunc(template, func(match string) string {
+		matches := componentOpenCloseRegex.FindStringSubmatch(match)
+
+		componentName := matches[1]
+		attrs := matches[2]
+		content := matches[3]
+		closingTag := matches[4]
+
+		if componentName != closingTag {
+			return match
+		}
+
+		componentPath, err := r.resolve(componentName)
+		if err != nil {
+			return fmt.Sprintf("<!-- Component resolution error: %v -->", err)
+		}
+
+		props := r.compiler.parseAttributes(attrs, ctx)
+
+		slots := make(map[string]string)
+		trimmedContent := strings.TrimSpace(content)
+		if trimmedContent != "" {
+			slotEngine := tmpl.NewEngine(ctx)
+			renderedSlot, err := slotEngine.Render(trimmedContent, nil)
+			if err != nil {
+				return fmt.Sprintf("<!-- Error rendering slot: %v -->", err)
+			}
+			slots["default"] = renderedSlot
+		}
+
+		rendered, err := r.Compile(componentPath, props, slots)
+		if err != nil {
+			return fmt.Sprintf("<!-- Error rendering %s: %v -->", componentName, err)
+		}
+
+		return rendered
+	})
+
+	result = componentSelfCloseRegex.ReplaceAllStringFunc(result, func(match string) string {
+		matches := componentSelfCloseRegex.FindStringSubmatch(match)
+
+		componentName := matches[1]
+		attrs := matches[2]
+
+		componentPath, err := r.resolve(componentName)
+		if err != nil {
+			return fmt.Sprintf("<!-- Component resolution error: %v -->", err)
+		}
+
+		props := r.compiler.parseAttributes(attrs, ctx)
+
+		rendered, err := r.Compile(componentPath, props, make(map[string]string))
+		if err != nil {
+			return fmt.Sprintf("<!-- Error rendering %s: %v -->", componentName, err)
+		}
+
+		return rendered
+	})
+
+	return result
+}
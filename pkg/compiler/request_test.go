@@ -0,0 +1,146 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/executor"
+	tmpl "github.com/cameron-webmatter/galaxy/pkg/template"
+)
+
+// newRequestProject lays out two pages that import different components
+// under the same name, plus a shared component that renders per-request
+// props.
+func newRequestProject(t *testing.T) (*ComponentCompiler, []string) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"components/Badge.gxc": "---\nshown := label\n---\n<span class=\"badge\">{shown}</span>\n<style>.badge { color: blue }</style>",
+		"blog/Card.gxc":        "<article>blog <Badge label={label} /></article>\n<style>article.blog { margin: 1px }</style>",
+		"shop/Card.gxc":        "<section>shop <Badge label={label} /></section>\n<style>section.shop { margin: 2px }</style>",
+		"blog/page.gxc":        "",
+		"shop/page.gxc":        "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	return NewComponentCompiler(dir), []string{filepath.Join(dir, "blog", "page.gxc"), filepath.Join(dir, "shop", "page.gxc")}
+}
+
+func TestRequestResolvesPerPage(t *testing.T) {
+	c, pages := newRequestProject(t)
+	imports := []Import{{Path: "./Card.gxc", Alias: "Card", IsComponent: true}}
+
+	ctx := executor.NewContext()
+	ctx.Set("label", "x")
+
+	blog := c.NewRequest(pages[0], imports)
+	shop := c.NewRequest(pages[1], imports)
+
+	if got := blog.ProcessComponentTags(`<Card label={label} />`, ctx); !strings.Contains(got, "<article>blog") {
+		t.Errorf("Expected blog card, got %q", got)
+	}
+	if got := shop.ProcessComponentTags(`<Card label={label} />`, ctx); !strings.Contains(got, "<section>shop") {
+		t.Errorf("Expected shop card, got %q", got)
+	}
+	if len(blog.Styles) != 2 || len(shop.Styles) != 2 {
+		t.Errorf("Expected 2 styles per request, got %d and %d", len(blog.Styles), len(shop.Styles))
+	}
+	if len(c.CollectedStyles) != 0 {
+		t.Errorf("Expected requests to leave CollectedStyles alone, got %d", len(c.CollectedStyles))
+	}
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	c, pages := newRequestProject(t)
+	imports := []Import{{Path: "./Card.gxc", Alias: "Card", IsComponent: true}}
+	page := `<html><head></head><body><h1>{label}</h1><Card label={label} /></body></html>`
+
+	workers, renders := 16, 50
+	if testing.Short() {
+		renders = 5
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < renders; i++ {
+				file, kind, other := pages[i%2], "blog", "shop"
+				if i%2 == 1 {
+					kind, other = "shop", "blog"
+				}
+				label := fmt.Sprintf("w%d-%d", w, i)
+
+				ctx := executor.NewContext()
+				ctx.Set("label", label)
+				req := c.NewRequest(file, imports)
+
+				var html string
+				if i%3 == 0 {
+					var out bytes.Buffer
+					if err := req.Stream(&out, page, ctx, StreamOptions{}); err != nil {
+						t.Error(err)
+						return
+					}
+					html = out.String()
+				} else {
+					html, _ = tmpl.NewEngine(ctx).Render(req.ProcessComponentTags(page, ctx), nil)
+				}
+
+				if !strings.Contains(html, kind) || strings.Contains(html, other) {
+					t.Errorf("%s render got %q", kind, html)
+				}
+				if strings.Count(html, label) != 2 {
+					t.Errorf("Expected label %s twice, got %q", label, html)
+				}
+				for _, style := range req.Styles {
+					if strings.Contains(style.Content, other) {
+						t.Errorf("%s render collected %s styles", kind, other)
+					}
+				}
+				if len(req.Styles) != 2 {
+					t.Errorf("Expected 2 styles, got %d", len(req.Styles))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func TestSlotFormGetsOneCSRFField(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Box.gxc"), []byte(`<div class="box"><slot /></div>`), 0644)
+	page := filepath.Join(dir, "page.gxc")
+	imports := []Import{{Path: "./Box.gxc", Alias: "Box", IsComponent: true}}
+	template := `<Box><form method="post"><button>Send</button></form></Box>`
+
+	ctx := executor.NewContext()
+	ctx.SetCSRFToken("tok")
+	processed := NewComponentCompiler(dir).NewRequest(page, imports).ProcessComponentTags(template, ctx)
+	engine := tmpl.NewEngine(ctx)
+	engine.Page = true
+	rendered, err := engine.Render(processed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(rendered, `name="_csrf"`); n != 1 {
+		t.Errorf("Expected one token field, got %d in %q", n, rendered)
+	}
+
+	var b bytes.Buffer
+	if err := NewComponentCompiler(dir).NewRequest(page, imports).Stream(&b, template, ctx, StreamOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(b.String(), `name="_csrf"`); n != 1 {
+		t.Errorf("Expected one token field when streaming, got %d in %q", n, b.String())
+	}
+}
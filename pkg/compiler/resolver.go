@@ -2,10 +2,12 @@ package compiler
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 type ComponentResolver struct {
@@ -15,6 +17,10 @@ type ComponentResolver struct {
 	ExplicitPaths  map[string]string
 	Cache          map[string]string
 	ComponentIndex map[string]string
+	// FS, when set, holds the components instead of the disk.
+	FS fs.FS
+
+	mu sync.Mutex
 }
 
 func NewComponentResolver(baseDir string, componentDirs []string) *ComponentResolver {
@@ -34,29 +40,65 @@ func NewComponentResolver(baseDir string, componentDirs []string) *ComponentReso
 	return resolver
 }
 
+// NewComponentResolverFS resolves components in fsys, with slash-separated
+// paths relative to its root.
+func NewComponentResolverFS(fsys fs.FS, baseDir string, componentDirs []string) *ComponentResolver {
+	if componentDirs == nil {
+		componentDirs = []string{"components"}
+	}
+
+	resolver := &ComponentResolver{
+		BaseDir:        baseDir,
+		ComponentDirs:  componentDirs,
+		ExplicitPaths:  make(map[string]string),
+		Cache:          make(map[string]string),
+		ComponentIndex: make(map[string]string),
+		FS:             fsys,
+	}
+
+	resolver.buildComponentIndex()
+	return resolver
+}
+
 func (r *ComponentResolver) buildComponentIndex() {
+	if r.FS != nil {
+		fs.WalkDir(r.FS, r.BaseDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			return r.indexPath(path, d.IsDir())
+		})
+		return
+	}
+
 	filepath.Walk(r.BaseDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil
 		}
+		return r.indexPath(path, info.IsDir())
+	})
+}
 
-		if info.IsDir() {
-			dirName := filepath.Base(path)
-			if dirName == "pages" || dirName == "node_modules" || dirName == ".git" || strings.HasPrefix(dirName, ".") {
-				return filepath.SkipDir
-			}
+func (r *ComponentResolver) indexPath(path string, isDir bool) error {
+	if isDir {
+		dirName := filepath.Base(path)
+		if path == r.BaseDir {
 			return nil
 		}
+		if dirName == "pages" || dirName == "node_modules" || dirName == ".git" || strings.HasPrefix(dirName, ".") {
+			return filepath.SkipDir
+		}
+		return nil
+	}
 
-		if filepath.Ext(path) == ".gxc" {
-			baseName := strings.TrimSuffix(filepath.Base(path), ".gxc")
-			if _, exists := r.ComponentIndex[baseName]; !exists {
-				r.ComponentIndex[baseName] = path
-			}
+	if filepath.Ext(path) == ".gxc" {
+		baseName := strings.TrimSuffix(filepath.Base(path), ".gxc")
+		if _, exists := r.ComponentIndex[baseName]; !exists {
+			r.ComponentIndex[baseName] = path
 		}
+	}
 
-		return nil
-	})
+	return nil
 }
 
 func (r *ComponentResolver) SetCurrentFile(file string) {
@@ -96,28 +138,39 @@ func (r *ComponentResolver) ExtractComponentRefs(template string) []string {
 }
 
 func (r *ComponentResolver) Resolve(name string) (string, error) {
-	if cached, ok := r.Cache[name]; ok {
+	r.mu.Lock()
+	cached, ok := r.Cache[name]
+	r.mu.Unlock()
+	if ok {
 		return cached, nil
 	}
 
-	if path, ok := r.ExplicitPaths[name]; ok {
-		resolved, err := r.resolveImportPath(path)
-		if err != nil {
-			return "", err
-		}
-		r.Cache[name] = resolved
-		return resolved, nil
+	path, err := r.ResolveFrom(name, r.CurrentFile, r.ExplicitPaths)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.Cache[name] = path
+	r.mu.Unlock()
+	return path, nil
+}
+
+// ResolveFrom resolves name for the page at currentFile with the given
+// explicit imports. It only reads the component index, so it is safe to
+// call concurrently.
+func (r *ComponentResolver) ResolveFrom(name, currentFile string, explicit map[string]string) (string, error) {
+	if path, ok := explicit[name]; ok {
+		return r.resolveImportPath(path, currentFile)
 	}
 
 	if path, ok := r.ComponentIndex[name]; ok {
-		r.Cache[name] = path
 		return path, nil
 	}
 
-	if r.CurrentFile != "" {
-		path := filepath.Join(filepath.Dir(r.CurrentFile), name+".gxc")
-		if fileExists(path) {
-			r.Cache[name] = path
+	if currentFile != "" {
+		path := filepath.Join(filepath.Dir(currentFile), name+".gxc")
+		if r.exists(path) {
 			return path, nil
 		}
 	}
@@ -125,13 +178,13 @@ func (r *ComponentResolver) Resolve(name string) (string, error) {
 	return "", fmt.Errorf("component %s not found in %s", name, r.BaseDir)
 }
 
-func (r *ComponentResolver) resolveImportPath(importPath string) (string, error) {
+func (r *ComponentResolver) resolveImportPath(importPath, currentFile string) (string, error) {
 	if strings.HasPrefix(importPath, "./") || strings.HasPrefix(importPath, "../") {
-		if r.CurrentFile == "" {
+		if currentFile == "" {
 			return "", fmt.Errorf("relative import requires current file context")
 		}
-		resolved := filepath.Join(filepath.Dir(r.CurrentFile), importPath)
-		if fileExists(resolved) {
+		resolved := filepath.Join(filepath.Dir(currentFile), importPath)
+		if r.exists(resolved) {
 			return resolved, nil
 		}
 		return "", fmt.Errorf("import path not found: %s", importPath)
@@ -139,21 +192,25 @@ func (r *ComponentResolver) resolveImportPath(importPath string) (string, error)
 
 	if strings.HasPrefix(importPath, "@/") {
 		resolved := filepath.Join(r.BaseDir, strings.TrimPrefix(importPath, "@/"))
-		if fileExists(resolved) {
+		if r.exists(resolved) {
 			return resolved, nil
 		}
 		return "", fmt.Errorf("import path not found: %s", importPath)
 	}
 
 	resolved := filepath.Join(r.BaseDir, importPath)
-	if fileExists(resolved) {
+	if r.exists(resolved) {
 		return resolved, nil
 	}
 
 	return "", fmt.Errorf("import path not found: %s", importPath)
 }
 
-func fileExists(path string) bool {
+func (r *ComponentResolver) exists(path string) bool {
+	if r.FS != nil {
+		_, err := fs.Stat(r.FS, filepath.ToSlash(path))
+		return err == nil
+	}
 	_, err := os.Stat(path)
 	return err == nil
 }
@@ -3,7 +3,11 @@ package compiler
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/cameron-webmatter/galaxy/pkg/executor"
 )
 
 func TestComponentResolverDynamicDiscovery(t *testing.T) {
@@ -83,3 +87,21 @@ func TestComponentResolverExcludesPages(t *testing.T) {
 		t.Errorf("Expected Index to not be found (it's in pages dir)")
 	}
 }
+
+func TestComponentCompilerFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"components/Card.gxc":  {Data: []byte(`<div class="card">{title}</div>`)},
+		"pages/blog/Badge.gxc": {Data: []byte(`<span>badge</span>`)},
+		"pages/blog/post.gxc":  {Data: []byte(``)},
+	}
+
+	c := NewComponentCompilerFS(fsys, ".")
+	req := c.NewRequest("pages/blog/post.gxc", nil)
+	ctx := executor.NewContext()
+	ctx.Set("title", "hi")
+
+	got := req.ProcessComponentTags(`<Card title={title} /><Badge />`, ctx)
+	if !strings.Contains(got, `<div class="card">hi</div>`) || !strings.Contains(got, "<span>badge</span>") {
+		t.Errorf("Expected components read from FS, got %q", got)
+	}
+}
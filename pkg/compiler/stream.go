@@ -0,0 +1,288 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/executor"
+	"github.com/cameron-webmatter/galaxy/pkg/parser"
+	tmpl "github.com/cameron-webmatter/galaxy/pkg/template"
+)
+
+// IslandAttr defers a component to a server island: the page streams past
+// it, showing its children as a fallback, and the rendered component is
+// swapped in once the rest of the document has been sent.
+const IslandAttr = "server:defer"
+
+var islandAttrRegex = regexp.MustCompile(`\s+` + regexp.QuoteMeta(IslandAttr) + `\b`)
+
+// islandScript swaps a deferred island's <template> in for its placeholder.
+const islandScript = `<script>function galaxyIsland(id){var p=document.getElementById(id),t=document.getElementById(id+"-content");if(p&&t){p.replaceWith(t.content);t.remove()}}</script>`
+
+type StreamOptions struct {
+	// Transform post-processes each chunk before it is written, e.g. to add
+	// styles before </head> or scripts before </body>.
+	Transform func(string) string
+}
+
+// HasIslands reports whether template defers any component.
+func HasIslands(template string) bool {
+	return strings.Contains(template, IslandAttr)
+}
+
+// Stream renders a page template to w, flushing the head and the shell up
+// to the first component straight away and each top-level component as it
+// completes. The output matches ProcessComponentTags followed by
+// Engine.Render, except that component styles are written next to the
+// component instead of in <head>, and deferred islands arrive last.
+func (r *Request) Stream(w io.Writer, template string, ctx *executor.Context, opts StreamOptions) error {
+	engine := tmpl.NewEngine(ctx)
+	engine.Page = true
+	s := &streamer{
+		req:       r,
+		w:         w,
+		ctx:       ctx,
+		engine:    engine,
+		transform: opts.Transform,
+	}
+	return s.stream(template)
+}
+
+// stream writes template chunk by chunk. A render error stops it; what was
+// already flushed stays sent.
+func (s *streamer) stream(template string) error {
+	for _, ch := range splitChunks(template) {
+		if ch.island {
+			if err := s.placeholder(ch); err != nil {
+				return err
+			}
+			continue
+		}
+		html, err := s.render(ch.text)
+		if err != nil {
+			return err
+		}
+		if err := s.write(html); err != nil {
+			return err
+		}
+	}
+
+	return s.finish()
+}
+
+type chunk struct {
+	text string
+	// island chunks hold the deferred component tag, without IslandAttr,
+	// and the fallback shown until it arrives.
+	island   bool
+	fallback string
+}
+
+// renderer is the part of tmpl.Engine a streamer uses.
+type renderer interface {
+	Render(template string, opts *tmpl.RenderOptions) (string, error)
+}
+
+type streamer struct {
+	req       *Request
+	w         io.Writer
+	ctx       *executor.Context
+	engine    renderer
+	transform func(string) string
+
+	islands []chunk
+	// tail holds the document from </body> on until the islands are sent.
+	tail string
+}
+
+func (s *streamer) render(text string) (string, error) {
+	before := len(s.req.Styles)
+	processed := s.req.ProcessComponentTags(text, s.ctx)
+	html, err := s.engine.Render(processed, nil)
+	if err != nil {
+		return "", fmt.Errorf("render: %w", err)
+	}
+
+	if styles := s.req.Styles[before:]; len(styles) > 0 {
+		tag := styleTag(styles)
+		if strings.Contains(html, "</head>") {
+			html = strings.Replace(html, "</head>", tag+"\n</head>", 1)
+		} else {
+			html = tag + html
+		}
+	}
+	return html, nil
+}
+
+func (s *streamer) placeholder(ch chunk) error {
+	s.islands = append(s.islands, ch)
+	id := islandID(len(s.islands))
+	fallback, err := s.render(ch.fallback)
+	if err != nil {
+		return err
+	}
+	return s.write(`<galaxy-island id="` + id + `">` + fallback + `</galaxy-island>`)
+}
+
+func (s *streamer) write(html string) error {
+	if len(s.islands) > 0 && s.tail == "" {
+		if i := strings.LastIndex(html, "</body>"); i != -1 {
+			html, s.tail = html[:i], html[i:]
+		}
+	}
+	return s.send(html)
+}
+
+func (s *streamer) send(html string) error {
+	if s.transform != nil {
+		html = s.transform(html)
+	}
+	if html == "" {
+		return nil
+	}
+	if _, err := io.WriteString(s.w, html); err != nil {
+		return err
+	}
+	flush(s.w)
+	return nil
+}
+
+// finish renders the deferred islands in order, then closes the document.
+func (s *streamer) finish() error {
+	for i, island := range s.islands {
+		id := islandID(i + 1)
+		content, err := s.render(island.text)
+		if err != nil {
+			return err
+		}
+		html := fmt.Sprintf(`<template id="%s-content">%s</template><script>galaxyIsland(%q)</script>`, id, content, id)
+		if i == 0 {
+			html = islandScript + html
+		}
+
+		if _, err := io.WriteString(s.w, html); err != nil {
+			return err
+		}
+		flush(s.w)
+	}
+
+	return s.send(s.tail)
+}
+
+func islandID(n int) string {
+	return fmt.Sprintf("galaxy-island-%d", n)
+}
+
+func styleTag(styles []parser.Style) string {
+	var content string
+	for _, style := range styles {
+		content += style.Content + "\n"
+	}
+	return "<style>" + content + "</style>"
+}
+
+func flush(w io.Writer) {
+	if rw, ok := w.(http.ResponseWriter); ok {
+		http.NewResponseController(rw).Flush()
+		return
+	}
+	if f, ok := w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+// splitChunks cuts a page template after </head> and around each top-level
+// component. It never cuts inside a directive or a component tag, so every
+// chunk renders exactly as it would within the whole template.
+func splitChunks(template string) []chunk {
+	type span struct {
+		start, end int
+		island     bool
+		fallback   string
+		text       string
+	}
+
+	noSplit := tmpl.DirectiveSpans(template)
+	var components []span
+
+	for _, m := range componentOpenCloseRegex.FindAllStringSubmatchIndex(template, -1) {
+		if template[m[2]:m[3]] != template[m[8]:m[9]] {
+			noSplit = append(noSplit, [2]int{m[0], m[1]})
+			continue
+		}
+		sp := span{start: m[0], end: m[1]}
+		if attrs := template[m[4]:m[5]]; islandAttrRegex.MatchString(attrs) {
+			name := template[m[2]:m[3]]
+			sp.island = true
+			sp.fallback = strings.TrimSpace(template[m[6]:m[7]])
+			sp.text = "<" + name + islandAttrRegex.ReplaceAllString(attrs, "") + "></" + name + ">"
+		}
+		components = append(components, sp)
+	}
+
+	for _, m := range componentSelfCloseRegex.FindAllStringIndex(template, -1) {
+		overlaps := false
+		for _, sp := range components {
+			if m[0] < sp.end && m[1] > sp.start {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps || within(noSplit, m[0]) {
+			continue
+		}
+		sp := span{start: m[0], end: m[1]}
+		if text := template[m[0]:m[1]]; islandAttrRegex.MatchString(text) {
+			sp.island = true
+			sp.text = islandAttrRegex.ReplaceAllString(text, "")
+		}
+		components = append(components, sp)
+	}
+
+	sort.Slice(components, func(i, j int) bool { return components[i].start < components[j].start })
+
+	cuts := []int{}
+	if i := strings.Index(template, "</head>"); i != -1 {
+		cuts = append(cuts, i+len("</head>"))
+	}
+	islands := make(map[int]span)
+	for _, sp := range components {
+		if within(noSplit, sp.start) || within(noSplit, sp.end) {
+			continue
+		}
+		cuts = append(cuts, sp.start, sp.end)
+		if sp.island {
+			islands[sp.start] = sp
+		}
+	}
+	sort.Ints(cuts)
+
+	var chunks []chunk
+	prev := 0
+	for _, cut := range append(cuts, len(template)) {
+		if cut <= prev || within(noSplit, cut) {
+			continue
+		}
+		if sp, ok := islands[prev]; ok && sp.end == cut {
+			chunks = append(chunks, chunk{text: sp.text, island: true, fallback: sp.fallback})
+		} else {
+			chunks = append(chunks, chunk{text: template[prev:cut]})
+		}
+		prev = cut
+	}
+	return chunks
+}
+
+// within reports whether pos falls strictly inside one of spans.
+func within(spans [][2]int, pos int) bool {
+	for _, sp := range spans {
+		if pos > sp[0] && pos < sp[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package compiler
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/executor"
+	tmpl "github.com/cameron-webmatter/galaxy/pkg/template"
+)
+
+type chunkWriter struct {
+	bytes.Buffer
+	chunks []string
+	last   int
+}
+
+func (w *chunkWriter) Flush() {
+	w.chunks = append(w.chunks, w.String()[w.last:])
+	w.last = w.Len()
+}
+
+func newStreamCompiler(t *testing.T) *ComponentCompiler {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "components"), 0755)
+	os.WriteFile(filepath.Join(dir, "components", "Card.gxc"), []byte("<div class=\"card\">{title}<slot /></div>"), 0644)
+	os.WriteFile(filepath.Join(dir, "components", "Comments.gxc"), []byte("---\ncount := 3\n---\n<ul>{count} comments</ul>\n<style>ul { margin: 0 }</style>"), 0644)
+	return NewComponentCompiler(dir)
+}
+
+const streamPage = `<html><head><title>{title}</title></head><body>
+<h1>{title}</h1>
+<Card title="first" />
+<div galaxy:if={show}><Card title="hidden" /></div>
+<p galaxy:else>none</p>
+<Card title="second"><em>slot</em></Card>
+</body></html>`
+
+func streamContext() *executor.Context {
+	ctx := executor.NewContext()
+	ctx.Set("title", "Post")
+	ctx.Set("show", true)
+	return ctx
+}
+
+func TestStreamMatchesRender(t *testing.T) {
+	c := newStreamCompiler(t)
+
+	expected, _ := tmpl.NewEngine(streamContext()).Render(c.ProcessComponentTags(streamPage, streamContext()), nil)
+
+	w := &chunkWriter{}
+	if err := c.NewRequest("", nil).Stream(w, streamPage, streamContext(), StreamOptions{}); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	if w.String() != expected {
+		t.Errorf("Expected streamed output to match Render\nexpected: %q\ngot:      %q", expected, w.String())
+	}
+	if len(w.chunks) < 4 {
+		t.Errorf("Expected several flushes, got %d", len(w.chunks))
+	}
+	if w.chunks[0] != "<html><head><title>Post</title></head>" {
+		t.Errorf("Expected the head to be flushed first, got %q", w.chunks[0])
+	}
+}
+
+func TestStreamIslands(t *testing.T) {
+	c := newStreamCompiler(t)
+	page := `<html><head></head><body><Card title="a" /><Comments server:defer><p>Loading</p></Comments><footer>end</footer></body></html>`
+
+	var out bytes.Buffer
+	err := c.NewRequest("", nil).Stream(&out, page, executor.NewContext(), StreamOptions{
+		Transform: func(html string) string {
+			return strings.Replace(html, "</body>", "<script src=\"/app.js\"></script></body>", 1)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	html := out.String()
+
+	placeholder := strings.Index(html, `<galaxy-island id="galaxy-island-1"><p>Loading</p></galaxy-island>`)
+	footer := strings.Index(html, "<footer>end</footer>")
+	island := strings.Index(html, `<template id="galaxy-island-1-content"><style>ul { margin: 0 }`+"\n</style><ul>3 comments</ul>")
+	script := strings.Index(html, `<script src="/app.js"></script></body></html>`)
+
+	if placeholder == -1 || footer == -1 || island == -1 || script == -1 {
+		t.Fatalf("Expected placeholder, footer, island and scripts, got %q", html)
+	}
+	if !(placeholder < footer && footer < island && island < script) {
+		t.Errorf("Expected island after the document and before </body>, got %q", html)
+	}
+	if !strings.Contains(html, `galaxyIsland("galaxy-island-1")`) {
+		t.Errorf("Expected swap script, got %q", html)
+	}
+}
+
+// failingEngine renders normally until it meets fail.
+type failingEngine struct {
+	*tmpl.Engine
+	fail string
+}
+
+func (e failingEngine) Render(template string, opts *tmpl.RenderOptions) (string, error) {
+	if strings.Contains(template, e.fail) {
+		return "", errors.New("bad expression")
+	}
+	return e.Engine.Render(template, opts)
+}
+
+func TestStreamRenderError(t *testing.T) {
+	c := newStreamCompiler(t)
+	ctx := streamContext()
+
+	w := &chunkWriter{}
+	s := &streamer{
+		req:    c.NewRequest("", nil),
+		w:      w,
+		ctx:    ctx,
+		engine: failingEngine{Engine: tmpl.NewEngine(ctx), fail: "second"},
+	}
+	err := s.stream(streamPage)
+	if err == nil || !strings.Contains(err.Error(), "bad expression") {
+		t.Fatalf("Expected the render error, got %v", err)
+	}
+	if !strings.HasPrefix(w.String(), "<html><head><title>Post</title></head>") {
+		t.Errorf("Expected the head sent before the error, got %q", w.String())
+	}
+	if strings.Contains(w.String(), "</html>") {
+		t.Errorf("Expected streaming to stop at the error, got %q", w.String())
+	}
+}
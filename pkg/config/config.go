@@ -45,16 +45,43 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid output type: %s (must be static, server, or hybrid)", c.Output.Type)
 	}
 
-	if c.Output.Type == OutputServer || c.Output.Type == OutputHybrid {
-		if c.Adapter.Name == "" {
-			c.Adapter.Name = AdapterStandalone
-		}
-
-		switch c.Adapter.Name {
-		case AdapterStandalone, AdapterCloudflare, AdapterNetlify, AdapterVercel:
-		default:
-			return fmt.Errorf("invalid adapter: %s", c.Adapter.Name)
-		}
+	switch c.Output.Assets {
+	case AssetsEmbed, AssetsDisk:
+	case "":
+		c.Output.Assets = AssetsEmbed
+	default:
+		return fmt.Errorf("invalid output assets: %s (must be embed or disk)", c.Output.Assets)
+	}
+
+	// Any name is accepted here; builds of every output type resolve it
+	// through the adapters registry, which third-party adapters register
+	// with.
+	if c.Adapter.Name == "" {
+		c.Adapter.Name = AdapterStandalone
+	}
+
+	switch c.Telemetry.Log.Level {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", c.Telemetry.Log.Level)
+	}
+
+	switch c.Telemetry.Log.Format {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("invalid log format: %s (must be text or json)", c.Telemetry.Log.Format)
+	}
+
+	switch c.Telemetry.Tracing.Exporter {
+	case "", "stdout", "otlp":
+	default:
+		return fmt.Errorf("invalid trace exporter: %s (must be stdout or otlp)", c.Telemetry.Tracing.Exporter)
+	}
+
+	switch c.Budgets.Compression {
+	case "", "gzip", "brotli", "raw":
+	default:
+		return fmt.Errorf("invalid budgets compression: %s (must be gzip, brotli, or raw)", c.Budgets.Compression)
 	}
 
 	if c.Server.Port == 0 {
@@ -8,6 +8,16 @@ const (
 	OutputHybrid OutputType = "hybrid"
 )
 
+// AssetsMode selects where built servers read pages and assets from.
+type AssetsMode string
+
+const (
+	// AssetsEmbed compiles them into the server binary.
+	AssetsEmbed AssetsMode = "embed"
+	// AssetsDisk reads them from the directory next to the binary.
+	AssetsDisk AssetsMode = "disk"
+)
+
 type AdapterName string
 
 const (
@@ -15,28 +25,62 @@ const (
 	AdapterCloudflare AdapterName = "cloudflare"
 	AdapterNetlify    AdapterName = "netlify"
 	AdapterVercel     AdapterName = "vercel"
+	AdapterOCI        AdapterName = "oci"
+	AdapterAWSLambda  AdapterName = "aws-lambda"
 )
 
 type Config struct {
-	Site           string          `toml:"site"`
-	Base           string          `toml:"base"`
-	OutDir         string          `toml:"outDir"`
-	SrcDir         string          `toml:"srcDir"`
-	PackageManager string          `toml:"packageManager"`
-	Output         OutputConfig    `toml:"output"`
-	Server         ServerConfig    `toml:"server"`
-	Adapter        AdapterConfig   `toml:"adapter"`
-	Lifecycle      LifecycleConfig `toml:"lifecycle"`
-	Plugins        []PluginConfig  `toml:"plugins"`
+	Site           string           `toml:"site"`
+	Base           string           `toml:"base"`
+	OutDir         string           `toml:"outDir"`
+	SrcDir         string           `toml:"srcDir"`
+	PackageManager string           `toml:"packageManager"`
+	Output         OutputConfig     `toml:"output"`
+	Server         ServerConfig     `toml:"server"`
+	Adapter        AdapterConfig    `toml:"adapter"`
+	Lifecycle      LifecycleConfig  `toml:"lifecycle"`
+	Middleware     MiddlewareConfig `toml:"middleware"`
+	Cache          CacheConfig      `toml:"cache"`
+	Telemetry      TelemetryConfig  `toml:"telemetry"`
+	Budgets        BudgetsConfig    `toml:"budgets"`
+	Plugins        []PluginConfig   `toml:"plugins"`
 }
 
 type OutputConfig struct {
 	Type OutputType `toml:"type"`
+	// Streaming flushes SSR pages in chunks as their components render.
+	// Pages with server:defer islands always stream.
+	Streaming bool `toml:"streaming"`
+	// Assets is "embed" (the default) or "disk".
+	Assets AssetsMode `toml:"assets"`
 }
 
+// ServerConfig configures the server of built apps. Timeouts are in
+// seconds; zero keeps the default and a negative value disables one. The
+// HOST and PORT environment variables override Host and Port.
 type ServerConfig struct {
-	Port int    `toml:"port"`
-	Host string `toml:"host"`
+	Port              int       `toml:"port"`
+	Host              string    `toml:"host"`
+	ReadHeaderTimeout int       `toml:"readHeaderTimeout"`
+	ReadTimeout       int       `toml:"readTimeout"`
+	WriteTimeout      int       `toml:"writeTimeout"`
+	IdleTimeout       int       `toml:"idleTimeout"`
+	ShutdownTimeout   int       `toml:"shutdownTimeout"`
+	TLS               TLSConfig `toml:"tls"`
+	// H2C serves HTTP/2 without TLS, for proxies that speak it.
+	H2C bool `toml:"h2c"`
+	// WebSocketOrigins are the origins, besides the site's own, that may
+	// open WebSocket connections.
+	WebSocketOrigins []string `toml:"websocketOrigins"`
+}
+
+type TLSConfig struct {
+	Cert string `toml:"cert"`
+	Key  string `toml:"key"`
+}
+
+func (t TLSConfig) Enabled() bool {
+	return t.Cert != "" && t.Key != ""
 }
 
 type AdapterConfig struct {
@@ -50,6 +94,146 @@ type LifecycleConfig struct {
 	ShutdownTimeout int  `toml:"shutdownTimeout"`
 }
 
+// MiddlewareConfig enables the built-in middleware in pkg/middleware/builtin.
+// They run before src/middleware.go.
+type MiddlewareConfig struct {
+	RequestID bool            `toml:"requestId"`
+	Logger    bool            `toml:"logger"`
+	BodyLimit string          `toml:"bodyLimit"`
+	Compress  CompressConfig  `toml:"compress"`
+	CORS      CORSConfig      `toml:"cors"`
+	Security  SecurityConfig  `toml:"security"`
+	RateLimit RateLimitConfig `toml:"rateLimit"`
+	Session   SessionConfig   `toml:"session"`
+	CSRF      CSRFConfig      `toml:"csrf"`
+}
+
+type CompressConfig struct {
+	Enabled bool `toml:"enabled"`
+	Level   int  `toml:"level"`
+	MinSize int  `toml:"minSize"`
+}
+
+type CORSConfig struct {
+	Enabled          bool     `toml:"enabled"`
+	AllowOrigins     []string `toml:"allowOrigins"`
+	AllowMethods     []string `toml:"allowMethods"`
+	AllowHeaders     []string `toml:"allowHeaders"`
+	ExposeHeaders    []string `toml:"exposeHeaders"`
+	AllowCredentials bool     `toml:"allowCredentials"`
+	MaxAge           int      `toml:"maxAge"`
+}
+
+type SecurityConfig struct {
+	Enabled               bool   `toml:"enabled"`
+	HSTSMaxAge            int    `toml:"hstsMaxAge"`
+	HSTSIncludeSubdomains bool   `toml:"hstsIncludeSubdomains"`
+	FrameOptions          string `toml:"frameOptions"`
+	ContentSecurityPolicy string `toml:"contentSecurityPolicy"`
+	ReferrerPolicy        string `toml:"referrerPolicy"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool    `toml:"enabled"`
+	Rate    float64 `toml:"rate"`
+	Burst   int     `toml:"burst"`
+}
+
+// SessionConfig selects a session store. Secrets may instead come from the
+// comma separated GALAXY_SESSION_SECRETS environment variable, which keeps
+// them out of the config file and generated servers.
+type SessionConfig struct {
+	Enabled bool     `toml:"enabled"`
+	Store   string   `toml:"store"`
+	Name    string   `toml:"name"`
+	MaxAge  int      `toml:"maxAge"`
+	Encrypt bool     `toml:"encrypt"`
+	Secure  bool     `toml:"secure"`
+	Dir     string   `toml:"dir"`
+	Secrets []string `toml:"secrets"`
+}
+
+type CSRFConfig struct {
+	Enabled    bool     `toml:"enabled"`
+	CookieName string   `toml:"cookieName"`
+	HeaderName string   `toml:"headerName"`
+	Exclude    []string `toml:"exclude"`
+}
+
+func (m MiddlewareConfig) Enabled() bool {
+	return m.RequestID || m.Logger || m.BodyLimit != "" || m.Compress.Enabled ||
+		m.CORS.Enabled || m.Security.Enabled || m.RateLimit.Enabled || m.Session.Enabled || m.CSRF.Enabled
+}
+
+// CacheConfig enables the SSR response cache. The revalidate secret may
+// instead come from the GALAXY_REVALIDATE_SECRET environment variable.
+type CacheConfig struct {
+	Enabled          bool         `toml:"enabled"`
+	MaxEntries       int          `toml:"maxEntries"`
+	RevalidateSecret string       `toml:"revalidateSecret"`
+	Routes           []CacheRoute `toml:"routes"`
+}
+
+// CacheRoute declares caching for pages matching Path. TTL and
+// StaleWhileRevalidate are in seconds; Vary lists request headers, or
+// cookies as "cookie:<name>".
+type CacheRoute struct {
+	Path                 string   `toml:"path"`
+	TTL                  int      `toml:"ttl"`
+	StaleWhileRevalidate int      `toml:"staleWhileRevalidate"`
+	Vary                 []string `toml:"vary"`
+	Tags                 []string `toml:"tags"`
+}
+
+// TelemetryConfig configures the logs, metrics and traces of the dev and
+// built servers.
+type TelemetryConfig struct {
+	Log     LogConfig     `toml:"log"`
+	Metrics MetricsConfig `toml:"metrics"`
+	Tracing TracingConfig `toml:"tracing"`
+}
+
+// LogConfig configures the structured logs written to stderr.
+type LogConfig struct {
+	// Level is "debug", "info", "warn" or "error".
+	Level string `toml:"level"`
+	// Format is "text" or "json".
+	Format string `toml:"format"`
+	// Requests logs a line per request.
+	Requests bool `toml:"requests"`
+}
+
+// MetricsConfig serves Prometheus metrics at Path.
+type MetricsConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Path    string `toml:"path"`
+}
+
+// TracingConfig exports spans to stdout or to an OTLP/HTTP collector.
+type TracingConfig struct {
+	// Exporter is "stdout", "otlp" or empty to disable tracing.
+	Exporter string `toml:"exporter"`
+	// Endpoint is the collector's OTLP/HTTP traces URL.
+	Endpoint string `toml:"endpoint"`
+	// Service is reported as the service.name resource attribute.
+	Service string `toml:"service"`
+}
+
+// BudgetsConfig fails builds whose pages exceed a limit. Sizes are like
+// "100KB" and measured after Compression: "gzip" (the default), "brotli"
+// or "raw". MaxRenderTime is a duration like "200ms".
+type BudgetsConfig struct {
+	// MaxJS limits the JavaScript each page loads, in total.
+	MaxJS string `toml:"maxJS"`
+	// MaxCSS limits the stylesheets each page loads, in total.
+	MaxCSS string `toml:"maxCSS"`
+	// MaxWASM limits each WebAssembly module.
+	MaxWASM string `toml:"maxWASM"`
+	// MaxRenderTime limits how long a prerendered page takes to render.
+	MaxRenderTime string `toml:"maxRenderTime"`
+	Compression   string `toml:"compression"`
+}
+
 type PluginConfig struct {
 	Name   string                 `toml:"name"`
 	Config map[string]interface{} `toml:"config"`
@@ -78,5 +262,10 @@ func DefaultConfig() *Config {
 			StartupTimeout:  30,
 			ShutdownTimeout: 10,
 		},
+		Telemetry: TelemetryConfig{
+			Log:     LogConfig{Level: "info", Format: "text", Requests: true},
+			Metrics: MetricsConfig{Path: "/metrics"},
+			Tracing: TracingConfig{Endpoint: "http://localhost:4318/v1/traces", Service: "galaxy"},
+		},
 	}
 }
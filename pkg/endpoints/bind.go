@@ -0,0 +1,385 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const defaultMaxMemory = 32 << 20
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// BindError reports a request value that could not be converted into the
+// target field. It is rendered as a 400 response by WriteError.
+type BindError struct {
+	Field  string
+	Source string
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("bind %s %q: %v", e.Source, e.Field, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// fieldSet records the fields a request supplied, by their Go path such as
+// "Address.City", so validation can tell a missing field from a zero one.
+type fieldSet map[string]bool
+
+// Bind populates v from the request and validates it. Fields are bound from
+// the JSON body (json tags), then path params (param), query (query),
+// headers (header) and form or multipart values (form).
+func (c *Context) Bind(v any) error {
+	rv, err := bindTarget(v)
+	if err != nil {
+		return err
+	}
+
+	seen := make(fieldSet)
+	if isJSONRequest(c.Request) {
+		if err := c.decodeJSON(v, seen); err != nil {
+			return err
+		}
+	}
+
+	if isFormRequest(c.Request) {
+		if err := c.parseForm(); err != nil {
+			return &BindError{Source: "form", Err: err}
+		}
+	}
+
+	if err := c.bindValues(rv, seen); err != nil {
+		return err
+	}
+
+	return validate(v, seen)
+}
+
+func (c *Context) BindJSON(v any) error {
+	if c.Request.Body == nil {
+		return fmt.Errorf("request body is nil")
+	}
+	seen := make(fieldSet)
+	if err := c.decodeJSON(v, seen); err != nil {
+		return err
+	}
+	return validate(v, seen)
+}
+
+func (c *Context) BindForm(v any) error {
+	rv, err := bindTarget(v)
+	if err != nil {
+		return err
+	}
+
+	if err := c.parseForm(); err != nil {
+		return &BindError{Source: "form", Err: err}
+	}
+
+	seen := make(fieldSet)
+	if err := bindStruct(rv, "", "form", c.formValues, c.formFiles, seen); err != nil {
+		return err
+	}
+
+	return validate(v, seen)
+}
+
+func (c *Context) decodeJSON(v any, seen fieldSet) error {
+	if c.Request.Body == nil {
+		return nil
+	}
+	defer c.Request.Body.Close()
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return &BindError{Source: "body", Err: err}
+	}
+
+	var data any
+	json.Unmarshal(body, &data)
+	markJSON(reflect.TypeOf(v), data, "", seen)
+	return nil
+}
+
+// markJSON adds the fields of t that data, a decoded JSON body, has a key
+// for to seen, matching keys the way encoding/json does.
+func markJSON(t reflect.Type, data any, path string, seen fieldSet) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	obj, ok := data.(map[string]any)
+	if t.Kind() != reflect.Struct || !ok {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+		name := tagName(field, "json")
+		if field.Anonymous && name == "" {
+			markJSON(field.Type, obj, path, seen)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		value, ok := obj[name]
+		if !ok {
+			for key, v := range obj {
+				if strings.EqualFold(key, name) {
+					value, ok = v, true
+					break
+				}
+			}
+		}
+		if ok {
+			seen[path+field.Name] = true
+			markJSON(field.Type, value, path+field.Name+".", seen)
+		}
+	}
+}
+
+func (c *Context) parseForm() error {
+	if strings.HasPrefix(c.Request.Header.Get("Content-Type"), "multipart/form-data") {
+		if c.Request.MultipartForm != nil {
+			return nil
+		}
+		return c.Request.ParseMultipartForm(defaultMaxMemory)
+	}
+	return c.Request.ParseForm()
+}
+
+func (c *Context) bindValues(rv reflect.Value, seen fieldSet) error {
+	sources := []struct {
+		tag    string
+		values func(string) []string
+		files  func(string) []*multipart.FileHeader
+	}{
+		{"param", c.paramValues, nil},
+		{"query", c.queryValues, nil},
+		{"header", c.headerValues, nil},
+		{"form", c.formValues, c.formFiles},
+	}
+
+	for _, src := range sources {
+		if err := bindStruct(rv, "", src.tag, src.values, src.files, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Context) paramValues(key string) []string {
+	if v, ok := c.Params[key]; ok {
+		return []string{v}
+	}
+	return nil
+}
+
+func (c *Context) queryValues(key string) []string {
+	return c.Request.URL.Query()[key]
+}
+
+func (c *Context) headerValues(key string) []string {
+	return c.Request.Header.Values(key)
+}
+
+func (c *Context) formValues(key string) []string {
+	if c.Request.MultipartForm != nil {
+		if v, ok := c.Request.MultipartForm.Value[key]; ok {
+			return v
+		}
+	}
+	if c.Request.PostForm != nil {
+		return c.Request.PostForm[key]
+	}
+	return nil
+}
+
+func (c *Context) formFiles(key string) []*multipart.FileHeader {
+	if c.Request.MultipartForm == nil {
+		return nil
+	}
+	return c.Request.MultipartForm.File[key]
+}
+
+// bindTarget returns the struct v points to. A pointer to a nil struct
+// pointer, as Typed passes for a *T input, gets a new struct.
+func bindTarget(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return reflect.Value{}, fmt.Errorf("bind target must be a non-nil pointer, got %T", v)
+	}
+	rv = rv.Elem()
+	if rv.Kind() == reflect.Ptr && rv.Type().Elem().Kind() == reflect.Struct {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("bind target must point to a struct, got %T", v)
+	}
+	return rv, nil
+}
+
+func bindStruct(rv reflect.Value, path, tag string, values func(string) []string, files func(string) []*multipart.FileHeader, seen fieldSet) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if field.Anonymous && fv.Kind() == reflect.Struct {
+			if err := bindStruct(fv, path, tag, values, files, seen); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := tagName(field, tag)
+		if name == "" {
+			continue
+		}
+
+		if files != nil && isFileField(field.Type) {
+			if headers := files(name); len(headers) > 0 {
+				bindFiles(fv, headers)
+				seen[path+field.Name] = true
+			}
+			continue
+		}
+
+		raw := values(name)
+		if len(raw) == 0 {
+			continue
+		}
+		seen[path+field.Name] = true
+
+		if err := setField(fv, raw); err != nil {
+			return &BindError{Field: name, Source: tag, Err: err}
+		}
+	}
+	return nil
+}
+
+func tagName(field reflect.StructField, tag string) string {
+	name := field.Tag.Get(tag)
+	if idx := strings.Index(name, ","); idx != -1 {
+		name = name[:idx]
+	}
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func isFileField(t reflect.Type) bool {
+	if t == fileHeaderType {
+		return true
+	}
+	return t.Kind() == reflect.Slice && t.Elem() == fileHeaderType
+}
+
+func bindFiles(fv reflect.Value, headers []*multipart.FileHeader) {
+	if len(headers) == 0 {
+		return
+	}
+	if fv.Type() == fileHeaderType {
+		fv.Set(reflect.ValueOf(headers[0]))
+		return
+	}
+	fv.Set(reflect.ValueOf(headers))
+}
+
+func setField(fv reflect.Value, raw []string) error {
+	if fv.Kind() == reflect.Ptr {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setField(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+
+	if fv.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(fv.Type(), len(raw), len(raw))
+		for i, s := range raw {
+			if err := setScalar(slice.Index(i), s); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	return setScalar(fv, raw[0])
+}
+
+func setScalar(fv reflect.Value, s string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		if s == "on" {
+			fv.SetBool(true)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+func isJSONRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
+
+func isFormRequest(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	return strings.HasPrefix(ct, "application/x-www-form-urlencoded") || strings.HasPrefix(ct, "multipart/form-data")
+}
@@ -0,0 +1,293 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type createUserInput struct {
+	ID      int      `param:"id"`
+	Page    int      `query:"page" validate:"min=1"`
+	Tags    []string `query:"tag"`
+	Token   string   `header:"X-Token" validate:"required"`
+	Name    string   `json:"name" validate:"required,min=2"`
+	Email   string   `json:"email" validate:"email"`
+	Role    string   `json:"role" validate:"oneof=admin user"`
+	Verbose *bool    `query:"verbose"`
+}
+
+func TestBindAllSources(t *testing.T) {
+	body := `{"name":"Ada","email":"ada@example.com","role":"admin"}`
+	req := httptest.NewRequest("POST", "/api/users/42?page=3&tag=a&tag=b&verbose=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Token", "secret")
+
+	ctx := NewContext(httptest.NewRecorder(), req, map[string]string{"id": "42"}, nil)
+
+	var in createUserInput
+	if err := ctx.Bind(&in); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+
+	if in.ID != 42 {
+		t.Errorf("Expected ID 42, got %d", in.ID)
+	}
+	if in.Page != 3 {
+		t.Errorf("Expected page 3, got %d", in.Page)
+	}
+	if len(in.Tags) != 2 || in.Tags[1] != "b" {
+		t.Errorf("Expected tags [a b], got %v", in.Tags)
+	}
+	if in.Token != "secret" {
+		t.Errorf("Expected token from header, got %q", in.Token)
+	}
+	if in.Name != "Ada" || in.Role != "admin" {
+		t.Errorf("Expected JSON body fields, got %+v", in)
+	}
+	if in.Verbose == nil || !*in.Verbose {
+		t.Error("Expected verbose pointer to be set")
+	}
+}
+
+func TestBindValidationErrors(t *testing.T) {
+	body := `{"name":"A","email":"nope","role":"root"}`
+	req := httptest.NewRequest("POST", "/api/users?page=0", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	ctx := NewContext(httptest.NewRecorder(), req, nil, nil)
+
+	var in createUserInput
+	err := ctx.Bind(&in)
+
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	rules := make(map[string]string)
+	for _, fe := range verrs {
+		rules[fe.Field] = fe.Rule
+	}
+
+	expected := map[string]string{
+		"X-Token": "required",
+		"page":    "min",
+		"name":    "min",
+		"email":   "email",
+		"role":    "oneof",
+	}
+	for field, rule := range expected {
+		if rules[field] != rule {
+			t.Errorf("Expected %s to fail %q, got %q", field, rule, rules[field])
+		}
+	}
+}
+
+func TestBindValidationSkipsMissingFields(t *testing.T) {
+	type input struct {
+		Page  int    `query:"page" validate:"min=1"`
+		Name  string `json:"name" validate:"required"`
+		Email string `json:"email" validate:"email"`
+		Note  string `json:"note" validate:"omitempty,min=3"`
+	}
+
+	req := httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"Ada","note":""}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	var in input
+	if err := NewContext(httptest.NewRecorder(), req, nil, nil).Bind(&in); err != nil {
+		t.Errorf("Expected missing and omitempty fields to be skipped, got %v", err)
+	}
+
+	req = httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"Ada","email":""}`))
+	req.Header.Set("Content-Type", "application/json")
+	err := NewContext(httptest.NewRecorder(), req, nil, nil).Bind(&in)
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Field != "email" {
+		t.Errorf("Expected the supplied empty email to fail, got %v", err)
+	}
+}
+
+func TestBindFormPopulatesStruct(t *testing.T) {
+	form := url.Values{"title": {"Hello"}, "published": {"on"}, "count": {"7"}}
+	req := httptest.NewRequest("POST", "/api/posts", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	ctx := NewContext(httptest.NewRecorder(), req, nil, nil)
+
+	var in struct {
+		Title     string `form:"title" validate:"required"`
+		Published bool   `form:"published"`
+		Count     uint   `form:"count"`
+	}
+	if err := ctx.BindForm(&in); err != nil {
+		t.Fatalf("BindForm failed: %v", err)
+	}
+
+	if in.Title != "Hello" || !in.Published || in.Count != 7 {
+		t.Errorf("Unexpected form binding: %+v", in)
+	}
+}
+
+func TestBindMultipartFile(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("caption", "sunset")
+	fw, _ := mw.CreateFormFile("photo", "sunset.jpg")
+	fw.Write([]byte("jpeg-bytes"))
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/api/upload", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	ctx := NewContext(httptest.NewRecorder(), req, nil, nil)
+
+	var in struct {
+		Caption string                `form:"caption"`
+		Photo   *multipart.FileHeader `form:"photo" validate:"required"`
+	}
+	if err := ctx.Bind(&in); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+
+	if in.Caption != "sunset" {
+		t.Errorf("Expected caption sunset, got %q", in.Caption)
+	}
+	if in.Photo == nil || in.Photo.Filename != "sunset.jpg" {
+		t.Errorf("Expected photo file header, got %+v", in.Photo)
+	}
+}
+
+func TestBindTypeError(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/users?page=abc", nil)
+	ctx := NewContext(httptest.NewRecorder(), req, nil, nil)
+
+	var in struct {
+		Page int `query:"page"`
+	}
+	err := ctx.Bind(&in)
+
+	var berr *BindError
+	if !errors.As(err, &berr) {
+		t.Fatalf("Expected BindError, got %v", err)
+	}
+	if berr.Field != "page" || berr.Source != "query" {
+		t.Errorf("Unexpected bind error: %+v", berr)
+	}
+}
+
+type greetInput struct {
+	Name string `query:"name" validate:"required"`
+}
+
+type greetOutput struct {
+	Message string `json:"message"`
+}
+
+func TestTypedHandler(t *testing.T) {
+	handler := Typed(func(ctx *Context, in greetInput) (greetOutput, error) {
+		return greetOutput{Message: "Hello " + in.Name}, nil
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/greet?name=Ada", nil)
+	if err := handler(NewContext(w, req, nil, nil)); err != nil {
+		t.Fatalf("Handler failed: %v", err)
+	}
+
+	var out greetOutput
+	json.Unmarshal(w.Body.Bytes(), &out)
+	if out.Message != "Hello Ada" {
+		t.Errorf("Expected greeting, got %q", out.Message)
+	}
+}
+
+func TestTypedHandlerPointerInput(t *testing.T) {
+	handler := Typed(func(ctx *Context, in *greetInput) (greetOutput, error) {
+		return greetOutput{Message: "Hello " + in.Name}, nil
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/greet?name=Ada", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	if err := handler(NewContext(w, req, nil, nil)); err != nil {
+		t.Fatalf("Handler failed: %v", err)
+	}
+	if !strings.Contains(w.Body.String(), "Hello Ada") {
+		t.Errorf("Expected greeting, got %q", w.Body.String())
+	}
+
+	err := handler(NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/greet", nil), nil, nil))
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Errorf("Expected a pointer input to be validated, got %v", err)
+	}
+}
+
+func TestWriteErrorValidation(t *testing.T) {
+	handler := Typed(func(ctx *Context, in greetInput) (greetOutput, error) {
+		return greetOutput{}, nil
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/greet", nil)
+	err := handler(NewContext(w, req, nil, nil))
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	WriteError(w, err)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", w.Code)
+	}
+
+	var resp struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected JSON body: %v", err)
+	}
+	if len(resp.Fields) != 1 || resp.Fields[0].Field != "name" {
+		t.Errorf("Expected name field error, got %+v", resp.Fields)
+	}
+}
+
+func TestParseSignatures(t *testing.T) {
+	src := `package api
+
+import "github.com/cameron-webmatter/galaxy/pkg/endpoints"
+
+func GET(ctx *endpoints.Context) error { return nil }
+
+// POST creates a user.
+func POST(ctx *endpoints.Context, in CreateUser) (*User, error) { return nil, nil }
+
+func helper() {}
+`
+	sigs, err := ParseSignaturesSource("users.go", []byte(src))
+	if err != nil {
+		t.Fatalf("ParseSignaturesSource failed: %v", err)
+	}
+
+	if len(sigs) != 2 {
+		t.Fatalf("Expected 2 signatures, got %d", len(sigs))
+	}
+	if sigs[0].Method != GET || sigs[0].Typed {
+		t.Errorf("Expected plain GET, got %+v", sigs[0])
+	}
+	if sigs[1].Method != POST || !sigs[1].Typed {
+		t.Errorf("Expected typed POST, got %+v", sigs[1])
+	}
+	if sigs[1].Doc != "POST creates a user.\n" {
+		t.Errorf("Expected doc comment, got %q", sigs[1].Doc)
+	}
+}
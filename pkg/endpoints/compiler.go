@@ -87,27 +87,15 @@ func (c *EndpointCompiler) Load(filePath string) (*LoadedEndpoint, error) {
 	return endpoint, nil
 }
 
-func (c *EndpointCompiler) detectMethods(filePath string) []string {
-	content, err := os.ReadFile(filePath)
+func (c *EndpointCompiler) detectMethods(filePath string) []Signature {
+	sigs, err := ParseSignatures(filePath)
 	if err != nil {
 		return nil
 	}
-
-	src := string(content)
-	methods := []string{}
-	httpMethods := []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "ALL"}
-
-	for _, method := range httpMethods {
-		pattern := fmt.Sprintf("func %s(", method)
-		if strings.Contains(src, pattern) {
-			methods = append(methods, method)
-		}
-	}
-
-	return methods
+	return sigs
 }
 
-func (c *EndpointCompiler) compile(filePath string, methods []string) (string, error) {
+func (c *EndpointCompiler) compile(filePath string, methods []Signature) (string, error) {
 	if err := os.MkdirAll(c.CacheDir, 0755); err != nil {
 		return "", err
 	}
@@ -153,8 +141,16 @@ func (c *EndpointCompiler) compile(filePath string, methods []string) (string, e
 	importPath := filepath.Join(c.ModuleName, ".galaxy/endpoints/src", sanitizedRelPath)
 
 	var methodExports strings.Builder
-	for _, method := range methods {
-		methodExports.WriteString(fmt.Sprintf("func %s(ctx *endpoints.Context) error { return %s.%s(ctx) }\n", method, pkgName, method))
+	for _, sig := range methods {
+		if sig.Method == WS {
+			methodExports.WriteString(fmt.Sprintf("func WS(ctx *endpoints.Context, conn *endpoints.WSConn) error { return %s.WS(ctx, conn) }\n", pkgName))
+			continue
+		}
+		if sig.Typed {
+			methodExports.WriteString(fmt.Sprintf("func %s(ctx *endpoints.Context) error { return endpoints.Typed(%s.%s)(ctx) }\n", sig.Method, pkgName, sig.Method))
+			continue
+		}
+		methodExports.WriteString(fmt.Sprintf("func %s(ctx *endpoints.Context) error { return %s.%s(ctx) }\n", sig.Method, pkgName, sig.Method))
 	}
 
 	pluginSrc := fmt.Sprintf(`package main
@@ -6,6 +6,10 @@ import (
 )
 
 func HandleEndpoint(endpoint *LoadedEndpoint, w http.ResponseWriter, r *http.Request, params map[string]string, locals map[string]any) error {
+	if endpoint.WebSocket != nil && IsWebSocketUpgrade(r) {
+		return ServeWebSocket(endpoint.WebSocket, w, r, params, locals)
+	}
+
 	method := HTTPMethod(r.Method)
 
 	handler, ok := endpoint.Handlers[method]
@@ -18,10 +18,17 @@ const (
 	HEAD    HTTPMethod = "HEAD"
 	OPTIONS HTTPMethod = "OPTIONS"
 	ALL     HTTPMethod = "ALL"
+
+	// WS is the export name for WebSocket handlers. It is not an HTTP method
+	// and is matched on upgrade requests only.
+	WS HTTPMethod = "WS"
 )
 
+var Methods = []HTTPMethod{GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS, ALL}
+
 type LoadedEndpoint struct {
-	Handlers map[HTTPMethod]HandlerFunc
+	Handlers  map[HTTPMethod]HandlerFunc
+	WebSocket WSHandlerFunc
 }
 
 func Load(projectDir string) (map[string]*LoadedEndpoint, error) {
@@ -45,8 +52,7 @@ func LoadPlugin(pluginPath string) (*LoadedEndpoint, error) {
 		Handlers: make(map[HTTPMethod]HandlerFunc),
 	}
 
-	methods := []HTTPMethod{GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS, ALL}
-	for _, method := range methods {
+	for _, method := range Methods {
 		sym, err := p.Lookup(string(method))
 		if err != nil {
 			continue
@@ -60,7 +66,13 @@ func LoadPlugin(pluginPath string) (*LoadedEndpoint, error) {
 		endpoint.Handlers[method] = handler
 	}
 
-	if len(endpoint.Handlers) == 0 {
+	if sym, err := p.Lookup(string(WS)); err == nil {
+		if handler, ok := sym.(func(*Context, *WSConn) error); ok {
+			endpoint.WebSocket = handler
+		}
+	}
+
+	if len(endpoint.Handlers) == 0 && endpoint.WebSocket == nil {
 		return nil, fmt.Errorf("no HTTP method handlers found")
 	}
 
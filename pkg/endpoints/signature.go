@@ -0,0 +1,93 @@
+package endpoints
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+)
+
+// Signature describes an exported HTTP method handler found in an endpoint
+// source file. Typed handlers use the generic form
+// func(*endpoints.Context, In) (Out, error); Input and Output are nil otherwise.
+// A WS export has the form func(*endpoints.Context, *endpoints.WSConn) error.
+type Signature struct {
+	Method HTTPMethod
+	Typed  bool
+	Input  ast.Expr
+	Output ast.Expr
+	Doc    string
+}
+
+// ParseSignatures returns the handler signatures declared in filePath.
+func ParseSignatures(filePath string) ([]Signature, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSignaturesSource(filePath, src)
+}
+
+func ParseSignaturesSource(filename string, src []byte) ([]Signature, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, m := range Methods {
+		known[string(m)] = true
+	}
+	known[string(WS)] = true
+
+	var sigs []Signature
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !known[fn.Name.Name] {
+			continue
+		}
+
+		sig := Signature{Method: HTTPMethod(fn.Name.Name)}
+		if fn.Doc != nil {
+			sig.Doc = fn.Doc.Text()
+		}
+
+		params := fieldTypes(fn.Type.Params)
+		results := fieldTypes(fn.Type.Results)
+		switch {
+		case sig.Method == WS:
+			if len(params) != 2 || len(results) != 1 {
+				continue
+			}
+		case len(params) == 1 && len(results) == 1:
+		case len(params) == 2 && len(results) == 2:
+			sig.Typed = true
+			sig.Input = params[1]
+			sig.Output = results[0]
+		default:
+			continue
+		}
+
+		sigs = append(sigs, sig)
+	}
+
+	return sigs, nil
+}
+
+func fieldTypes(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+	var types []ast.Expr
+	for _, f := range fields.List {
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			types = append(types, f.Type)
+		}
+	}
+	return types
+}
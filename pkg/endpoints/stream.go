@@ -0,0 +1,186 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamWriter writes a chunked response, flushing after every write so the
+// client sees data as soon as it is produced.
+type StreamWriter struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context
+	mu  sync.Mutex
+}
+
+// Stream starts a chunked response with the given status and content type.
+func (c *Context) Stream(status int, contentType string) (*StreamWriter, error) {
+	rc := http.NewResponseController(c.Response)
+
+	c.Response.Header().Set("Content-Type", contentType)
+	c.Response.Header().Set("X-Content-Type-Options", "nosniff")
+	c.Response.Header().Del("Content-Length")
+	c.Response.WriteHeader(status)
+
+	if err := rc.Flush(); err != nil {
+		return nil, fmt.Errorf("streaming not supported: %w", err)
+	}
+	// Streams may outlive the server's write timeout.
+	rc.SetWriteDeadline(time.Time{})
+
+	return &StreamWriter{w: c.Response, rc: rc, ctx: c.Request.Context()}, nil
+}
+
+func (s *StreamWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := s.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, s.rc.Flush()
+}
+
+func (s *StreamWriter) WriteString(str string) (int, error) {
+	return s.Write([]byte(str))
+}
+
+// Done is closed when the client disconnects.
+func (s *StreamWriter) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// SSEEvent is a single Server-Sent Event. Data that is not a string or
+// []byte is encoded as JSON.
+type SSEEvent struct {
+	Event string
+	ID    string
+	Retry time.Duration
+	Data  any
+}
+
+// SSEWriter sends Server-Sent Events over a streaming response.
+type SSEWriter struct {
+	stream *StreamWriter
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// SSE switches the response to text/event-stream.
+func (c *Context) SSE() (*SSEWriter, error) {
+	c.Response.Header().Set("Cache-Control", "no-cache")
+	c.Response.Header().Set("Connection", "keep-alive")
+	c.Response.Header().Set("X-Accel-Buffering", "no")
+
+	stream, err := c.Stream(http.StatusOK, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSEWriter{stream: stream, stop: make(chan struct{})}, nil
+}
+
+// LastEventID returns the Last-Event-ID sent by a reconnecting client.
+func (c *Context) LastEventID() string {
+	return c.Request.Header.Get("Last-Event-ID")
+}
+
+func (s *SSEWriter) Send(event SSEEvent) error {
+	var sb strings.Builder
+
+	if event.ID != "" {
+		fmt.Fprintf(&sb, "id: %s\n", sanitizeSSEField(event.ID))
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&sb, "event: %s\n", sanitizeSSEField(event.Event))
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&sb, "retry: %d\n", event.Retry.Milliseconds())
+	}
+
+	data, err := encodeSSEData(event.Data)
+	if err != nil {
+		return err
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&sb, "data: %s\n", line)
+	}
+	sb.WriteString("\n")
+
+	_, err = s.stream.WriteString(sb.String())
+	return err
+}
+
+// SendData sends an unnamed event containing data.
+func (s *SSEWriter) SendData(data any) error {
+	return s.Send(SSEEvent{Data: data})
+}
+
+// Comment writes an SSE comment line, which clients ignore.
+func (s *SSEWriter) Comment(text string) error {
+	_, err := s.stream.WriteString(": " + sanitizeSSEField(text) + "\n\n")
+	return err
+}
+
+// KeepAlive sends a comment every interval until the client disconnects or
+// Close is called, keeping idle proxies from dropping the connection.
+func (s *SSEWriter) KeepAlive(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Comment("keep-alive"); err != nil {
+					return
+				}
+			case <-s.stream.Done():
+				return
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Done is closed when the client disconnects.
+func (s *SSEWriter) Done() <-chan struct{} {
+	return s.stream.Done()
+}
+
+func (s *SSEWriter) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+func encodeSSEData(data any) (string, error) {
+	switch v := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	}
+}
+
+func sanitizeSSEField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
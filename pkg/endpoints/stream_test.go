@@ -0,0 +1,347 @@
+package endpoints
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEFormatsEvents(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/events", nil)
+	ctx := NewContext(w, req, nil, nil)
+
+	sse, err := ctx.SSE()
+	if err != nil {
+		t.Fatalf("SSE failed: %v", err)
+	}
+	sse.Send(SSEEvent{ID: "1", Event: "greeting", Retry: 3 * time.Second, Data: "hello\nworld"})
+	sse.SendData(map[string]int{"count": 2})
+	sse.Close()
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+
+	expected := "id: 1\nevent: greeting\nretry: 3000\ndata: hello\ndata: world\n\n" +
+		"data: {\"count\":2}\n\n"
+	if w.Body.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, w.Body.String())
+	}
+	if !w.Flushed {
+		t.Error("Expected response to be flushed")
+	}
+}
+
+func TestStreamStopsAfterDisconnect(t *testing.T) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/api/feed", nil).WithContext(reqCtx)
+	w := httptest.NewRecorder()
+
+	stream, err := NewContext(w, req, nil, nil).Stream(http.StatusOK, "text/plain")
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	stream.WriteString("chunk 1\n")
+	cancel()
+
+	if _, err := stream.WriteString("chunk 2\n"); err == nil {
+		t.Error("Expected write after disconnect to fail")
+	}
+	select {
+	case <-stream.Done():
+	default:
+		t.Error("Expected Done to be closed")
+	}
+	if w.Body.String() != "chunk 1\n" {
+		t.Errorf("Expected single chunk, got %q", w.Body.String())
+	}
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	endpoint := &LoadedEndpoint{
+		Handlers: map[HTTPMethod]HandlerFunc{},
+		WebSocket: func(ctx *Context, conn *WSConn) error {
+			for {
+				msgType, data, err := conn.ReadMessage()
+				if err != nil {
+					return err
+				}
+				if err := conn.WriteMessage(msgType, data); err != nil {
+					return err
+				}
+			}
+		},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := HandleEndpoint(endpoint, w, r, nil, nil); err != nil {
+			WriteError(w, err)
+		}
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	handshake := "GET /api/ws HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: " + key + "\r\n\r\n"
+	conn.Write([]byte(handshake))
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("ReadResponse failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %d", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != acceptKey(key) {
+		t.Errorf("Unexpected accept key %q", accept)
+	}
+
+	writeClientFrame(conn, wsOpText, []byte("hello galaxy"))
+
+	var header [2]byte
+	io.ReadFull(br, header[:])
+	if header[0] != 0x80|wsOpText {
+		t.Fatalf("Expected final text frame, got %x", header[0])
+	}
+	payload := make([]byte, header[1]&0x7F)
+	io.ReadFull(br, payload)
+	if string(payload) != "hello galaxy" {
+		t.Errorf("Expected echo, got %q", payload)
+	}
+
+	closePayload := make([]byte, 2)
+	binary.BigEndian.PutUint16(closePayload, WSCloseNormal)
+	writeClientFrame(conn, wsOpClose, closePayload)
+
+	io.ReadFull(br, header[:])
+	if header[0]&0x0F != wsOpClose {
+		t.Errorf("Expected close frame, got %x", header[0])
+	}
+}
+
+func TestWebSocketRequiresUpgrade(t *testing.T) {
+	endpoint := &LoadedEndpoint{
+		Handlers:  map[HTTPMethod]HandlerFunc{},
+		WebSocket: func(ctx *Context, conn *WSConn) error { return nil },
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/ws", nil)
+	HandleEndpoint(endpoint, w, req, nil, nil)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for plain GET, got %d", w.Code)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	tests := []struct {
+		origin  string
+		allowed []string
+		ok      bool
+	}{
+		{"", nil, true},
+		{"http://example.com", nil, true},
+		{"http://evil.com", nil, false},
+		{"https://app.example.com", []string{"https://app.example.com/"}, true},
+		{"https://evil.com", []string{"https://app.example.com"}, false},
+		{"https://evil.com", []string{"*"}, true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://example.com/api/ws", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.allowed != nil {
+			req = req.WithContext(WithWebSocketOrigins(req.Context(), tt.allowed))
+		}
+
+		_, err := NewContext(httptest.NewRecorder(), req, nil, nil).Upgrade()
+		httpErr, forbidden := err.(*HTTPError)
+		forbidden = forbidden && httpErr.Status == http.StatusForbidden
+		if forbidden == tt.ok {
+			t.Errorf("Origin %q with %v: expected allowed=%v, got %v", tt.origin, tt.allowed, tt.ok, err)
+		}
+	}
+}
+
+// dialWebSocket opens a WebSocket connection to srv.
+func dialWebSocket(t *testing.T, srv *httptest.Server) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	conn.Write([]byte("GET /api/ws HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: " + key + "\r\n\r\n"))
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("ReadResponse failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %d", resp.StatusCode)
+	}
+	return conn, br
+}
+
+func webSocketServer(ws WSHandlerFunc) *httptest.Server {
+	endpoint := &LoadedEndpoint{Handlers: map[HTTPMethod]HandlerFunc{}, WebSocket: ws}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		HandleEndpoint(endpoint, w, r, nil, nil)
+	}))
+}
+
+// Run with -race: the peer's close must not race with concurrent writes.
+func TestWebSocketCloseWhileWriting(t *testing.T) {
+	done := make(chan error, 1)
+	srv := webSocketServer(func(ctx *Context, conn *WSConn) error {
+		writing := make(chan struct{})
+		go func() {
+			defer close(writing)
+			for conn.WriteText("tick") == nil {
+			}
+		}()
+		_, _, err := conn.ReadMessage()
+		<-writing
+		done <- err
+		return err
+	})
+	defer srv.Close()
+
+	conn, br := dialWebSocket(t, srv)
+	closePayload := make([]byte, 2)
+	binary.BigEndian.PutUint16(closePayload, WSCloseNormal)
+	writeClientFrame(conn, wsOpClose, closePayload)
+	io.Copy(io.Discard, br)
+
+	select {
+	case err := <-done:
+		if _, ok := err.(*WSCloseError); !ok {
+			t.Errorf("Expected WSCloseError, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the handler to finish")
+	}
+}
+
+func TestWebSocketRejectsBadControlFrames(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame func(w io.Writer)
+	}{
+		{"fragmented ping", func(w io.Writer) {
+			w.Write([]byte{wsOpPing, 0x80, 0, 0, 0, 0})
+		}},
+		{"oversized ping", func(w io.Writer) {
+			frame := []byte{0x80 | wsOpPing, 0x80 | 126, 0, 126, 0, 0, 0, 0}
+			w.Write(append(frame, make([]byte, 126)...))
+		}},
+	}
+
+	for _, tt := range tests {
+		errs := make(chan error, 1)
+		srv := webSocketServer(func(ctx *Context, conn *WSConn) error {
+			_, _, err := conn.ReadMessage()
+			errs <- err
+			return err
+		})
+
+		conn, br := dialWebSocket(t, srv)
+		tt.frame(conn)
+
+		var header [4]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			t.Fatalf("%s: expected a close frame, got %v", tt.name, err)
+		}
+		if header[0]&0x0F != wsOpClose || binary.BigEndian.Uint16(header[2:]) != WSCloseProtocolError {
+			t.Errorf("%s: expected close with %d, got %x", tt.name, WSCloseProtocolError, header)
+		}
+		if err := <-errs; err == nil || !strings.Contains(err.Error(), "protocol error") {
+			t.Errorf("%s: expected protocol error, got %v", tt.name, err)
+		}
+		srv.Close()
+	}
+}
+
+func TestWebSocketCloseCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler WSHandlerFunc
+		code    uint16
+	}{
+		{"clean exit", func(ctx *Context, conn *WSConn) error { return nil }, WSCloseNormal},
+		{"handler error", func(ctx *Context, conn *WSConn) error { return errors.New("boom") }, WSCloseInternalError},
+	}
+
+	for _, tt := range tests {
+		srv := webSocketServer(tt.handler)
+		_, br := dialWebSocket(t, srv)
+
+		var header [4]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			t.Fatalf("%s: expected a close frame, got %v", tt.name, err)
+		}
+		if header[0]&0x0F != wsOpClose || binary.BigEndian.Uint16(header[2:]) != tt.code {
+			t.Errorf("%s: expected close with %d, got %x", tt.name, tt.code, header)
+		}
+		srv.Close()
+	}
+}
+
+func TestParseSignaturesWebSocket(t *testing.T) {
+	src := `package api
+
+import "github.com/cameron-webmatter/galaxy/pkg/endpoints"
+
+func WS(ctx *endpoints.Context, conn *endpoints.WSConn) error { return nil }
+`
+	sigs, err := ParseSignaturesSource("chat.go", []byte(src))
+	if err != nil {
+		t.Fatalf("ParseSignaturesSource failed: %v", err)
+	}
+	if len(sigs) != 1 || sigs[0].Method != WS || sigs[0].Typed {
+		t.Errorf("Expected WS signature, got %+v", sigs)
+	}
+}
+
+func writeClientFrame(w io.Writer, opcode byte, payload []byte) {
+	mask := make([]byte, 4)
+	rand.Read(mask)
+
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	w.Write(frame)
+}
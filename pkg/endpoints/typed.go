@@ -0,0 +1,90 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Typed adapts the optional generic endpoint signature to a HandlerFunc.
+// The input, a struct or a pointer to one, is bound and validated with Bind;
+// the output is written as JSON.
+func Typed[In, Out any](fn func(*Context, In) (Out, error)) HandlerFunc {
+	return func(ctx *Context) error {
+		var in In
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+
+		out, err := fn(ctx, in)
+		if err != nil {
+			return err
+		}
+
+		status := http.StatusOK
+		if s, ok := any(out).(interface{ StatusCode() int }); ok {
+			status = s.StatusCode()
+		}
+		return ctx.JSON(status, out)
+	}
+}
+
+// HTTPError lets a handler return an error with a specific status code.
+type HTTPError struct {
+	Status  int
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+func NewHTTPError(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+type errorResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// WriteError renders a handler error. Validation and bind errors become
+// structured 400 responses, HTTPError keeps its status, anything else is a
+// plain 500.
+func WriteError(w http.ResponseWriter, err error) {
+	var verrs ValidationErrors
+	if errors.As(err, &verrs) {
+		writeErrorJSON(w, http.StatusBadRequest, errorResponse{Error: "validation failed", Fields: verrs})
+		return
+	}
+
+	var merr *http.MaxBytesError
+	if errors.As(err, &merr) {
+		writeErrorJSON(w, http.StatusRequestEntityTooLarge, errorResponse{Error: "request body too large"})
+		return
+	}
+
+	var berr *BindError
+	if errors.As(err, &berr) {
+		resp := errorResponse{Error: berr.Error()}
+		if berr.Field != "" {
+			resp.Fields = []FieldError{{Field: berr.Field, Rule: "type", Message: berr.Err.Error()}}
+		}
+		writeErrorJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		writeErrorJSON(w, herr.Status, errorResponse{Error: herr.Message})
+		return
+	}
+
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func writeErrorJSON(w http.ResponseWriter, status int, resp errorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
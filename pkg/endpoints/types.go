@@ -2,9 +2,9 @@ package endpoints
 
 import (
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
+
+	"github.com/cameron-webmatter/galaxy/pkg/session"
 )
 
 type HandlerFunc func(*Context) error
@@ -41,6 +41,16 @@ func (c *Context) Cookie(name string) (*http.Cookie, error) {
 	return c.Request.Cookie(name)
 }
 
+// Session returns the request's session. It is only persisted when session
+// middleware is configured.
+func (c *Context) Session() *session.Session {
+	return session.FromRequest(c.Request)
+}
+
+func (c *Context) Cookies() *session.Cookies {
+	return session.NewCookies(c.Response, c.Request)
+}
+
 func (c *Context) JSON(status int, data any) error {
 	c.Response.Header().Set("Content-Type", "application/json")
 	c.Response.WriteHeader(status)
@@ -65,24 +75,3 @@ func (c *Context) Redirect(url string, status int) error {
 	http.Redirect(c.Response, c.Request, url, status)
 	return nil
 }
-
-func (c *Context) BindJSON(v any) error {
-	if c.Request.Body == nil {
-		return fmt.Errorf("request body is nil")
-	}
-	defer c.Request.Body.Close()
-
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		return err
-	}
-
-	return json.Unmarshal(body, v)
-}
-
-func (c *Context) BindForm(v any) error {
-	if err := c.Request.ParseForm(); err != nil {
-		return err
-	}
-	return nil
-}
@@ -0,0 +1,212 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes a single failed validation rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is returned by Validate and the Bind helpers when one or
+// more `validate` rules fail. WriteError renders it as a 400 response.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks v against the rules in its `validate` struct tags.
+// Supported rules: required, min, max, len, email, url, oneof, and
+// omitempty, which skips the others for a zero value.
+//
+// Rules other than required only apply to fields the request supplied.
+// The Bind helpers know which those are; Validate on its own takes zero
+// values to be missing.
+func Validate(v any) error {
+	return validate(v, nil)
+}
+
+// validate checks v; seen holds the fields the request supplied, or is nil
+// when unknown.
+func validate(v any, seen fieldSet) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	validateStruct(rv, "", "", seen, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(rv reflect.Value, prefix, path string, seen fieldSet, errs *ValidationErrors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if field.Anonymous && fv.Kind() == reflect.Struct {
+			validateStruct(fv, prefix, path, seen, errs)
+			continue
+		}
+
+		name := prefix + fieldName(field)
+
+		if rules := field.Tag.Get("validate"); rules != "" {
+			missing := fv.IsZero()
+			if seen != nil {
+				missing = !seen[path+field.Name]
+			}
+			validateField(fv, name, rules, missing, errs)
+		}
+
+		nested := fv
+		if nested.Kind() == reflect.Ptr && !nested.IsNil() {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && nested.Type() != fileHeaderType.Elem() {
+			validateStruct(nested, name+".", path+field.Name+".", seen, errs)
+		}
+	}
+}
+
+// fieldName picks the name a client would recognise for a field, preferring
+// the tag it was bound from.
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "query", "param", "header"} {
+		if name := tagName(field, tag); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// validateField checks fv against rules. Only required applies to a field
+// that is missing, or zero and marked omitempty.
+func validateField(fv reflect.Value, name, rules string, missing bool, errs *ValidationErrors) {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			if hasRule(rules, "required") {
+				*errs = append(*errs, FieldError{Field: name, Rule: "required", Message: fmt.Sprintf("%s is required", name)})
+			}
+			return
+		}
+		fv = fv.Elem()
+	}
+	skip := missing || (hasRule(rules, "omitempty") && fv.IsZero())
+
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		key, param, _ := strings.Cut(rule, "=")
+		if key == "omitempty" || (key != "required" && skip) {
+			continue
+		}
+
+		if msg, ok := checkRule(fv, name, key, param); !ok {
+			*errs = append(*errs, FieldError{Field: name, Rule: key, Param: param, Message: msg})
+			if key == "required" {
+				return
+			}
+		}
+	}
+}
+
+func hasRule(rules, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if strings.TrimSpace(rule) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func checkRule(fv reflect.Value, name, rule, param string) (string, bool) {
+	switch rule {
+	case "required":
+		return fmt.Sprintf("%s is required", name), !fv.IsZero()
+	case "min":
+		n, _ := strconv.ParseFloat(param, 64)
+		return fmt.Sprintf("%s must be at least %s%s", name, param, unit(fv)), measure(fv) >= n
+	case "max":
+		n, _ := strconv.ParseFloat(param, 64)
+		return fmt.Sprintf("%s must be at most %s%s", name, param, unit(fv)), measure(fv) <= n
+	case "len":
+		n, _ := strconv.ParseFloat(param, 64)
+		return fmt.Sprintf("%s must be exactly %s%s", name, param, unit(fv)), measure(fv) == n
+	case "email":
+		addr, err := mail.ParseAddress(fv.String())
+		return fmt.Sprintf("%s must be a valid email address", name), err == nil && addr.Address == fv.String()
+	case "url":
+		u, err := url.Parse(fv.String())
+		return fmt.Sprintf("%s must be a valid URL", name), err == nil && u.Scheme != "" && u.Host != ""
+	case "oneof":
+		options := strings.Fields(param)
+		value := fmt.Sprintf("%v", fv.Interface())
+		for _, opt := range options {
+			if opt == value {
+				return "", true
+			}
+		}
+		return fmt.Sprintf("%s must be one of [%s]", name, strings.Join(options, " ")), false
+	default:
+		return fmt.Sprintf("%s has unknown validation rule %q", name, rule), false
+	}
+}
+
+// measure returns the length of strings and collections, or the numeric
+// value of numbers, so min/max/len share one comparison.
+func measure(fv reflect.Value) float64 {
+	switch fv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(fv.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return fv.Float()
+	default:
+		return 0
+	}
+}
+
+func unit(fv reflect.Value) string {
+	switch fv.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return " items"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,419 @@
+package endpoints
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/telemetry"
+)
+
+// WSHandlerFunc is the signature of a `WS` export in an endpoint file.
+type WSHandlerFunc func(*Context, *WSConn) error
+
+type WSMessageType int
+
+const (
+	WSText   WSMessageType = 1
+	WSBinary WSMessageType = 2
+)
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+
+	wsGUID           = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsMaxMessageSize = 16 << 20
+	// wsMaxControlSize is the largest ping, pong or close payload (RFC 6455
+	// section 5.5).
+	wsMaxControlSize = 125
+)
+
+const (
+	WSCloseNormal        = 1000
+	WSCloseGoingAway     = 1001
+	WSCloseProtocolError = 1002
+	WSCloseTooLarge      = 1009
+	WSCloseInternalError = 1011
+)
+
+// WSCloseError is returned by ReadMessage once the peer closes the connection.
+type WSCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WSCloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// WSConn is a server-side WebSocket connection. Reads must come from a single
+// goroutine; writes may be made concurrently.
+type WSConn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// IsWebSocketUpgrade reports whether r asks to upgrade to WebSocket.
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+type wsOriginsKey struct{}
+
+// WithWebSocketOrigins allows upgrades of requests with ctx from origins,
+// such as "https://app.example.com", besides the request's own host. "*"
+// allows any origin.
+func WithWebSocketOrigins(ctx context.Context, origins []string) context.Context {
+	return context.WithValue(ctx, wsOriginsKey{}, origins)
+}
+
+// checkOrigin reports whether r may be upgraded: browsers send Origin, so a
+// cross-origin page can't open a socket that carries the user's cookies.
+// Requests without Origin come from other clients and are allowed.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	allowed, _ := r.Context().Value(wsOriginsKey{}).([]string)
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Upgrade performs the WebSocket handshake and takes over the connection.
+// Upgrades from another origin are refused unless allowed with
+// WithWebSocketOrigins.
+func (c *Context) Upgrade() (*WSConn, error) {
+	r := c.Request
+	if r.Method != http.MethodGet || !IsWebSocketUpgrade(r) {
+		return nil, NewHTTPError(http.StatusBadRequest, "expected websocket upgrade")
+	}
+	if !checkOrigin(r) {
+		return nil, NewHTTPError(http.StatusForbidden, "websocket origin not allowed")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.Response.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, NewHTTPError(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, NewHTTPError(http.StatusBadRequest, "missing Sec-WebSocket-Key")
+	}
+
+	conn, brw, err := http.NewResponseController(c.Response).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("hijack connection: %w", err)
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := brw.WriteString(response); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &WSConn{conn: conn, br: brw.Reader}, nil
+}
+
+// ServeWebSocket upgrades the request and runs handler on the connection.
+// The socket closes with 1000 when handler returns nil or the peer closed
+// it, 1001 when the peer went away, and 1011 when handler fails, which is
+// logged: once upgraded, there is no HTTP response left to report it in.
+func ServeWebSocket(handler WSHandlerFunc, w http.ResponseWriter, r *http.Request, params map[string]string, locals map[string]any) error {
+	ctx := NewContext(w, r, params, locals)
+	conn, err := ctx.Upgrade()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = handler(ctx, conn)
+	var closeErr *WSCloseError
+	switch {
+	case err == nil || errors.As(err, &closeErr):
+	case errors.Is(err, io.EOF):
+		conn.CloseWithReason(WSCloseGoingAway, "")
+	default:
+		telemetry.Logger(r.Context()).Error("websocket handler failed", "path", r.URL.Path, "error", err)
+		conn.CloseWithReason(WSCloseInternalError, "internal error")
+	}
+	return nil
+}
+
+// ReadMessage returns the next complete text or binary message. Ping and
+// close frames are answered automatically.
+func (ws *WSConn) ReadMessage() (WSMessageType, []byte, error) {
+	var (
+		msgType WSMessageType
+		message []byte
+	)
+
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if err := ws.writeFrame(wsOpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			code, reason := WSCloseNormal, ""
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+				reason = string(payload[2:])
+			}
+			ws.writeMu.Lock()
+			if !ws.closed {
+				ws.closed = true
+				ws.writeRaw(wsOpClose, payload)
+			}
+			ws.writeMu.Unlock()
+			ws.conn.Close()
+			return 0, nil, &WSCloseError{Code: code, Reason: reason}
+		case wsOpText, wsOpBinary:
+			if message != nil {
+				return 0, nil, ws.protocolError("unexpected data frame during fragmented message")
+			}
+			msgType = WSMessageType(opcode)
+			message = payload
+		case wsOpContinuation:
+			if message == nil {
+				return 0, nil, ws.protocolError("unexpected continuation frame")
+			}
+			message = append(message, payload...)
+		default:
+			return 0, nil, ws.protocolError(fmt.Sprintf("unknown opcode %d", opcode))
+		}
+
+		if len(message) > wsMaxMessageSize {
+			ws.CloseWithReason(WSCloseTooLarge, "message too large")
+			return 0, nil, fmt.Errorf("websocket message exceeds %d bytes", wsMaxMessageSize)
+		}
+
+		if fin {
+			if message == nil {
+				message = []byte{}
+			}
+			return msgType, message, nil
+		}
+	}
+}
+
+func (ws *WSConn) ReadText() (string, error) {
+	_, data, err := ws.ReadMessage()
+	return string(data), err
+}
+
+func (ws *WSConn) ReadJSON(v any) error {
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (ws *WSConn) WriteMessage(msgType WSMessageType, data []byte) error {
+	return ws.writeFrame(byte(msgType), data)
+}
+
+func (ws *WSConn) WriteText(text string) error {
+	return ws.writeFrame(wsOpText, []byte(text))
+}
+
+func (ws *WSConn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.writeFrame(wsOpText, data)
+}
+
+func (ws *WSConn) Ping(data []byte) error {
+	return ws.writeFrame(wsOpPing, data)
+}
+
+func (ws *WSConn) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+func (ws *WSConn) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+func (ws *WSConn) Close() error {
+	return ws.CloseWithReason(WSCloseNormal, "")
+}
+
+func (ws *WSConn) CloseWithReason(code int, reason string) error {
+	ws.writeMu.Lock()
+	closed := ws.closed
+	ws.closed = true
+	ws.writeMu.Unlock()
+	if closed {
+		return nil
+	}
+
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], reason)
+
+	ws.writeMu.Lock()
+	ws.writeRaw(wsOpClose, payload)
+	ws.writeMu.Unlock()
+	return ws.conn.Close()
+}
+
+func (ws *WSConn) protocolError(msg string) error {
+	ws.CloseWithReason(WSCloseProtocolError, msg)
+	return errors.New("websocket protocol error: " + msg)
+}
+
+func (ws *WSConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(ws.br, header[:]); err != nil {
+		return
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(ws.br, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(ws.br, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if !masked {
+		err = ws.protocolError("client frames must be masked")
+		return
+	}
+	if opcode >= wsOpClose {
+		if !fin {
+			err = ws.protocolError("fragmented control frame")
+			return
+		}
+		if length > wsMaxControlSize {
+			err = ws.protocolError("control frame too large")
+			return
+		}
+	}
+	if length > wsMaxMessageSize {
+		ws.CloseWithReason(WSCloseTooLarge, "frame too large")
+		err = fmt.Errorf("websocket frame exceeds %d bytes", wsMaxMessageSize)
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.br, mask[:]); err != nil {
+		return
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return
+}
+
+func (ws *WSConn) writeFrame(opcode byte, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	if ws.closed && opcode != wsOpClose {
+		return net.ErrClosed
+	}
+	return ws.writeRaw(opcode, payload)
+}
+
+func (ws *WSConn) writeRaw(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	length := len(payload)
+	switch {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	if _, err := ws.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := ws.conn.Write(payload)
+	return err
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
@@ -33,13 +34,53 @@ type Context struct {
 	RedirectURL    string
 	RedirectStatus int
 	ShouldRedirect bool
-	PackageFuncs   map[string]PackageFunc
+	// NotFound is set by Galaxy.NotFound(); the page responds 404 instead
+	// of rendering.
+	NotFound     bool
+	PackageFuncs map[string]PackageFunc
 }
 
 type GalaxyAPI struct {
-	ctx    *Context
-	Params map[string]interface{}
-	Locals map[string]interface{}
+	ctx          *Context
+	Params       map[string]interface{}
+	Locals       map[string]interface{}
+	ActionResult interface{}
+	// Session and Cookies hold the request's *session.Session and
+	// *session.Cookies; their methods are called from frontmatter.
+	Session interface{}
+	Cookies interface{}
+	// CSRFToken is set when the CSRF middleware is enabled; the template
+	// engine adds it to POST forms.
+	CSRFToken string
+	Response  *Response
+	// Cache holds the request's *cache.Policy, e.g. Galaxy.Cache.TTL = 60.
+	Cache interface{}
+}
+
+// Response is the status code and headers set from frontmatter, e.g.
+// Galaxy.Response.Status = 410 or Galaxy.Response.Headers["Cache-Control"].
+type Response struct {
+	Status  int
+	Headers map[string]string
+}
+
+func NewResponse() *Response {
+	return &Response{Headers: make(map[string]string)}
+}
+
+// ApplyHeaders copies the headers set from frontmatter onto h.
+func (r *Response) ApplyHeaders(h http.Header) {
+	for name, value := range r.Headers {
+		h.Set(name, value)
+	}
+}
+
+// StatusOr returns the status set from frontmatter, or fallback.
+func (r *Response) StatusOr(fallback int) int {
+	if r.Status != 0 {
+		return r.Status
+	}
+	return fallback
 }
 
 func (g *GalaxyAPI) Redirect(url string, status int) {
@@ -48,6 +89,13 @@ func (g *GalaxyAPI) Redirect(url string, status int) {
 	g.ctx.ShouldRedirect = true
 }
 
+// NotFound stops the frontmatter and responds 404. Static builds skip the
+// path.
+func (g *GalaxyAPI) NotFound() {
+	g.Response.Status = http.StatusNotFound
+	g.ctx.NotFound = true
+}
+
 func NewContext() *Context {
 	ctx := &Context{
 		Variables:    make(map[string]interface{}),
@@ -65,9 +113,10 @@ func NewContext() *Context {
 	globalFuncsMutex.RUnlock()
 
 	galaxyAPI := &GalaxyAPI{
-		ctx:    ctx,
-		Params: make(map[string]interface{}),
-		Locals: ctx.Locals,
+		ctx:      ctx,
+		Params:   make(map[string]interface{}),
+		Locals:   ctx.Locals,
+		Response: NewResponse(),
 	}
 	ctx.Variables["Galaxy"] = galaxyAPI
 	return ctx
@@ -142,7 +191,7 @@ func (c *Context) Execute(code string) error {
 					if err := c.executeStmt(stmt); err != nil {
 						return err
 					}
-					if c.ShouldRedirect {
+					if c.halted() {
 						return nil
 					}
 				}
@@ -153,6 +202,11 @@ func (c *Context) Execute(code string) error {
 	return nil
 }
 
+// halted reports whether frontmatter ended the request early.
+func (c *Context) halted() bool {
+	return c.ShouldRedirect || c.NotFound
+}
+
 func (c *Context) executeStmt(stmt ast.Stmt) error {
 	switch s := stmt.(type) {
 	case *ast.IfStmt:
@@ -196,7 +250,7 @@ func (c *Context) executeIfStmt(stmt *ast.IfStmt) error {
 			if err := c.executeStmt(s); err != nil {
 				return err
 			}
-			if c.ShouldRedirect {
+			if c.halted() {
 				return nil
 			}
 		}
@@ -207,7 +261,7 @@ func (c *Context) executeIfStmt(stmt *ast.IfStmt) error {
 				if err := c.executeStmt(s); err != nil {
 					return err
 				}
-				if c.ShouldRedirect {
+				if c.halted() {
 					return nil
 				}
 			}
@@ -255,14 +309,94 @@ func (c *Context) executeAssignStmt(stmt *ast.AssignStmt) error {
 			return err
 		}
 
-		if ident, ok := lhs.(*ast.Ident); ok {
-			c.Variables[ident.Name] = val
+		if err := c.assign(lhs, val); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// assign stores val in a variable, a struct field or map entry such as
+// Galaxy.Response.Status, or an index such as headers["X-Id"].
+func (c *Context) assign(lhs ast.Expr, val interface{}) error {
+	switch l := lhs.(type) {
+	case *ast.Ident:
+		c.Variables[l.Name] = val
+		return nil
+
+	case *ast.SelectorExpr:
+		x, err := c.evalExpr(l.X)
+		if err != nil {
+			return err
+		}
+		if m, ok := x.(map[string]interface{}); ok {
+			m[l.Sel.Name] = val
+			return nil
+		}
+
+		v := reflect.ValueOf(x)
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return fmt.Errorf("cannot assign field %s on type %T", l.Sel.Name, x)
+		}
+		field := v.FieldByName(l.Sel.Name)
+		if !field.IsValid() || !field.CanSet() {
+			return fmt.Errorf("cannot assign field %s on type %T", l.Sel.Name, x)
+		}
+		converted, ok := convertValue(val, field.Type())
+		if !ok {
+			return fmt.Errorf("cannot assign %T to %s", val, l.Sel.Name)
+		}
+		field.Set(converted)
+		return nil
+
+	case *ast.IndexExpr:
+		x, err := c.evalExpr(l.X)
+		if err != nil {
+			return err
+		}
+		index, err := c.evalExpr(l.Index)
+		if err != nil {
+			return err
+		}
+
+		v := reflect.ValueOf(x)
+		switch v.Kind() {
+		case reflect.Map:
+			key, ok := convertValue(index, v.Type().Key())
+			if !ok {
+				return fmt.Errorf("invalid map key %v for %T", index, x)
+			}
+			elem, ok := convertValue(val, v.Type().Elem())
+			if !ok {
+				return fmt.Errorf("cannot assign %T into %T", val, x)
+			}
+			if v.IsNil() {
+				return fmt.Errorf("assignment to entry in nil map")
+			}
+			v.SetMapIndex(key, elem)
+			return nil
+		case reflect.Slice:
+			idx, ok := index.(int64)
+			if !ok || int(idx) < 0 || int(idx) >= v.Len() {
+				return fmt.Errorf("index out of bounds")
+			}
+			elem, ok := convertValue(val, v.Type().Elem())
+			if !ok {
+				return fmt.Errorf("cannot assign %T into %T", val, x)
+			}
+			v.Index(int(idx)).Set(elem)
+			return nil
+		}
+		return fmt.Errorf("invalid index assignment on type %T", x)
+	}
+
+	return nil
+}
+
 func (c *Context) processVarSpec(spec *ast.ValueSpec) error {
 	if len(spec.Names) == 2 && len(spec.Values) == 1 {
 		result, err := c.evalExpr(spec.Values[0])
@@ -467,6 +601,14 @@ func (c *Context) evalSelectorExpr(expr *ast.SelectorExpr) (interface{}, error)
 		}
 	}
 
+	if v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String {
+		val := v.MapIndex(reflect.ValueOf(expr.Sel.Name).Convert(v.Type().Key()))
+		if !val.IsValid() {
+			return nil, nil
+		}
+		return val.Interface(), nil
+	}
+
 	return nil, fmt.Errorf("cannot select field %s from type %T", expr.Sel.Name, x)
 }
 
@@ -613,36 +755,36 @@ func (c *Context) convertArgsToTypes(args []interface{}, method reflect.Value) (
 
 	for i, arg := range args {
 		paramType := methodType.In(i)
-		argValue := reflect.ValueOf(arg)
 
-		// Handle nil
-		if arg == nil {
-			values[i] = reflect.Zero(paramType)
-			continue
+		value, ok := convertValue(arg, paramType)
+		if !ok {
+			return nil, fmt.Errorf("cannot convert arg %d from %T to %v", i, arg, paramType)
 		}
+		values[i] = value
+	}
 
-		// Direct assignment
-		if argValue.Type().AssignableTo(paramType) {
-			values[i] = argValue
-			continue
-		}
+	return values, nil
+}
 
-		// Conversion
-		if argValue.Type().ConvertibleTo(paramType) {
-			values[i] = argValue.Convert(paramType)
-			continue
-		}
+func convertValue(val interface{}, t reflect.Type) (reflect.Value, bool) {
+	// Handle nil
+	if val == nil {
+		return reflect.Zero(t), true
+	}
 
-		// Special case: int64 -> int
-		if argValue.Kind() == reflect.Int64 && paramType.Kind() == reflect.Int {
-			values[i] = reflect.ValueOf(int(arg.(int64)))
-			continue
-		}
+	v := reflect.ValueOf(val)
 
-		return nil, fmt.Errorf("cannot convert arg %d from %v to %v", i, argValue.Type(), paramType)
+	// Direct assignment
+	if v.Type().AssignableTo(t) {
+		return v, true
 	}
 
-	return values, nil
+	// Conversion (covers int64 -> int); numbers never become strings
+	if v.Type().ConvertibleTo(t) && !(t.Kind() == reflect.String && v.Kind() != reflect.String) {
+		return v.Convert(t), true
+	}
+
+	return reflect.Value{}, false
 }
 
 func (c *Context) handleMethodReturns(results []reflect.Value) (interface{}, error) {
@@ -995,6 +1137,46 @@ func (c *Context) SetParams(params map[string]string) {
 	}
 }
 
+func (c *Context) SetActionResult(result interface{}) {
+	if galaxy, ok := c.Variables["Galaxy"].(*GalaxyAPI); ok {
+		galaxy.ActionResult = result
+	}
+}
+
+func (c *Context) SetSession(sess, cookies interface{}) {
+	if galaxy, ok := c.Variables["Galaxy"].(*GalaxyAPI); ok {
+		galaxy.Session = sess
+		galaxy.Cookies = cookies
+	}
+}
+
+func (c *Context) SetCSRFToken(token string) {
+	if galaxy, ok := c.Variables["Galaxy"].(*GalaxyAPI); ok {
+		galaxy.CSRFToken = token
+	}
+}
+
+func (c *Context) SetCache(policy interface{}) {
+	if galaxy, ok := c.Variables["Galaxy"].(*GalaxyAPI); ok {
+		galaxy.Cache = policy
+	}
+}
+
+func (c *Context) GetCSRFToken() string {
+	if galaxy, ok := c.Variables["Galaxy"].(*GalaxyAPI); ok {
+		return galaxy.CSRFToken
+	}
+	return ""
+}
+
+// GetResponse returns the status and headers set from frontmatter.
+func (c *Context) GetResponse() *Response {
+	if galaxy, ok := c.Variables["Galaxy"].(*GalaxyAPI); ok && galaxy.Response != nil {
+		return galaxy.Response
+	}
+	return NewResponse()
+}
+
 func (c *Context) GetParams() map[string]interface{} {
 	if galaxy, ok := c.Variables["Galaxy"].(*GalaxyAPI); ok {
 		return galaxy.Params
@@ -2,8 +2,12 @@ package executor
 
 import (
 	"fmt"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
+	"github.com/cameron-webmatter/galaxy/pkg/session"
 )
 
 func TestExecuteSimpleAssignment(t *testing.T) {
@@ -1292,3 +1296,133 @@ func TestMethodCallReturnsNilValue(t *testing.T) {
 		t.Errorf("Expected nil, got %v", result)
 	}
 }
+
+func TestGalaxySession(t *testing.T) {
+	sess := session.New()
+	sess.Set("user", "ada")
+	sess.AddFlash("notice", "Saved")
+
+	ctx := NewContext()
+	ctx.SetSession(sess, session.NewCookies(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)))
+
+	code := `
+var user = Galaxy.Session.GetString("user")
+var flashes = Galaxy.Session.Flashes("notice")
+Galaxy.Session.Set("visits", 1)
+var theme = Galaxy.Cookies.Get("theme")
+`
+
+	if err := ctx.Execute(code); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if user, _ := ctx.Get("user"); user != "ada" {
+		t.Errorf("Expected user ada, got %v", user)
+	}
+	if flashes, _ := ctx.Get("flashes"); fmt.Sprint(flashes) != "[Saved]" {
+		t.Errorf("Expected [Saved], got %v", flashes)
+	}
+	if sess.Get("visits") != int64(1) {
+		t.Errorf("Expected visits 1, got %v", sess.Get("visits"))
+	}
+	if theme, ok := ctx.Get("theme"); !ok || theme != "" {
+		t.Errorf("Expected empty theme, got %v", theme)
+	}
+}
+
+func TestGalaxyResponse(t *testing.T) {
+	ctx := NewContext()
+
+	code := `
+Galaxy.Response.Status = 410
+Galaxy.Response.Headers["Cache-Control"] = "max-age=60"
+`
+
+	if err := ctx.Execute(code); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	resp := ctx.GetResponse()
+	if resp.Status != 410 {
+		t.Errorf("Expected status 410, got %d", resp.Status)
+	}
+	if resp.Headers["Cache-Control"] != "max-age=60" {
+		t.Errorf("Expected Cache-Control header, got %v", resp.Headers)
+	}
+	if resp.StatusOr(200) != 410 || NewResponse().StatusOr(200) != 200 {
+		t.Error("Expected StatusOr to prefer the frontmatter status")
+	}
+}
+
+func TestGalaxyNotFoundStopsExecution(t *testing.T) {
+	ctx := NewContext()
+
+	code := `
+var post = nil
+if post == nil {
+	Galaxy.NotFound()
+}
+var after = "ran"
+`
+
+	if err := ctx.Execute(code); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if !ctx.NotFound {
+		t.Error("Expected NotFound to be set")
+	}
+	if ctx.GetResponse().Status != 404 {
+		t.Errorf("Expected status 404, got %d", ctx.GetResponse().Status)
+	}
+	if _, ok := ctx.Get("after"); ok {
+		t.Error("Expected execution to stop after NotFound")
+	}
+}
+
+func TestAssignMapIndex(t *testing.T) {
+	ctx := NewContext()
+
+	code := `
+var scores = map[string]int{"math": 95}
+scores["art"] = 70
+`
+
+	if err := ctx.Execute(code); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	scores, _ := ctx.Get("scores")
+	if m, ok := scores.(map[string]interface{}); !ok || m["art"] != int64(70) {
+		t.Errorf("Expected art=70, got %v", scores)
+	}
+
+	if err := ctx.Execute(`Galaxy.Response.Missing = 1`); err == nil {
+		t.Error("Expected error assigning unknown field")
+	}
+}
+
+func TestGalaxyCache(t *testing.T) {
+	policy := cache.PolicyFrom(httptest.NewRequest("GET", "/", nil))
+
+	ctx := NewContext()
+	ctx.SetCache(policy)
+
+	code := `
+Galaxy.Cache.TTL = 60
+Galaxy.Cache.StaleWhileRevalidate = 300
+Galaxy.Cache.Tag("posts")
+Galaxy.Cache.VaryBy("cookie:theme")
+`
+
+	if err := ctx.Execute(code); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if policy.TTL != 60 || policy.StaleWhileRevalidate != 300 {
+		t.Errorf("Expected TTL 60 and SWR 300, got %d and %d", policy.TTL, policy.StaleWhileRevalidate)
+	}
+	if fmt.Sprint(policy.Tags, policy.Vary) != "[posts] [cookie:theme]" {
+		t.Errorf("Expected posts tag and theme cookie vary, got %v %v", policy.Tags, policy.Vary)
+	}
+}
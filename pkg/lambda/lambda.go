@@ -0,0 +1,141 @@
+// Package lambda runs an http.Handler as an AWS Lambda function, the way
+// Netlify Functions, Vercel Functions and other Lambda-based platforms
+// invoke Go. Events in either API Gateway proxy format, which function URLs
+// also use, or Vercel's, become requests and the handler's response is
+// returned in the same format.
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Request is an API Gateway proxy event (payload format 1.0).
+type Request struct {
+	HTTPMethod                      string              `json:"httpMethod"`
+	Path                            string              `json:"path"`
+	Headers                         map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders,omitempty"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters,omitempty"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters,omitempty"`
+	Body                            string              `json:"body"`
+	IsBase64Encoded                 bool                `json:"isBase64Encoded"`
+	RequestContext                  RequestContext      `json:"requestContext"`
+}
+
+type RequestContext struct {
+	Identity struct {
+		SourceIP string `json:"sourceIp"`
+	} `json:"identity"`
+}
+
+// Response is the proxy response to a Request.
+type Response struct {
+	StatusCode        int                 `json:"statusCode"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+// NewRequest turns ev into a request carrying ctx.
+func NewRequest(ctx context.Context, ev *Request) (*http.Request, error) {
+	body := []byte(ev.Body)
+	if ev.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(ev.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	query := url.Values{}
+	for k, vs := range ev.MultiValueQueryStringParameters {
+		query[k] = append(query[k], vs...)
+	}
+	for k, v := range ev.QueryStringParameters {
+		if _, ok := query[k]; !ok {
+			query.Set(k, v)
+		}
+	}
+
+	path := ev.Path
+	if path == "" {
+		path = "/"
+	}
+	target := (&url.URL{Path: path, RawQuery: query.Encode()}).RequestURI()
+
+	method := ev.HTTPMethod
+	if method == "" {
+		method = http.MethodGet
+	}
+	r, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, vs := range ev.MultiValueHeaders {
+		for _, v := range vs {
+			r.Header.Add(k, v)
+		}
+	}
+	for k, v := range ev.Headers {
+		if r.Header.Get(k) == "" {
+			r.Header.Set(k, v)
+		}
+	}
+
+	r.Host = r.Header.Get("Host")
+	r.URL.Host = r.Host
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		r.URL.Scheme = proto
+	}
+	if ip := ev.RequestContext.Identity.SourceIP; ip != "" {
+		r.RemoteAddr = ip
+	}
+	return r, nil
+}
+
+// Invoke serves ev with h and returns its response, the way the platform
+// would. It runs functions locally, without the Lambda runtime.
+func Invoke(ctx context.Context, h http.Handler, ev *Request) *Response {
+	r, err := NewRequest(ctx, ev)
+	if err != nil {
+		return &Response{StatusCode: http.StatusBadRequest, Body: err.Error()}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return newResponse(rec)
+}
+
+func newResponse(rec *httptest.ResponseRecorder) *Response {
+	res := rec.Result()
+	out := &Response{StatusCode: res.StatusCode, MultiValueHeaders: map[string][]string(res.Header)}
+
+	body := rec.Body.Bytes()
+	if isBinary(res.Header, body) {
+		out.Body = base64.StdEncoding.EncodeToString(body)
+		out.IsBase64Encoded = true
+	} else {
+		out.Body = string(body)
+	}
+	return out
+}
+
+// isBinary reports whether a response body must be base64-encoded to
+// survive the JSON response.
+func isBinary(h http.Header, body []byte) bool {
+	if enc := h.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return true
+	}
+	ct := h.Get("Content-Type")
+	if strings.HasPrefix(ct, "image/") || strings.HasPrefix(ct, "font/") || ct == "application/octet-stream" || ct == "application/wasm" {
+		return true
+	}
+	return !utf8.Valid(body)
+}
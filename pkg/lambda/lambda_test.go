@@ -0,0 +1,222 @@
+package lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func echo() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+		if r.URL.Path == "/bin" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" "+r.Host+" "+r.Header.Get("X-Test")+" "+r.RemoteAddr+" "+string(body))
+	})
+}
+
+func TestInvoke(t *testing.T) {
+	ev := &Request{
+		HTTPMethod:                      "POST",
+		Path:                            "/blog/hello",
+		Headers:                         map[string]string{"Host": "example.com", "X-Test": "yes"},
+		MultiValueQueryStringParameters: map[string][]string{"tag": {"a", "b"}},
+		Body:                            base64.StdEncoding.EncodeToString([]byte("payload")),
+		IsBase64Encoded:                 true,
+	}
+	ev.RequestContext.Identity.SourceIP = "203.0.113.9"
+
+	res := Invoke(context.Background(), echo(), ev)
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("Expected 201, got %d", res.StatusCode)
+	}
+	want := "POST /blog/hello?tag=a&tag=b example.com yes 203.0.113.9 payload"
+	if res.Body != want || res.IsBase64Encoded {
+		t.Errorf("Expected %q, got %q (base64 %v)", want, res.Body, res.IsBase64Encoded)
+	}
+	if cookies := res.MultiValueHeaders["Set-Cookie"]; len(cookies) != 2 {
+		t.Errorf("Expected both cookies, got %v", cookies)
+	}
+
+	res = Invoke(context.Background(), echo(), &Request{Path: "/bin"})
+	if !res.IsBase64Encoded || res.Body != base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G'}) {
+		t.Errorf("Expected base64 binary body, got %q", res.Body)
+	}
+}
+
+func TestInvokeVercel(t *testing.T) {
+	req := &VercelRequest{
+		Host:    "example.vercel.app",
+		Path:    "/blog/hello?tag=a",
+		Method:  "PUT",
+		Headers: map[string]string{"x-test": "yes", "x-real-ip": "203.0.113.9"},
+		Body:    "payload",
+	}
+
+	res := InvokeVercel(context.Background(), echo(), req)
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("Expected 201, got %d", res.StatusCode)
+	}
+	want := "PUT /blog/hello?tag=a example.vercel.app yes 203.0.113.9 payload"
+	if res.Body != want || res.Encoding != "" {
+		t.Errorf("Expected %q, got %q (encoding %q)", want, res.Body, res.Encoding)
+	}
+	if cookies, ok := res.Headers["Set-Cookie"].([]string); !ok || len(cookies) != 2 {
+		t.Errorf("Expected both cookies, got %v", res.Headers["Set-Cookie"])
+	}
+
+	res = InvokeVercel(context.Background(), echo(), &VercelRequest{Path: "/bin"})
+	if res.Encoding != "base64" || res.Body != base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G'}) {
+		t.Errorf("Expected base64 binary body, got %q", res.Body)
+	}
+	if ct, ok := res.Headers["Content-Type"].(string); !ok || ct != "image/png" {
+		t.Errorf("Expected a single Content-Type, got %v", res.Headers["Content-Type"])
+	}
+}
+
+func TestInvokeV2(t *testing.T) {
+	ev := &V2Request{
+		Version:         "2.0",
+		RawPath:         "/prod/blog/hello",
+		RawQueryString:  "tag=a&tag=b",
+		Cookies:         []string{"session=abc", "theme=dark"},
+		Headers:         map[string]string{"host": "api.example.com", "x-test": "yes", "accept": "text/html,application/json"},
+		Body:            base64.StdEncoding.EncodeToString([]byte("payload")),
+		IsBase64Encoded: true,
+	}
+	ev.RequestContext.Stage = "prod"
+	ev.RequestContext.HTTP.Method = "POST"
+	ev.RequestContext.HTTP.SourceIP = "203.0.113.9"
+
+	var cookie, accept string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, accept = r.Header.Get("Cookie"), r.Header.Get("Accept")
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Cookie")
+		echo().ServeHTTP(w, r)
+	})
+
+	res := InvokeV2(context.Background(), h, ev)
+	want := "POST /blog/hello?tag=a&tag=b api.example.com yes 203.0.113.9 payload"
+	if res.StatusCode != http.StatusCreated || res.Body != want || res.IsBase64Encoded {
+		t.Errorf("Expected 201 %q, got %d %q", want, res.StatusCode, res.Body)
+	}
+	if cookie != "session=abc; theme=dark" || accept != "text/html,application/json" {
+		t.Errorf("Expected cookies and headers to reach the handler, got %q %q", cookie, accept)
+	}
+	if len(res.Cookies) != 2 || res.Headers["Set-Cookie"] != "" {
+		t.Errorf("Expected Set-Cookie values in cookies, got %v %v", res.Cookies, res.Headers)
+	}
+	if res.Headers["Vary"] != "Accept,Cookie" {
+		t.Errorf("Expected comma-joined headers, got %q", res.Headers["Vary"])
+	}
+
+	ev = &V2Request{Version: "2.0", RawPath: "/bin"}
+	ev.RequestContext.Stage = "$default"
+	res = InvokeV2(context.Background(), echo(), ev)
+	if !res.IsBase64Encoded || res.Body != base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G'}) {
+		t.Errorf("Expected base64 binary body, got %q", res.Body)
+	}
+}
+
+// runtimeAPI stands in for the Lambda runtime API, handing out events and
+// collecting the results posted back.
+type runtimeAPI struct {
+	mu      sync.Mutex
+	events  []string
+	results map[string]string
+	done    chan struct{}
+}
+
+func (api *runtimeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/2018-06-01/runtime/invocation/"
+	name := strings.TrimPrefix(r.URL.Path, prefix)
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if name == "next" {
+		if len(api.events) == 0 {
+			close(api.done)
+			http.Error(w, "no more events", http.StatusGone)
+			return
+		}
+		id := string(rune('a' + len(api.results)))
+		w.Header().Set("Lambda-Runtime-Aws-Request-Id", id)
+		w.Header().Set("Lambda-Runtime-Deadline-Ms", "9999999999999")
+		io.WriteString(w, api.events[0])
+		api.events = api.events[1:]
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	api.results[name] = string(body)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestStart(t *testing.T) {
+	api := &runtimeAPI{
+		events: []string{
+			`{"httpMethod":"GET","path":"/","headers":{"Host":"site"}}`,
+			`not json`,
+			`{"Action":"Invoke","body":"{\"method\":\"GET\",\"path\":\"/v\",\"host\":\"vercel\"}"}`,
+			`{"version":"2.0","rawPath":"/u","cookies":["a=1"],"requestContext":{"domainName":"url","http":{"method":"GET"}}}`,
+		},
+		results: make(map[string]string),
+		done:    make(chan struct{}),
+	}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	errc := make(chan error, 1)
+	go func() { errc <- Start(strings.TrimPrefix(srv.URL, "http://"), echo()) }()
+
+	select {
+	case <-api.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the runtime to drain the events")
+	}
+	if err := <-errc; err == nil || !strings.Contains(err.Error(), "410") {
+		t.Errorf("Expected Start to stop when the runtime API fails, got %v", err)
+	}
+
+	var res Response
+	if err := json.Unmarshal([]byte(api.results["a/response"]), &res); err != nil {
+		t.Fatalf("Expected a response for the first event, got %v", api.results)
+	}
+	if res.StatusCode != http.StatusCreated || !strings.HasPrefix(res.Body, "GET / site") {
+		t.Errorf("Unexpected response: %+v", res)
+	}
+	if !strings.Contains(api.results["b/error"], "errorMessage") {
+		t.Errorf("Expected an error for the malformed event, got %v", api.results)
+	}
+
+	var vres VercelResponse
+	if err := json.Unmarshal([]byte(api.results["c/response"]), &vres); err != nil {
+		t.Fatalf("Expected a response for the Vercel event, got %v", api.results)
+	}
+	if vres.StatusCode != http.StatusCreated || !strings.HasPrefix(vres.Body, "GET /v vercel") {
+		t.Errorf("Unexpected Vercel response: %+v", vres)
+	}
+
+	var v2res V2Response
+	if err := json.Unmarshal([]byte(api.results["d/response"]), &v2res); err != nil {
+		t.Fatalf("Expected a response for the function URL event, got %v", api.results)
+	}
+	if v2res.StatusCode != http.StatusCreated || !strings.HasPrefix(v2res.Body, "GET /u url") || len(v2res.Cookies) != 2 {
+		t.Errorf("Unexpected function URL response: %+v", v2res)
+	}
+}
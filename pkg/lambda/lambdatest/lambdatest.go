@@ -0,0 +1,76 @@
+// Package lambdatest runs a built Lambda function against a stand-in for the
+// Lambda runtime API, for adapter tests.
+package lambdatest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/lambda"
+)
+
+// Invoke runs function, hands it events one at a time through the runtime
+// API and returns its response to each, in order. The function is expected
+// to exit once the API has no more events.
+func Invoke(t testing.TB, function string, events ...string) [][]byte {
+	t.Helper()
+
+	var mu sync.Mutex
+	pending := events
+	results := make(map[string][]byte)
+	served := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		const prefix = "/2018-06-01/runtime/invocation/"
+		switch name := strings.TrimPrefix(r.URL.Path, prefix); {
+		case name == "next" && len(pending) > 0:
+			served++
+			w.Header().Set("Lambda-Runtime-Aws-Request-Id", requestID(served))
+			io.WriteString(w, pending[0])
+			pending = pending[1:]
+		case name == "next":
+			http.Error(w, "no more events", http.StatusGone)
+		case strings.HasSuffix(name, "/response"):
+			results[strings.TrimSuffix(name, "/response")], _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	cmd := exec.Command(function)
+	cmd.Env = append(os.Environ(), lambda.RuntimeAPIEnv+"="+strings.TrimPrefix(api.URL, "http://"))
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() { cmd.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("Expected the function to stop when the runtime API runs out of events")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	responses := make([][]byte, len(events))
+	for i := range events {
+		responses[i] = results[requestID(i+1)]
+	}
+	return responses
+}
+
+func requestID(n int) string {
+	return fmt.Sprintf("req-%d", n)
+}
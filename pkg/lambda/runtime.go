@@ -0,0 +1,121 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RuntimeAPIEnv names the environment variable Lambda sets to the address
+// of its runtime API.
+const RuntimeAPIEnv = "AWS_LAMBDA_RUNTIME_API"
+
+// Start serves invocations from the runtime API at api with h until the
+// runtime API fails. Each event is served as in Invoke, InvokeV2 or
+// InvokeVercel, with a context that ends at the invocation's deadline.
+func Start(api string, h http.Handler) error {
+	client := &http.Client{}
+	base := "http://" + api + "/2018-06-01/runtime/invocation/"
+
+	for {
+		resp, err := client.Get(base + "next")
+		if err != nil {
+			return fmt.Errorf("next invocation: %w", err)
+		}
+		payload, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("next invocation: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("next invocation: %s", resp.Status)
+		}
+
+		id := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+		if trace := resp.Header.Get("Lambda-Runtime-Trace-Id"); trace != "" {
+			os.Setenv("_X_AMZN_TRACE_ID", trace)
+		}
+
+		ctx, cancel := context.Background(), func() {}
+		if ms, err := strconv.ParseInt(resp.Header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64); err == nil {
+			ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(ms))
+		}
+
+		out, err := handle(ctx, h, payload)
+		cancel()
+		if err != nil {
+			if err := post(client, base+id+"/error", errorBody(err)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := post(client, base+id+"/response", out); err != nil {
+			return err
+		}
+	}
+}
+
+// handle serves one event: an API Gateway proxy event in payload format
+// 1.0 or 2.0, which function URLs also send, or, for functions deployed
+// to Vercel, a VercelEvent.
+func handle(ctx context.Context, h http.Handler, payload []byte) ([]byte, error) {
+	var kind struct {
+		Action  string `json:"Action"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(payload, &kind); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case kind.Action == "Invoke":
+		var vercel VercelEvent
+		if err := json.Unmarshal(payload, &vercel); err != nil {
+			return nil, err
+		}
+		var req VercelRequest
+		if err := json.Unmarshal([]byte(vercel.Body), &req); err != nil {
+			return nil, err
+		}
+		return json.Marshal(InvokeVercel(ctx, h, &req))
+	case kind.Version == "2.0":
+		var ev V2Request
+		if err := json.Unmarshal(payload, &ev); err != nil {
+			return nil, err
+		}
+		return json.Marshal(InvokeV2(ctx, h, &ev))
+	}
+
+	var ev Request
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return nil, err
+	}
+	return json.Marshal(Invoke(ctx, h, &ev))
+}
+
+func errorBody(err error) []byte {
+	body, _ := json.Marshal(map[string]string{
+		"errorMessage": err.Error(),
+		"errorType":    fmt.Sprintf("%T", err),
+	})
+	return body
+}
+
+func post(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("post invocation result: %w", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("post invocation result: %s", resp.Status)
+	}
+	return nil
+}
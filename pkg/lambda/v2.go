@@ -0,0 +1,126 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// V2Request is an event in payload format 2.0, which API Gateway HTTP APIs
+// and Lambda function URLs send. Headers with several values arrive
+// comma-separated, except cookies, which arrive in Cookies.
+type V2Request struct {
+	Version         string            `json:"version"`
+	RawPath         string            `json:"rawPath"`
+	RawQueryString  string            `json:"rawQueryString"`
+	Cookies         []string          `json:"cookies,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body,omitempty"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+	RequestContext  V2RequestContext  `json:"requestContext"`
+}
+
+type V2RequestContext struct {
+	DomainName string `json:"domainName"`
+	Stage      string `json:"stage"`
+	HTTP       struct {
+		Method   string `json:"method"`
+		Path     string `json:"path"`
+		Protocol string `json:"protocol"`
+		SourceIP string `json:"sourceIp"`
+	} `json:"http"`
+}
+
+// V2Response is the response to a V2Request. Headers with several values
+// are comma-separated, except Set-Cookie, whose values go in Cookies.
+type V2Response struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Cookies         []string          `json:"cookies,omitempty"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// NewV2Request turns ev into a request carrying ctx. A named stage's
+// prefix is removed from the path, so routes match as they do at the root.
+func NewV2Request(ctx context.Context, ev *V2Request) (*http.Request, error) {
+	body := []byte(ev.Body)
+	if ev.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(ev.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	path := ev.RawPath
+	if path == "" {
+		path = ev.RequestContext.HTTP.Path
+	}
+	if stage := ev.RequestContext.Stage; stage != "" && stage != "$default" {
+		if rest, ok := strings.CutPrefix(path, "/"+stage); ok && (rest == "" || rest[0] == '/') {
+			path = rest
+		}
+	}
+	if path == "" {
+		path = "/"
+	}
+	target := path
+	if ev.RawQueryString != "" {
+		target += "?" + ev.RawQueryString
+	}
+
+	method := ev.RequestContext.HTTP.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	r, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range ev.Headers {
+		r.Header.Set(k, v)
+	}
+	if len(ev.Cookies) > 0 {
+		r.Header.Set("Cookie", strings.Join(ev.Cookies, "; "))
+	}
+
+	r.Host = ev.RequestContext.DomainName
+	if host := r.Header.Get("Host"); host != "" {
+		r.Host = host
+	}
+	r.URL.Host = r.Host
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		r.URL.Scheme = proto
+	}
+	if ip := ev.RequestContext.HTTP.SourceIP; ip != "" {
+		r.RemoteAddr = ip
+	}
+	return r, nil
+}
+
+// InvokeV2 serves ev with h and returns its response, the way API Gateway
+// or a function URL would.
+func InvokeV2(ctx context.Context, h http.Handler, ev *V2Request) *V2Response {
+	r, err := NewV2Request(ctx, ev)
+	if err != nil {
+		return &V2Response{StatusCode: http.StatusBadRequest, Body: err.Error()}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	res := newResponse(rec)
+	out := &V2Response{StatusCode: res.StatusCode, Headers: make(map[string]string), Body: res.Body, IsBase64Encoded: res.IsBase64Encoded}
+	for k, vs := range res.MultiValueHeaders {
+		if k == "Set-Cookie" {
+			out.Cookies = vs
+			continue
+		}
+		out.Headers[k] = strings.Join(vs, ",")
+	}
+	return out
+}
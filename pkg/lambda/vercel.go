@@ -0,0 +1,91 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+)
+
+// VercelEvent is the event Vercel sends functions on a custom runtime. Body
+// holds the JSON-encoded VercelRequest.
+type VercelEvent struct {
+	Action string `json:"Action"`
+	Body   string `json:"body"`
+}
+
+// VercelRequest is the request a Vercel function serves.
+type VercelRequest struct {
+	Host     string            `json:"host"`
+	Path     string            `json:"path"`
+	Method   string            `json:"method"`
+	Headers  map[string]string `json:"headers"`
+	Encoding string            `json:"encoding,omitempty"`
+	Body     string            `json:"body"`
+}
+
+// VercelResponse is the response to a VercelRequest. A header with several
+// values, such as Set-Cookie, is a list.
+type VercelResponse struct {
+	StatusCode int            `json:"statusCode"`
+	Headers    map[string]any `json:"headers"`
+	Encoding   string         `json:"encoding,omitempty"`
+	Body       string         `json:"body"`
+}
+
+// InvokeVercel serves req with h and returns its response, the way Vercel
+// would.
+func InvokeVercel(ctx context.Context, h http.Handler, req *VercelRequest) *VercelResponse {
+	body := []byte(req.Body)
+	if req.Encoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(req.Body); err != nil {
+			return &VercelResponse{StatusCode: http.StatusBadRequest, Body: err.Error()}
+		}
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := req.Path
+	if path == "" {
+		path = "/"
+	}
+	r, err := http.NewRequestWithContext(ctx, method, path, bytes.NewReader(body))
+	if err != nil {
+		return &VercelResponse{StatusCode: http.StatusBadRequest, Body: err.Error()}
+	}
+	for k, v := range req.Headers {
+		r.Header.Set(k, v)
+	}
+	r.Host = req.Host
+	if r.Host == "" {
+		r.Host = r.Header.Get("Host")
+	}
+	r.URL.Host = r.Host
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		r.URL.Scheme = proto
+	}
+	if ip := r.Header.Get("X-Real-Ip"); ip != "" {
+		r.RemoteAddr = ip
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	res := newResponse(rec)
+	out := &VercelResponse{StatusCode: res.StatusCode, Headers: make(map[string]any), Body: res.Body}
+	if res.IsBase64Encoded {
+		out.Encoding = "base64"
+	}
+	for k, vs := range res.MultiValueHeaders {
+		if len(vs) == 1 {
+			out.Headers[k] = vs[0]
+		} else {
+			out.Headers[k] = vs
+		}
+	}
+	return out
+}
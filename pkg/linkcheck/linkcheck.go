@@ -0,0 +1,417 @@
+// Package linkcheck finds broken links and missing assets in a site's
+// pages: links to routes that don't exist, anchors missing from their page,
+// and public or bundled files that were never written. Issues point at the
+// .gxc line the link was written on.
+package linkcheck
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+)
+
+// Document is a page to check.
+type Document struct {
+	// Pattern is the route the page is served at. Components, which have
+	// none, only have their root-relative links checked.
+	Pattern string
+	// Source is the .gxc file the page is built from.
+	Source string
+	HTML   []byte
+	// Template marks a .gxc template rather than a rendered page. Links
+	// built by expressions are not seen, and anchors and bundled assets,
+	// which only exist once built, are not checked.
+	Template bool
+}
+
+// Issue is a broken link, with every page it was found on.
+type Issue struct {
+	File    string   `json:"file"`
+	Line    int      `json:"line,omitempty"`
+	Link    string   `json:"link"`
+	Message string   `json:"message"`
+	Pages   []string `json:"pages,omitempty"`
+}
+
+func (i Issue) String() string {
+	loc := i.File
+	if i.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", loc, i.Line)
+	}
+	s := fmt.Sprintf("%s: %q %s", loc, i.Link, i.Message)
+	if len(i.Pages) > 0 {
+		pages := i.Pages
+		if len(pages) > 3 {
+			pages = append(pages[:3:3], fmt.Sprintf("%d more", len(i.Pages)-3))
+		}
+		s += " (on " + strings.Join(pages, ", ") + ")"
+	}
+	return s
+}
+
+type Checker struct {
+	// Router holds the routes served on request; a static site has none,
+	// its pages being files.
+	Router *router.Router
+	Base   string
+	// Dirs are searched, in order, for the files links point at.
+	Dirs []string
+	// RootDir is the project; files in issues are named relative to it.
+	RootDir string
+	// Sources returns the files a page's HTML comes from, its layouts and
+	// components, to find where a link was written. It defaults to the
+	// page alone.
+	Sources func(page string) []string
+	// External also requests links to other sites.
+	External bool
+	Client   *http.Client
+}
+
+var (
+	linkTagRegex = regexp.MustCompile(`(?is)<(a|area|img|link|script|source|video|audio|iframe)\b[^>]*>`)
+	linkAttrs    = regexp.MustCompile(`(?is)\s(href|src|rel)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	anchorRegex  = regexp.MustCompile(`(?is)<[a-z][^>]*?\s(?:id|name)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// link is a link found on a page.
+type link struct {
+	raw string
+	doc *Document
+}
+
+// Check returns the broken links in docs, sorted by where they were
+// written.
+func (c *Checker) Check(docs []Document) []Issue {
+	base := strings.TrimSuffix(c.Base, "/")
+	anchors := make(map[string]map[string]bool, len(docs))
+	for i := range docs {
+		if !docs[i].Template && docs[i].Pattern != "" {
+			anchors[docs[i].Pattern] = findAnchors(docs[i].HTML)
+		}
+	}
+
+	issues := newIssues(c)
+	external := make(map[string][]link)
+	for i := range docs {
+		doc := &docs[i]
+		for _, raw := range findLinks(doc.HTML) {
+			if doc.Template && strings.ContainsAny(raw, "{}") {
+				continue
+			}
+			l := link{raw: raw, doc: doc}
+			if msg, ext := c.checkLink(l, base, anchors); ext {
+				external[raw] = append(external[raw], l)
+			} else if msg != "" {
+				issues.add(l, msg)
+			}
+		}
+	}
+
+	if c.External {
+		for raw, msg := range c.checkExternal(external) {
+			for _, l := range external[raw] {
+				issues.add(l, msg)
+			}
+		}
+	}
+	return issues.sorted()
+}
+
+// checkLink returns what is wrong with l, if anything, or reports that it
+// points to another site.
+func (c *Checker) checkLink(l link, base string, anchors map[string]map[string]bool) (msg string, external bool) {
+	raw := strings.TrimSpace(l.raw)
+	if raw == "" || raw == "#" {
+		return "", false
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return "is not a valid URL", false
+	}
+	switch {
+	case ref.Scheme == "http" || ref.Scheme == "https" || (ref.Scheme == "" && ref.Host != ""):
+		return "", true
+	case ref.Scheme != "":
+		// mailto:, tel:, data: and the like.
+		return "", false
+	}
+
+	doc := l.doc
+	if doc.Pattern == "" && !strings.HasPrefix(ref.Path, "/") {
+		return "", false
+	}
+	if ref.Path == "" && ref.RawQuery == "" {
+		if doc.Template || ref.Fragment == "" {
+			return "", false
+		}
+		if !hasAnchor(anchors[doc.Pattern], ref.Fragment) {
+			return fmt.Sprintf("has no #%s anchor on its page", ref.Fragment), false
+		}
+		return "", false
+	}
+
+	page := &url.URL{Path: base + doc.Pattern}
+	target := page.ResolveReference(ref).Path
+	if base != "" && target != base && !strings.HasPrefix(target, base+"/") {
+		return fmt.Sprintf("is outside the site's base %s", c.Base), false
+	}
+	rel := strings.TrimPrefix(target, base)
+	if rel == "" {
+		rel = "/"
+	}
+	if doc.Template && strings.HasPrefix(rel, "/_assets/") {
+		return "", false
+	}
+
+	ids, found := c.resolve(rel, anchors)
+	if !found {
+		if path.Ext(rel) != "" {
+			return "points to a missing file", false
+		}
+		return "points to no page", false
+	}
+	if ref.Fragment != "" && !doc.Template && ids != nil && !hasAnchor(ids, ref.Fragment) {
+		return fmt.Sprintf("has no #%s anchor on %s", ref.Fragment, rel), false
+	}
+	return "", false
+}
+
+// resolve finds what serves rel, a path below the base: a file, a page
+// built as a directory's index.html, or a route. It returns the anchors of
+// the page found, when known.
+func (c *Checker) resolve(rel string, anchors map[string]map[string]bool) (map[string]bool, bool) {
+	pattern := strings.TrimSuffix(rel, "/")
+	if pattern == "" {
+		pattern = "/"
+	}
+	ids, isDoc := anchors[pattern]
+
+	for _, dir := range c.Dirs {
+		name := filepath.Join(dir, filepath.FromSlash(rel))
+		info, err := os.Stat(name)
+		if err == nil && info.IsDir() {
+			name = filepath.Join(name, "index.html")
+			info, err = os.Stat(name)
+		}
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if isDoc || !strings.HasSuffix(name, ".html") {
+			return ids, true
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, true
+		}
+		return findAnchors(data), true
+	}
+
+	if c.Router != nil {
+		if route, _ := c.Router.Match(pattern); route != nil {
+			if route.Type == router.RouteStatic && isDoc {
+				return ids, true
+			}
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+// checkExternal requests each URL, a few at a time, and returns what is
+// wrong with those that fail.
+func (c *Checker) checkExternal(links map[string][]link) map[string]string {
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	var mu sync.Mutex
+	failed := make(map[string]string)
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for raw := range jobs {
+				if msg := requestExternal(client, raw); msg != "" {
+					mu.Lock()
+					failed[raw] = msg
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for raw := range links {
+		jobs <- raw
+	}
+	close(jobs)
+	wg.Wait()
+	return failed
+}
+
+// requestExternal asks for raw with HEAD, falling back to GET for servers
+// that don't answer HEAD.
+func requestExternal(client *http.Client, raw string) string {
+	target := raw
+	if strings.HasPrefix(target, "//") {
+		target = "https:" + target
+	}
+	status := 0
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequest(method, target, nil)
+		if err != nil {
+			return "is not a valid URL"
+		}
+		req.Header.Set("User-Agent", "galaxy-linkcheck")
+		resp, err := client.Do(req)
+		if err != nil {
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return fmt.Sprintf("is unreachable: %v", err)
+		}
+		resp.Body.Close()
+		status = resp.StatusCode
+		if status < 400 {
+			return ""
+		}
+		if status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented && status != http.StatusForbidden {
+			break
+		}
+	}
+	return fmt.Sprintf("returned %d %s", status, http.StatusText(status))
+}
+
+// findLinks returns the URLs a page links to or loads.
+func findLinks(html []byte) []string {
+	var links []string
+	for _, m := range linkTagRegex.FindAllSubmatch(html, -1) {
+		attrs := make(map[string]string)
+		for _, a := range linkAttrs.FindAllSubmatch(m[0], -1) {
+			attrs[strings.ToLower(string(a[1]))] = string(a[2]) + string(a[3])
+		}
+		href, hasHref := attrs["href"]
+		if hasHref && strings.EqualFold(string(m[1]), "link") {
+			switch strings.ToLower(attrs["rel"]) {
+			case "preconnect", "dns-prefetch":
+				// Origins, not pages.
+				hasHref = false
+			}
+		}
+		if hasHref {
+			links = append(links, href)
+		}
+		if src, ok := attrs["src"]; ok {
+			links = append(links, src)
+		}
+	}
+	return links
+}
+
+// hasAnchor reports whether fragment targets something on a page with ids.
+// Browsers scroll to the top for #top, and text fragments need no id.
+func hasAnchor(ids map[string]bool, fragment string) bool {
+	return ids[fragment] || strings.EqualFold(fragment, "top") || strings.HasPrefix(fragment, ":~:")
+}
+
+// findAnchors returns the ids, and names, a page's fragments can target.
+func findAnchors(html []byte) map[string]bool {
+	ids := make(map[string]bool)
+	for _, m := range anchorRegex.FindAllSubmatch(html, -1) {
+		ids[string(m[1])+string(m[2])] = true
+	}
+	return ids
+}
+
+// issues gathers the broken links found, merging those written at the
+// same place.
+type issues struct {
+	c     *Checker
+	byKey map[string]*Issue
+	lines map[string][]string
+}
+
+func newIssues(c *Checker) *issues {
+	return &issues{c: c, byKey: make(map[string]*Issue), lines: make(map[string][]string)}
+}
+
+func (s *issues) add(l link, msg string) {
+	file, line := s.locate(l)
+	key := fmt.Sprintf("%s:%d:%s:%s", file, line, l.raw, msg)
+	issue, ok := s.byKey[key]
+	if !ok {
+		issue = &Issue{File: s.name(file), Line: line, Link: l.raw, Message: msg}
+		s.byKey[key] = issue
+	}
+	if p := l.doc.Pattern; p != "" && !slices.Contains(issue.Pages, p) {
+		issue.Pages = append(issue.Pages, p)
+	}
+}
+
+// locate finds the line a link was written on: in the page, or else in the
+// layouts and components it uses.
+func (s *issues) locate(l link) (string, int) {
+	files := []string{l.doc.Source}
+	if s.c.Sources != nil && !l.doc.Template {
+		files = append(files, s.c.Sources(l.doc.Source)...)
+	}
+	for _, file := range files {
+		for i, text := range s.read(file) {
+			if strings.Contains(text, `"`+l.raw+`"`) || strings.Contains(text, `'`+l.raw+`'`) {
+				return file, i + 1
+			}
+		}
+	}
+	return l.doc.Source, 0
+}
+
+func (s *issues) read(file string) []string {
+	if lines, ok := s.lines[file]; ok {
+		return lines
+	}
+	data, _ := os.ReadFile(file)
+	lines := strings.Split(string(data), "\n")
+	s.lines[file] = lines
+	return lines
+}
+
+func (s *issues) name(file string) string {
+	if s.c.RootDir != "" {
+		if rel, err := filepath.Rel(s.c.RootDir, file); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return file
+}
+
+func (s *issues) sorted() []Issue {
+	list := make([]Issue, 0, len(s.byKey))
+	for _, issue := range s.byKey {
+		list = append(list, *issue)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Link < b.Link
+	})
+	return list
+}
@@ -0,0 +1,155 @@
+package linkcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func issueStrings(issues []Issue) string {
+	var lines []string
+	for _, issue := range issues {
+		lines = append(lines, issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	outDir := filepath.Join(dir, "dist")
+	layout := filepath.Join(dir, "src", "layouts", "Base.gxc")
+	index := filepath.Join(dir, "src", "pages", "index.gxc")
+	about := filepath.Join(dir, "src", "pages", "about.gxc")
+
+	writeFile(t, layout, "---\n---\n<nav>\n  <a href=\"/docs/abut\">About</a>\n</nav>\n<slot />\n")
+	writeFile(t, index, "---\n---\n<Base>\n  <a href=\"/docs/about#team\">Team</a>\n  <a href=\"#intro\">Intro</a>\n  <img src=\"/docs/logo.png\">\n</Base>\n")
+	writeFile(t, about, "---\n---\n<Base>\n  <a href=\"/blog\">Blog</a>\n  <a href=\"team\">Relative</a>\n</Base>\n")
+
+	indexHTML := `<nav><a href="/docs/abut">About</a></nav>
+<h1 id="intro">Hi</h1>
+<a href="/docs/about#team">Team</a>
+<a href="#intro">Intro</a>
+<a href="#top">Top</a>
+<img src="/docs/logo.png">
+<link rel="preconnect" href="https://fonts.example.com">
+<a href="mailto:hi@example.com">Mail</a>
+<a href="/docs/posts/hello">Post</a>
+<a href="/docs/api/ping">API</a>
+<script src="/docs/_assets/app.js"></script>`
+	aboutHTML := `<nav><a href="/docs/abut">About</a></nav>
+<a href="/blog">Blog</a>
+<a href="team">Relative</a>`
+	writeFile(t, filepath.Join(outDir, "index.html"), indexHTML)
+	writeFile(t, filepath.Join(outDir, "about", "index.html"), aboutHTML)
+	writeFile(t, filepath.Join(outDir, "_assets", "app.js"), "")
+
+	r := router.NewRouter(filepath.Join(dir, "src", "pages"))
+	r.Routes = []*router.Route{
+		{Pattern: "/api/ping", Type: router.RouteEndpoint, IsEndpoint: true},
+		{Pattern: "/posts/[slug]", Type: router.RouteDynamic, Regex: regexp.MustCompile("^/posts/([^/]+)$"), ParamNames: []string{"slug"}},
+	}
+
+	checker := &Checker{
+		Router:  r,
+		Base:    "/docs/",
+		Dirs:    []string{outDir},
+		RootDir: dir,
+		Sources: func(page string) []string { return []string{layout} },
+	}
+	issues := checker.Check([]Document{
+		{Pattern: "/", Source: index, HTML: []byte(indexHTML)},
+		{Pattern: "/about", Source: about, HTML: []byte(aboutHTML)},
+	})
+
+	expected := strings.Join([]string{
+		`src/layouts/Base.gxc:4: "/docs/abut" points to no page (on /, /about)`,
+		`src/pages/about.gxc:4: "/blog" is outside the site's base /docs/ (on /about)`,
+		`src/pages/about.gxc:5: "team" points to no page (on /about)`,
+		`src/pages/index.gxc:4: "/docs/about#team" has no #team anchor on /about (on /)`,
+		`src/pages/index.gxc:6: "/docs/logo.png" points to a missing file (on /)`,
+	}, "\n")
+	if got := issueStrings(issues); got != expected {
+		t.Errorf("Expected issues:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestCheckTemplates(t *testing.T) {
+	dir := t.TempDir()
+	publicDir := filepath.Join(dir, "public")
+	writeFile(t, filepath.Join(publicDir, "favicon.ico"), "")
+
+	r := router.NewRouter(filepath.Join(dir, "src", "pages"))
+	r.Routes = []*router.Route{{Pattern: "/", Type: router.RouteStatic}, {Pattern: "/about", Type: router.RouteStatic}}
+
+	page := filepath.Join(dir, "src", "pages", "index.gxc")
+	template := `<link rel="icon" href="/favicon.ico">
+<a href="/about">About</a>
+<a href="/contact">Contact</a>
+<a href="#anything">Anchor</a>
+<a href="/posts/{slug}">Post</a>
+<script src="/_assets/app.js"></script>`
+	writeFile(t, page, "---\nslug := \"x\"\n---\n"+template)
+
+	component := filepath.Join(dir, "src", "components", "Card.gxc")
+	writeFile(t, component, `<a href="details">Details</a><img src="/missing.svg">`)
+
+	checker := &Checker{Router: r, Base: "/", Dirs: []string{publicDir}, RootDir: dir}
+	issues := checker.Check([]Document{
+		{Pattern: "/", Source: page, HTML: []byte(template), Template: true},
+		{Source: component, HTML: []byte(`<a href="details">Details</a><img src="/missing.svg">`), Template: true},
+	})
+
+	expected := strings.Join([]string{
+		`src/components/Card.gxc:1: "/missing.svg" points to a missing file`,
+		`src/pages/index.gxc:6: "/contact" points to no page (on /)`,
+	}, "\n")
+	if got := issueStrings(issues); got != expected {
+		t.Errorf("Expected issues:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestCheckExternal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/get-only":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	html := `<a href="` + srv.URL + `/ok">a</a><a href="` + srv.URL + `/get-only">b</a><a href="` + srv.URL + `/gone">c</a>`
+	docs := []Document{{Pattern: "/", Source: "index.gxc", HTML: []byte(html)}}
+
+	if issues := (&Checker{}).Check(docs); len(issues) != 0 {
+		t.Errorf("Expected external links unchecked by default, got %v", issues)
+	}
+
+	issues := (&Checker{External: true}).Check(docs)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %v", issues)
+	}
+	if issues[0].Link != srv.URL+"/gone" || issues[0].Message != "returned 404 Not Found" {
+		t.Errorf("Expected the missing page to return 404, got %+v", issues[0])
+	}
+}
@@ -0,0 +1,98 @@
+package linkcheck
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Render fills in the HTML of pages by starting the server binary at
+// server on a free local port and requesting each page's Pattern. Pages
+// that don't answer 200 with HTML, like those behind a login, are left
+// out.
+func Render(server string, pages []Document) ([]Document, error) {
+	if len(pages) == 0 {
+		return nil, nil
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	addr := ln.Addr().String()
+	_, port, _ := net.SplitHostPort(addr)
+	ln.Close()
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(server)
+	cmd.Dir = filepath.Dir(server)
+	cmd.Env = append(os.Environ(), "HOST=127.0.0.1", "PORT="+port)
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start server: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	defer func() {
+		cmd.Process.Kill()
+		<-exited
+	}()
+
+	if err := waitForServer(addr, exited); err != nil {
+		return nil, fmt.Errorf("start server: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	var rendered []Document
+	for _, page := range pages {
+		resp, err := client.Get("http://" + addr + page.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("render %s: %w", page.Pattern, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("render %s: %w", page.Pattern, err)
+		}
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+			continue
+		}
+		page.HTML = body
+		rendered = append(rendered, page)
+	}
+	return rendered, nil
+}
+
+// waitForServer waits for addr to accept connections, giving up if the
+// server exits first. The exit is put back for the caller to see.
+func waitForServer(addr string, exited chan error) error {
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-exited:
+			exited <- err
+			if err == nil {
+				err = fmt.Errorf("server exited")
+			}
+			return err
+		default:
+		}
+		if conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond); err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("server did not listen on %s", addr)
+}
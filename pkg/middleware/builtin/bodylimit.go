@@ -0,0 +1,24 @@
+package builtin
+
+import (
+	"net/http"
+
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+)
+
+// BodyLimit rejects requests declaring a larger Content-Length with 413 and
+// caps the body reader for the rest, so reading past the limit fails with
+// *http.MaxBytesError.
+func BodyLimit(limit int64) middleware.Middleware {
+	return func(ctx *middleware.Context, next func() error) error {
+		if ctx.Request.ContentLength > limit {
+			http.Error(ctx.Response, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return nil
+		}
+
+		if ctx.Request.Body != nil {
+			ctx.Request.Body = http.MaxBytesReader(ctx.Response, ctx.Request.Body, limit)
+		}
+		return next()
+	}
+}
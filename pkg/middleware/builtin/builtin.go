@@ -0,0 +1,167 @@
+// Package builtin provides ready-made middleware for Galaxy servers. Each
+// constructor returns a middleware.Middleware usable in a Sequence, and
+// FromConfig builds the set enabled under [middleware] in galaxy.config.toml.
+package builtin
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+	"github.com/cameron-webmatter/galaxy/pkg/session"
+)
+
+// FromConfig returns the configured middleware in the order they should run:
+// request ID, logger, security headers, CORS, rate limit, body limit,
+// session, CSRF and compression.
+func FromConfig(cfg config.MiddlewareConfig) ([]middleware.Middleware, error) {
+	var mws []middleware.Middleware
+
+	if cfg.RequestID {
+		mws = append(mws, RequestID(RequestIDOptions{}))
+	}
+
+	if cfg.Logger {
+		mws = append(mws, Logger(LoggerOptions{}))
+	}
+
+	if cfg.Security.Enabled {
+		opts := DefaultSecurityOptions()
+		if cfg.Security.HSTSMaxAge > 0 {
+			opts.HSTSMaxAge = cfg.Security.HSTSMaxAge
+		}
+		opts.HSTSIncludeSubdomains = cfg.Security.HSTSIncludeSubdomains
+		if cfg.Security.FrameOptions != "" {
+			opts.FrameOptions = cfg.Security.FrameOptions
+		}
+		if cfg.Security.ReferrerPolicy != "" {
+			opts.ReferrerPolicy = cfg.Security.ReferrerPolicy
+		}
+		opts.ContentSecurityPolicy = cfg.Security.ContentSecurityPolicy
+		mws = append(mws, SecurityHeaders(opts))
+	}
+
+	if cfg.CORS.Enabled {
+		mws = append(mws, CORS(CORSOptions{
+			AllowOrigins:     cfg.CORS.AllowOrigins,
+			AllowMethods:     cfg.CORS.AllowMethods,
+			AllowHeaders:     cfg.CORS.AllowHeaders,
+			ExposeHeaders:    cfg.CORS.ExposeHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		}))
+	}
+
+	if cfg.RateLimit.Enabled {
+		if cfg.RateLimit.Rate <= 0 {
+			return nil, fmt.Errorf("middleware.rateLimit: rate must be positive")
+		}
+		mws = append(mws, RateLimit(RateLimitOptions{
+			Rate:  cfg.RateLimit.Rate,
+			Burst: cfg.RateLimit.Burst,
+		}))
+	}
+
+	if cfg.BodyLimit != "" {
+		limit, err := ParseSize(cfg.BodyLimit)
+		if err != nil {
+			return nil, fmt.Errorf("middleware.bodyLimit: %w", err)
+		}
+		mws = append(mws, BodyLimit(limit))
+	}
+
+	if cfg.Session.Enabled {
+		store, err := SessionStore(cfg.Session)
+		if err != nil {
+			return nil, fmt.Errorf("middleware.session: %w", err)
+		}
+		mws = append(mws, Session(store))
+	}
+
+	if cfg.CSRF.Enabled {
+		mws = append(mws, CSRF(CSRFOptions{
+			CookieName: cfg.CSRF.CookieName,
+			HeaderName: cfg.CSRF.HeaderName,
+			Exclude:    cfg.CSRF.Exclude,
+		}))
+	}
+
+	if cfg.Compress.Enabled {
+		mws = append(mws, Compress(CompressOptions{
+			Level:   cfg.Compress.Level,
+			MinSize: cfg.Compress.MinSize,
+		}))
+	}
+
+	return mws, nil
+}
+
+// SessionStore builds the store described by a [middleware.session] table.
+func SessionStore(cfg config.SessionConfig) (session.Store, error) {
+	secrets := cfg.Secrets
+	if env := os.Getenv("GALAXY_SESSION_SECRETS"); env != "" {
+		secrets = strings.Split(env, ",")
+	}
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("no secrets configured; set GALAXY_SESSION_SECRETS")
+	}
+
+	codec, err := session.NewCodec(cfg.Encrypt, secrets...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := session.Options{
+		Name:   cfg.Name,
+		MaxAge: time.Duration(cfg.MaxAge) * time.Second,
+		Secure: cfg.Secure,
+	}
+
+	switch cfg.Store {
+	case "", "cookie":
+		return session.NewCookieStore(codec, opts), nil
+	case "memory":
+		return session.NewMemoryStore(codec, opts), nil
+	case "file":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = ".galaxy/sessions"
+		}
+		return session.NewFileStore(dir, codec, opts)
+	default:
+		return nil, fmt.Errorf("unknown store %q (must be cookie, memory or file)", cfg.Store)
+	}
+}
+
+// ParseSize parses sizes such as "512", "64KB" or "10MB" (binary units).
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.size
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return n * multiplier, nil
+}
@@ -0,0 +1,404 @@
+package builtin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+)
+
+func serve(mws []middleware.Middleware, req *http.Request, handler func(ctx *middleware.Context) error) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	ctx := middleware.NewContext(w, req)
+	middleware.NewChain().Use(mws...).Execute(ctx, handler)
+	return w
+}
+
+func writeBody(body string) func(ctx *middleware.Context) error {
+	return func(ctx *middleware.Context) error {
+		ctx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(ctx.Response, body)
+		return nil
+	}
+}
+
+func TestCompressGzip(t *testing.T) {
+	body := strings.Repeat("<p>galaxy</p>", 200)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "deflate, gzip;q=0.8")
+
+	w := serve([]middleware.Middleware{Compress(CompressOptions{})}, req, writeBody(body))
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+	}
+
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Expected gzip body: %v", err)
+	}
+	decoded, _ := io.ReadAll(zr)
+	if string(decoded) != body {
+		t.Error("Decoded body does not match")
+	}
+}
+
+func TestCompressBrotli(t *testing.T) {
+	body := strings.Repeat("<p>galaxy</p>", 200)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "br;q=1, gzip;q=0.8")
+
+	w := serve([]middleware.Middleware{Compress(CompressOptions{})}, req, writeBody(body))
+
+	if w.Header().Get("Content-Encoding") != "br" {
+		t.Fatalf("Expected br encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	decoded, _ := io.ReadAll(brotli.NewReader(w.Body))
+	if string(decoded) != body {
+		t.Error("Decoded body does not match")
+	}
+}
+
+func TestCompressSkipsSmallAndBinary(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	mws := []middleware.Middleware{Compress(CompressOptions{})}
+
+	w := serve(mws, req, writeBody("<p>hi</p>"))
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "<p>hi</p>" {
+		t.Errorf("Expected small body to pass through, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	w = serve(mws, req, func(ctx *middleware.Context) error {
+		ctx.Response.Header().Set("Content-Type", "image/png")
+		ctx.Response.Write(bytes.Repeat([]byte{0x89}, 4096))
+		return nil
+	})
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Error("Expected images not to be compressed")
+	}
+}
+
+func TestCompressRegisteredEncoder(t *testing.T) {
+	RegisterEncoder("test", func(w io.Writer, level int) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, level)
+	})
+	defer RegisterEncoder("test", nil)
+
+	if got := negotiateEncoding("gzip, test", []string{"test", "gzip"}); got != "test" {
+		t.Errorf("Expected registered encoding to win, got %q", got)
+	}
+	if got := negotiateEncoding("gzip;q=0, zstd", []string{"zstd", "gzip"}); got != "" {
+		t.Errorf("Expected no encoding without a zstd encoder, got %q", got)
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	mws := []middleware.Middleware{CORS(CORSOptions{
+		AllowOrigins: []string{"https://*.example.com"},
+		AllowHeaders: []string{"Content-Type"},
+		MaxAge:       600,
+	})}
+
+	req := httptest.NewRequest("OPTIONS", "/api/posts", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+
+	called := false
+	w := serve(mws, req, func(ctx *middleware.Context) error {
+		called = true
+		return nil
+	})
+
+	if called || w.Code != http.StatusNoContent {
+		t.Errorf("Expected preflight to be answered with 204, got %d (handler called: %v)", w.Code, called)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("Expected origin to be echoed, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("Expected max age, got %q", w.Header().Get("Access-Control-Max-Age"))
+	}
+
+	req = httptest.NewRequest("GET", "/api/posts", nil)
+	req.Header.Set("Origin", "https://evil.test")
+	w = serve(mws, req, writeBody("ok"))
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Expected disallowed origin to get no CORS headers")
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	mws := []middleware.Middleware{SecurityHeaders(DefaultSecurityOptions())}
+
+	w := serve(mws, httptest.NewRequest("GET", "/", nil), writeBody("ok"))
+	if w.Header().Get("X-Frame-Options") != "DENY" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("Expected default security headers, got %v", w.Header())
+	}
+	if w.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("Expected no HSTS over plain HTTP")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w = serve(mws, req, writeBody("ok"))
+	if !strings.HasPrefix(w.Header().Get("Strict-Transport-Security"), "max-age=") {
+		t.Errorf("Expected HSTS over HTTPS, got %q", w.Header().Get("Strict-Transport-Security"))
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	mws := []middleware.Middleware{RequestID(RequestIDOptions{})}
+
+	var seen string
+	w := serve(mws, httptest.NewRequest("GET", "/", nil), func(ctx *middleware.Context) error {
+		seen = GetRequestID(ctx)
+		return nil
+	})
+	if len(seen) != 32 || w.Header().Get("X-Request-ID") != seen {
+		t.Errorf("Expected generated ID in Locals and header, got %q / %q", seen, w.Header().Get("X-Request-ID"))
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "upstream-123")
+	serve(mws, req, func(ctx *middleware.Context) error {
+		seen = ctx.Locals[RequestIDKey].(string)
+		return nil
+	})
+	if seen != "upstream-123" {
+		t.Errorf("Expected incoming ID to be kept, got %q", seen)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewLimiter(1, 2)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("Expected request %d to pass", i)
+		}
+	}
+
+	ok, _, retry := limiter.Allow("a")
+	if ok || retry != time.Second {
+		t.Errorf("Expected limit with 1s retry, got ok=%v retry=%v", ok, retry)
+	}
+	if ok, _, _ := limiter.Allow("b"); !ok {
+		t.Error("Expected other keys to have their own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _, _ := limiter.Allow("a"); !ok {
+		t.Error("Expected bucket to refill")
+	}
+}
+
+func TestRateLimitResponds429(t *testing.T) {
+	mws := []middleware.Middleware{RateLimit(RateLimitOptions{Rate: 1, Burst: 1})}
+
+	serve(mws, httptest.NewRequest("GET", "/", nil), writeBody("ok"))
+	w := serve(mws, httptest.NewRequest("GET", "/", nil), writeBody("ok"))
+
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After, got %d", w.Code)
+	}
+}
+
+func TestRateLimitNeedsRate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a zero rate to panic")
+		}
+	}()
+	RateLimit(RateLimitOptions{})
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.2:4000"
+	r.Header.Add("X-Forwarded-For", "1.1.1.1, 203.0.113.9")
+	r.Header.Add("X-Forwarded-For", "10.0.0.1")
+
+	tests := []struct {
+		proxies int
+		ip      string
+	}{
+		{0, "10.0.0.2"},
+		{1, "10.0.0.1"},
+		{2, "203.0.113.9"},
+		{5, "1.1.1.1"},
+	}
+	for _, tt := range tests {
+		if ip := ClientIP(r, tt.proxies); ip != tt.ip {
+			t.Errorf("Expected %s behind %d proxies, got %s", tt.ip, tt.proxies, ip)
+		}
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	mws := []middleware.Middleware{BodyLimit(8)}
+
+	w := serve(mws, httptest.NewRequest("POST", "/", strings.NewReader("0123456789")), writeBody("ok"))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader("0123456789"))
+	req.ContentLength = -1
+	var readErr error
+	serve(mws, req, func(ctx *middleware.Context) error {
+		_, readErr = io.ReadAll(ctx.Request.Body)
+		return nil
+	})
+	if _, ok := readErr.(*http.MaxBytesError); !ok {
+		t.Errorf("Expected MaxBytesError for chunked body, got %v", readErr)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var out bytes.Buffer
+	mws := []middleware.Middleware{RequestID(RequestIDOptions{Generator: func() string { return "abc" }}), Logger(LoggerOptions{Output: &out})}
+
+	serve(mws, httptest.NewRequest("GET", "/missing?x=1", nil), func(ctx *middleware.Context) error {
+		http.NotFound(ctx.Response, ctx.Request)
+		return nil
+	})
+
+	line := out.String()
+	if !strings.Contains(line, "GET /missing?x=1 404") || !strings.Contains(line, "id=abc") {
+		t.Errorf("Unexpected log line: %q", line)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	mws, err := FromConfig(config.MiddlewareConfig{
+		RequestID: true,
+		BodyLimit: "1MB",
+		Compress:  config.CompressConfig{Enabled: true},
+		CORS:      config.CORSConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+	if len(mws) != 4 {
+		t.Errorf("Expected 4 middleware, got %d", len(mws))
+	}
+
+	if _, err := FromConfig(config.MiddlewareConfig{BodyLimit: "lots"}); err == nil {
+		t.Error("Expected invalid body limit to fail")
+	}
+
+	if n, _ := ParseSize("64KB"); n != 64<<10 {
+		t.Errorf("Expected 65536, got %d", n)
+	}
+}
+
+func TestSessionMiddleware(t *testing.T) {
+	store, err := SessionStore(config.SessionConfig{Secrets: []string{"0123456789abcdef0123456789abcdef"}})
+	if err != nil {
+		t.Fatalf("SessionStore failed: %v", err)
+	}
+
+	w := serve([]middleware.Middleware{Session(store)}, httptest.NewRequest("GET", "/", nil), func(ctx *middleware.Context) error {
+		ctx.Session().Set("user", "ada")
+		return nil
+	})
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "galaxy_session" {
+		t.Fatalf("Expected session cookie, got %v", cookies)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookies[0])
+	var user string
+	serve([]middleware.Middleware{Session(store)}, req, func(ctx *middleware.Context) error {
+		user = ctx.Session().GetString("user")
+		return nil
+	})
+	if user != "ada" {
+		t.Errorf("Expected ada, got %q", user)
+	}
+}
+
+func TestSessionStoreConfig(t *testing.T) {
+	t.Setenv("GALAXY_SESSION_SECRETS", "")
+	if _, err := SessionStore(config.SessionConfig{}); err == nil {
+		t.Error("Expected error without secrets")
+	}
+
+	t.Setenv("GALAXY_SESSION_SECRETS", "0123456789abcdef0123456789abcdef,fedcba9876543210fedcba9876543210")
+	if _, err := SessionStore(config.SessionConfig{Store: "memory"}); err != nil {
+		t.Errorf("Expected secrets from env, got %v", err)
+	}
+	if _, err := SessionStore(config.SessionConfig{Store: "redis"}); err == nil {
+		t.Error("Expected unknown store to fail")
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	mws := []middleware.Middleware{CSRF(CSRFOptions{Exclude: []string{"/hooks/**"}})}
+
+	var token string
+	w := serve(mws, httptest.NewRequest("GET", "/", nil), func(ctx *middleware.Context) error {
+		token = CSRFToken(ctx.Request)
+		return nil
+	})
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || token == "" {
+		t.Fatalf("Expected CSRF cookie and token, got %v %q", cookies, token)
+	}
+
+	post := func(body, header string, path string) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		req.AddCookie(cookies[0])
+		return serve(mws, req, func(ctx *middleware.Context) error { return nil }).Code
+	}
+
+	if code := post(CSRFField+"="+token, "", "/"); code != http.StatusOK {
+		t.Errorf("Expected form token to pass, got %d", code)
+	}
+	if code := post("", token, "/"); code != http.StatusOK {
+		t.Errorf("Expected header token to pass, got %d", code)
+	}
+	if code := post("", "", "/"); code != http.StatusForbidden {
+		t.Errorf("Expected missing token to get 403, got %d", code)
+	}
+	if code := post(CSRFField+"=forged", "", "/"); code != http.StatusForbidden {
+		t.Errorf("Expected forged token to get 403, got %d", code)
+	}
+	if code := post("", "", "/hooks/stripe"); code != http.StatusOK {
+		t.Errorf("Expected excluded path to pass, got %d", code)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookies[0])
+	var second string
+	serve(mws, req, func(ctx *middleware.Context) error {
+		second = CSRFToken(ctx.Request)
+		return nil
+	})
+	if second == token {
+		t.Error("Expected tokens to be masked differently per call")
+	}
+	if code := post(CSRFField+"="+second, "", "/"); code != http.StatusOK {
+		t.Errorf("Expected second token to pass, got %d", code)
+	}
+}
@@ -0,0 +1,264 @@
+package builtin
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+)
+
+// EncoderFunc wraps w in a compressing writer for one content coding.
+type EncoderFunc func(w io.Writer, level int) (io.WriteCloser, error)
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]EncoderFunc{
+		"gzip": func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level == 0 {
+				level = gzip.DefaultCompression
+			}
+			return gzip.NewWriterLevel(w, level)
+		},
+		"br": func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level == 0 {
+				level = brotli.DefaultCompression
+			}
+			return brotli.NewWriterLevel(w, level), nil
+		},
+	}
+)
+
+// RegisterEncoder adds or replaces a content coding for Compress, such as
+// zstd. gzip and br are built in. A nil fn removes the encoding.
+func RegisterEncoder(name string, fn EncoderFunc) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	if fn == nil {
+		delete(encoders, name)
+		return
+	}
+	encoders[name] = fn
+}
+
+func lookupEncoder(name string) (EncoderFunc, bool) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	fn, ok := encoders[name]
+	return fn, ok
+}
+
+type CompressOptions struct {
+	// Level is passed to the encoder; 0 selects its default.
+	Level int
+	// MinSize is the smallest body worth compressing. Defaults to 1024.
+	MinSize int
+	// Encodings in order of server preference. Defaults to br, gzip;
+	// unregistered encodings are skipped.
+	Encodings []string
+}
+
+// Compress encodes responses with the best encoding the client accepts.
+// Small bodies, already-encoded responses, binary content types and
+// WebSocket upgrades are passed through untouched. Flushes (SSE, streaming)
+// are honoured.
+func Compress(opts CompressOptions) middleware.Middleware {
+	if opts.MinSize <= 0 {
+		opts.MinSize = 1024
+	}
+	if len(opts.Encodings) == 0 {
+		opts.Encodings = []string{"br", "gzip"}
+	}
+
+	return func(ctx *middleware.Context, next func() error) error {
+		if ctx.Request.Method == http.MethodHead || ctx.Request.Header.Get("Upgrade") != "" {
+			return next()
+		}
+
+		ctx.Response.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(ctx.Request.Header.Get("Accept-Encoding"), opts.Encodings)
+		if encoding == "" {
+			return next()
+		}
+		factory, _ := lookupEncoder(encoding)
+
+		cw := &compressWriter{
+			ResponseWriter: ctx.Response,
+			encoding:       encoding,
+			factory:        factory,
+			level:          opts.Level,
+			minSize:        opts.MinSize,
+			status:         http.StatusOK,
+		}
+		ctx.Response = cw
+
+		err := next()
+		ctx.Response = cw.ResponseWriter
+		if cerr := cw.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+}
+
+// negotiateEncoding picks the first preferred, registered encoding with a
+// non-zero q-value in the Accept-Encoding header.
+func negotiateEncoding(header string, preferred []string) string {
+	if header == "" {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	for _, name := range preferred {
+		q, ok := accepted[name]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if !ok || q <= 0 {
+			continue
+		}
+		if _, registered := lookupEncoder(name); registered {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// compressWriter buffers the first MinSize bytes to decide whether the
+// response is worth compressing, then streams through the encoder.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	factory  EncoderFunc
+	level    int
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		return
+	}
+	w.status = code
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.minSize {
+			return len(p), nil
+		}
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.start(len(w.buf) > 0)
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if err := w.start(len(w.buf) >= w.minSize); err != nil {
+			return err
+		}
+	}
+	if w.enc != nil {
+		return w.enc.Close()
+	}
+	return nil
+}
+
+// start commits the headers and writes any buffered body.
+func (w *compressWriter) start(compress bool) error {
+	w.decide(compress)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+	h := w.Header()
+
+	// Sniff before encoding, or the client would see the compressed bytes'
+	// type.
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if compress && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type"), w.buf) {
+		enc, err := w.factory(w.ResponseWriter, w.level)
+		if err == nil {
+			w.enc = enc
+			h.Set("Content-Encoding", w.encoding)
+			h.Del("Content-Length")
+			h.Del("Accept-Ranges")
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func compressible(contentType string, sniff []byte) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(sniff)
+	}
+	contentType = strings.ToLower(contentType)
+
+	if strings.HasPrefix(contentType, "text/") {
+		return true
+	}
+	for _, t := range []string{"json", "javascript", "xml", "svg", "wasm", "font/ttf", "font/otf"} {
+		if strings.Contains(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package builtin
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+)
+
+type CORSOptions struct {
+	// AllowOrigins lists allowed origins. "*" allows any origin and
+	// "https://*.example.com" any subdomain. Defaults to "*".
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	// MaxAge is how long, in seconds, browsers may cache a preflight.
+	MaxAge int
+}
+
+// CORS adds Access-Control-* headers for allowed origins and answers
+// preflight requests with 204 without calling the rest of the chain.
+func CORS(opts CORSOptions) middleware.Middleware {
+	if len(opts.AllowOrigins) == 0 {
+		opts.AllowOrigins = []string{"*"}
+	}
+	if len(opts.AllowMethods) == 0 {
+		opts.AllowMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	}
+
+	methods := strings.Join(opts.AllowMethods, ", ")
+	allowHeaders := strings.Join(opts.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(opts.ExposeHeaders, ", ")
+
+	return func(ctx *middleware.Context, next func() error) error {
+		h := ctx.Response.Header()
+		h.Add("Vary", "Origin")
+
+		origin := ctx.Request.Header.Get("Origin")
+		preflight := ctx.Request.Method == http.MethodOptions && ctx.Request.Header.Get("Access-Control-Request-Method") != ""
+
+		allowed, wildcard := matchOrigin(opts.AllowOrigins, origin)
+		if origin == "" || !allowed {
+			if preflight {
+				ctx.Response.WriteHeader(http.StatusNoContent)
+				return nil
+			}
+			return next()
+		}
+
+		if wildcard && !opts.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if opts.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			return next()
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		h.Set("Access-Control-Allow-Methods", methods)
+		if allowHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := ctx.Request.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			h.Set("Access-Control-Allow-Headers", requested)
+		}
+		if opts.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
+		}
+
+		ctx.Response.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+}
+
+func matchOrigin(allowed []string, origin string) (ok bool, wildcard bool) {
+	for _, pattern := range allowed {
+		if pattern == "*" {
+			return true, true
+		}
+		if strings.EqualFold(pattern, origin) {
+			return true, false
+		}
+		if i := strings.Index(pattern, "://*."); i >= 0 {
+			scheme, suffix := pattern[:i+3], pattern[i+4:]
+			if strings.HasPrefix(origin, scheme) && strings.HasSuffix(origin, suffix) && len(origin) > len(scheme)+len(suffix) {
+				return true, false
+			}
+		}
+	}
+	return false, false
+}
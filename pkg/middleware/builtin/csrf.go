@@ -0,0 +1,135 @@
+package builtin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+)
+
+// CSRFField is the form field carrying the token. The template engine adds
+// it to every POST form it renders.
+const CSRFField = "_csrf"
+
+const csrfTokenSize = 32
+
+type CSRFOptions struct {
+	// CookieName defaults to galaxy_csrf.
+	CookieName string
+	// HeaderName is checked before the form field, for fetch and JSON
+	// requests. Defaults to X-CSRF-Token.
+	HeaderName string
+	// Exclude lists path patterns (see middleware.MatchPath) that are not
+	// verified, such as webhook endpoints.
+	Exclude []string
+}
+
+type csrfContextKey struct{}
+
+// CSRF issues a per-browser secret cookie and rejects unsafe requests
+// (POST, PUT, PATCH, DELETE) whose header or form token does not match it
+// with 403.
+func CSRF(opts CSRFOptions) middleware.Middleware {
+	if opts.CookieName == "" {
+		opts.CookieName = "galaxy_csrf"
+	}
+	if opts.HeaderName == "" {
+		opts.HeaderName = "X-CSRF-Token"
+	}
+
+	return func(ctx *middleware.Context, next func() error) error {
+		secret := readCSRFSecret(ctx.Request, opts.CookieName)
+		if secret == nil {
+			secret = make([]byte, csrfTokenSize)
+			rand.Read(secret)
+			http.SetCookie(ctx.Response, &http.Cookie{
+				Name:     opts.CookieName,
+				Value:    base64.RawURLEncoding.EncodeToString(secret),
+				Path:     "/",
+				Secure:   isHTTPS(ctx),
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), csrfContextKey{}, secret))
+
+		if !isSafeMethod(ctx.Request.Method) && !excluded(opts.Exclude, ctx.Request.URL.Path) {
+			token := ctx.Request.Header.Get(opts.HeaderName)
+			if token == "" {
+				token = ctx.Request.PostFormValue(CSRFField)
+			}
+			if !validCSRFToken(secret, token) {
+				http.Error(ctx.Response, "Invalid CSRF token", http.StatusForbidden)
+				return nil
+			}
+		}
+
+		return next()
+	}
+}
+
+// CSRFToken returns a token for the request's CSRF secret, or "" when the
+// CSRF middleware is not in use. Each call returns a differently masked
+// token so compressed responses do not leak the secret.
+func CSRFToken(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	secret, _ := r.Context().Value(csrfContextKey{}).([]byte)
+	if secret == nil {
+		return ""
+	}
+
+	token := make([]byte, 2*csrfTokenSize)
+	rand.Read(token[:csrfTokenSize])
+	for i, b := range secret {
+		token[csrfTokenSize+i] = b ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+func readCSRFSecret(r *http.Request, name string) []byte {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(secret) != csrfTokenSize {
+		return nil
+	}
+	return secret
+}
+
+func validCSRFToken(secret []byte, token string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 2*csrfTokenSize {
+		return false
+	}
+
+	unmasked := make([]byte, csrfTokenSize)
+	for i := range unmasked {
+		unmasked[i] = raw[csrfTokenSize+i] ^ raw[i]
+	}
+	return subtle.ConstantTimeCompare(unmasked, secret) == 1
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func excluded(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if middleware.MatchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package builtin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+)
+
+type LoggerOptions struct {
+	// Output defaults to os.Stdout.
+	Output io.Writer
+}
+
+// Logger writes one access log line per request:
+//
+//	2024/01/02 15:04:05 GET /about 200 1532B 3ms id=4f1c...
+//
+// The id is included when RequestID runs earlier in the chain.
+func Logger(opts LoggerOptions) middleware.Middleware {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	return func(ctx *middleware.Context, next func() error) error {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: ctx.Response, status: http.StatusOK}
+		ctx.Response = rec
+
+		err := next()
+		ctx.Response = rec.ResponseWriter
+
+		status := rec.status
+		if err != nil && !rec.wroteHeader {
+			status = http.StatusInternalServerError
+		}
+
+		line := fmt.Sprintf("%s %s %s %d %dB %dms",
+			start.Format("2006/01/02 15:04:05"),
+			ctx.Request.Method,
+			ctx.Request.URL.RequestURI(),
+			status,
+			rec.bytes,
+			time.Since(start).Milliseconds())
+		if id := GetRequestID(ctx); id != "" {
+			line += " id=" + id
+		}
+		fmt.Fprintln(out, line)
+
+		return err
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,165 @@
+package builtin
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+)
+
+type RateLimitOptions struct {
+	// Rate is the number of requests per second refilled into each bucket.
+	Rate float64
+	// Burst is the bucket size. Defaults to Rate rounded up.
+	Burst int
+	// KeyFunc groups requests; defaults to the client IP.
+	KeyFunc func(*middleware.Context) string
+	// TrustProxy makes the default key come from X-Forwarded-For, as set by
+	// one reverse proxy in front of the server.
+	TrustProxy bool
+	// Proxies is the number of reverse proxies in front of the server, for
+	// chains of more than one. It implies TrustProxy.
+	Proxies int
+}
+
+// RateLimit rejects requests over the limit with 429 and a Retry-After
+// header, using an in-memory token bucket per key. It panics if Rate is not
+// positive.
+func RateLimit(opts RateLimitOptions) middleware.Middleware {
+	limiter := NewLimiter(opts.Rate, opts.Burst)
+
+	proxies := opts.Proxies
+	if proxies == 0 && opts.TrustProxy {
+		proxies = 1
+	}
+	keyFunc := opts.KeyFunc
+	if keyFunc == nil {
+		keyFunc = func(ctx *middleware.Context) string {
+			return ClientIP(ctx.Request, proxies)
+		}
+	}
+
+	return func(ctx *middleware.Context, next func() error) error {
+		ok, remaining, retryAfter := limiter.Allow(keyFunc(ctx))
+
+		h := ctx.Response.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(limiter.burst))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+		if !ok {
+			h.Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			http.Error(ctx.Response, "Too Many Requests", http.StatusTooManyRequests)
+			return nil
+		}
+
+		return next()
+	}
+}
+
+// Limiter is a set of token buckets keyed by string.
+type Limiter struct {
+	rate    float64
+	burst   int
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a Limiter refilling rate tokens per second, up to
+// burst. It panics if rate is not positive.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if !(rate > 0) {
+		panic("builtin: rate limit needs a positive rate")
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &Limiter{
+		rate:    rate,
+		burst:   burst,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token for key. It reports the tokens left and, when denied,
+// how long until the next token is available.
+func (l *Limiter) Allow(key string) (ok bool, remaining int, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.calls++
+	if l.calls%1024 == 0 {
+		l.prune(now)
+	}
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, 0, wait
+	}
+
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// prune drops buckets that have refilled completely; they behave the same
+// as a new bucket.
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ClientIP returns the request's remote IP or, behind the given number of
+// reverse proxies, the address the outermost of them saw. Each proxy appends the
+// address it received from to X-Forwarded-For, so that is the entry proxies
+// from the right; entries further left come from the client and can't be
+// trusted.
+func ClientIP(r *http.Request, proxies int) string {
+	if proxies > 0 {
+		var entries []string
+		for _, v := range r.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(v, ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					entries = append(entries, entry)
+				}
+			}
+		}
+		if len(entries) > 0 {
+			return entries[max(0, len(entries)-proxies)]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,66 @@
+package builtin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+)
+
+// RequestIDKey is the Locals key holding the request ID.
+const RequestIDKey = "requestId"
+
+type RequestIDOptions struct {
+	// Header is read from the request and echoed on the response.
+	// Defaults to X-Request-ID.
+	Header string
+	// Generator creates IDs for requests that arrive without one.
+	Generator func() string
+}
+
+// RequestID keeps a valid incoming request ID or generates one, stores it in
+// Locals under RequestIDKey and sets it on the request and response headers.
+func RequestID(opts RequestIDOptions) middleware.Middleware {
+	if opts.Header == "" {
+		opts.Header = "X-Request-ID"
+	}
+	if opts.Generator == nil {
+		opts.Generator = newRequestID
+	}
+
+	return func(ctx *middleware.Context, next func() error) error {
+		id := ctx.Request.Header.Get(opts.Header)
+		if !validRequestID(id) {
+			id = opts.Generator()
+			ctx.Request.Header.Set(opts.Header, id)
+		}
+
+		ctx.Set(RequestIDKey, id)
+		ctx.Response.Header().Set(opts.Header, id)
+		return next()
+	}
+}
+
+// GetRequestID returns the ID stored by RequestID, or "".
+func GetRequestID(ctx *middleware.Context) string {
+	id, _ := ctx.Get(RequestIDKey).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
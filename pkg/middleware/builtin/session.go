@@ -0,0 +1,25 @@
+package builtin
+
+import (
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+	"github.com/cameron-webmatter/galaxy/pkg/session"
+)
+
+// Session makes ctx.Session(), endpoint sessions and Galaxy.Session use
+// store. The session is loaded on first use and saved before the response
+// headers are written.
+func Session(store session.Store) middleware.Middleware {
+	return func(ctx *middleware.Context, next func() error) error {
+		ctx.Request = session.WithStore(ctx.Request, store)
+
+		sw := session.NewResponseWriter(ctx.Response, ctx.Request)
+		ctx.Response = sw
+
+		err := next()
+		ctx.Response = sw.ResponseWriter
+		if cerr := sw.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+}
@@ -1,5 +1,11 @@
 package middleware
 
+import (
+	"log/slog"
+
+	"github.com/cameron-webmatter/galaxy/pkg/telemetry"
+)
+
 type Chain struct {
 	middleware []Middleware
 }
@@ -10,23 +16,42 @@ func NewChain() *Chain {
 	}
 }
 
-func (c *Chain) Use(m Middleware) *Chain {
-	c.middleware = append(c.middleware, m)
+func (c *Chain) Use(mws ...Middleware) *Chain {
+	c.middleware = append(c.middleware, mws...)
 	return c
 }
 
-func (c *Chain) Execute(ctx *Context, final HandlerFunc) error {
-	ctx.middleware = c.middleware
-	ctx.index = -1
+func (c *Chain) Len() int {
+	return len(c.middleware)
+}
 
+func (c *Chain) Execute(ctx *Context, final HandlerFunc) error {
 	finalMiddleware := func(ctx *Context, next func() error) error {
 		return final(ctx)
 	}
 
-	allMiddleware := append(c.middleware, finalMiddleware)
+	// Copy so concurrent requests sharing a chain never append into the
+	// same backing array.
+	allMiddleware := make([]Middleware, 0, len(c.middleware)+1)
+	allMiddleware = append(allMiddleware, c.middleware...)
+	allMiddleware = append(allMiddleware, finalMiddleware)
+
 	ctx.middleware = allMiddleware
+	ctx.index = -1
+
+	if len(c.middleware) == 0 || ctx.Request == nil {
+		return ctx.Next()
+	}
+
+	spanCtx, span := telemetry.Start(ctx.Request.Context(), "middleware", slog.Int("middleware.count", len(c.middleware)))
+	defer span.End()
+	if span != nil {
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+	}
 
-	return ctx.Next()
+	err := ctx.Next()
+	span.RecordError(err)
+	return err
 }
 
 func Sequence(middlewares ...Middleware) []Middleware {
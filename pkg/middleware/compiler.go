@@ -14,7 +14,7 @@ type MiddlewareCompiler struct {
 	CacheDir   string
 	BaseDir    string
 	ModuleName string
-	cache      *cacheEntry
+	cache      map[string]*cacheEntry
 }
 
 type cacheEntry struct {
@@ -29,6 +29,7 @@ func NewCompiler(baseDir, cacheDir string) *MiddlewareCompiler {
 		CacheDir:   cacheDir,
 		BaseDir:    baseDir,
 		ModuleName: moduleName,
+		cache:      make(map[string]*cacheEntry),
 	}
 }
 
@@ -56,8 +57,8 @@ func (c *MiddlewareCompiler) Load(filePath string) (*LoadedMiddleware, error) {
 		return nil, err
 	}
 
-	if c.cache != nil && c.cache.modTime.Equal(info.ModTime()) {
-		return c.cache.middleware, nil
+	if entry, ok := c.cache[filePath]; ok && entry.modTime.Equal(info.ModTime()) {
+		return entry.middleware, nil
 	}
 
 	hasOnRequest, hasSequence := c.detectFunctions(filePath)
@@ -75,7 +76,7 @@ func (c *MiddlewareCompiler) Load(filePath string) (*LoadedMiddleware, error) {
 		return nil, err
 	}
 
-	c.cache = &cacheEntry{
+	c.cache[filePath] = &cacheEntry{
 		middleware: loaded,
 		modTime:    info.ModTime(),
 		soPath:     soPath,
@@ -85,6 +86,11 @@ func (c *MiddlewareCompiler) Load(filePath string) (*LoadedMiddleware, error) {
 }
 
 func (c *MiddlewareCompiler) detectFunctions(filePath string) (hasOnRequest, hasSequence bool) {
+	return Detect(filePath)
+}
+
+// Detect reports which of OnRequest and Sequence a middleware file declares.
+func Detect(filePath string) (hasOnRequest, hasSequence bool) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return false, false
@@ -109,6 +115,16 @@ func (c *MiddlewareCompiler) compile(filePath string, hasOnRequest, hasSequence
 	relPath, _ := filepath.Rel(c.BaseDir, srcDir)
 	importPath := filepath.Join(c.ModuleName, relPath)
 
+	// The go tool ignores files starting with "_", so directory middleware
+	// is compiled from a copy in its own package.
+	if strings.HasPrefix(filepath.Base(filePath), "_") {
+		copied, err := c.copySource(filePath, relPath)
+		if err != nil {
+			return "", err
+		}
+		importPath = copied
+	}
+
 	var exports strings.Builder
 	if hasOnRequest {
 		exports.WriteString("func OnRequest(ctx *middleware.Context, next func() error) error {\n")
@@ -145,3 +161,31 @@ import (
 
 	return soPath, nil
 }
+
+func (c *MiddlewareCompiler) copySource(filePath, relPath string) (string, error) {
+	sanitizedRelPath := strings.NewReplacer("[", "_", "]", "_", "...", "").Replace(relPath)
+	cacheSourceDir := filepath.Join(c.CacheDir, "src", sanitizedRelPath)
+	if err := os.MkdirAll(cacheSourceDir, 0755); err != nil {
+		return "", err
+	}
+
+	source, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(source), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "//go:build") && !strings.HasPrefix(trimmed, "// +build") {
+			lines = append(lines, line)
+		}
+	}
+
+	destPath := filepath.Join(cacheSourceDir, "middleware.go")
+	if err := os.WriteFile(destPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(filepath.Join(c.ModuleName, c.CacheDir, "src", sanitizedRelPath)), nil
+}
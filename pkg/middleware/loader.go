@@ -12,6 +12,21 @@ type LoadedMiddleware struct {
 	Sequence  []Middleware
 }
 
+// Middleware returns what the file contributes to a chain: its Sequence if
+// it declares one, otherwise OnRequest.
+func (lm *LoadedMiddleware) Middleware() []Middleware {
+	if lm == nil {
+		return nil
+	}
+	if len(lm.Sequence) > 0 {
+		return lm.Sequence
+	}
+	if lm.OnRequest != nil {
+		return []Middleware{lm.OnRequest}
+	}
+	return nil
+}
+
 func Load(projectDir string) (*LoadedMiddleware, error) {
 	middlewarePath := filepath.Join(projectDir, "src", "middleware.go")
 
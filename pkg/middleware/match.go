@@ -0,0 +1,69 @@
+package middleware
+
+import "strings"
+
+// Match runs the given middleware only for requests whose path matches
+// pattern; other requests go straight to next. Patterns are slash separated:
+// "*" matches a single segment and "**" any number of segments, so
+// "/admin/**" covers "/admin" and everything below it.
+func Match(pattern string, mws ...Middleware) Middleware {
+	composed := Compose(mws...)
+	return func(ctx *Context, next func() error) error {
+		if !MatchPath(pattern, ctx.Request.URL.Path) {
+			return next()
+		}
+		return composed(ctx, next)
+	}
+}
+
+// Compose folds several middleware into one, run in order.
+func Compose(mws ...Middleware) Middleware {
+	return func(ctx *Context, next func() error) error {
+		var run func(i int) error
+		run = func(i int) error {
+			if i >= len(mws) {
+				return next()
+			}
+			return mws[i](ctx, func() error {
+				return run(i + 1)
+			})
+		}
+		return run(0)
+	}
+}
+
+func MatchPath(pattern, path string) bool {
+	return matchSegments(splitPath(pattern), splitPath(path))
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		seg := pattern[0]
+		if seg == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(rest, path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(path) == 0 {
+			return false
+		}
+		if seg != "*" && seg != path[0] {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
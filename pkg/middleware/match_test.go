@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/admin/**", "/admin", true},
+		{"/admin/**", "/admin/users/1", true},
+		{"/admin/**", "/administrator", false},
+		{"/blog/*", "/blog/hello", true},
+		{"/blog/*", "/blog/hello/comments", false},
+		{"/**/edit", "/posts/1/edit", true},
+		{"/about", "/about/", true},
+		{"/**", "/", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, expected %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatchInSequence(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(ctx *Context, next func() error) error {
+			calls = append(calls, name)
+			return next()
+		}
+	}
+
+	chain := NewChain().Use(Sequence(
+		record("logger"),
+		Match("/admin/**", record("auth"), record("audit")),
+	)...)
+
+	for _, path := range []string{"/", "/admin/users"} {
+		ctx := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		chain.Execute(ctx, func(ctx *Context) error {
+			calls = append(calls, "handler")
+			return nil
+		})
+	}
+
+	expected := "logger,handler,logger,auth,audit,handler"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestMatchCanShortCircuit(t *testing.T) {
+	deny := func(ctx *Context, next func() error) error {
+		ctx.Response.WriteHeader(401)
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	ctx := NewContext(w, httptest.NewRequest("GET", "/admin", nil))
+	handled := false
+	NewChain().Use(Match("/admin/**", deny)).Execute(ctx, func(ctx *Context) error {
+		handled = true
+		return nil
+	})
+
+	if handled || w.Code != 401 {
+		t.Errorf("Expected 401 without reaching handler, got %d (handled=%v)", w.Code, handled)
+	}
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"net/http"
+
+	"github.com/cameron-webmatter/galaxy/pkg/session"
 )
 
 type HandlerFunc func(*Context) error
@@ -44,6 +46,16 @@ func (c *Context) Next() error {
 	return nil
 }
 
+// Session returns the request's session. It is only persisted when session
+// middleware runs earlier in the chain.
+func (c *Context) Session() *session.Session {
+	return session.FromRequest(c.Request)
+}
+
+func (c *Context) Cookies() *session.Cookies {
+	return session.NewCookies(c.Response, c.Request)
+}
+
 func (c *Context) Redirect(url string, status int) {
 	http.Redirect(c.Response, c.Request, url, status)
 }
@@ -0,0 +1,504 @@
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/endpoints"
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+)
+
+var (
+	catchAllParamRegex = regexp.MustCompile(`\[\.\.\.(\w+)\]`)
+	paramRegex         = regexp.MustCompile(`\[(\w+)\]`)
+)
+
+type Generator struct {
+	Title   string
+	Version string
+
+	doc      *Document
+	packages map[string]map[string]*ast.TypeSpec
+}
+
+func NewGenerator(title, version string) *Generator {
+	return &Generator{
+		Title:    title,
+		Version:  version,
+		packages: make(map[string]map[string]*ast.TypeSpec),
+	}
+}
+
+// Generate builds an OpenAPI 3 document for every Go endpoint in routes.
+func (g *Generator) Generate(routes []*router.Route) (*Document, error) {
+	g.doc = &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: g.Title, Version: g.Version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+	}
+
+	for _, route := range routes {
+		if !route.IsEndpoint {
+			continue
+		}
+
+		sigs, err := endpoints.ParseSignatures(route.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", route.FilePath, err)
+		}
+
+		types, err := g.packageTypes(filepath.Dir(route.FilePath))
+		if err != nil {
+			return nil, fmt.Errorf("load types for %s: %w", route.FilePath, err)
+		}
+
+		path := ConvertPattern(route.Pattern)
+		item := PathItem{}
+		for _, sig := range sigs {
+			if sig.Method == endpoints.WS {
+				continue
+			}
+			methods := []string{string(sig.Method)}
+			if sig.Method == endpoints.ALL {
+				methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+			}
+			for _, method := range methods {
+				item[strings.ToLower(method)] = g.operation(route, method, sig, types)
+			}
+		}
+
+		if len(item) > 0 {
+			g.doc.Paths[path] = item
+		}
+	}
+
+	return g.doc, nil
+}
+
+// ConvertPattern rewrites a router pattern such as /api/users/[id] into the
+// OpenAPI path template /api/users/{id}.
+func ConvertPattern(pattern string) string {
+	path := catchAllParamRegex.ReplaceAllString(pattern, "{$1}")
+	return paramRegex.ReplaceAllString(path, "{$1}")
+}
+
+func (g *Generator) operation(route *router.Route, method string, sig endpoints.Signature, types map[string]*ast.TypeSpec) *Operation {
+	op := &Operation{
+		OperationID: operationID(method, route.Pattern),
+		Responses:   make(map[string]*Response),
+	}
+
+	if doc := strings.TrimSpace(sig.Doc); doc != "" {
+		summary, description, _ := strings.Cut(doc, "\n")
+		op.Summary = strings.TrimSpace(summary)
+		op.Description = strings.TrimSpace(description)
+	}
+
+	declared := make(map[string]bool)
+
+	if sig.Typed {
+		g.inputParameters(op, method, sig.Input, types, declared)
+
+		op.Responses["200"] = &Response{
+			Description: "Successful response",
+			Content: map[string]*MediaType{
+				"application/json": {Schema: g.schemaFor(sig.Output, types)},
+			},
+		}
+		op.Responses["400"] = &Response{
+			Description: "Invalid request",
+			Content: map[string]*MediaType{
+				"application/json": {Schema: g.validationErrorSchema()},
+			},
+		}
+	} else {
+		op.Responses["200"] = &Response{Description: "Successful response"}
+	}
+
+	for _, name := range route.ParamNames {
+		if declared["path:"+name] {
+			continue
+		}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+
+	return op
+}
+
+func (g *Generator) inputParameters(op *Operation, method string, input ast.Expr, types map[string]*ast.TypeSpec, declared map[string]bool) {
+	st := resolveStruct(input, types)
+	if st == nil {
+		return
+	}
+
+	jsonBody := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	formBody := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	multipart := false
+
+	for _, field := range flattenFields(st, types) {
+		tag := fieldTag(field)
+		rules := tag.Get("validate")
+		required := hasRule(rules, "required")
+
+		for _, loc := range []struct{ tag, in string }{{"param", "path"}, {"query", "query"}, {"header", "header"}} {
+			name := tagName(tag, loc.tag)
+			if name == "" {
+				continue
+			}
+			schema := g.schemaFor(field.Type, types)
+			applyRules(schema, rules)
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:     name,
+				In:       loc.in,
+				Required: required || loc.in == "path",
+				Schema:   schema,
+			})
+			declared[loc.in+":"+name] = true
+		}
+
+		if name := tagName(tag, "form"); name != "" {
+			schema := g.schemaFor(field.Type, types)
+			if schema.Format == "binary" || (schema.Items != nil && schema.Items.Format == "binary") {
+				multipart = true
+			}
+			applyRules(schema, rules)
+			formBody.Properties[name] = schema
+			if required {
+				formBody.Required = append(formBody.Required, name)
+			}
+		}
+
+		if name := tagName(tag, "json"); name != "" {
+			schema := g.schemaFor(field.Type, types)
+			applyRules(schema, rules)
+			jsonBody.Properties[name] = schema
+			if required {
+				jsonBody.Required = append(jsonBody.Required, name)
+			}
+		}
+	}
+
+	if method == "GET" || method == "HEAD" || method == "DELETE" {
+		return
+	}
+
+	content := make(map[string]*MediaType)
+	if len(jsonBody.Properties) > 0 {
+		content["application/json"] = &MediaType{Schema: jsonBody}
+	}
+	if len(formBody.Properties) > 0 {
+		mediaType := "application/x-www-form-urlencoded"
+		if multipart {
+			mediaType = "multipart/form-data"
+		}
+		content[mediaType] = &MediaType{Schema: formBody}
+	}
+	if len(content) > 0 {
+		op.RequestBody = &RequestBody{
+			Required: len(jsonBody.Required) > 0 || len(formBody.Required) > 0,
+			Content:  content,
+		}
+	}
+}
+
+func (g *Generator) schemaFor(expr ast.Expr, types map[string]*ast.TypeSpec) *Schema {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if s := basicSchema(t.Name); s != nil {
+			return s
+		}
+		if spec, ok := types[t.Name]; ok {
+			return g.componentRef(t.Name, spec, types)
+		}
+		return &Schema{Type: "object"}
+	case *ast.StarExpr:
+		return g.schemaFor(t.X, types)
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elt, types)}
+	case *ast.MapType:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Value, types)}
+	case *ast.StructType:
+		return g.structSchema(t, types)
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok {
+			switch pkg.Name + "." + t.Sel.Name {
+			case "time.Time":
+				return &Schema{Type: "string", Format: "date-time"}
+			case "time.Duration":
+				return &Schema{Type: "integer", Format: "int64"}
+			case "multipart.FileHeader":
+				return &Schema{Type: "string", Format: "binary"}
+			}
+		}
+		return &Schema{Type: "object"}
+	case *ast.InterfaceType:
+		return &Schema{}
+	default:
+		return &Schema{Type: "object"}
+	}
+}
+
+func (g *Generator) componentRef(name string, spec *ast.TypeSpec, types map[string]*ast.TypeSpec) *Schema {
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := g.doc.Components.Schemas[name]; ok {
+		return ref
+	}
+
+	// Reserve the name before descending so recursive types terminate.
+	g.doc.Components.Schemas[name] = &Schema{}
+	*g.doc.Components.Schemas[name] = *g.schemaFor(spec.Type, types)
+	return ref
+}
+
+func (g *Generator) structSchema(st *ast.StructType, types map[string]*ast.TypeSpec) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for _, field := range flattenFields(st, types) {
+		tag := fieldTag(field)
+		name := tagName(tag, "json")
+		if name == "" {
+			if tag.Get("json") == "-" || len(field.Names) == 0 || !field.Names[0].IsExported() {
+				continue
+			}
+			name = field.Names[0].Name
+		}
+
+		prop := g.schemaFor(field.Type, types)
+		rules := tag.Get("validate")
+		applyRules(prop, rules)
+		schema.Properties[name] = prop
+		if hasRule(rules, "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (g *Generator) validationErrorSchema() *Schema {
+	name := "ValidationError"
+	if _, ok := g.doc.Components.Schemas[name]; !ok {
+		g.doc.Components.Schemas[name] = &Schema{
+			Type:     "object",
+			Required: []string{"error"},
+			Properties: map[string]*Schema{
+				"error": {Type: "string"},
+				"fields": {
+					Type: "array",
+					Items: &Schema{
+						Type: "object",
+						Properties: map[string]*Schema{
+							"field":   {Type: "string"},
+							"rule":    {Type: "string"},
+							"param":   {Type: "string"},
+							"message": {Type: "string"},
+						},
+					},
+				},
+			},
+		}
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *Generator) packageTypes(dir string) (map[string]*ast.TypeSpec, error) {
+	if types, ok := g.packages[dir]; ok {
+		return types, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]*ast.TypeSpec)
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".go" || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, entry.Name()), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					types[ts.Name.Name] = ts
+				}
+			}
+		}
+	}
+
+	g.packages[dir] = types
+	return types, nil
+}
+
+func resolveStruct(expr ast.Expr, types map[string]*ast.TypeSpec) *ast.StructType {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return resolveStruct(t.X, types)
+	case *ast.StructType:
+		return t
+	case *ast.Ident:
+		if spec, ok := types[t.Name]; ok {
+			return resolveStruct(spec.Type, types)
+		}
+	}
+	return nil
+}
+
+// flattenFields expands embedded structs declared in the same package so
+// their fields are treated as if they were declared inline.
+func flattenFields(st *ast.StructType, types map[string]*ast.TypeSpec) []*ast.Field {
+	var fields []*ast.Field
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			if embedded := resolveStruct(field.Type, types); embedded != nil {
+				fields = append(fields, flattenFields(embedded, types)...)
+				continue
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func fieldTag(field *ast.Field) reflect.StructTag {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag)
+}
+
+func tagName(tag reflect.StructTag, key string) string {
+	name, _, _ := strings.Cut(tag.Get(key), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func hasRule(rules, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if strings.TrimSpace(rule) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func applyRules(schema *Schema, rules string) {
+	if rules == "" || schema.Ref != "" {
+		return
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		key, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch key {
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			if key == "min" || key == "len" {
+				setBound(schema, n, true)
+			}
+			if key == "max" || key == "len" {
+				setBound(schema, n, false)
+			}
+		}
+	}
+}
+
+func setBound(schema *Schema, n float64, lower bool) {
+	i := int(n)
+	switch schema.Type {
+	case "string":
+		if lower {
+			schema.MinLength = &i
+		} else {
+			schema.MaxLength = &i
+		}
+	case "array":
+		if lower {
+			schema.MinItems = &i
+		} else {
+			schema.MaxItems = &i
+		}
+	case "integer", "number":
+		if lower {
+			schema.Minimum = &n
+		} else {
+			schema.Maximum = &n
+		}
+	}
+}
+
+func basicSchema(name string) *Schema {
+	switch name {
+	case "string":
+		return &Schema{Type: "string"}
+	case "bool":
+		return &Schema{Type: "boolean"}
+	case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32":
+		return &Schema{Type: "integer", Format: "int32"}
+	case "int64", "uint64":
+		return &Schema{Type: "integer", Format: "int64"}
+	case "float32":
+		return &Schema{Type: "number", Format: "float"}
+	case "float64":
+		return &Schema{Type: "number", Format: "double"}
+	case "any":
+		return &Schema{}
+	}
+	return nil
+}
+
+func operationID(method, pattern string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(pattern, func(r rune) bool {
+		return r == '/' || r == '[' || r == ']' || r == '.' || r == '-' || r == '_'
+	}) {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
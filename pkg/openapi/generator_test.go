@@ -0,0 +1,136 @@
+package openapi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+)
+
+const usersEndpoint = `package users
+
+import (
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/endpoints"
+)
+
+type User struct {
+	ID        int       ` + "`json:\"id\"`" + `
+	Name      string    ` + "`json:\"name\" validate:\"required\"`" + `
+	CreatedAt time.Time ` + "`json:\"createdAt\"`" + `
+}
+
+type GetUser struct {
+	ID     int    ` + "`param:\"id\"`" + `
+	Fields string ` + "`query:\"fields\"`" + `
+}
+
+type UpdateUser struct {
+	ID   int    ` + "`param:\"id\"`" + `
+	Name string ` + "`json:\"name\" validate:\"required,min=2,max=50\"`" + `
+	Role string ` + "`json:\"role\" validate:\"oneof=admin user\"`" + `
+}
+
+// GET returns a single user.
+func GET(ctx *endpoints.Context, in GetUser) (*User, error) {
+	return &User{ID: in.ID}, nil
+}
+
+func PUT(ctx *endpoints.Context, in UpdateUser) (User, error) {
+	return User{ID: in.ID, Name: in.Name}, nil
+}
+
+func DELETE(ctx *endpoints.Context) error {
+	return nil
+}
+`
+
+func TestGenerateTypedEndpoint(t *testing.T) {
+	tmpDir := t.TempDir()
+	usersDir := filepath.Join(tmpDir, "api", "users")
+	os.MkdirAll(usersDir, 0755)
+	os.WriteFile(filepath.Join(usersDir, "[id].go"), []byte(usersEndpoint), 0644)
+
+	rt := router.NewRouter(tmpDir)
+	if err := rt.Discover(); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	doc, err := NewGenerator("Test", "1.0.0").Generate(rt.Routes)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	item, ok := doc.Paths["/api/users/{id}"]
+	if !ok {
+		t.Fatalf("Expected /api/users/{id}, got paths %v", doc.Paths)
+	}
+
+	get := item["get"]
+	if get == nil {
+		t.Fatal("Expected get operation")
+	}
+	if get.Summary != "GET returns a single user." {
+		t.Errorf("Expected summary from doc comment, got %q", get.Summary)
+	}
+	if len(get.Parameters) != 2 {
+		t.Fatalf("Expected 2 parameters, got %d", len(get.Parameters))
+	}
+	if get.Parameters[0].In != "path" || !get.Parameters[0].Required {
+		t.Errorf("Expected required path param, got %+v", get.Parameters[0])
+	}
+	if get.RequestBody != nil {
+		t.Error("Expected no request body for GET")
+	}
+	if ref := get.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/User" {
+		t.Errorf("Expected User ref, got %q", ref)
+	}
+
+	put := item["put"]
+	if put == nil || put.RequestBody == nil {
+		t.Fatal("Expected put operation with request body")
+	}
+	body := put.RequestBody.Content["application/json"].Schema
+	if len(body.Required) != 1 || body.Required[0] != "name" {
+		t.Errorf("Expected name to be required, got %v", body.Required)
+	}
+	if name := body.Properties["name"]; name.MinLength == nil || *name.MinLength != 2 || *name.MaxLength != 50 {
+		t.Errorf("Expected name length bounds, got %+v", name)
+	}
+	if role := body.Properties["role"]; len(role.Enum) != 2 {
+		t.Errorf("Expected role enum, got %+v", role)
+	}
+	if _, ok := put.Responses["400"]; !ok {
+		t.Error("Expected 400 response for typed handler")
+	}
+
+	del := item["delete"]
+	if del == nil || len(del.Parameters) != 1 {
+		t.Fatalf("Expected plain delete with path param, got %+v", del)
+	}
+
+	user := doc.Components.Schemas["User"]
+	if user == nil {
+		t.Fatal("Expected User component schema")
+	}
+	if user.Properties["createdAt"].Format != "date-time" {
+		t.Errorf("Expected date-time format, got %+v", user.Properties["createdAt"])
+	}
+}
+
+func TestConvertPattern(t *testing.T) {
+	tests := map[string]string{
+		"/api/users":             "/api/users",
+		"/api/users/[id]":        "/api/users/{id}",
+		"/api/files/[...path]":   "/api/files/{path}",
+		"/api/[org]/repos/[rid]": "/api/{org}/repos/{rid}",
+	}
+
+	for in, want := range tests {
+		if got := ConvertPattern(in); got != want {
+			t.Errorf("ConvertPattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/cameron-webmatter/galaxy/pkg/config"
 )
@@ -10,6 +11,10 @@ type Manager struct {
 	registry map[string]Plugin
 	plugins  []Plugin
 	config   *config.Config
+
+	// mu runs transforms one at a time, so plugins needn't be safe for
+	// concurrent use when pages build in parallel.
+	mu sync.Mutex
 }
 
 func NewManager(cfg *config.Config) *Manager {
@@ -49,6 +54,9 @@ func (m *Manager) Load(rootDir, outDir string) error {
 }
 
 func (m *Manager) TransformCSS(css string, filePath string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	result := css
 	for _, plugin := range m.plugins {
 		transformed, err := plugin.TransformCSS(result, filePath)
@@ -63,6 +71,9 @@ func (m *Manager) TransformCSS(css string, filePath string) (string, error) {
 }
 
 func (m *Manager) TransformJS(js string, filePath string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	result := js
 	for _, plugin := range m.plugins {
 		transformed, err := plugin.TransformJS(result, filePath)
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware/builtin"
+)
+
+// Budgets are the parsed [budgets] limits; zero means no limit.
+type Budgets struct {
+	MaxJS         int64
+	MaxCSS        int64
+	MaxWASM       int64
+	MaxRenderTime time.Duration
+	Compression   string
+}
+
+func ParseBudgets(cfg config.BudgetsConfig) (Budgets, error) {
+	b := Budgets{Compression: cfg.Compression}
+	if b.Compression == "" {
+		b.Compression = "gzip"
+	}
+	for _, s := range []struct {
+		name  string
+		value string
+		dest  *int64
+	}{
+		{"maxJS", cfg.MaxJS, &b.MaxJS},
+		{"maxCSS", cfg.MaxCSS, &b.MaxCSS},
+		{"maxWASM", cfg.MaxWASM, &b.MaxWASM},
+	} {
+		if s.value == "" {
+			continue
+		}
+		n, err := builtin.ParseSize(s.value)
+		if err != nil {
+			return Budgets{}, fmt.Errorf("budgets.%s: %w", s.name, err)
+		}
+		*s.dest = n
+	}
+	if cfg.MaxRenderTime != "" {
+		d, err := time.ParseDuration(cfg.MaxRenderTime)
+		if err != nil || d <= 0 {
+			return Budgets{}, fmt.Errorf("budgets.maxRenderTime: invalid duration %q", cfg.MaxRenderTime)
+		}
+		b.MaxRenderTime = d
+	}
+	return b, nil
+}
+
+// Enabled reports whether any limit is set.
+func (b Budgets) Enabled() bool {
+	return b.MaxJS > 0 || b.MaxCSS > 0 || b.MaxWASM > 0 || b.MaxRenderTime > 0
+}
+
+// Violation is a route, or one of its assets, over budget.
+type Violation struct {
+	Route  string `json:"route"`
+	Asset  string `json:"asset,omitempty"`
+	Budget string `json:"budget"`
+	Limit  string `json:"limit"`
+	Actual string `json:"actual"`
+}
+
+func (v Violation) String() string {
+	subject := v.Route
+	if v.Asset != "" {
+		subject += " " + v.Asset
+	}
+	return fmt.Sprintf("%s: %s is %s, over the budget of %s", subject, v.Budget, v.Actual, v.Limit)
+}
+
+// Check returns where the report exceeds b. JS and CSS budgets apply to
+// each route's total, the WASM budget to each module.
+func (r *Report) Check(b Budgets) []Violation {
+	var violations []Violation
+	for _, route := range r.Routes {
+		for _, c := range []struct {
+			budget string
+			typ    string
+			limit  int64
+		}{
+			{"maxJS", TypeJS, b.MaxJS},
+			{"maxCSS", TypeCSS, b.MaxCSS},
+		} {
+			if total := route.Total(c.typ).In(b.Compression); c.limit > 0 && total > c.limit {
+				violations = append(violations, Violation{Route: route.Pattern, Budget: c.budget, Limit: FormatSize(c.limit), Actual: FormatSize(total)})
+			}
+		}
+		if b.MaxWASM > 0 {
+			for _, a := range route.Assets {
+				if size := a.In(b.Compression); a.Type == TypeWASM && size > b.MaxWASM {
+					violations = append(violations, Violation{Route: route.Pattern, Asset: a.Path, Budget: "maxWASM", Limit: FormatSize(b.MaxWASM), Actual: FormatSize(size)})
+				}
+			}
+		}
+		if b.MaxRenderTime > 0 && route.RenderMS > float64(b.MaxRenderTime.Microseconds())/1000 {
+			violations = append(violations, Violation{Route: route.Pattern, Budget: "maxRenderTime", Limit: b.MaxRenderTime.String(), Actual: fmt.Sprintf("%.1fms", route.RenderMS)})
+		}
+	}
+	return violations
+}
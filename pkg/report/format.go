@@ -0,0 +1,156 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// FormatSize formats n bytes in binary units, like "12.3 KB".
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+func formatRender(r Route) string {
+	if r.RenderMS == 0 {
+		return "-"
+	}
+	s := fmt.Sprintf("%.1fms", r.RenderMS)
+	if r.Cached {
+		s += " (cached)"
+	}
+	return s
+}
+
+func formatTotal(r Route, typ string) string {
+	if total := r.Total(typ); total.Raw > 0 {
+		return FormatSize(total.Gzip)
+	}
+	return "-"
+}
+
+// WriteTable writes the routes, with gzipped sizes, then every asset
+// with all three sizes.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Route\tMode\tRender\tHTML\tCSS\tJS\tWASM")
+	for _, route := range r.Routes {
+		html := "-"
+		if route.HTML != nil {
+			html = FormatSize(route.HTML.Gzip)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", route.Pattern, route.Mode, formatRender(route), html,
+			formatTotal(route, TypeCSS), formatTotal(route, TypeJS), formatTotal(route, TypeWASM))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "Sizes are gzipped.")
+
+	assets := r.Assets()
+	if len(assets) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(tw, "Asset\tType\tRaw\tGzip\tBrotli")
+	for _, a := range assets {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", a.Path, a.Type, FormatSize(a.Raw), FormatSize(a.Gzip), FormatSize(a.Brotli))
+	}
+	return tw.Flush()
+}
+
+// formatDelta formats a size and its change from before, like
+// "12.3 KB (+1.0 KB)".
+func formatDelta(before, after int64) string {
+	if before == 0 && after == 0 {
+		return "-"
+	}
+	switch d := after - before; {
+	case d > 0:
+		return fmt.Sprintf("%s (+%s)", FormatSize(after), FormatSize(d))
+	case d < 0:
+		return fmt.Sprintf("%s (-%s)", FormatSize(after), FormatSize(-d))
+	}
+	return FormatSize(after)
+}
+
+// WriteDiff writes, as a Markdown table for a pull request comment, the
+// routes whose gzipped sizes differ between base and head, and those added
+// or removed.
+func WriteDiff(w io.Writer, base, head *Report) error {
+	before := make(map[string]Route, len(base.Routes))
+	for _, r := range base.Routes {
+		before[r.Pattern] = r
+	}
+	sizes := func(r Route) [4]int64 {
+		var html int64
+		if r.HTML != nil {
+			html = r.HTML.Gzip
+		}
+		return [4]int64{html, r.Total(TypeCSS).Gzip, r.Total(TypeJS).Gzip, r.Total(TypeWASM).Gzip}
+	}
+
+	var rows []string
+	var totalBefore, totalAfter [4]int64
+	row := func(pattern, status string, old, cur [4]int64) {
+		cells := []string{"`" + pattern + "`" + status}
+		for i := range cur {
+			cells = append(cells, formatDelta(old[i], cur[i]))
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+	}
+	for _, r := range head.Routes {
+		cur := sizes(r)
+		old, ok := before[r.Pattern]
+		delete(before, r.Pattern)
+		for i := range cur {
+			totalAfter[i] += cur[i]
+		}
+		if !ok {
+			row(r.Pattern, " (new)", [4]int64{}, cur)
+			continue
+		}
+		prev := sizes(old)
+		for i := range prev {
+			totalBefore[i] += prev[i]
+		}
+		if prev != cur {
+			row(r.Pattern, "", prev, cur)
+		}
+	}
+	for _, r := range base.Routes {
+		if _, removed := before[r.Pattern]; removed {
+			prev := sizes(r)
+			for i := range prev {
+				totalBefore[i] += prev[i]
+			}
+			row(r.Pattern, " (removed)", prev, [4]int64{})
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("### Build size changes\n\n")
+	if len(rows) == 0 {
+		b.WriteString("No route changed size.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	b.WriteString("| Route | HTML | CSS | JS | WASM |\n|---|---|---|---|---|\n")
+	for _, r := range rows {
+		b.WriteString(r + "\n")
+	}
+	cells := []string{"**Total**"}
+	for i := range totalAfter {
+		cells = append(cells, formatDelta(totalBefore[i], totalAfter[i]))
+	}
+	b.WriteString("| " + strings.Join(cells, " | ") + " |\n\nSizes are gzipped.\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,355 @@
+// Package report describes a build: every route, how it is served, how
+// long its page took to render and what it weighs, with the CSS, JS and
+// WASM it loads measured raw, gzipped and brotli-compressed.
+package report
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/cameron-webmatter/galaxy/pkg/wasm"
+)
+
+type Mode string
+
+const (
+	ModeStatic   Mode = "static"
+	ModeSSR      Mode = "ssr"
+	ModeEndpoint Mode = "endpoint"
+)
+
+// Asset types.
+const (
+	TypeCSS  = "css"
+	TypeJS   = "js"
+	TypeWASM = "wasm"
+)
+
+// Report is what `galaxy build --report=json` writes.
+type Report struct {
+	Routes     []Route     `json:"routes"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+type Route struct {
+	Pattern string `json:"pattern"`
+	Mode    Mode   `json:"mode"`
+	// RenderMS is how long a prerendered page took to render. Cached
+	// pages report the render they were cached from.
+	RenderMS float64 `json:"renderMs,omitempty"`
+	Cached   bool    `json:"cached,omitempty"`
+	// HTML is the prerendered page; SSR pages have none.
+	HTML   *Size   `json:"html,omitempty"`
+	Assets []Asset `json:"assets,omitempty"`
+}
+
+// Size is a file's size in bytes, as is and compressed.
+type Size struct {
+	Raw    int64 `json:"raw"`
+	Gzip   int64 `json:"gzip"`
+	Brotli int64 `json:"brotli"`
+}
+
+// Asset is a file a page loads, by URL path.
+type Asset struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size
+}
+
+// In returns the size after compression: "gzip", "brotli" or "raw".
+func (s Size) In(compression string) int64 {
+	switch compression {
+	case "brotli":
+		return s.Brotli
+	case "raw":
+		return s.Raw
+	}
+	return s.Gzip
+}
+
+func (s Size) add(o Size) Size {
+	return Size{Raw: s.Raw + o.Raw, Gzip: s.Gzip + o.Gzip, Brotli: s.Brotli + o.Brotli}
+}
+
+// Total sums the route's assets of type typ.
+func (r Route) Total(typ string) Size {
+	var total Size
+	for _, a := range r.Assets {
+		if a.Type == typ {
+			total = total.add(a.Size)
+		}
+	}
+	return total
+}
+
+// Page is a route as built, to be measured by Collect.
+type Page struct {
+	Pattern string
+	Mode    Mode
+	// Source is the page file, which its WASM manifest entry is keyed by.
+	Source string
+	// HTML is the prerendered file of a static page.
+	HTML       string
+	RenderTime time.Duration
+	Cached     bool
+}
+
+// Collect measures pages built into outDir. Static pages load the assets
+// their HTML references; SSR pages, those the server injects from the
+// WASM manifest.
+func Collect(outDir, pagesDir, base string, pages []Page) (*Report, error) {
+	c := &collector{
+		outDir: outDir,
+		base:   strings.TrimSuffix(base, "/"),
+		sizes:  make(map[string]Size),
+	}
+	manifest, err := wasm.LoadManifest(filepath.Join(outDir, "server", "_assets", "wasm-manifest.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read wasm manifest: %w", err)
+	}
+
+	r := &Report{Routes: []Route{}}
+	for _, p := range pages {
+		route := Route{Pattern: p.Pattern, Mode: p.Mode, Cached: p.Cached}
+		if p.RenderTime > 0 {
+			route.RenderMS = float64(p.RenderTime.Microseconds()) / 1000
+		}
+
+		var refs []string
+		if p.HTML != "" {
+			data, err := os.ReadFile(p.HTML)
+			if err != nil {
+				return nil, err
+			}
+			size, err := c.size(p.HTML)
+			if err != nil {
+				return nil, err
+			}
+			route.HTML = &size
+			refs = htmlRefs(data)
+		} else if manifest != nil && p.Mode == ModeSSR {
+			rel, err := filepath.Rel(pagesDir, p.Source)
+			if err == nil {
+				assets := manifest.Assets["pages/"+filepath.ToSlash(rel)]
+				for _, mod := range assets.WasmModules {
+					refs = append(refs, "/wasm_exec.js", mod.LoaderPath)
+				}
+				refs = append(refs, assets.JSScripts...)
+			}
+		}
+
+		if route.Assets, err = c.assets(refs); err != nil {
+			return nil, err
+		}
+		r.Routes = append(r.Routes, route)
+	}
+	return r, nil
+}
+
+// Load reads a report written by Save.
+func Load(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Save writes the report as JSON.
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+var (
+	tagRegex     = regexp.MustCompile(`(?is)<(script|link)\b[^>]*>`)
+	attrRegex    = regexp.MustCompile(`(?is)\b(src|href|rel)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	wasmRefRegex = regexp.MustCompile(`["'\x60]([^"'\x60\s]+\.wasm)["'\x60]`)
+)
+
+// htmlRefs returns the scripts and stylesheets a page loads, in order.
+func htmlRefs(html []byte) []string {
+	var refs []string
+	for _, m := range tagRegex.FindAllSubmatch(html, -1) {
+		attrs := make(map[string]string)
+		for _, a := range attrRegex.FindAllSubmatch(m[0], -1) {
+			attrs[strings.ToLower(string(a[1]))] = string(a[2]) + string(a[3])
+		}
+		switch strings.ToLower(string(m[1])) {
+		case "script":
+			if attrs["src"] != "" {
+				refs = append(refs, attrs["src"])
+			}
+		case "link":
+			if strings.EqualFold(attrs["rel"], "stylesheet") && attrs["href"] != "" {
+				refs = append(refs, attrs["href"])
+			}
+		}
+	}
+	return refs
+}
+
+type collector struct {
+	outDir string
+	base   string
+	sizes  map[string]Size
+}
+
+// assets resolves refs to the site's files and measures them, following
+// scripts to the WASM modules they fetch. External URLs are left out.
+func (c *collector) assets(refs []string) ([]Asset, error) {
+	var assets []Asset
+	seen := make(map[string]bool)
+	for len(refs) > 0 {
+		ref := refs[0]
+		refs = refs[1:]
+
+		urlPath, file := c.resolve(ref)
+		if file == "" || seen[urlPath] {
+			continue
+		}
+		seen[urlPath] = true
+
+		typ := assetType(urlPath)
+		if typ == "" {
+			continue
+		}
+		size, err := c.size(file)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, Asset{Path: urlPath, Type: typ, Size: size})
+
+		if typ == TypeJS {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range wasmRefRegex.FindAllSubmatch(data, -1) {
+				ref := string(m[1])
+				if !strings.HasPrefix(ref, "/") {
+					ref = path.Join(path.Dir(urlPath), ref)
+				}
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return assets, nil
+}
+
+// resolve maps a reference to its URL path and the file serving it: a
+// prerendered or bundled file in outDir, a public file, or one the server
+// carries.
+func (c *collector) resolve(ref string) (urlPath, file string) {
+	if strings.Contains(ref, "://") || strings.HasPrefix(ref, "//") || strings.HasPrefix(ref, "data:") {
+		return "", ""
+	}
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	if !strings.HasPrefix(ref, "/") {
+		ref = "/" + ref
+	}
+	urlPath = path.Clean(ref)
+	rel := strings.TrimPrefix(urlPath, c.base)
+	for _, dir := range []string{c.outDir, filepath.Join(c.outDir, "public"), filepath.Join(c.outDir, "server"), filepath.Join(c.outDir, "server", "public")} {
+		f := filepath.Join(dir, filepath.FromSlash(rel))
+		if info, err := os.Stat(f); err == nil && !info.IsDir() {
+			return urlPath, f
+		}
+	}
+	return urlPath, ""
+}
+
+func assetType(urlPath string) string {
+	switch path.Ext(urlPath) {
+	case ".css":
+		return TypeCSS
+	case ".js", ".mjs":
+		return TypeJS
+	case ".wasm":
+		return TypeWASM
+	}
+	return ""
+}
+
+// size measures file once, however many pages load it.
+func (c *collector) size(file string) (Size, error) {
+	if s, ok := c.sizes[file]; ok {
+		return s, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Size{}, err
+	}
+	s, err := Measure(data)
+	if err != nil {
+		return Size{}, err
+	}
+	c.sizes[file] = s
+	return s, nil
+}
+
+// Measure returns the size of data as is, gzipped and brotli-compressed,
+// both at their default levels.
+func Measure(data []byte) (Size, error) {
+	var gz, br countWriter
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write(data); err != nil {
+		return Size{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return Size{}, err
+	}
+	bw := brotli.NewWriterLevel(&br, brotli.DefaultCompression)
+	if _, err := bw.Write(data); err != nil {
+		return Size{}, err
+	}
+	if err := bw.Close(); err != nil {
+		return Size{}, err
+	}
+	return Size{Raw: int64(len(data)), Gzip: gz.n, Brotli: br.n}, nil
+}
+
+type countWriter struct{ n int64 }
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// Assets lists every asset the report's routes load once, by path.
+func (r *Report) Assets() []Asset {
+	byPath := make(map[string]Asset)
+	for _, route := range r.Routes {
+		for _, a := range route.Assets {
+			byPath[a.Path] = a
+		}
+	}
+	assets := make([]Asset, 0, len(byPath))
+	for _, a := range byPath {
+		assets = append(assets, a)
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Path < assets[j].Path })
+	return assets
+}
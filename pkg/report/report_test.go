@@ -0,0 +1,181 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/wasm"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	data := []byte(strings.Repeat("galaxy ", 1000))
+	size, err := Measure(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size.Raw != 7000 {
+		t.Errorf("Expected raw size 7000, got %d", size.Raw)
+	}
+	if size.Gzip <= 0 || size.Gzip >= size.Raw {
+		t.Errorf("Expected a gzip size below the raw one, got %d", size.Gzip)
+	}
+	if size.Brotli <= 0 || size.Brotli >= size.Raw {
+		t.Errorf("Expected a brotli size below the raw one, got %d", size.Brotli)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	outDir := filepath.Join(dir, "dist")
+	pagesDir := filepath.Join(dir, "src", "pages")
+
+	writeFile(t, filepath.Join(outDir, "index.html"), `<html><head>
+<link rel="stylesheet" href="/docs/_assets/styles.css">
+<link rel="icon" href="/docs/favicon.ico">
+<script src="https://cdn.example.com/lib.js"></script>
+<script type="module" src="/docs/_assets/script-ab12-loader.js?v=1"></script>
+</head></html>`)
+	writeFile(t, filepath.Join(outDir, "_assets", "styles.css"), "body { color: red; }")
+	writeFile(t, filepath.Join(outDir, "_assets", "script-ab12-loader.js"), `fetch("/_assets/wasm/script-ab12.wasm")`)
+	writeFile(t, filepath.Join(outDir, "_assets", "wasm", "script-ab12.wasm"), "\x00asm")
+	writeFile(t, filepath.Join(outDir, "server", "wasm_exec.js"), "// go runtime")
+	writeFile(t, filepath.Join(outDir, "server", "_assets", "app.js"), "console.log(1)")
+
+	manifest := wasm.NewManifest()
+	manifest.Assets["pages/app.gxc"] = wasm.WasmPageAssets{JSScripts: []string{"/_assets/app.js"}}
+	if err := manifest.Save(filepath.Join(outDir, "server", "_assets", "wasm-manifest.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Collect(outDir, pagesDir, "/docs/", []Page{
+		{Pattern: "/", Mode: ModeStatic, Source: filepath.Join(pagesDir, "index.gxc"), HTML: filepath.Join(outDir, "index.html"), RenderTime: 1500 * time.Microsecond, Cached: true},
+		{Pattern: "/app", Mode: ModeSSR, Source: filepath.Join(pagesDir, "app.gxc")},
+		{Pattern: "/api/ping", Mode: ModeEndpoint, Source: filepath.Join(pagesDir, "api", "ping.go")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Routes) != 3 {
+		t.Fatalf("Expected 3 routes, got %d", len(r.Routes))
+	}
+
+	index := r.Routes[0]
+	if index.RenderMS != 1.5 || !index.Cached {
+		t.Errorf("Expected a cached 1.5ms render, got %vms cached=%v", index.RenderMS, index.Cached)
+	}
+	if index.HTML == nil || index.HTML.Raw == 0 {
+		t.Errorf("Expected the HTML size, got %+v", index.HTML)
+	}
+	var paths []string
+	for _, a := range index.Assets {
+		paths = append(paths, a.Type+" "+a.Path)
+	}
+	expected := "css /docs/_assets/styles.css, js /docs/_assets/script-ab12-loader.js, wasm /_assets/wasm/script-ab12.wasm"
+	if got := strings.Join(paths, ", "); got != expected {
+		t.Errorf("Expected assets %q, got %q", expected, got)
+	}
+
+	app := r.Routes[1]
+	if app.HTML != nil || len(app.Assets) != 1 || app.Assets[0].Path != "/_assets/app.js" {
+		t.Errorf("Expected the SSR page to load /_assets/app.js from the manifest, got %+v", app)
+	}
+	if len(r.Routes[2].Assets) != 0 {
+		t.Errorf("Expected no assets for an endpoint, got %+v", r.Routes[2].Assets)
+	}
+
+	if got := len(r.Assets()); got != 4 {
+		t.Errorf("Expected 4 distinct assets, got %d", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	budgets, err := ParseBudgets(config.BudgetsConfig{MaxJS: "1KB", MaxWASM: "2KB", MaxRenderTime: "100ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if budgets.Compression != "gzip" {
+		t.Errorf("Expected gzip by default, got %q", budgets.Compression)
+	}
+
+	r := &Report{Routes: []Route{
+		{Pattern: "/", RenderMS: 250, Assets: []Asset{
+			{Path: "/a.js", Type: TypeJS, Size: Size{Raw: 4000, Gzip: 600}},
+			{Path: "/b.js", Type: TypeJS, Size: Size{Raw: 4000, Gzip: 600}},
+			{Path: "/app.wasm", Type: TypeWASM, Size: Size{Raw: 9000, Gzip: 1000}},
+		}},
+		{Pattern: "/about", RenderMS: 20, Assets: []Asset{
+			{Path: "/a.js", Type: TypeJS, Size: Size{Raw: 4000, Gzip: 600}},
+		}},
+	}}
+	violations := r.Check(budgets)
+	if len(violations) != 2 {
+		t.Fatalf("Expected 2 violations, got %v", violations)
+	}
+	if got := violations[0].String(); got != "/: maxJS is 1.2 KB, over the budget of 1.0 KB" {
+		t.Errorf("Expected the JS total to exceed its budget, got %q", got)
+	}
+	if violations[1].Budget != "maxRenderTime" {
+		t.Errorf("Expected the render time to exceed its budget, got %+v", violations[1])
+	}
+
+	budgets.Compression = "raw"
+	if got := len(r.Check(budgets)); got != 4 {
+		t.Errorf("Expected 4 violations uncompressed, got %d", got)
+	}
+
+	if _, err := ParseBudgets(config.BudgetsConfig{MaxCSS: "lots"}); err == nil || !strings.Contains(err.Error(), "budgets.maxCSS") {
+		t.Errorf("Expected an invalid size error naming the budget, got %v", err)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	base := &Report{Routes: []Route{
+		{Pattern: "/", HTML: &Size{Gzip: 1024}},
+		{Pattern: "/about", HTML: &Size{Gzip: 512}},
+		{Pattern: "/old", HTML: &Size{Gzip: 100}},
+	}}
+	head := &Report{Routes: []Route{
+		{Pattern: "/", HTML: &Size{Gzip: 2048}},
+		{Pattern: "/about", HTML: &Size{Gzip: 512}},
+		{Pattern: "/new", Assets: []Asset{{Path: "/a.js", Type: TypeJS, Size: Size{Gzip: 300}}}},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, base, head); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, expected := range []string{
+		"| `/` | 2.0 KB (+1.0 KB) | - | - | - |",
+		"| `/new` (new) | - | - | 300 B (+300 B) | - |",
+		"| `/old` (removed) | 0 B (-100 B) | - | - | - |",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in diff, got:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "/about") {
+		t.Errorf("Expected unchanged routes left out, got:\n%s", out)
+	}
+
+	buf.Reset()
+	WriteDiff(&buf, base, base)
+	if !strings.Contains(buf.String(), "No route changed size.") {
+		t.Errorf("Expected no changes, got:\n%s", buf.String())
+	}
+}
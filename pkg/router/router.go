@@ -2,6 +2,7 @@ package router
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -27,12 +28,27 @@ type Route struct {
 	Priority   int
 	Regex      *regexp.Regexp
 	IsEndpoint bool
+	// ActionsFile is the page's sibling <name>.actions.go, if any.
+	ActionsFile string
+	// Middleware lists the _middleware.go files of the route's directory and
+	// its parents, outermost first.
+	Middleware []string
 }
 
+// ActionsSuffix marks Go files holding form actions for the page of the same
+// name. They are not routes themselves.
+const ActionsSuffix = ".actions.go"
+
+// MiddlewareFile wraps every route in its directory and below.
+const MiddlewareFile = "_middleware.go"
+
 type Router struct {
 	Routes   []*Route
 	PagesDir string
-	mu       sync.RWMutex
+	// FS, when set, holds the pages instead of the disk. PagesDir and route
+	// file paths are then slash-separated and relative to its root.
+	FS fs.FS
+	mu sync.RWMutex
 }
 
 func NewRouter(pagesDir string) *Router {
@@ -165,17 +181,20 @@ func (r *Router) Reload() error {
 }
 
 func (r *Router) discover() error {
-	return filepath.Walk(r.PagesDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	middlewareDirs := make(map[string]string)
+
+	err := r.walk(func(path string, isDir bool) error {
+		if isDir {
+			return nil
 		}
 
-		if info.IsDir() {
+		if filepath.Base(path) == MiddlewareFile {
+			middlewareDirs[filepath.Dir(path)] = path
 			return nil
 		}
 
 		isGxc := strings.HasSuffix(path, ".gxc")
-		isGoEndpoint := strings.HasSuffix(path, ".go")
+		isGoEndpoint := strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, ActionsSuffix)
 
 		if !isGxc && !isGoEndpoint {
 			return nil
@@ -191,10 +210,69 @@ func (r *Router) discover() error {
 			route.IsEndpoint = true
 			route.Type = RouteEndpoint
 		}
+		if isGxc {
+			actionsPath := strings.TrimSuffix(path, ".gxc") + ActionsSuffix
+			if r.exists(actionsPath) {
+				route.ActionsFile = actionsPath
+			}
+		}
 		r.Routes = append(r.Routes, route)
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	for _, route := range r.Routes {
+		route.Middleware = r.middlewareFor(filepath.Dir(route.FilePath), middlewareDirs)
+	}
+
+	return nil
+}
+
+func (r *Router) walk(fn func(path string, isDir bool) error) error {
+	if r.FS != nil {
+		return fs.WalkDir(r.FS, r.PagesDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return fn(path, d.IsDir())
+		})
+	}
+
+	return filepath.Walk(r.PagesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return fn(path, info.IsDir())
+	})
+}
+
+func (r *Router) exists(path string) bool {
+	if r.FS != nil {
+		_, err := fs.Stat(r.FS, path)
+		return err == nil
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (r *Router) middlewareFor(dir string, middlewareDirs map[string]string) []string {
+	var files []string
+	for {
+		if file, ok := middlewareDirs[dir]; ok {
+			files = append([]string{file}, files...)
+		}
+		if dir == filepath.Clean(r.PagesDir) {
+			return files
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return files
+		}
+		dir = parent
+	}
 }
 
 func (r *Router) sort() {
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 )
 
 func TestStaticRoute(t *testing.T) {
@@ -117,3 +118,99 @@ func TestRoutePriority(t *testing.T) {
 		t.Error("Expected static route to have higher priority")
 	}
 }
+
+func TestActionsFileAttachedToPage(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(tmpDir, "contact.gxc"), []byte(""), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "contact.actions.go"), []byte("package pages"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "about.gxc"), []byte(""), 0644)
+
+	router := NewRouter(tmpDir)
+	if err := router.Discover(); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	if len(router.Routes) != 2 {
+		t.Fatalf("Expected 2 routes, got %d", len(router.Routes))
+	}
+
+	route, _ := router.Match("/contact")
+	if route == nil {
+		t.Fatal("Expected to match /contact")
+	}
+	if route.ActionsFile != filepath.Join(tmpDir, "contact.actions.go") {
+		t.Errorf("Expected actions file, got %q", route.ActionsFile)
+	}
+
+	if route, _ := router.Match("/contact.actions"); route != nil {
+		t.Error("Expected actions file not to be routed")
+	}
+
+	route, _ = router.Match("/about")
+	if route == nil || route.ActionsFile != "" {
+		t.Errorf("Expected /about without actions, got %+v", route)
+	}
+}
+
+func TestMiddlewareFilesOutermostFirst(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(tmpDir, "admin", "users"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "index.gxc"), []byte(""), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "_middleware.go"), []byte("package pages"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "admin", "_middleware.go"), []byte("package admin"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "admin", "users", "[id].gxc"), []byte(""), 0644)
+
+	router := NewRouter(tmpDir)
+	if err := router.Discover(); err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	if len(router.Routes) != 2 {
+		t.Fatalf("Expected middleware files not to be routes, got %d routes", len(router.Routes))
+	}
+
+	route, _ := router.Match("/admin/users/1")
+	if route == nil {
+		t.Fatal("Expected to match /admin/users/1")
+	}
+	expected := []string{filepath.Join(tmpDir, "_middleware.go"), filepath.Join(tmpDir, "admin", "_middleware.go")}
+	if len(route.Middleware) != 2 || route.Middleware[0] != expected[0] || route.Middleware[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, route.Middleware)
+	}
+
+	route, _ = router.Match("/")
+	if len(route.Middleware) != 1 {
+		t.Errorf("Expected only root middleware for /, got %v", route.Middleware)
+	}
+}
+
+func TestDiscoverFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/index.gxc":              {Data: []byte("<h1>home</h1>")},
+		"pages/blog/[slug].gxc":        {Data: []byte("<h1>post</h1>")},
+		"pages/blog/[slug].actions.go": {Data: []byte("package blog")},
+		"pages/blog/" + MiddlewareFile: {Data: []byte("package blog")},
+	}
+
+	r := NewRouter("pages")
+	r.FS = fsys
+	if err := r.Discover(); err != nil {
+		t.Fatal(err)
+	}
+
+	route, params := r.Match("/blog/hello")
+	if route == nil {
+		t.Fatal("Expected route for /blog/hello")
+	}
+	if route.FilePath != "pages/blog/[slug].gxc" || params["slug"] != "hello" {
+		t.Errorf("Expected pages/blog/[slug].gxc with slug hello, got %s %v", route.FilePath, params)
+	}
+	if route.ActionsFile != "pages/blog/[slug].actions.go" {
+		t.Errorf("Expected actions file from FS, got %q", route.ActionsFile)
+	}
+	if len(route.Middleware) != 1 {
+		t.Errorf("Expected directory middleware from FS, got %v", route.Middleware)
+	}
+}
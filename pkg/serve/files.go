@@ -0,0 +1,172 @@
+package serve
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hashedName matches file names carrying a content hash, such as the
+// bundler's styles-1a2b3c4d.css and script-1a2b3c4d-loader.js.
+var hashedName = regexp.MustCompile(`[-.][0-9a-f]{8,}[-.]`)
+
+// Hashed reports whether name carries a content hash, so its content never
+// changes and clients may cache it for good.
+func Hashed(name string) bool {
+	return hashedName.MatchString(path.Base(name))
+}
+
+// FileServer serves files from an fs.FS, either embedded into the binary or
+// on disk. Responses carry a content-hash ETag and support Range requests;
+// hashed bundler output under _assets/ is cached as immutable.
+type FileServer struct {
+	fsys fs.FS
+
+	mu    sync.Mutex
+	etags map[string]etag
+}
+
+type etag struct {
+	size    int64
+	modTime time.Time
+	value   string
+}
+
+func Files(fsys fs.FS) *FileServer {
+	return &FileServer{fsys: fsys, etags: make(map[string]etag)}
+}
+
+func (s *FileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.ServeFile(w, r, fsName(r.URL.Path)) {
+		http.NotFound(w, r)
+	}
+}
+
+// ServeFile serves the file called name and reports whether it exists.
+func (s *FileServer) ServeFile(w http.ResponseWriter, r *http.Request, name string) bool {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return false
+		}
+		content = bytes.NewReader(data)
+	}
+
+	tag, err := s.etag(name, info, content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+
+	w.Header().Set("ETag", tag)
+	if strings.HasPrefix(name, "_assets/") && Hashed(name) {
+		// Only the bundler's output is known to be content-hashed; public/
+		// files keep their names across edits however they look.
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), content)
+	return true
+}
+
+// etag hashes the file once and reuses the result until its size or
+// modification time changes.
+func (s *FileServer) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	s.mu.Lock()
+	cached, ok := s.etags[name]
+	s.mu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.value, nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	value := `"` + hex.EncodeToString(h.Sum(nil)[:8]) + `"`
+
+	s.mu.Lock()
+	s.etags[name] = etag{size: info.Size(), modTime: info.ModTime(), value: value}
+	s.mu.Unlock()
+	return value, nil
+}
+
+// Site serves the files of a built app from fsys in front of next: bundled
+// assets from _assets/, wasm_exec.js, files from public/ and pages
+// prerendered into static/. Pages listed in dynamic, such as incremental
+// ones regenerated through the cache, are left to next.
+func Site(fsys fs.FS, next http.Handler, dynamic ...string) http.Handler {
+	files := Files(fsys)
+	skip := make(map[string]bool, len(dynamic))
+	for _, p := range dynamic {
+		skip[path.Clean("/"+p)] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := fsName(r.URL.Path)
+		if strings.HasPrefix(name, "_assets/") || name == "wasm_exec.js" {
+			files.ServeHTTP(w, r)
+			return
+		}
+
+		if path.Ext(name) != "" {
+			if files.ServeFile(w, r, "public/"+name) || files.ServeFile(w, r, "static/"+name) {
+				return
+			}
+		} else if !skip[path.Clean(r.URL.Path)] && files.ServeFile(w, r, path.Join("static", name, "index.html")) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ExecutableDir returns the directory of the running binary, where servers
+// built with assets = "disk" find their files.
+func ExecutableDir() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(exe), nil
+}
+
+// fsName turns a URL path into a name within an fs.FS; the root is "".
+func fsName(urlPath string) string {
+	return strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+}
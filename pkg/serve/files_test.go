@@ -0,0 +1,109 @@
+package serve
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func siteFS() fstest.MapFS {
+	return fstest.MapFS{
+		"_assets/styles-1a2b3c4d.css": {Data: []byte("body { margin: 0 }")},
+		"wasm_exec.js":                {Data: []byte("// go")},
+		"public/robots.txt":           {Data: []byte("User-agent: *")},
+		"public/report-20240131.pdf":  {Data: []byte("%PDF")},
+		"static/index.html":           {Data: []byte("<h1>home</h1>")},
+		"static/about/index.html":     {Data: []byte("<h1>about</h1>")},
+		"static/news/index.html":      {Data: []byte("<h1>stale</h1>")},
+	}
+}
+
+func TestFilesETagAndCaching(t *testing.T) {
+	files := Files(siteFS())
+
+	w := httptest.NewRecorder()
+	files.ServeHTTP(w, httptest.NewRequest("GET", "/_assets/styles-1a2b3c4d.css", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "body { margin: 0 }" {
+		t.Fatalf("Expected asset, got %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+		t.Errorf("Expected immutable hashed asset, got %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/css; charset=utf-8" {
+		t.Errorf("Expected text/css, got %q", got)
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag")
+	}
+	r := httptest.NewRequest("GET", "/_assets/styles-1a2b3c4d.css", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	files.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for matching ETag, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	files.ServeHTTP(w, httptest.NewRequest("GET", "/wasm_exec.js", nil))
+	if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Expected unhashed file to revalidate, got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	files.ServeHTTP(w, httptest.NewRequest("GET", "/public/report-20240131.pdf", nil))
+	if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Expected public file outside _assets to revalidate, got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	files.ServeHTTP(w, httptest.NewRequest("GET", "/missing.js", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+}
+
+func TestFilesRange(t *testing.T) {
+	r := httptest.NewRequest("GET", "/_assets/styles-1a2b3c4d.css", nil)
+	r.Header.Set("Range", "bytes=0-3")
+	w := httptest.NewRecorder()
+	Files(siteFS()).ServeHTTP(w, r)
+
+	if w.Code != http.StatusPartialContent {
+		t.Errorf("Expected 206, got %d", w.Code)
+	}
+	if w.Body.String() != "body" {
+		t.Errorf("Expected first 4 bytes, got %q", w.Body.String())
+	}
+}
+
+func TestSite(t *testing.T) {
+	handler := Site(siteFS(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "rendered "+r.URL.Path)
+	}), "/news")
+
+	tests := []struct {
+		method, path, want string
+	}{
+		{"GET", "/", "<h1>home</h1>"},
+		{"GET", "/about", "<h1>about</h1>"},
+		{"GET", "/about/", "<h1>about</h1>"},
+		{"GET", "/robots.txt", "User-agent: *"},
+		{"GET", "/_assets/styles-1a2b3c4d.css", "body { margin: 0 }"},
+		{"GET", "/news", "rendered /news"},
+		{"GET", "/blog/post", "rendered /blog/post"},
+		{"GET", "/data.json", "rendered /data.json"},
+		{"POST", "/about", "rendered /about"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Body.String() != tt.want {
+			t.Errorf("%s %s: expected %q, got %q", tt.method, tt.path, tt.want, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package serve
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+// HealthcheckCommand is the argument that makes a built server probe a
+// running copy of itself instead of serving, for images without curl.
+const HealthcheckCommand = "healthcheck"
+
+const healthcheckTimeout = 5 * time.Second
+
+// HandleHealthcheck exits when the binary was started as
+// `server healthcheck [path]`: with 0 if the server answers path, which
+// defaults to /, and 1 otherwise. It returns for any other arguments.
+func HandleHealthcheck(cfg config.ServerConfig) {
+	if len(os.Args) < 2 || os.Args[1] != HealthcheckCommand {
+		return
+	}
+	path := "/"
+	if len(os.Args) > 2 {
+		path = os.Args[2]
+	}
+	if err := Healthcheck(cfg, path); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// Healthcheck requests path from the server listening on cfg's address
+// and fails unless it answers with a status below 400.
+func Healthcheck(cfg config.ServerConfig, path string) error {
+	host, port, err := net.SplitHostPort(Addr(cfg))
+	if err != nil {
+		return err
+	}
+	switch host {
+	case "", "0.0.0.0", "::", "localhost":
+		host = "127.0.0.1"
+	}
+
+	scheme := "http"
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLS.Enabled() {
+		scheme = "https"
+		// The probe checks the server is up, not who it is.
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	client := &http.Client{Timeout: healthcheckTimeout, Transport: transport}
+
+	resp, err := client.Get(fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, port), path))
+	if err != nil {
+		return fmt.Errorf("healthcheck: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("healthcheck: %s returned %d", path, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package serve
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+func serverConfig(t *testing.T, srv *httptest.Server) config.ServerConfig {
+	t.Helper()
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := strconv.Atoi(port)
+	return config.ServerConfig{Host: "0.0.0.0", Port: n}
+}
+
+func TestHealthcheck(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	cfg := serverConfig(t, srv)
+
+	if err := Healthcheck(cfg, "/"); err != nil {
+		t.Errorf("Expected a healthy server, got %v", err)
+	}
+	if err := Healthcheck(cfg, "/down"); err == nil {
+		t.Error("Expected a 503 to fail the healthcheck")
+	}
+
+	tlsSrv := httptest.NewTLSServer(handler)
+	defer tlsSrv.Close()
+	cfg = serverConfig(t, tlsSrv)
+	cfg.TLS = config.TLSConfig{Cert: "cert.pem", Key: "key.pem"}
+	if err := Healthcheck(cfg, "/"); err != nil {
+		t.Errorf("Expected the probe to accept the server's own certificate, got %v", err)
+	}
+
+	srv.Close()
+	if err := Healthcheck(serverConfig(t, srv), "/"); err == nil {
+		t.Error("Expected a stopped server to fail the healthcheck")
+	}
+}
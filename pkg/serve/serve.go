@@ -0,0 +1,212 @@
+// Package serve runs the HTTP server of built Galaxy apps: timeouts, panic
+// recovery, TLS, h2c and graceful shutdown, configured under [server], and
+// the files they serve from the binary or disk.
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/lambda"
+	"github.com/cameron-webmatter/galaxy/pkg/lifecycle"
+	"github.com/cameron-webmatter/galaxy/pkg/worker"
+)
+
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 60 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultShutdownTimeout   = 30 * time.Second
+)
+
+// Addr returns the address to listen on. The HOST and PORT environment
+// variables override the config, as most platforms assign the port.
+func Addr(cfg config.ServerConfig) string {
+	host := cfg.Host
+	if env := os.Getenv("HOST"); env != "" {
+		host = env
+	}
+	port := strconv.Itoa(cfg.Port)
+	if env := os.Getenv("PORT"); env != "" {
+		port = env
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// URL returns the address the server can be reached at, for logging.
+func URL(cfg config.ServerConfig) string {
+	addr := Addr(cfg)
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	if cfg.TLS.Enabled() {
+		return "https://" + addr
+	}
+	return "http://" + addr
+}
+
+// New returns a server for handler with cfg's timeouts and panic recovery.
+func New(cfg config.ServerConfig, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              Addr(cfg),
+		Handler:           Recover(handler),
+		ReadHeaderTimeout: timeout(cfg.ReadHeaderTimeout, defaultReadHeaderTimeout),
+		ReadTimeout:       timeout(cfg.ReadTimeout, defaultReadTimeout),
+		WriteTimeout:      timeout(cfg.WriteTimeout, defaultWriteTimeout),
+		IdleTimeout:       timeout(cfg.IdleTimeout, defaultIdleTimeout),
+	}
+
+	if cfg.H2C {
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetHTTP2(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	return srv
+}
+
+// Run serves handler until SIGINT or SIGTERM, then drains in-flight
+// requests and runs lc's shutdown hooks. lc may be nil. Deployed as an AWS
+// Lambda function, such as a Netlify Function, it serves invocations from
+// the Lambda runtime API instead, and compiled to js/wasm it serves a
+// JavaScript worker's fetch events.
+func Run(cfg config.ServerConfig, handler http.Handler, lc *lifecycle.Lifecycle) error {
+	if api := os.Getenv(lambda.RuntimeAPIEnv); api != "" {
+		slog.Info("serving lambda invocations", "runtime_api", api)
+		return lambda.Start(api, Recover(handler))
+	}
+	if runtime.GOOS == "js" {
+		slog.Info("serving worker fetch events")
+		return worker.Start(Recover(handler))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := New(cfg, handler)
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	slog.Info("server running", "url", URL(cfg))
+	return Serve(ctx, ln, srv, cfg, lc)
+}
+
+// Serve serves srv on ln until ctx is done. It then stops accepting
+// connections, waits up to the shutdown timeout for in-flight requests and
+// runs lc's shutdown hooks.
+func Serve(ctx context.Context, ln net.Listener, srv *http.Server, cfg config.ServerConfig, lc *lifecycle.Lifecycle) error {
+	if (cfg.TLS.Cert == "") != (cfg.TLS.Key == "") {
+		ln.Close()
+		return fmt.Errorf("tls: both cert and key are required")
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if cfg.TLS.Enabled() {
+			serveErr <- srv.ServeTLS(ln, cfg.TLS.Cert, cfg.TLS.Key)
+		} else {
+			serveErr <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down gracefully")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout(cfg.ShutdownTimeout, defaultShutdownTimeout))
+	defer cancel()
+
+	var errs []error
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("drain: %w", err))
+		srv.Close()
+	}
+	if lc != nil {
+		if err := lc.ExecuteShutdown(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Recover turns a panic in next into a 500 response and logs its stack. If
+// the response has already started, the connection is aborted instead.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			slog.Error("panic serving request", "method", r.Method, "path", r.URL.Path, "error", err, "stack", string(debug.Stack()))
+			if rw.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach Flush and Hijack on the
+// underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// timeout converts seconds from the config: zero selects def and a negative
+// value disables the timeout.
+func timeout(seconds int, def time.Duration) time.Duration {
+	switch {
+	case seconds == 0:
+		return def
+	case seconds < 0:
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
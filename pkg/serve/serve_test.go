@@ -0,0 +1,234 @@
+package serve
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/lifecycle"
+)
+
+type hook struct {
+	shutdown chan struct{}
+}
+
+func (h *hook) OnStartup() error { return nil }
+func (h *hook) OnShutdown() error {
+	close(h.shutdown)
+	return nil
+}
+
+func TestAddr(t *testing.T) {
+	cfg := config.ServerConfig{Host: "localhost", Port: 4322}
+	if got := Addr(cfg); got != "localhost:4322" {
+		t.Errorf("Expected localhost:4322, got %s", got)
+	}
+
+	t.Setenv("PORT", "8080")
+	t.Setenv("HOST", "0.0.0.0")
+	if got := Addr(cfg); got != "0.0.0.0:8080" {
+		t.Errorf("Expected env override 0.0.0.0:8080, got %s", got)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	srv := New(config.ServerConfig{ReadTimeout: 5, WriteTimeout: -1}, http.NotFoundHandler())
+
+	if srv.ReadHeaderTimeout != defaultReadHeaderTimeout {
+		t.Errorf("Expected default read header timeout, got %v", srv.ReadHeaderTimeout)
+	}
+	if srv.ReadTimeout != 5*time.Second {
+		t.Errorf("Expected 5s read timeout, got %v", srv.ReadTimeout)
+	}
+	if srv.WriteTimeout != 0 {
+		t.Errorf("Expected write timeout disabled, got %v", srv.WriteTimeout)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", w.Code)
+	}
+
+	started := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("boom")
+	}))
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("Expected ErrAbortHandler once the response started, got %v", err)
+		}
+	}()
+	started.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestServeDrainsOnShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inFlight := make(chan struct{})
+	srv := New(config.ServerConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+
+	h := &hook{shutdown: make(chan struct{})}
+	lc := lifecycle.NewLifecycle().Register(h)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, ln, srv, config.ServerConfig{}, lc) }()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+
+	<-inFlight
+	cancel()
+
+	if got := <-body; got != "done" {
+		t.Errorf("Expected in-flight request to finish, got %q", got)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+	select {
+	case <-h.shutdown:
+	default:
+		t.Error("Expected lifecycle shutdown hooks to run")
+	}
+
+	if _, err := http.Get("http://" + ln.Addr().String()); err == nil {
+		t.Error("Expected server to stop accepting connections")
+	}
+}
+
+func TestH2C(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.ServerConfig{H2C: true}
+	srv := New(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Serve(ctx, ln, srv, cfg, nil)
+
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	resp, err := (&http.Client{Transport: transport}).Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if data, _ := io.ReadAll(resp.Body); string(data) != "HTTP/2.0" {
+		t.Errorf("Expected HTTP/2.0, got %s", data)
+	}
+}
+
+func writeCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestServeTLS(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := writeCert(t)
+	cfg := config.ServerConfig{TLS: config.TLSConfig{Cert: certFile, Key: keyFile}}
+	srv := New(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.TLS != nil)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Serve(ctx, ln, srv, cfg, nil)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get("https://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if data, _ := io.ReadAll(resp.Body); string(data) != "true" {
+		t.Errorf("Expected a TLS request, got %s", data)
+	}
+	if got := URL(cfg); got[:8] != "https://" {
+		t.Errorf("Expected https URL, got %s", got)
+	}
+}
+
+func TestServeRequiresCertAndKey(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.ServerConfig{TLS: config.TLSConfig{Cert: "cert.pem"}}
+	if err := Serve(context.Background(), ln, New(cfg, http.NotFoundHandler()), cfg, nil); err == nil {
+		t.Error("Expected error for cert without key")
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -10,17 +11,22 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/cameron-webmatter/galaxy/internal/assets"
+	"github.com/cameron-webmatter/galaxy/pkg/actions"
+	"github.com/cameron-webmatter/galaxy/pkg/auth"
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
 	"github.com/cameron-webmatter/galaxy/pkg/compiler"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
 	"github.com/cameron-webmatter/galaxy/pkg/endpoints"
 	"github.com/cameron-webmatter/galaxy/pkg/executor"
 	"github.com/cameron-webmatter/galaxy/pkg/lifecycle"
 	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware/builtin"
 	"github.com/cameron-webmatter/galaxy/pkg/parser"
 	"github.com/cameron-webmatter/galaxy/pkg/router"
 	"github.com/cameron-webmatter/galaxy/pkg/ssr"
+	"github.com/cameron-webmatter/galaxy/pkg/telemetry"
 	"github.com/cameron-webmatter/galaxy/pkg/template"
 )
 
@@ -33,16 +39,39 @@ type DevServer struct {
 	Bundler            *assets.Bundler
 	Compiler           *compiler.ComponentCompiler
 	EndpointCompiler   *endpoints.EndpointCompiler
+	ActionCompiler     *actions.Compiler
 	Verbose            bool
 	Lifecycle          *lifecycle.Lifecycle
 	MiddlewareCompiler *middleware.MiddlewareCompiler
 	LoadedMiddleware   *middleware.LoadedMiddleware
 	MiddlewareChain    *middleware.Chain
-	HasMiddleware      bool
-	UseCodegen         bool
-	PageCache          *PageCache
-	PluginCompiler     *PluginCompiler
-	compileMu          sync.Mutex
+	// BuiltinMiddleware comes from [middleware] config and runs first.
+	BuiltinMiddleware []middleware.Middleware
+	HasMiddleware     bool
+	UseCodegen        bool
+	PageCache         *PageCache
+	PluginCompiler    *PluginCompiler
+	// Telemetry configures logs, metrics and tracing. Request logs are
+	// only written when Verbose is set.
+	Telemetry config.TelemetryConfig
+	// WebSocketOrigins are the other origins WS endpoints accept.
+	WebSocketOrigins []string
+
+	// Locals, when set, seeds the Locals of every request, so an embedding
+	// application can hand services to pages and endpoints.
+	Locals func(*http.Request) map[string]any
+	// Endpoints, Actions and RouteMiddleware replace the plugins compiled
+	// from the project's Go files. Endpoints and Actions are keyed by route
+	// pattern, RouteMiddleware by the pages-relative directory of a
+	// _middleware.go file ("." for the pages root).
+	Endpoints       map[string]*endpoints.LoadedEndpoint
+	Actions         map[string]actions.Set
+	RouteMiddleware map[string][]middleware.Middleware
+	// Emulate wraps every request in the deploy adapter's emulation of its
+	// platform, such as the request headers it sets.
+	Emulate func(http.Handler) http.Handler
+
+	compileMu sync.Mutex
 }
 
 func NewDevServer(rootDir, pagesDir, publicDir string, port int, verbose bool) *DevServer {
@@ -66,33 +95,26 @@ func NewDevServer(rootDir, pagesDir, publicDir string, port int, verbose bool) *
 		PagesDir:           pagesDir,
 		PublicDir:          publicDir,
 		Port:               port,
-		Bundler:            assets.NewBundler(".galaxy"),
+		Bundler:            assets.NewBundler(filepath.Join(rootDir, ".galaxy")),
 		Compiler:           compiler.NewComponentCompiler(srcDir),
 		EndpointCompiler:   endpoints.NewCompiler(rootDir, ".galaxy/endpoints"),
+		ActionCompiler:     actions.NewCompiler(rootDir, ".galaxy/actions"),
 		MiddlewareCompiler: middleware.NewCompiler(rootDir, ".galaxy/middleware"),
 		Verbose:            verbose,
 		UseCodegen:         useCodegen,
 		PageCache:          NewPageCache(),
 		PluginCompiler:     NewPluginCompiler(".galaxy", "dev-server", galaxyPath, rootDir),
+		Telemetry:          config.DefaultConfig().Telemetry,
 	}
 
 	middlewarePath := filepath.Join(srcDir, "middleware.go")
 	if _, err := os.Stat(middlewarePath); err == nil {
 		loaded, err := srv.MiddlewareCompiler.Load(middlewarePath)
 		if err != nil {
-			fmt.Printf("⚠ Middleware compile failed: %v\n", err)
+			slog.Warn("middleware compile failed", "file", middlewarePath, "error", err)
 		} else {
 			srv.LoadedMiddleware = loaded
-			srv.MiddlewareChain = middleware.NewChain()
-
-			if loaded.Sequence != nil && len(loaded.Sequence) > 0 {
-				for _, mw := range loaded.Sequence {
-					srv.MiddlewareChain.Use(mw)
-				}
-			} else if loaded.OnRequest != nil {
-				srv.MiddlewareChain.Use(loaded.OnRequest)
-			}
-
+			srv.MiddlewareChain = middleware.NewChain().Use(loaded.Middleware()...)
 			srv.HasMiddleware = true
 		}
 	}
@@ -112,7 +134,15 @@ func (s *DevServer) Start() error {
 		}
 	}
 
-	http.HandleFunc("/", s.logRequest(s.handleRequest))
+	tcfg := s.Telemetry
+	tcfg.Log.Requests = tcfg.Log.Requests && s.Verbose
+	stopTelemetry, err := telemetry.Setup(tcfg)
+	if err != nil {
+		return err
+	}
+	defer stopTelemetry()
+
+	http.Handle("/", s.handler(tcfg))
 
 	addr := fmt.Sprintf(":%d", s.Port)
 	fmt.Printf("🚀 Dev server running at http://localhost%s\n", addr)
@@ -124,6 +154,21 @@ func (s *DevServer) Start() error {
 	return http.ListenAndServe(addr, nil)
 }
 
+// Handler serves the site without starting a listener, for mounting in
+// another server. Routes must have been discovered first; unlike Start it
+// leaves the default logger and tracer to the caller.
+func (s *DevServer) Handler() http.Handler {
+	return s.handler(s.Telemetry)
+}
+
+func (s *DevServer) handler(tcfg config.TelemetryConfig) http.Handler {
+	h := telemetry.Handler(tcfg, http.HandlerFunc(s.handleRequest))
+	if s.Emulate != nil {
+		h = s.Emulate(h)
+	}
+	return h
+}
+
 func (s *DevServer) ReloadRoutes() error {
 	if err := s.Router.Reload(); err != nil {
 		return err
@@ -145,72 +190,50 @@ func (s *DevServer) ReloadMiddleware() error {
 	}
 
 	s.LoadedMiddleware = loaded
-	s.MiddlewareChain = middleware.NewChain()
-
-	if loaded.Sequence != nil && len(loaded.Sequence) > 0 {
-		for _, mw := range loaded.Sequence {
-			s.MiddlewareChain.Use(mw)
-		}
-	} else if loaded.OnRequest != nil {
-		s.MiddlewareChain.Use(loaded.OnRequest)
-	}
-
+	s.MiddlewareChain = middleware.NewChain().Use(loaded.Middleware()...)
 	s.HasMiddleware = true
 	return nil
 }
 
-func (s *DevServer) printRoutes() {
-	for _, route := range s.Router.Routes {
-		fmt.Printf("  %s\n", route.Pattern)
+// middlewareFor composes the configured built-ins, src/middleware.go and the
+// _middleware.go files above the route, outermost first.
+func (s *DevServer) middlewareFor(route *router.Route) (*middleware.Chain, error) {
+	chain := middleware.NewChain().Use(s.BuiltinMiddleware...)
+	if s.HasMiddleware && s.LoadedMiddleware != nil {
+		chain.Use(s.LoadedMiddleware.Middleware()...)
 	}
-	fmt.Println()
-}
 
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
-
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
-}
-
-func (s *DevServer) logRequest(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		rw := &responseWriter{ResponseWriter: w, statusCode: 200}
-
-		next(rw, r)
-
-		if s.Verbose {
-			duration := time.Since(start)
-			statusColor := getStatusColor(rw.statusCode)
-			methodColor := "\033[36m"
-			reset := "\033[0m"
+	for _, file := range route.Middleware {
+		if rel, err := filepath.Rel(s.PagesDir, filepath.Dir(file)); err == nil {
+			if mws, ok := s.RouteMiddleware[filepath.ToSlash(rel)]; ok {
+				chain.Use(mws...)
+				continue
+			}
+		}
 
-			fmt.Printf("%s%s%s %s - %s%d%s (%dms)\n",
-				methodColor, r.Method, reset,
-				r.URL.Path,
-				statusColor, rw.statusCode, reset,
-				duration.Milliseconds())
+		loaded, err := s.MiddlewareCompiler.Load(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
 		}
+		chain.Use(loaded.Middleware()...)
 	}
+
+	rule, err := auth.FileRule(route.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		chain.Use(auth.Guard(*rule))
+	}
+
+	return chain, nil
 }
 
-func getStatusColor(status int) string {
-	switch {
-	case status >= 500:
-		return "\033[31m"
-	case status >= 400:
-		return "\033[33m"
-	case status >= 300:
-		return "\033[36m"
-	case status >= 200:
-		return "\033[32m"
-	default:
-		return "\033[0m"
+func (s *DevServer) printRoutes() {
+	for _, route := range s.Router.Routes {
+		fmt.Printf("  %s\n", route.Pattern)
 	}
+	fmt.Println()
 }
 
 func (s *DevServer) handleRequest(w http.ResponseWriter, r *http.Request) {
@@ -229,12 +252,25 @@ func (s *DevServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	telemetry.SetRoute(r, route.Pattern)
 
 	mwCtx := middleware.NewContext(w, r)
 	mwCtx.Params = params
+	if s.Locals != nil {
+		for k, v := range s.Locals(r) {
+			mwCtx.Locals[k] = v
+		}
+	}
+
+	chain, err := s.middlewareFor(route)
+	if err != nil {
+		telemetry.Logger(r.Context()).Error("middleware compile failed", "route", route.Pattern, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	if s.HasMiddleware && s.MiddlewareChain != nil {
-		err := s.MiddlewareChain.Execute(mwCtx, func(ctx *middleware.Context) error {
+	if chain.Len() > 0 {
+		err := chain.Execute(mwCtx, func(ctx *middleware.Context) error {
 			if route.IsEndpoint {
 				s.handleEndpoint(route, ctx, params)
 			} else {
@@ -257,19 +293,34 @@ func (s *DevServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *DevServer) handleEndpoint(route *router.Route, mwCtx *middleware.Context, params map[string]string) {
-	endpoint, err := s.EndpointCompiler.Load(route.FilePath)
-	if err != nil {
-		http.Error(mwCtx.Response, fmt.Sprintf("Load endpoint: %v", err), http.StatusInternalServerError)
-		return
+	endpoint, ok := s.Endpoints[route.Pattern]
+	if !ok {
+		var err error
+		endpoint, err = s.EndpointCompiler.Load(route.FilePath)
+		if err != nil {
+			http.Error(mwCtx.Response, fmt.Sprintf("Load endpoint: %v", err), http.StatusInternalServerError)
+			return
+		}
 	}
 
-	if err := endpoints.HandleEndpoint(endpoint, mwCtx.Response, mwCtx.Request, params, mwCtx.Locals); err != nil {
-		http.Error(mwCtx.Response, err.Error(), http.StatusInternalServerError)
+	ctx, span := telemetry.Start(mwCtx.Request.Context(), "endpoint", slog.String("http.route", route.Pattern))
+	defer span.End()
+	ctx = endpoints.WithWebSocketOrigins(ctx, s.WebSocketOrigins)
+
+	if err := endpoints.HandleEndpoint(endpoint, mwCtx.Response, mwCtx.Request.WithContext(ctx), params, mwCtx.Locals); err != nil {
+		span.RecordError(err)
+		endpoints.WriteError(mwCtx.Response, err)
 	}
 }
 
 func (s *DevServer) handlePage(route *router.Route, mwCtx *middleware.Context, params map[string]string) {
+	actionSet, actionResult, handled := s.runActions(route, mwCtx, params)
+	if handled {
+		return
+	}
+
 	if s.UseCodegen {
+		mwCtx.Request = actions.WithResult(mwCtx.Request, actionResult)
 		s.handlePageWithCodegen(route, mwCtx, params)
 		return
 	}
@@ -286,9 +337,6 @@ func (s *DevServer) handlePage(route *router.Route, mwCtx *middleware.Context, p
 		return
 	}
 
-	resolver := s.Compiler.Resolver
-	resolver.SetCurrentFile(route.FilePath)
-
 	imports := make([]compiler.Import, len(comp.Imports))
 	for i, imp := range comp.Imports {
 		imports[i] = compiler.Import{
@@ -297,7 +345,7 @@ func (s *DevServer) handlePage(route *router.Route, mwCtx *middleware.Context, p
 			IsComponent: imp.IsComponent,
 		}
 	}
-	resolver.ParseImports(imports)
+	renderReq := s.Compiler.NewRequest(route.FilePath, imports)
 
 	ctx := executor.NewContext()
 
@@ -334,34 +382,56 @@ func (s *DevServer) handlePage(route *router.Route, mwCtx *middleware.Context, p
 	ctx.SetLocals(mwCtx.Locals)
 
 	ctx.SetParams(params)
+	ctx.SetSession(mwCtx.Session(), mwCtx.Cookies())
+	ctx.SetCSRFToken(builtin.CSRFToken(mwCtx.Request))
+	ctx.SetCache(cache.PolicyFrom(mwCtx.Request))
 
 	for k, v := range params {
 		ctx.Set(k, v)
 	}
 
+	if actionSet != nil {
+		actions.Expose(ctx, actionSet.Names(), actionResult)
+	}
+
 	if comp.Frontmatter != "" {
-		if err := ctx.Execute(comp.Frontmatter); err != nil {
+		_, span := telemetry.Start(mwCtx.Request.Context(), "frontmatter", slog.String("http.route", route.Pattern))
+		err := ctx.Execute(comp.Frontmatter)
+		span.RecordError(err)
+		span.End()
+		if err != nil {
 			http.Error(mwCtx.Response, fmt.Sprintf("Execution error: %v", err), http.StatusInternalServerError)
 			return
 		}
 	}
 
+	response := ctx.GetResponse()
+	response.ApplyHeaders(mwCtx.Response.Header())
+
 	if ctx.ShouldRedirect {
 		http.Redirect(mwCtx.Response, mwCtx.Request, ctx.RedirectURL, ctx.RedirectStatus)
 		return
 	}
 
-	s.Compiler.CollectedStyles = nil
-	processedTemplate := s.Compiler.ProcessComponentTags(comp.Template, ctx)
+	if ctx.NotFound {
+		http.NotFound(mwCtx.Response, mwCtx.Request)
+		return
+	}
+
+	renderCtx, done := telemetry.Render(mwCtx.Request.Context(), route.Pattern)
+	renderReq.Context = renderCtx
+	processedTemplate := renderReq.ProcessComponentTags(comp.Template, ctx)
 
 	engine := template.NewEngine(ctx)
+	engine.Page = true
 	rendered, err := engine.Render(processedTemplate, nil)
+	done(err)
 	if err != nil {
 		http.Error(mwCtx.Response, fmt.Sprintf("Render error: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	allStyles := append(comp.Styles, s.Compiler.CollectedStyles...)
+	allStyles := append(comp.Styles, renderReq.Styles...)
 	compWithStyles := &parser.Component{
 		Frontmatter: comp.Frontmatter,
 		Template:    comp.Template,
@@ -398,12 +468,44 @@ func (s *DevServer) handlePage(route *router.Route, mwCtx *middleware.Context, p
 
 	rendered = s.Bundler.InjectAssetsWithWasm(rendered, cssPath, jsPath, scopeID, wasmAssets)
 
-	mwCtx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if actionSet != nil {
+		rendered = actions.InjectClient(rendered)
+	}
+
+	if mwCtx.Response.Header().Get("Content-Type") == "" {
+		mwCtx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	status := http.StatusOK
+	if actionResult != nil {
+		status = actionResult.Status
+	}
+	mwCtx.Response.WriteHeader(response.StatusOr(status))
 	mwCtx.Response.Write([]byte(rendered))
 }
 
+// runActions executes the page's form action for POST requests. handled is
+// true when a response has already been written.
+func (s *DevServer) runActions(route *router.Route, mwCtx *middleware.Context, params map[string]string) (actions.Set, *actions.Result, bool) {
+	set, ok := s.Actions[route.Pattern]
+	if !ok {
+		if route.ActionsFile == "" {
+			return nil, nil, false
+		}
+
+		var err error
+		set, err = s.ActionCompiler.Load(route.ActionsFile)
+		if err != nil {
+			http.Error(mwCtx.Response, fmt.Sprintf("Load actions: %v", err), http.StatusInternalServerError)
+			return nil, nil, true
+		}
+	}
+
+	result, handled := actions.Handle(set, mwCtx.Response, mwCtx.Request, params, mwCtx.Locals)
+	return set, result, handled
+}
+
 func (s *DevServer) serveStatic(w http.ResponseWriter, r *http.Request) {
-	galaxyPath := filepath.Join(".galaxy", r.URL.Path)
+	galaxyPath := filepath.Join(s.Bundler.OutDir, r.URL.Path)
 	if _, err := os.Stat(galaxyPath); err == nil {
 		http.ServeFile(w, r, galaxyPath)
 		return
@@ -444,9 +546,6 @@ func (s *DevServer) handlePageWithCodegen(route *router.Route, mwCtx *middleware
 
 	// Process component tags BEFORE compiling
 	// This resolves <Layout>, <Nav>, etc.
-	resolver := s.Compiler.Resolver
-	resolver.SetCurrentFile(route.FilePath)
-
 	imports := make([]compiler.Import, len(comp.Imports))
 	for i, imp := range comp.Imports {
 		imports[i] = compiler.Import{
@@ -455,12 +554,11 @@ func (s *DevServer) handlePageWithCodegen(route *router.Route, mwCtx *middleware
 			IsComponent: imp.IsComponent,
 		}
 	}
-	resolver.ParseImports(imports)
+	renderReq := s.Compiler.NewRequest(route.FilePath, imports)
 
 	// Create minimal executor context for component processing only
 	dummyCtx := executor.NewContext()
-	s.Compiler.CollectedStyles = nil
-	processedTemplate := s.Compiler.ProcessComponentTags(comp.Template, dummyCtx)
+	processedTemplate := renderReq.ProcessComponentTags(comp.Template, dummyCtx)
 
 	// Update component with processed template
 	comp.Template = processedTemplate
@@ -475,7 +573,7 @@ func (s *DevServer) handlePageWithCodegen(route *router.Route, mwCtx *middleware
 			cached.Template = comp.Template
 			cached.TemplateHash = tmplHash
 		}
-		fmt.Printf("⚡ Cache hit (in-memory): %s\n", route.Pattern)
+		slog.Debug("page cache hit", "route", route.Pattern)
 	} else {
 		// Lock during compilation to prevent duplicate compiles
 		s.compileMu.Lock()
@@ -484,7 +582,7 @@ func (s *DevServer) handlePageWithCodegen(route *router.Route, mwCtx *middleware
 		cached, ok = s.PageCache.Get(route.Pattern)
 		if ok && cached.FrontmatterHash == fmHash {
 			s.compileMu.Unlock()
-			fmt.Printf("⚡ Cache hit (after lock): %s\n", route.Pattern)
+			slog.Debug("page cache hit after lock", "route", route.Pattern)
 		} else {
 			plugin, err := s.PluginCompiler.CompilePage(route, comp, fmHash)
 			s.compileMu.Unlock()
@@ -499,7 +597,7 @@ func (s *DevServer) handlePageWithCodegen(route *router.Route, mwCtx *middleware
 	}
 
 	// Bundle styles, scripts, and WASM
-	allStyles := append(comp.Styles, s.Compiler.CollectedStyles...)
+	allStyles := append(comp.Styles, renderReq.Styles...)
 	compWithStyles := &parser.Component{
 		Frontmatter: comp.Frontmatter,
 		Template:    comp.Template,
@@ -540,7 +638,9 @@ func (s *DevServer) handlePageWithCodegen(route *router.Route, mwCtx *middleware
 	mwCtx.Response = recorder
 
 	// Call plugin handler
-	cached.Handler(mwCtx.Response, mwCtx.Request, params, mwCtx.Locals)
+	renderCtx, done := telemetry.Render(mwCtx.Request.Context(), route.Pattern)
+	cached.Handler(mwCtx.Response, mwCtx.Request.WithContext(renderCtx), params, mwCtx.Locals)
+	done(nil)
 
 	// Get captured HTML
 	rendered := recorder.Body.String()
@@ -548,7 +648,20 @@ func (s *DevServer) handlePageWithCodegen(route *router.Route, mwCtx *middleware
 	// Inject assets (WASM, CSS, JS)
 	rendered = s.Bundler.InjectAssetsWithWasm(rendered, cssPath, jsPath, scopeID, wasmAssets)
 
-	// Write final output to original writer
-	originalWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Write final output to original writer, with the headers the page set:
+	// redirects, cookies and Galaxy.Response headers.
+	header := originalWriter.Header()
+	for key, values := range recorder.Header() {
+		if key == "Set-Cookie" {
+			header[key] = append(header[key], values...)
+			continue
+		}
+		header[key] = values
+	}
+	header.Del("Content-Length")
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/html; charset=utf-8")
+	}
+	originalWriter.WriteHeader(recorder.Code)
 	originalWriter.Write([]byte(rendered))
 }
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/parser"
+)
+
+func TestCodegenPageKeepsHeaders(t *testing.T) {
+	root := t.TempDir()
+	pagesDir := filepath.Join(root, "src", "pages")
+	if err := os.MkdirAll(pagesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	source := "---\nGalaxy.Redirect(\"/login\", 302)\n---\n<p>Private</p>\n"
+	if err := os.WriteFile(filepath.Join(pagesDir, "account.gxc"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewDevServer(root, pagesDir, filepath.Join(root, "public"), 0, false)
+	srv.UseCodegen = true
+	if err := srv.Router.Discover(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A compiled page that redirects and sets a cookie, as Galaxy.Redirect
+	// and Galaxy.Cookies.Set do.
+	comp, err := parser.Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.PageCache.Set("/account", &PagePlugin{
+		FrontmatterHash: HashContent(comp.Frontmatter),
+		Handler: func(w http.ResponseWriter, r *http.Request, params map[string]string, locals map[string]interface{}) {
+			http.SetCookie(w, &http.Cookie{Name: "flash", Value: "login-first"})
+			w.Header().Set("Location", "/login")
+			w.WriteHeader(http.StatusFound)
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/account", nil))
+
+	if rec.Code != http.StatusFound {
+		t.Errorf("Expected status 302, got %d", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/login" {
+		t.Errorf("Expected Location /login, got %q", loc)
+	}
+	if cookie := rec.Header().Get("Set-Cookie"); cookie != "flash=login-first" {
+		t.Errorf("Expected the flash cookie, got %q", cookie)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Expected the default content type, got %q", ct)
+	}
+}
+
+func TestCodegenPageKeepsContentType(t *testing.T) {
+	root := t.TempDir()
+	pagesDir := filepath.Join(root, "src", "pages")
+	if err := os.MkdirAll(pagesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	source := "---\n---\n<feed></feed>\n"
+	if err := os.WriteFile(filepath.Join(pagesDir, "feed.gxc"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewDevServer(root, pagesDir, filepath.Join(root, "public"), 0, false)
+	srv.UseCodegen = true
+	if err := srv.Router.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	comp, _ := parser.Parse(source)
+	srv.PageCache.Set("/feed", &PagePlugin{
+		FrontmatterHash: HashContent(comp.Frontmatter),
+		Handler: func(w http.ResponseWriter, r *http.Request, params map[string]string, locals map[string]interface{}) {
+			w.Header().Set("Content-Type", "application/atom+xml")
+			w.Header().Set("X-Custom", "yes")
+			w.Write([]byte("<feed></feed>"))
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/feed", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/atom+xml" {
+		t.Errorf("Expected the page's content type, got %q", ct)
+	}
+	if rec.Header().Get("X-Custom") != "yes" {
+		t.Errorf("Expected the page's headers, got %v", rec.Header())
+	}
+}
@@ -136,7 +136,7 @@ func init() {
 
 	goMod := fmt.Sprintf(`module %s
 
-go 1.23
+go 1.24
 
 replace github.com/cameron-webmatter/galaxy => %s
 `, uniqueModuleName, pc.GalaxyPath)
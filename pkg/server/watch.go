@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch reloads components, routes and middleware as files under the
+// source directory change, until ctx is done. It returns once the watcher
+// is set up.
+func (s *DevServer) Watch(ctx context.Context) error {
+	srcDir := filepath.Dir(s.PagesDir)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := addRecursive(watcher, srcDir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove) != 0 {
+					s.handleChange(watcher, event, srcDir)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("watcher error", "error", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *DevServer) handleChange(watcher *fsnotify.Watcher, event fsnotify.Event, srcDir string) {
+	slog.Info("change detected", "file", event.Name)
+
+	if event.Op&fsnotify.Create != 0 {
+		info, err := os.Stat(event.Name)
+		if err == nil && info.IsDir() && isUnderDir(event.Name, srcDir) {
+			if err := addRecursive(watcher, event.Name); err != nil {
+				slog.Warn("failed to watch new directory", "dir", event.Name, "error", err)
+			}
+		}
+	}
+
+	created := event.Op&(fsnotify.Create|fsnotify.Remove) != 0
+
+	if filepath.Ext(event.Name) == ".gxc" && isUnderDir(event.Name, srcDir) {
+		s.Compiler.ClearCache()
+
+		if created && isUnderDir(event.Name, s.PagesDir) {
+			if err := s.ReloadRoutes(); err != nil {
+				slog.Warn("route reload failed", "error", err)
+			}
+		}
+	}
+
+	isRouteFile := strings.HasSuffix(event.Name, router.ActionsSuffix) || filepath.Base(event.Name) == router.MiddlewareFile
+	if isRouteFile && created && isUnderDir(event.Name, s.PagesDir) {
+		if err := s.ReloadRoutes(); err != nil {
+			slog.Warn("route reload failed", "error", err)
+		}
+	}
+
+	if filepath.Base(event.Name) == "middleware.go" && isUnderDir(event.Name, srcDir) {
+		if err := s.ReloadMiddleware(); err != nil {
+			slog.Warn("middleware reload failed", "error", err)
+		} else {
+			slog.Info("middleware reloaded")
+		}
+	}
+}
+
+func addRecursive(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+func isUnderDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return !strings.HasPrefix(rel, "..") && rel != "."
+}
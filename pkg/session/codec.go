@@ -0,0 +1,138 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidValue = errors.New("session: invalid or tampered value")
+	ErrExpired      = errors.New("session: value expired")
+)
+
+// Codec signs, and optionally encrypts, cookie values. The first secret is
+// used for new values; the others are still accepted so secrets can be
+// rotated without logging everyone out.
+type Codec struct {
+	keys    []codecKey
+	encrypt bool
+}
+
+type codecKey struct {
+	sign []byte
+	aead cipher.AEAD
+}
+
+func NewCodec(encrypt bool, secrets ...string) (*Codec, error) {
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("session: at least one secret is required")
+	}
+
+	c := &Codec{encrypt: encrypt}
+	for i, secret := range secrets {
+		if len(secret) < 16 {
+			return nil, fmt.Errorf("session: secret %d is shorter than 16 bytes", i)
+		}
+
+		block, err := aes.NewCipher(derive(secret, "encrypt"))
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		c.keys = append(c.keys, codecKey{sign: derive(secret, "sign"), aead: aead})
+	}
+
+	return c, nil
+}
+
+func derive(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("galaxy-session-" + purpose))
+	return mac.Sum(nil)
+}
+
+// Encode binds value to name and the current time.
+func (c *Codec) Encode(name string, value []byte) (string, error) {
+	payload := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(payload, uint64(time.Now().Unix()))
+	copy(payload[8:], value)
+
+	key := c.keys[0]
+	if c.encrypt {
+		nonce := make([]byte, key.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		sealed := key.aead.Seal(nonce, nonce, payload, []byte(name))
+		return base64.RawURLEncoding.EncodeToString(sealed), nil
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sign(key.sign, name, encoded)), nil
+}
+
+// Decode verifies an encoded value. maxAge of zero disables the age check.
+// rotated reports that an older secret matched and the value should be
+// re-encoded.
+func (c *Codec) Decode(name, encoded string, maxAge time.Duration) (value []byte, rotated bool, err error) {
+	for i, key := range c.keys {
+		payload, ok := c.open(key, name, encoded)
+		if !ok {
+			continue
+		}
+		if len(payload) < 8 {
+			return nil, false, ErrInvalidValue
+		}
+
+		issued := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+		if maxAge > 0 && time.Since(issued) > maxAge {
+			return nil, false, ErrExpired
+		}
+
+		return payload[8:], i > 0, nil
+	}
+
+	return nil, false, ErrInvalidValue
+}
+
+func (c *Codec) open(key codecKey, name, encoded string) ([]byte, bool) {
+	if c.encrypt {
+		sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil || len(sealed) < key.aead.NonceSize() {
+			return nil, false
+		}
+		nonce, ciphertext := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+		payload, err := key.aead.Open(nil, nonce, ciphertext, []byte(name))
+		return payload, err == nil
+	}
+
+	data, mac, ok := strings.Cut(encoded, ".")
+	if !ok {
+		return nil, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(mac)
+	if err != nil || !hmac.Equal(sig, sign(key.sign, name, data)) {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(data)
+	return payload, err == nil
+}
+
+func sign(key []byte, name, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "|" + data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,99 @@
+package session
+
+import (
+	"net/http"
+	"time"
+)
+
+// Cookies reads request cookies and queues response cookies. Signed values
+// use the codec of the request's session store.
+type Cookies struct {
+	w http.ResponseWriter
+	r *http.Request
+}
+
+func NewCookies(w http.ResponseWriter, r *http.Request) *Cookies {
+	return &Cookies{w: w, r: r}
+}
+
+// Get returns the raw cookie value, or "".
+func (c *Cookies) Get(name string) string {
+	cookie, err := c.r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+func (c *Cookies) Has(name string) bool {
+	_, err := c.r.Cookie(name)
+	return err == nil
+}
+
+// Set queues a cookie for the whole site. maxAge is in seconds; 0 makes it
+// a browser-session cookie.
+func (c *Cookies) Set(name, value string, maxAge int) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if maxAge > 0 {
+		cookie.Expires = time.Now().Add(time.Duration(maxAge) * time.Second)
+	}
+	c.SetCookie(cookie)
+}
+
+func (c *Cookies) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(c.w, cookie)
+}
+
+func (c *Cookies) Delete(name string) {
+	c.SetCookie(&http.Cookie{Name: name, Path: "/", MaxAge: -1, Expires: time.Unix(0, 0)})
+}
+
+// GetSigned returns a value written by SetSigned, or "" if it is missing,
+// tampered with or no session store is configured.
+func (c *Cookies) GetSigned(name string) string {
+	codec := c.codec()
+	if codec == nil {
+		return ""
+	}
+
+	raw := c.Get(name)
+	if raw == "" {
+		return ""
+	}
+
+	value, _, err := codec.Decode(name, raw, 0)
+	if err != nil {
+		return ""
+	}
+	return string(value)
+}
+
+// SetSigned is Set with the value signed, and encrypted when the store's
+// codec encrypts.
+func (c *Cookies) SetSigned(name, value string, maxAge int) error {
+	codec := c.codec()
+	if codec == nil {
+		return ErrNoStore
+	}
+
+	encoded, err := codec.Encode(name, []byte(value))
+	if err != nil {
+		return err
+	}
+	c.Set(name, encoded, maxAge)
+	return nil
+}
+
+func (c *Cookies) codec() *Codec {
+	if st := stateFrom(c.r); st != nil {
+		return st.store.Codec()
+	}
+	return nil
+}
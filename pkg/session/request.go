@@ -0,0 +1,141 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+)
+
+var ErrNoStore = errors.New("session: no store configured for this request")
+
+type contextKey struct{}
+
+// state is shared by every copy of the request derived after WithStore, so
+// the session is loaded at most once and committed by whichever writer
+// goes first.
+type state struct {
+	store Store
+
+	mu      sync.Mutex
+	r       *http.Request
+	sess    *Session
+	err     error
+	loaded  bool
+	written bool
+}
+
+// WithStore attaches store to the request. The session itself is loaded on
+// first use.
+func WithStore(r *http.Request, store Store) *http.Request {
+	st := &state{store: store, r: r}
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, st))
+}
+
+func stateFrom(r *http.Request) *state {
+	if r == nil {
+		return nil
+	}
+	st, _ := r.Context().Value(contextKey{}).(*state)
+	return st
+}
+
+// Load returns the request's session, loading it from the store the first
+// time.
+func Load(r *http.Request) (*Session, error) {
+	st := stateFrom(r)
+	if st == nil {
+		return nil, ErrNoStore
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if !st.loaded {
+		st.sess, st.err = st.store.Load(st.r)
+		st.loaded = true
+	}
+	return st.sess, st.err
+}
+
+// FromRequest is Load for callers that cannot handle errors. Without a store
+// it returns an empty session that is never saved.
+func FromRequest(r *http.Request) *Session {
+	sess, err := Load(r)
+	if err != nil || sess == nil {
+		return New()
+	}
+	return sess
+}
+
+// Commit saves a loaded session, setting its cookie on w. It is safe to call
+// more than once; only the first call writes.
+func Commit(w http.ResponseWriter, r *http.Request) error {
+	st := stateFrom(r)
+	if st == nil {
+		return nil
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.written || !st.loaded || st.sess == nil {
+		return nil
+	}
+	st.written = true
+	return st.store.Save(w, r, st.sess)
+}
+
+// ResponseWriter commits the session just before the response headers are
+// sent, since Set-Cookie cannot be added afterwards.
+type ResponseWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	started bool
+}
+
+func NewResponseWriter(w http.ResponseWriter, r *http.Request) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w, r: r}
+}
+
+func (w *ResponseWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+
+	// The status line is about to go out, so a failed save can only be
+	// logged.
+	if err := Commit(w.ResponseWriter, w.r); err != nil {
+		log.Printf("session: %v", err)
+	}
+}
+
+func (w *ResponseWriter) WriteHeader(code int) {
+	w.start()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	w.start()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *ResponseWriter) Flush() {
+	w.start()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close commits a session that no write has saved yet.
+func (w *ResponseWriter) Close() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	return Commit(w.ResponseWriter, w.r)
+}
@@ -0,0 +1,240 @@
+package session
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// backend persists session data by ID for the server-side stores. The cookie
+// only carries the signed ID.
+type backend interface {
+	load(id string) ([]byte, bool, error)
+	save(id string, data []byte, expires time.Time) error
+	delete(id string) error
+}
+
+type serverStore struct {
+	codec   *Codec
+	opts    Options
+	backend backend
+}
+
+func (s *serverStore) Codec() *Codec {
+	return s.codec
+}
+
+func (s *serverStore) Load(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(s.opts.Name)
+	if err != nil {
+		return New(), nil
+	}
+
+	rawID, rotated, err := s.codec.Decode(s.opts.Name, cookie.Value, 0)
+	if err != nil || !validID(string(rawID)) {
+		return New(), nil
+	}
+	id := string(rawID)
+
+	data, ok, err := s.backend.load(id)
+	if err != nil {
+		return nil, fmt.Errorf("load session: %w", err)
+	}
+	if !ok {
+		return New(), nil
+	}
+
+	sess := &Session{ID: id, values: make(map[string]any), flashes: make(map[string][]string), needsCookie: rotated}
+	if err := sess.unmarshal(data); err != nil {
+		return New(), nil
+	}
+	return sess, nil
+}
+
+func (s *serverStore) Save(w http.ResponseWriter, r *http.Request, sess *Session) error {
+	if sess.destroyed {
+		if !sess.IsNew {
+			if err := s.backend.delete(sess.ID); err != nil {
+				return err
+			}
+		}
+		if sess.previousID != "" {
+			s.backend.delete(sess.previousID)
+		}
+		http.SetCookie(w, s.opts.expired())
+		return nil
+	}
+
+	if !sess.IsDirty() && !sess.needsCookie {
+		return nil
+	}
+
+	data, err := sess.marshal()
+	if err != nil {
+		return fmt.Errorf("encode session: %w", err)
+	}
+	if err := s.backend.save(sess.ID, data, time.Now().Add(s.opts.MaxAge)); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	if sess.previousID != "" {
+		s.backend.delete(sess.previousID)
+	}
+
+	value, err := s.codec.Encode(s.opts.Name, []byte(sess.ID))
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, s.opts.cookie(value))
+	return nil
+}
+
+func validID(id string) bool {
+	if len(id) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// NewMemoryStore keeps sessions in process memory. They are lost on restart
+// and not shared between instances.
+func NewMemoryStore(codec *Codec, opts Options) Store {
+	return &serverStore{
+		codec:   codec,
+		opts:    opts.withDefaults(),
+		backend: &memoryBackend{entries: make(map[string]memoryEntry)},
+	}
+}
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+type memoryBackend struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	saves   int
+}
+
+func (m *memoryBackend) load(id string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(m.entries, id)
+		return nil, false, nil
+	}
+	return entry.data, true, nil
+}
+
+func (m *memoryBackend) save(id string, data []byte, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[id] = memoryEntry{data: data, expires: expires}
+
+	m.saves++
+	if m.saves%1024 == 0 {
+		now := time.Now()
+		for key, entry := range m.entries {
+			if now.After(entry.expires) {
+				delete(m.entries, key)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *memoryBackend) delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, id)
+	return nil
+}
+
+// NewFileStore keeps one JSON file per session in dir.
+func NewFileStore(dir string, codec *Codec, opts Options) (Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create session dir: %w", err)
+	}
+
+	return &serverStore{
+		codec:   codec,
+		opts:    opts.withDefaults(),
+		backend: &fileBackend{dir: dir},
+	}, nil
+}
+
+type fileBackend struct {
+	dir string
+}
+
+type fileRecord struct {
+	Expires time.Time       `json:"expires"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (f *fileBackend) path(id string) string {
+	return filepath.Join(f.dir, id+".json")
+}
+
+func (f *fileBackend) load(id string) ([]byte, bool, error) {
+	content, err := os.ReadFile(f.path(id))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var rec fileRecord
+	if err := json.Unmarshal(content, &rec); err != nil {
+		return nil, false, nil
+	}
+	if time.Now().After(rec.Expires) {
+		os.Remove(f.path(id))
+		return nil, false, nil
+	}
+	return rec.Data, true, nil
+}
+
+func (f *fileBackend) save(id string, data []byte, expires time.Time) error {
+	content, err := json.Marshal(fileRecord{Expires: expires, Data: data})
+	if err != nil {
+		return err
+	}
+
+	// Write then rename so concurrent readers never see a partial file.
+	tmp, err := os.CreateTemp(f.dir, id+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(id))
+}
+
+func (f *fileBackend) delete(id string) error {
+	err := os.Remove(f.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
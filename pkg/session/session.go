@@ -0,0 +1,158 @@
+// Package session provides cookie, memory and file backed sessions with
+// flash messages, plus signed cookie helpers. Values are stored as JSON, so
+// numbers read back as float64.
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+)
+
+type Session struct {
+	ID    string
+	IsNew bool
+
+	mu          sync.Mutex
+	values      map[string]any
+	flashes     map[string][]string
+	dirty       bool
+	destroyed   bool
+	previousID  string
+	needsCookie bool
+}
+
+type record struct {
+	Values  map[string]any      `json:"values,omitempty"`
+	Flashes map[string][]string `json:"flashes,omitempty"`
+}
+
+func New() *Session {
+	return &Session{
+		ID:      newID(),
+		IsNew:   true,
+		values:  make(map[string]any),
+		flashes: make(map[string][]string),
+	}
+}
+
+func newID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (s *Session) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+func (s *Session) GetString(key string) string {
+	v, _ := s.Get(key).(string)
+	return v
+}
+
+func (s *Session) Has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.values[key]
+	return ok
+}
+
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.dirty = true
+}
+
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.dirty = true
+	}
+}
+
+// Values returns a copy of the stored values.
+func (s *Session) Values() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]any, len(s.values))
+	for k, v := range s.values {
+		out[k] = v
+	}
+	return out
+}
+
+// AddFlash queues a message that is kept until read with Flashes, typically
+// on the next request.
+func (s *Session) AddFlash(key, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flashes[key] = append(s.flashes[key], message)
+	s.dirty = true
+}
+
+// Flashes returns and clears the messages queued under key.
+func (s *Session) Flashes(key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := s.flashes[key]
+	if len(messages) > 0 {
+		delete(s.flashes, key)
+		s.dirty = true
+	}
+	return messages
+}
+
+// Regenerate gives the session a new ID while keeping its values. Call it
+// after login to prevent session fixation.
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.previousID == "" && !s.IsNew {
+		s.previousID = s.ID
+	}
+	s.ID = newID()
+	s.dirty = true
+}
+
+// Destroy clears the session and expires its cookie when saved.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]any)
+	s.flashes = make(map[string][]string)
+	s.destroyed = true
+	s.dirty = true
+}
+
+func (s *Session) IsDirty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dirty
+}
+
+func (s *Session) marshal() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(record{Values: s.values, Flashes: s.flashes})
+}
+
+func (s *Session) unmarshal(data []byte) error {
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+	if rec.Values != nil {
+		s.values = rec.Values
+	}
+	if rec.Flashes != nil {
+		s.flashes = rec.Flashes
+	}
+	return nil
+}
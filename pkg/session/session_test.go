@@ -0,0 +1,294 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	secret    = "0123456789abcdef0123456789abcdef"
+	oldSecret = "fedcba9876543210fedcba9876543210"
+)
+
+func newCodec(t *testing.T, encrypt bool, secrets ...string) *Codec {
+	t.Helper()
+	codec, err := NewCodec(encrypt, secrets...)
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+	return codec
+}
+
+// roundtrip saves sess with store and returns a request carrying the
+// resulting cookie.
+func roundtrip(t *testing.T, store Store, sess *Session) *http.Request {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if err := store.Save(w, httptest.NewRequest("GET", "/", nil), sess); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, c := range w.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	return req
+}
+
+func TestCodec(t *testing.T) {
+	for _, encrypt := range []bool{false, true} {
+		codec := newCodec(t, encrypt, secret)
+
+		encoded, err := codec.Encode("name", []byte("hello"))
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+
+		value, rotated, err := codec.Decode("name", encoded, 0)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if string(value) != "hello" || rotated {
+			t.Errorf("Expected hello without rotation, got %q (rotated=%v)", value, rotated)
+		}
+
+		if _, _, err := codec.Decode("other", encoded, 0); err == nil {
+			t.Error("Expected value bound to its cookie name")
+		}
+
+		tampered := []byte(encoded)
+		tampered[len(tampered)/2] ^= 1
+		if _, _, err := codec.Decode("name", string(tampered), 0); err == nil {
+			t.Error("Expected tampered value to be rejected")
+		}
+	}
+}
+
+func TestCodecShortSecret(t *testing.T) {
+	if _, err := NewCodec(false, "short"); err == nil {
+		t.Error("Expected error for short secret")
+	}
+}
+
+func TestCodecRotation(t *testing.T) {
+	encoded, _ := newCodec(t, true, oldSecret).Encode("name", []byte("v"))
+
+	value, rotated, err := newCodec(t, true, secret, oldSecret).Decode("name", encoded, 0)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if string(value) != "v" || !rotated {
+		t.Errorf("Expected v decoded with old key, got %q (rotated=%v)", value, rotated)
+	}
+
+	if _, _, err := newCodec(t, true, secret).Decode("name", encoded, 0); err == nil {
+		t.Error("Expected value from removed key to be rejected")
+	}
+}
+
+func TestCookieStore(t *testing.T) {
+	store := NewCookieStore(newCodec(t, false, secret), Options{})
+
+	sess := New()
+	sess.Set("user", "ada")
+	sess.AddFlash("notice", "Saved")
+
+	loaded, err := store.Load(roundtrip(t, store, sess))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.IsNew {
+		t.Error("Expected existing session")
+	}
+	if loaded.GetString("user") != "ada" {
+		t.Errorf("Expected user ada, got %v", loaded.Get("user"))
+	}
+
+	flashes := loaded.Flashes("notice")
+	if len(flashes) != 1 || flashes[0] != "Saved" {
+		t.Errorf("Expected [Saved], got %v", flashes)
+	}
+	if len(loaded.Flashes("notice")) != 0 {
+		t.Error("Expected flashes to be consumed")
+	}
+	if !loaded.IsDirty() {
+		t.Error("Expected reading flashes to mark the session dirty")
+	}
+}
+
+func TestCookieStoreRotatesCookie(t *testing.T) {
+	oldStore := NewCookieStore(newCodec(t, false, oldSecret), Options{})
+	sess := New()
+	sess.Set("user", "ada")
+	req := roundtrip(t, oldStore, sess)
+
+	store := NewCookieStore(newCodec(t, false, secret, oldSecret), Options{})
+	loaded, _ := store.Load(req)
+	if loaded.GetString("user") != "ada" {
+		t.Fatalf("Expected session readable with old key, got %v", loaded.Values())
+	}
+
+	w := httptest.NewRecorder()
+	store.Save(w, req, loaded)
+	if len(w.Result().Cookies()) != 1 {
+		t.Error("Expected cookie re-issued with the current key")
+	}
+}
+
+func TestCookieStoreIgnoresTamperedCookie(t *testing.T) {
+	store := NewCookieStore(newCodec(t, false, secret), Options{})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "galaxy_session", Value: "garbage"})
+
+	sess, err := store.Load(req)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !sess.IsNew || len(sess.Values()) != 0 {
+		t.Error("Expected fresh session for invalid cookie")
+	}
+}
+
+func TestServerStores(t *testing.T) {
+	codec := newCodec(t, false, secret)
+	fileStore, err := NewFileStore(t.TempDir(), codec, Options{})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(codec, Options{}),
+		"file":   fileStore,
+	}
+
+	for name, store := range stores {
+		sess := New()
+		sess.Set("count", 2)
+
+		req := roundtrip(t, store, sess)
+		cookie, _ := req.Cookie("galaxy_session")
+		if cookie == nil {
+			t.Fatalf("%s: expected session cookie", name)
+		}
+
+		loaded, err := store.Load(req)
+		if err != nil {
+			t.Fatalf("%s: Load failed: %v", name, err)
+		}
+		if loaded.ID != sess.ID {
+			t.Errorf("%s: expected ID %s, got %s", name, sess.ID, loaded.ID)
+		}
+		if loaded.Get("count") != float64(2) {
+			t.Errorf("%s: expected count 2, got %v", name, loaded.Get("count"))
+		}
+
+		loaded.Regenerate()
+		regenerated := roundtrip(t, store, loaded)
+		if old, _ := store.Load(req); !old.IsNew {
+			t.Errorf("%s: expected old ID to be invalid after Regenerate", name)
+		}
+		current, _ := store.Load(regenerated)
+		if current.Get("count") != float64(2) {
+			t.Errorf("%s: expected values kept across Regenerate, got %v", name, current.Values())
+		}
+
+		current.Destroy()
+		w := httptest.NewRecorder()
+		store.Save(w, regenerated, current)
+		if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+			t.Errorf("%s: expected expired cookie after Destroy", name)
+		}
+		if gone, _ := store.Load(regenerated); !gone.IsNew {
+			t.Errorf("%s: expected session removed after Destroy", name)
+		}
+	}
+}
+
+func TestFileStoreWritesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewFileStore(dir, newCodec(t, false, secret), Options{})
+
+	sess := New()
+	sess.Set("a", "b")
+	roundtrip(t, store, sess)
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != sess.ID+".json" {
+		t.Errorf("Expected only %s.json, got %v", sess.ID, entries)
+	}
+	if _, err := os.Stat(filepath.Join(dir, sess.ID+".json")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestResponseWriterCommitsBeforeHeaders(t *testing.T) {
+	store := NewCookieStore(newCodec(t, false, secret), Options{})
+	req := WithStore(httptest.NewRequest("GET", "/", nil), store)
+
+	rec := httptest.NewRecorder()
+	w := NewResponseWriter(rec, req)
+
+	FromRequest(req).Set("user", "ada")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+	w.Close()
+
+	if len(rec.Result().Cookies()) != 1 {
+		t.Errorf("Expected one session cookie, got %v", rec.Result().Cookies())
+	}
+}
+
+func TestUnchangedSessionSetsNoCookie(t *testing.T) {
+	store := NewCookieStore(newCodec(t, false, secret), Options{})
+	req := WithStore(httptest.NewRequest("GET", "/", nil), store)
+
+	rec := httptest.NewRecorder()
+	w := NewResponseWriter(rec, req)
+	FromRequest(req).Get("user")
+	w.Close()
+
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("Expected no cookie for an untouched session")
+	}
+}
+
+func TestFromRequestWithoutStore(t *testing.T) {
+	sess := FromRequest(httptest.NewRequest("GET", "/", nil))
+	if sess == nil || !sess.IsNew {
+		t.Error("Expected detached new session")
+	}
+}
+
+func TestSignedCookies(t *testing.T) {
+	store := NewCookieStore(newCodec(t, false, secret), Options{})
+	req := WithStore(httptest.NewRequest("GET", "/", nil), store)
+
+	w := httptest.NewRecorder()
+	if err := NewCookies(w, req).SetSigned("theme", "dark", 60); err != nil {
+		t.Fatalf("SetSigned failed: %v", err)
+	}
+
+	next := httptest.NewRequest("GET", "/", nil)
+	for _, c := range w.Result().Cookies() {
+		if c.Value == "dark" {
+			t.Error("Expected signed value, got plain text")
+		}
+		next.AddCookie(c)
+	}
+	next = WithStore(next, store)
+
+	cookies := NewCookies(httptest.NewRecorder(), next)
+	if cookies.GetSigned("theme") != "dark" {
+		t.Errorf("Expected dark, got %q", cookies.GetSigned("theme"))
+	}
+	if cookies.Get("theme") == "dark" {
+		t.Error("Expected raw Get to return the encoded value")
+	}
+
+	if err := NewCookies(w, httptest.NewRequest("GET", "/", nil)).SetSigned("x", "y", 0); err != ErrNoStore {
+		t.Errorf("Expected ErrNoStore, got %v", err)
+	}
+}
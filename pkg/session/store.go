@@ -0,0 +1,126 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Store loads and saves sessions for a request.
+type Store interface {
+	Load(r *http.Request) (*Session, error)
+	Save(w http.ResponseWriter, r *http.Request, s *Session) error
+	Codec() *Codec
+}
+
+type Options struct {
+	// Name of the session cookie. Defaults to "galaxy_session".
+	Name     string
+	Path     string
+	Domain   string
+	MaxAge   time.Duration
+	Secure   bool
+	SameSite http.SameSite
+}
+
+func (o Options) withDefaults() Options {
+	if o.Name == "" {
+		o.Name = "galaxy_session"
+	}
+	if o.Path == "" {
+		o.Path = "/"
+	}
+	if o.MaxAge == 0 {
+		o.MaxAge = 7 * 24 * time.Hour
+	}
+	if o.SameSite == 0 {
+		o.SameSite = http.SameSiteLaxMode
+	}
+	return o
+}
+
+func (o Options) cookie(value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     o.Name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		MaxAge:   int(o.MaxAge.Seconds()),
+		Expires:  time.Now().Add(o.MaxAge),
+		Secure:   o.Secure,
+		HttpOnly: true,
+		SameSite: o.SameSite,
+	}
+}
+
+func (o Options) expired() *http.Cookie {
+	c := o.cookie("")
+	c.MaxAge = -1
+	c.Expires = time.Unix(0, 0)
+	return c
+}
+
+// maxCookieSize is the value size browsers reliably accept.
+const maxCookieSize = 4000
+
+// CookieStore keeps the whole session in the cookie, signed and optionally
+// encrypted by its codec.
+type CookieStore struct {
+	codec *Codec
+	opts  Options
+}
+
+func NewCookieStore(codec *Codec, opts Options) *CookieStore {
+	return &CookieStore{codec: codec, opts: opts.withDefaults()}
+}
+
+func (s *CookieStore) Codec() *Codec {
+	return s.codec
+}
+
+func (s *CookieStore) Load(r *http.Request) (*Session, error) {
+	sess := New()
+
+	cookie, err := r.Cookie(s.opts.Name)
+	if err != nil {
+		return sess, nil
+	}
+
+	data, rotated, err := s.codec.Decode(s.opts.Name, cookie.Value, s.opts.MaxAge)
+	if err != nil {
+		return sess, nil
+	}
+	if err := sess.unmarshal(data); err != nil {
+		return New(), nil
+	}
+
+	sess.IsNew = false
+	sess.needsCookie = rotated
+	return sess, nil
+}
+
+func (s *CookieStore) Save(w http.ResponseWriter, r *http.Request, sess *Session) error {
+	if sess.destroyed {
+		http.SetCookie(w, s.opts.expired())
+		return nil
+	}
+	if !sess.IsDirty() && !sess.needsCookie {
+		return nil
+	}
+
+	data, err := sess.marshal()
+	if err != nil {
+		return fmt.Errorf("encode session: %w", err)
+	}
+
+	value, err := s.codec.Encode(s.opts.Name, data)
+	if err != nil {
+		return err
+	}
+	if len(value) > maxCookieSize {
+		return fmt.Errorf("session: cookie of %d bytes exceeds %d; use a server-side store", len(value), maxCookieSize)
+	}
+
+	http.SetCookie(w, s.opts.cookie(value))
+	return nil
+}
@@ -0,0 +1,199 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+// RequestIDHeader carries the request ID, as with the requestId middleware.
+// An incoming value is kept so IDs can be followed across proxies.
+const RequestIDHeader = "X-Request-ID"
+
+// unmatched labels requests that no route handled, such as static files
+// and 404s, keeping the route label's cardinality bounded.
+const unmatched = "unmatched"
+
+type requestKey struct{}
+
+// request is the per-request state Handler shares with the handlers below
+// it.
+type request struct {
+	id    string
+	route string
+}
+
+// Handler wraps next with request IDs, a root trace span, request metrics
+// and an access log, and serves the metrics endpoint when it is enabled.
+func Handler(cfg config.TelemetryConfig, next http.Handler) http.Handler {
+	metricsPath := cfg.Metrics.Path
+	if metricsPath == "" {
+		metricsPath = "/metrics"
+	}
+	metrics := MetricsHandler()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.Metrics.Enabled && r.URL.Path == metricsPath {
+			metrics.ServeHTTP(w, r)
+			return
+		}
+
+		began := time.Now()
+		info := &request{id: requestID(r.Header.Get(RequestIDHeader)), route: unmatched}
+		// Set on the request too, so the requestId middleware agrees.
+		r.Header.Set(RequestIDHeader, info.id)
+		w.Header().Set(RequestIDHeader, info.id)
+
+		ctx := context.WithValue(r.Context(), requestKey{}, info)
+		if remote := parseTraceParent(r.Header.Get("traceparent")); remote != nil {
+			ctx = context.WithValue(ctx, spanKey{}, remote)
+		}
+		ctx, span := start(ctx, r.Method, KindServer, []slog.Attr{
+			slog.String("http.method", r.Method),
+			slog.String("http.target", r.URL.Path),
+			slog.String("request.id", info.id),
+		})
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			duration := time.Since(began)
+			status := strconv.Itoa(sw.status)
+
+			RequestsTotal.Inc(r.Method, info.route, status)
+			RequestDuration.Observe(duration.Seconds(), info.route)
+
+			if span != nil && info.route != unmatched {
+				span.name = r.Method + " " + info.route
+			}
+			span.SetAttr(slog.String("http.route", info.route), slog.Int("http.status_code", sw.status))
+			if sw.status >= 500 {
+				span.RecordError(errStatus(sw.status))
+			}
+			span.End()
+
+			if cfg.Log.Requests {
+				level := slog.LevelInfo
+				if sw.status >= 500 {
+					level = slog.LevelError
+				}
+				Logger(ctx).Log(ctx, level, "request",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"route", info.route,
+					"status", sw.status,
+					"duration", duration,
+				)
+			}
+		}()
+
+		next.ServeHTTP(sw, r.WithContext(ctx))
+	})
+}
+
+// SetRoute records the route pattern that handles r, labelling its metrics,
+// span and log line.
+func SetRoute(r *http.Request, pattern string) {
+	if info, ok := r.Context().Value(requestKey{}).(*request); ok {
+		info.route = pattern
+	}
+	SpanFromContext(r.Context()).SetAttr(slog.String("http.route", pattern))
+}
+
+// Route returns the route pattern recorded for the request in ctx, or ""
+// outside of Handler.
+func Route(ctx context.Context) string {
+	if info, ok := ctx.Value(requestKey{}).(*request); ok && info.route != unmatched {
+		return info.route
+	}
+	return ""
+}
+
+// RequestID returns the ID of the request in ctx, or "" outside of Handler.
+func RequestID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestKey{}).(*request); ok {
+		return info.id
+	}
+	return ""
+}
+
+// Logger returns the default logger annotated with the request ID and
+// trace ID in ctx.
+func Logger(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := RequestID(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		logger = logger.With("trace_id", hex.EncodeToString(span.traceID[:]))
+	}
+	return logger
+}
+
+// Render times a page render of route, as a span and in RenderDuration. Call
+// the returned function when rendering is done.
+func Render(ctx context.Context, route string) (context.Context, func(error)) {
+	began := time.Now()
+	ctx, span := Start(ctx, "render", slog.String("http.route", route))
+	return ctx, func(err error) {
+		RenderDuration.Since(began, route)
+		span.RecordError(err)
+		span.End()
+	}
+}
+
+// requestID keeps a well-formed incoming ID and generates one otherwise.
+func requestID(incoming string) string {
+	if incoming != "" && len(incoming) <= 128 {
+		valid := true
+		for _, c := range incoming {
+			if c <= ' ' || c > '~' {
+				valid = false
+				break
+			}
+		}
+		if valid {
+			return incoming
+		}
+	}
+
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+type errStatus int
+
+func (e errStatus) Error() string {
+	return "HTTP " + strconv.Itoa(int(e)) + " " + http.StatusText(int(e))
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader && code >= 200 {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach Flush and Hijack on the
+// underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,242 @@
+package telemetry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefBuckets are the latency buckets, in seconds, of the request and render
+// histograms.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	RequestsTotal = NewCounter("galaxy_http_requests_total",
+		"HTTP requests served, by method, route pattern and status.", "method", "route", "status")
+	RequestDuration = NewHistogram("galaxy_http_request_duration_seconds",
+		"HTTP request latency by route pattern.", DefBuckets, "route")
+	RenderDuration = NewHistogram("galaxy_render_duration_seconds",
+		"Time spent rendering pages, by route pattern.", DefBuckets, "route")
+	CacheRequests = NewCounter("galaxy_cache_requests_total",
+		"Response cache lookups, by result (hit, stale or miss).", "result")
+	WasmCompileDuration = NewHistogram("galaxy_wasm_compile_duration_seconds",
+		"Time spent compiling WASM modules.", []float64{.5, 1, 2.5, 5, 10, 30, 60})
+)
+
+type metric interface {
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []metric
+)
+
+func register(m metric) {
+	registryMu.Lock()
+	registry = append(registry, m)
+	registryMu.Unlock()
+}
+
+// WriteMetrics writes every metric in the Prometheus text exposition format.
+func WriteMetrics(w io.Writer) {
+	registryMu.Lock()
+	metrics := append([]metric(nil), registry...)
+	registryMu.Unlock()
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// MetricsHandler serves WriteMetrics.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		WriteMetrics(bw)
+		bw.Flush()
+	})
+}
+
+// vec holds one value per combination of label values.
+type vec[T any] struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	series map[string]*series[T]
+}
+
+type series[T any] struct {
+	values []string
+	value  T
+}
+
+func (v *vec[T]) get(values []string, init func() T) *series[T] {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("telemetry: %s takes %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series[T]{values: append([]string(nil), values...), value: init()}
+		v.series[key] = s
+	}
+	return s
+}
+
+// sorted returns the series ordered by label values, for stable output.
+func (v *vec[T]) sorted() []*series[T] {
+	all := make([]*series[T], 0, len(v.series))
+	for _, s := range v.series {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].values, "\xff") < strings.Join(all[j].values, "\xff")
+	})
+	return all
+}
+
+func (v *vec[T]) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, kind)
+}
+
+// Counter is a monotonically increasing value per set of labels.
+type Counter struct {
+	vec[float64]
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{vec[float64]{name: name, help: help, labels: labels, series: make(map[string]*series[float64])}}
+	register(c)
+	return c
+}
+
+// Inc adds one to the series with the given label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *Counter) Add(delta float64, values ...string) {
+	c.mu.Lock()
+	c.get(values, func() float64 { return 0 }).value += delta
+	c.mu.Unlock()
+}
+
+// Value returns the current value of a series.
+func (c *Counter) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(values, func() float64 { return 0 }).value
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header(w, "counter")
+	for _, s := range c.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labelPairs(c.labels, s.values), formatFloat(s.value))
+	}
+}
+
+// Histogram counts observations into buckets per set of labels.
+type Histogram struct {
+	vec[*histogramValue]
+	buckets []float64
+}
+
+type histogramValue struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		vec:     vec[*histogramValue]{name: name, help: help, labels: labels, series: make(map[string]*series[*histogramValue])},
+		buckets: buckets,
+	}
+	register(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.get(values, h.newValue).value
+	i := sort.SearchFloat64s(h.buckets, v)
+	if i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+// Since observes the seconds elapsed since start.
+func (h *Histogram) Since(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+// Count returns the number of observations of a series.
+func (h *Histogram) Count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.get(values, h.newValue).value.count
+}
+
+func (h *Histogram) newValue() *histogramValue {
+	return &histogramValue{counts: make([]uint64, len(h.buckets))}
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.header(w, "histogram")
+	labels := append(append([]string(nil), h.labels...), "le")
+	for _, s := range h.sorted() {
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.value.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(labels, append(s.values, formatFloat(bound))), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(labels, append(s.values, "+Inf")), s.value.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelPairs(h.labels, s.values), formatFloat(s.value.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelPairs(h.labels, s.values), s.value.count)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelPairs(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
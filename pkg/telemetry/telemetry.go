@@ -0,0 +1,70 @@
+// Package telemetry provides the structured logs, Prometheus metrics and
+// OpenTelemetry-compatible trace spans of the dev and built servers,
+// configured under [telemetry].
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+// Setup installs the default slog logger and starts the trace exporter
+// configured in cfg. The returned function flushes pending spans and stops
+// the exporter.
+func Setup(cfg config.TelemetryConfig) (func(), error) {
+	logger, err := NewLogger(os.Stderr, cfg.Log)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+
+	var exp exporter
+	switch cfg.Tracing.Exporter {
+	case "":
+		return func() {}, nil
+	case "stdout":
+		exp = &stdoutExporter{w: os.Stdout}
+	case "otlp":
+		exp = newOTLPExporter(cfg.Tracing.Endpoint, service(cfg.Tracing))
+	default:
+		return nil, fmt.Errorf("invalid trace exporter: %s", cfg.Tracing.Exporter)
+	}
+
+	t := newTracer(exp)
+	setTracer(t)
+	return func() {
+		setTracer(nil)
+		t.shutdown()
+	}, nil
+}
+
+// NewLogger returns a logger writing text or JSON lines to w at the
+// configured level.
+func NewLogger(w io.Writer, cfg config.LogConfig) (*slog.Logger, error) {
+	var level slog.Level
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level: %s", cfg.Level)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch cfg.Format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format: %s", cfg.Format)
+}
+
+func service(cfg config.TracingConfig) string {
+	if cfg.Service != "" {
+		return cfg.Service
+	}
+	return "galaxy"
+}
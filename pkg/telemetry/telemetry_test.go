@@ -0,0 +1,230 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	c := &Counter{vec[float64]{name: "test_total", help: "Test counter.", labels: []string{"route"}, series: make(map[string]*series[float64])}}
+	c.Inc(`/blog/"quoted"`)
+	c.Add(2, "/")
+
+	h := &Histogram{
+		vec:     vec[*histogramValue]{name: "test_seconds", help: "Test histogram.", series: make(map[string]*series[*histogramValue])},
+		buckets: []float64{0.1, 1},
+	}
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	var buf bytes.Buffer
+	c.write(&buf)
+	h.write(&buf)
+
+	want := `# HELP test_total Test counter.
+# TYPE test_total counter
+test_total{route="/"} 2
+test_total{route="/blog/\"quoted\""} 1
+# HELP test_seconds Test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{le="0.1"} 1
+test_seconds_bucket{le="1"} 2
+test_seconds_bucket{le="+Inf"} 3
+test_seconds_sum 3.55
+test_seconds_count 3
+`
+	if buf.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	cfg := config.TelemetryConfig{Metrics: config.MetricsConfig{Enabled: true}}
+	var gotID, gotRoute string
+	handler := Handler(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r, "/posts/[slug]")
+		gotID = RequestID(r.Context())
+		gotRoute = Route(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	before := RequestsTotal.Value("GET", "/posts/[slug]", "418")
+
+	r := httptest.NewRequest("GET", "/posts/hello", nil)
+	r.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if gotID != "abc-123" || w.Header().Get(RequestIDHeader) != "abc-123" {
+		t.Errorf("Expected incoming request ID to be kept, got %q / %q", gotID, w.Header().Get(RequestIDHeader))
+	}
+	if gotRoute != "/posts/[slug]" {
+		t.Errorf("Expected route, got %q", gotRoute)
+	}
+	if got := RequestsTotal.Value("GET", "/posts/[slug]", "418"); got != before+1 {
+		t.Errorf("Expected request to be counted, got %v", got-before)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if id := w.Header().Get(RequestIDHeader); len(id) != 32 {
+		t.Errorf("Expected generated request ID, got %q", id)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `galaxy_http_requests_total{method="GET",route="/posts/[slug]",status="418"}`) {
+		t.Errorf("Expected request metric in /metrics, got:\n%s", w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "# TYPE galaxy_http_request_duration_seconds histogram") {
+		t.Error("Expected latency histogram in /metrics")
+	}
+}
+
+func TestRequestLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, config.LogConfig{Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	handler := Handler(config.TelemetryConfig{Log: config.LogConfig{Requests: true}}, http.NotFoundHandler())
+	r := httptest.NewRequest("GET", "/missing", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q", buf.String())
+	}
+	if line["request_id"] != "req-1" || line["path"] != "/missing" || line["status"] != float64(404) || line["route"] != "unmatched" {
+		t.Errorf("Unexpected log line: %v", line)
+	}
+
+	if _, err := NewLogger(io.Discard, config.LogConfig{Level: "loud"}); err == nil {
+		t.Error("Expected error for invalid level")
+	}
+}
+
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (e *memoryExporter) export(spans []*Span) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mu.Unlock()
+	return nil
+}
+
+func withTracer(t *testing.T, exp exporter) {
+	tr := newTracer(exp)
+	setTracer(tr)
+	t.Cleanup(func() {
+		setTracer(nil)
+		tr.shutdown()
+	})
+}
+
+func TestSpans(t *testing.T) {
+	if _, span := Start(context.Background(), "off"); span != nil {
+		t.Fatal("Expected no span while tracing is off")
+	}
+
+	exp := &memoryExporter{}
+	withTracer(t, exp)
+
+	handler := Handler(config.TelemetryConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := Start(r.Context(), "frontmatter")
+		_, child := Start(ctx, "component", slog.String("component", "Nav"))
+		child.End()
+		span.RecordError(errors.New("boom"))
+		span.End()
+		span.End()
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	Flush()
+
+	if len(exp.spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(exp.spans))
+	}
+	component, frontmatter, root := exp.spans[0], exp.spans[1], exp.spans[2]
+	if component.parentID != frontmatter.spanID || frontmatter.parentID != root.spanID {
+		t.Error("Expected spans to nest")
+	}
+	if got := root.TraceParent()[3:35]; got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected trace to continue the incoming traceparent, got %s", got)
+	}
+	if root.name != "GET" || root.kind != KindServer || frontmatter.errMessage() != "boom" {
+		t.Error("Expected server root span and recorded error")
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	withTracer(t, &stdoutExporter{w: &buf})
+
+	_, span := Start(context.Background(), "render", slog.String("http.route", "/"))
+	span.End()
+	Flush()
+
+	var out stdoutSpan
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("Expected a JSON span, got %q", buf.String())
+	}
+	if out.Name != "render" || out.Attributes["http.route"] != "/" || len(out.TraceID) != 32 {
+		t.Errorf("Unexpected span: %+v", out)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	bodies := make(chan map[string]any, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body
+	}))
+	defer collector.Close()
+
+	withTracer(t, newOTLPExporter(collector.URL, "shop"))
+	_, span := Start(context.Background(), "endpoint", slog.Int("status", 201))
+	span.End()
+	Flush()
+
+	body := <-bodies
+	resource := body["resourceSpans"].([]any)[0].(map[string]any)
+	attr := resource["resource"].(map[string]any)["attributes"].([]any)[0].(map[string]any)
+	if attr["value"].(map[string]any)["stringValue"] != "shop" {
+		t.Errorf("Expected service.name shop, got %v", attr)
+	}
+	spans := resource["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	got := spans[0].(map[string]any)
+	if got["name"] != "endpoint" || got["kind"] != float64(KindInternal) {
+		t.Errorf("Unexpected span: %v", got)
+	}
+	if _, ok := got["startTimeUnixNano"].(string); !ok {
+		t.Error("Expected startTimeUnixNano as a string")
+	}
+	attrs := got["attributes"].([]any)[0].(map[string]any)
+	if attrs["value"].(map[string]any)["intValue"] != "201" {
+		t.Errorf("Expected intValue attribute, got %v", attrs)
+	}
+}
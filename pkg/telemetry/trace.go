@@ -0,0 +1,414 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Span kinds, as numbered by OTLP.
+const (
+	KindInternal = 1
+	KindServer   = 2
+)
+
+const (
+	batchSize     = 256
+	queueSize     = 2048
+	flushInterval = 5 * time.Second
+)
+
+// Span times one operation of a trace. Spans are nil when tracing is off;
+// every method is safe to call on a nil Span.
+type Span struct {
+	tracer   *tracer
+	name     string
+	kind     int
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	start    time.Time
+	end      time.Time
+
+	mu     sync.Mutex
+	attrs  []slog.Attr
+	err    string
+	ended  bool
+	remote bool
+}
+
+type spanKey struct{}
+
+// Start begins a span named name as a child of the span in ctx, if any, and
+// returns a context carrying it.
+func Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, *Span) {
+	return start(ctx, name, KindInternal, attrs)
+}
+
+func start(ctx context.Context, name string, kind int, attrs []slog.Attr) (context.Context, *Span) {
+	t := currentTracer()
+	if t == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	s := &Span{tracer: t, name: name, kind: kind, start: time.Now(), attrs: attrs}
+	if parent := SpanFromContext(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// SpanFromContext returns the span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SetAttr records attributes on the span.
+func (s *Span) SetAttr(attrs ...slog.Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err.Error()
+	s.mu.Unlock()
+}
+
+// End ends the span and queues it for export. Only the first call counts,
+// so a deferred End can back up an explicit one.
+func (s *Span) End() {
+	if s == nil || s.remote {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.tracer.queue(s)
+}
+
+func (s *Span) attributes() []slog.Attr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]slog.Attr(nil), s.attrs...)
+}
+
+func (s *Span) errMessage() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// TraceParent formats the span as a W3C traceparent header.
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.spanID[:]) + "-01"
+}
+
+// parseTraceParent reads a W3C traceparent header into a remote span that
+// local spans can continue.
+func parseTraceParent(header string) *Span {
+	if len(header) != 55 || header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return nil
+	}
+	s := &Span{remote: true}
+	if _, err := hex.Decode(s.traceID[:], []byte(header[3:35])); err != nil {
+		return nil
+	}
+	if _, err := hex.Decode(s.spanID[:], []byte(header[36:52])); err != nil {
+		return nil
+	}
+	if s.traceID == [16]byte{} || s.spanID == [8]byte{} {
+		return nil
+	}
+	return s
+}
+
+var active atomic.Pointer[tracer]
+
+func currentTracer() *tracer {
+	return active.Load()
+}
+
+func setTracer(t *tracer) {
+	active.Store(t)
+}
+
+// exporter sends finished spans to a backend.
+type exporter interface {
+	export(spans []*Span) error
+}
+
+// tracer batches finished spans and hands them to its exporter in the
+// background. Spans are dropped rather than blocking requests when the
+// queue is full.
+type tracer struct {
+	exp   exporter
+	spans chan *Span
+	flush chan chan struct{}
+	done  chan struct{}
+}
+
+func newTracer(exp exporter) *tracer {
+	t := &tracer{
+		exp:   exp,
+		spans: make(chan *Span, queueSize),
+		flush: make(chan chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+func (t *tracer) queue(s *Span) {
+	select {
+	case t.spans <- s:
+	default:
+	}
+}
+
+func (t *tracer) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.exp.export(batch); err != nil {
+			slog.Warn("trace export failed", "spans", len(batch), "error", err)
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case s := <-t.spans:
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-t.flush:
+			for drained := false; !drained; {
+				select {
+				case s := <-t.spans:
+					batch = append(batch, s)
+				default:
+					drained = true
+				}
+			}
+			send()
+			close(ack)
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// Flush exports the spans queued so far.
+func (t *tracer) Flush() {
+	ack := make(chan struct{})
+	t.flush <- ack
+	<-ack
+}
+
+func (t *tracer) shutdown() {
+	t.Flush()
+	close(t.done)
+}
+
+// Flush exports the spans finished so far. It does nothing when tracing is
+// off.
+func Flush() {
+	if t := currentTracer(); t != nil {
+		t.Flush()
+	}
+}
+
+// stdoutExporter writes one JSON object per span.
+type stdoutExporter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+type stdoutSpan struct {
+	Name       string         `json:"name"`
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	Start      time.Time      `json:"start"`
+	DurationMS float64        `json:"duration_ms"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+func (e *stdoutExporter) export(spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		out := stdoutSpan{
+			Name:       s.name,
+			TraceID:    hex.EncodeToString(s.traceID[:]),
+			SpanID:     hex.EncodeToString(s.spanID[:]),
+			Start:      s.start,
+			DurationMS: float64(s.end.Sub(s.start).Microseconds()) / 1000,
+			Error:      s.errMessage(),
+		}
+		if s.parentID != [8]byte{} {
+			out.ParentID = hex.EncodeToString(s.parentID[:])
+		}
+		if attrs := s.attributes(); len(attrs) > 0 {
+			out.Attributes = make(map[string]any, len(attrs))
+			for _, a := range attrs {
+				out.Attributes[a.Key] = a.Value.Resolve().Any()
+			}
+		}
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// otlpExporter posts spans to an OpenTelemetry collector using OTLP/HTTP
+// with JSON encoding.
+type otlpExporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+}
+
+func newOTLPExporter(endpoint, service string) *otlpExporter {
+	return &otlpExporter{endpoint: endpoint, service: service, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type otlpAttr struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []otlpAttr `json:"attributes,omitempty"`
+	Status            otlpStatus `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *otlpExporter) export(spans []*Span) error {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		o := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parentID != [8]byte{} {
+			o.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		for _, a := range s.attributes() {
+			o.Attributes = append(o.Attributes, otlpAttribute(a))
+		}
+		if msg := s.errMessage(); msg != "" {
+			o.Status = otlpStatus{Code: 2, Message: msg}
+		}
+		out = append(out, o)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpAttr{otlpAttribute(slog.String("service.name", e.service))},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/cameron-webmatter/galaxy"},
+				"spans": out,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("otlp: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp: %s", resp.Status)
+	}
+	return nil
+}
+
+func otlpAttribute(a slog.Attr) otlpAttr {
+	v := a.Value.Resolve()
+	var value map[string]any
+	switch v.Kind() {
+	case slog.KindBool:
+		value = map[string]any{"boolValue": v.Bool()}
+	case slog.KindInt64:
+		value = map[string]any{"intValue": strconv.FormatInt(v.Int64(), 10)}
+	case slog.KindUint64:
+		value = map[string]any{"intValue": strconv.FormatUint(v.Uint64(), 10)}
+	case slog.KindFloat64:
+		value = map[string]any{"doubleValue": v.Float64()}
+	default:
+		value = map[string]any{"stringValue": v.String()}
+	}
+	return otlpAttr{Key: a.Key, Value: value}
+}
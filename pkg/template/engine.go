@@ -2,6 +2,7 @@ package template
 
 import (
 	"fmt"
+	"html"
 	"reflect"
 	"regexp"
 	"strings"
@@ -11,6 +12,10 @@ import (
 
 type Engine struct {
 	ctx *executor.Context
+	// Page marks the render of a whole page, which adds the CSRF token to
+	// its POST forms. Component and slot output is rendered again as part
+	// of the page, so those renders leave forms alone.
+	Page bool
 }
 
 func NewEngine(ctx *executor.Context) *Engine {
@@ -19,7 +24,9 @@ func NewEngine(ctx *executor.Context) *Engine {
 
 var (
 	expressionRegex = regexp.MustCompile(`\{([^}]+)\}`)
-	attrRegex       = regexp.MustCompile(`(\w+)=\{([^}]+)\}|(\w+)="([^"]+)"|(\w+)='([^']+)'|(\w+)`)
+	// Expressions used as plain HTML attribute values are quoted on output.
+	attrExpressionRegex = regexp.MustCompile(`(\s[\w-]+)=\{([^}]+)\}`)
+	attrRegex           = regexp.MustCompile(`(\w+)=\{([^}]+)\}|(\w+)="([^"]+)"|(\w+)='([^']+)'|(\w+)`)
 )
 
 type RenderOptions struct {
@@ -44,33 +51,70 @@ func (e *Engine) Render(template string, opts *RenderOptions) (string, error) {
 	result = e.renderSlots(result)
 	result = e.renderExpressions(result)
 
+	if token := e.ctx.GetCSRFToken(); token != "" && e.Page {
+		result = injectCSRFToken(result, token)
+	}
+
 	return result, nil
 }
 
-func (e *Engine) renderExpressions(template string) string {
-	return expressionRegex.ReplaceAllStringFunc(template, func(match string) string {
-		expr := strings.Trim(match, "{}")
-		expr = strings.TrimSpace(expr)
+// csrfField matches builtin.CSRFField.
+const csrfField = "_csrf"
 
-		if val, ok := e.ctx.Get(expr); ok {
-			return fmt.Sprintf("%v", val)
+var (
+	formTagRegex    = regexp.MustCompile(`(?i)<form\b[^>]*>`)
+	postMethodRegex = regexp.MustCompile(`(?i)\smethod\s*=\s*["']?post\b`)
+)
+
+// injectCSRFToken adds a hidden token field to every POST form.
+func injectCSRFToken(template, token string) string {
+	field := `<input type="hidden" name="` + csrfField + `" value="` + html.EscapeString(token) + `">`
+	return formTagRegex.ReplaceAllStringFunc(template, func(tag string) string {
+		if !postMethodRegex.MatchString(tag) {
+			return tag
 		}
+		return tag + field
+	})
+}
 
-		if val, ok := e.ctx.GetProp(expr); ok {
-			return fmt.Sprintf("%v", val)
+func (e *Engine) renderExpressions(template string) string {
+	template = attrExpressionRegex.ReplaceAllStringFunc(template, func(match string) string {
+		parts := attrExpressionRegex.FindStringSubmatch(match)
+		val, ok := e.resolveExpression(strings.TrimSpace(parts[2]))
+		if !ok {
+			return match
 		}
+		return parts[1] + `="` + html.EscapeString(val) + `"`
+	})
 
-		if strings.Contains(expr, ".") {
-			result, ok := e.evaluateExpression(expr)
-			if ok {
-				return result
-			}
+	return expressionRegex.ReplaceAllStringFunc(template, func(match string) string {
+		expr := strings.Trim(match, "{}")
+		expr = strings.TrimSpace(expr)
+
+		if val, ok := e.resolveExpression(expr); ok {
+			return val
 		}
 
 		return match
 	})
 }
 
+func (e *Engine) resolveExpression(expr string) (string, bool) {
+	if val, ok := e.ctx.Get(expr); ok {
+		return fmt.Sprintf("%v", val), true
+	}
+
+	if val, ok := e.ctx.GetProp(expr); ok {
+		return fmt.Sprintf("%v", val), true
+	}
+
+	if strings.Contains(expr, ".") {
+		return e.evaluateExpression(expr)
+	}
+
+	return "", false
+}
+
 func (e *Engine) renderSlots(template string) string {
 	slotRegex := regexp.MustCompile(`<slot(?:\s+name="(\w+)")?\s*/>|<slot(?:\s+name="(\w+)")?>.*?</slot>`)
 
@@ -402,6 +446,33 @@ func findConditionalBlock(template string, directive string) (branches []Conditi
 	return branches, blockStart, blockEnd, true
 }
 
+// DirectiveSpans returns the [start, end) offsets of every galaxy:if block,
+// including its elsif and else branches, and every galaxy:for element.
+// Streaming renders never split a template inside one.
+func DirectiveSpans(template string) [][2]int {
+	var spans [][2]int
+
+	for offset := 0; ; {
+		_, start, end, found := findConditionalBlock(template[offset:], "galaxy:if")
+		if !found {
+			break
+		}
+		spans = append(spans, [2]int{offset + start, offset + end})
+		offset += end
+	}
+
+	for offset := 0; ; {
+		_, _, _, start, end, found := findDirectiveElement(template[offset:], "galaxy:for")
+		if !found {
+			break
+		}
+		spans = append(spans, [2]int{offset + start, offset + end})
+		offset += end
+	}
+
+	return spans
+}
+
 func (e *Engine) renderDirectives(template string) string {
 	template = e.renderIfDirective(template)
 	template = e.renderForDirective(template)
@@ -632,6 +703,14 @@ func (e *Engine) evaluateValue(expr string) interface{} {
 		return val
 	}
 
+	if parts := strings.Split(expr, "."); len(parts) > 1 {
+		if root, ok := e.ctx.Get(parts[0]); ok {
+			if val, ok := lookupPath(root, parts[1:]); ok {
+				return val
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -782,32 +861,52 @@ func (e *Engine) evaluateExpression(expr string) (string, bool) {
 			}
 		}
 
-		// Try reflection for struct fields (handle multi-level: project.Owner.Name)
-		v := reflect.ValueOf(val)
+		// Try reflection for struct fields and map keys (multi-level: project.Owner.Name)
+		result, ok := lookupPath(val, parts[1:])
+		if !ok {
+			return "", false
+		}
+		if result == nil {
+			return "", true
+		}
+		return fmt.Sprintf("%v", result), true
+	}
 
-		// Navigate through all field parts (parts[1], parts[2], etc.)
-		for i := 1; i < len(parts); i++ {
-			// Dereference pointers
-			for v.Kind() == reflect.Ptr {
-				if v.IsNil() {
-					return "", false
-				}
-				v = v.Elem()
-			}
+	return "", false
+}
 
-			if v.Kind() == reflect.Struct {
-				field := v.FieldByName(parts[i])
-				if !field.IsValid() {
-					return "", false
-				}
-				v = field
-			} else {
-				return "", false
+// lookupPath walks struct fields and string-keyed map entries. A missing map
+// key yields nil rather than failing so optional values render empty.
+func lookupPath(val interface{}, path []string) (interface{}, bool) {
+	v := reflect.ValueOf(val)
+
+	for _, name := range path {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil, false
 			}
+			v = v.Elem()
 		}
 
-		return fmt.Sprintf("%v", v.Interface()), true
+		switch {
+		case v.Kind() == reflect.Struct:
+			field := v.FieldByName(name)
+			if !field.IsValid() {
+				return nil, false
+			}
+			v = field
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return nil, true
+			}
+		default:
+			return nil, false
+		}
 	}
 
-	return "", false
+	if !v.IsValid() {
+		return nil, true
+	}
+	return v.Interface(), true
 }
@@ -138,3 +138,38 @@ func TestRenderWithProps(t *testing.T) {
 		t.Error("Expected description in result")
 	}
 }
+
+func TestRenderInjectsCSRFToken(t *testing.T) {
+	ctx := executor.NewContext()
+	ctx.SetCSRFToken("tok")
+	engine := NewEngine(ctx)
+	engine.Page = true
+
+	template := `<form method="POST" action="/save"><button>Save</button></form><form method="get"></form><p>{Galaxy.CSRFToken}</p>`
+	result, err := engine.Render(template, nil)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	expected := `<form method="POST" action="/save"><input type="hidden" name="_csrf" value="tok"><button>Save</button></form><form method="get"></form><p>tok</p>`
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestRenderWithoutCSRFToken(t *testing.T) {
+	engine := NewEngine(executor.NewContext())
+	engine.Page = true
+
+	result, _ := engine.Render(`<form method="post"></form>`, nil)
+	if strings.Contains(result, "_csrf") {
+		t.Errorf("Expected no token field, got %q", result)
+	}
+
+	ctx := executor.NewContext()
+	ctx.SetCSRFToken("tok")
+	result, _ = NewEngine(ctx).Render(`<form method="post"></form>`, nil)
+	if strings.Contains(result, "_csrf") {
+		t.Errorf("Expected component renders to leave forms alone, got %q", result)
+	}
+}
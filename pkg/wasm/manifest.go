@@ -2,6 +2,7 @@ package wasm
 
 import (
 	"encoding/json"
+	"io/fs"
 	"os"
 )
 
@@ -39,7 +40,19 @@ func LoadManifest(path string) (*WasmManifest, error) {
 	if err != nil {
 		return nil, err
 	}
+	return parseManifest(data)
+}
+
+// LoadManifestFS reads the manifest at name in fsys.
+func LoadManifestFS(fsys fs.FS, name string) (*WasmManifest, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return parseManifest(data)
+}
 
+func parseManifest(data []byte) (*WasmManifest, error) {
 	var manifest WasmManifest
 	if err := json.Unmarshal(data, &manifest); err != nil {
 		return nil, err
@@ -0,0 +1,66 @@
+// Package worker runs an http.Handler inside a JavaScript worker runtime,
+// such as Cloudflare Workers, when the server is compiled to js/wasm. The
+// worker's fetch handler passes each request to Go as a Request and answers
+// with the Response; bodies are buffered, not streamed.
+package worker
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+)
+
+// FetchFunc names the global function Start registers for the worker shim
+// to call with each request.
+const FetchFunc = "galaxyFetch"
+
+// Request is a fetch request passed from the worker.
+type Request struct {
+	Method  string
+	URL     string
+	Headers [][2]string
+	Body    []byte
+}
+
+// Response is the handler's answer to a Request.
+type Response struct {
+	Status  int
+	Headers [][2]string
+	Body    []byte
+}
+
+// Serve serves req with h and returns its response, the way the worker
+// does for each fetch event.
+func Serve(ctx context.Context, h http.Handler, req *Request) *Response {
+	r, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return &Response{Status: http.StatusBadRequest, Body: []byte(err.Error())}
+	}
+	for _, kv := range req.Headers {
+		r.Header.Add(kv[0], kv[1])
+	}
+	r.Host = r.URL.Host
+	r.RequestURI = r.URL.RequestURI()
+	if ip := r.Header.Get("CF-Connecting-IP"); ip != "" {
+		r.RemoteAddr = ip
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	res := rec.Result()
+	out := &Response{Status: res.StatusCode, Body: rec.Body.Bytes()}
+	keys := make([]string, 0, len(res.Header))
+	for k := range res.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range res.Header[k] {
+			out.Headers = append(out.Headers, [2]string{k, v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+//go:build js && wasm
+
+package worker
+
+import (
+	"context"
+	"net/http"
+	"syscall/js"
+)
+
+// Start registers h as FetchFunc and blocks, leaving the worker to call it.
+// The function takes {method, url, headers, body} and returns a promise of
+// {status, headers, body}, with headers as [name, value] pairs and bodies
+// as Uint8Arrays.
+func Start(h http.Handler) error {
+	js.Global().Set(FetchFunc, js.FuncOf(func(this js.Value, args []js.Value) any {
+		req := fromJS(args[0])
+		var executor js.Func
+		executor = js.FuncOf(func(this js.Value, args []js.Value) any {
+			resolve := args[0]
+			go func() {
+				defer executor.Release()
+				resolve.Invoke(toJS(Serve(context.Background(), h, req)))
+			}()
+			return nil
+		})
+		return js.Global().Get("Promise").New(executor)
+	}))
+	select {}
+}
+
+func fromJS(v js.Value) *Request {
+	req := &Request{Method: v.Get("method").String(), URL: v.Get("url").String()}
+	headers := v.Get("headers")
+	for i := 0; i < headers.Length(); i++ {
+		kv := headers.Index(i)
+		req.Headers = append(req.Headers, [2]string{kv.Index(0).String(), kv.Index(1).String()})
+	}
+	if body := v.Get("body"); body.Truthy() {
+		req.Body = make([]byte, body.Length())
+		js.CopyBytesToGo(req.Body, body)
+	}
+	return req
+}
+
+func toJS(res *Response) js.Value {
+	headers := js.Global().Get("Array").New()
+	for _, kv := range res.Headers {
+		headers.Call("push", js.ValueOf([]any{kv[0], kv[1]}))
+	}
+	body := js.Global().Get("Uint8Array").New(len(res.Body))
+	js.CopyBytesToJS(body, res.Body)
+	return js.ValueOf(map[string]any{
+		"status":  res.Status,
+		"headers": headers,
+		"body":    body,
+	})
+}
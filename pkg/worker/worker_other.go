@@ -0,0 +1,13 @@
+//go:build !(js && wasm)
+
+package worker
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Start is only available to servers compiled to js/wasm.
+func Start(h http.Handler) error {
+	return errors.New("worker: Start requires a js/wasm build")
+}
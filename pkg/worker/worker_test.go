@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, r.Method+" "+r.Host+" "+r.RequestURI+" "+r.Header.Get("X-Test")+" "+r.RemoteAddr+" "+string(body))
+	})
+
+	res := Serve(context.Background(), h, &Request{
+		Method:  "POST",
+		URL:     "https://site.pages.dev/blog/hello?x=1",
+		Headers: [][2]string{{"X-Test", "yes"}, {"CF-Connecting-IP", "203.0.113.9"}},
+		Body:    []byte("payload"),
+	})
+	if res.Status != http.StatusAccepted {
+		t.Errorf("Expected 202, got %d", res.Status)
+	}
+	want := "POST site.pages.dev /blog/hello?x=1 yes 203.0.113.9 payload"
+	if string(res.Body) != want {
+		t.Errorf("Expected %q, got %q", want, res.Body)
+	}
+
+	var cookies int
+	for i, kv := range res.Headers {
+		if kv[0] == "Set-Cookie" {
+			cookies++
+		}
+		if i > 0 && res.Headers[i-1][0] > kv[0] {
+			t.Errorf("Expected headers in order, got %v", res.Headers)
+		}
+	}
+	if cookies != 2 {
+		t.Errorf("Expected both cookies, got %v", res.Headers)
+	}
+
+	res = Serve(context.Background(), h, &Request{Method: "GET", URL: "::bad"})
+	if res.Status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad URL, got %d", res.Status)
+	}
+}
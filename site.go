@@ -0,0 +1,358 @@
+package galaxy
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cameron-webmatter/galaxy/pkg/actions"
+	"github.com/cameron-webmatter/galaxy/pkg/auth"
+	"github.com/cameron-webmatter/galaxy/pkg/cache"
+	"github.com/cameron-webmatter/galaxy/pkg/compiler"
+	"github.com/cameron-webmatter/galaxy/pkg/config"
+	"github.com/cameron-webmatter/galaxy/pkg/endpoints"
+	"github.com/cameron-webmatter/galaxy/pkg/executor"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware"
+	"github.com/cameron-webmatter/galaxy/pkg/middleware/builtin"
+	"github.com/cameron-webmatter/galaxy/pkg/parser"
+	"github.com/cameron-webmatter/galaxy/pkg/router"
+	"github.com/cameron-webmatter/galaxy/pkg/serve"
+	"github.com/cameron-webmatter/galaxy/pkg/ssr"
+	"github.com/cameron-webmatter/galaxy/pkg/telemetry"
+	"github.com/cameron-webmatter/galaxy/pkg/template"
+	"github.com/cameron-webmatter/galaxy/pkg/wasm"
+)
+
+// site renders a project from an fs.FS, the way the standalone server does
+// from its build output.
+type site struct {
+	cfg          *config.Config
+	opts         Options
+	fsys         fs.FS
+	srcDir       string
+	rt           *router.Router
+	comp         *compiler.ComponentCompiler
+	middleware   []middleware.Middleware
+	wasmManifest *wasm.WasmManifest
+}
+
+func newSiteHandler(cfg *config.Config, opts Options, mws []middleware.Middleware) (http.Handler, error) {
+	fsys := opts.FS
+	if fsys == nil {
+		fsys = os.DirFS(opts.Dir)
+	}
+
+	srcDir := path.Clean(filepath.ToSlash(cfg.SrcDir))
+	if !fs.ValidPath(srcDir) {
+		return nil, fmt.Errorf("srcDir must be relative to the project: %s", cfg.SrcDir)
+	}
+
+	s := &site{
+		cfg:        cfg,
+		opts:       opts,
+		fsys:       fsys,
+		srcDir:     srcDir,
+		rt:         router.NewRouter(path.Join(srcDir, "pages")),
+		comp:       compiler.NewComponentCompilerFS(fsys, srcDir),
+		middleware: mws,
+	}
+	s.rt.FS = fsys
+	if err := s.rt.Discover(); err != nil {
+		return nil, fmt.Errorf("route discovery: %w", err)
+	}
+	s.rt.Sort()
+	if err := s.checkHandlers(); err != nil {
+		return nil, err
+	}
+
+	// public/ is served from the project, and bundled assets, copied public
+	// files and prerendered pages from the build output when there is one.
+	var handler http.Handler = serve.Site(fsys, http.HandlerFunc(s.handleRequest))
+	if outDir := path.Join(path.Clean(filepath.ToSlash(cfg.OutDir)), "server"); fs.ValidPath(outDir) {
+		if built, err := fs.Sub(fsys, outDir); err == nil {
+			if _, err := fs.Stat(built, "."); err == nil {
+				s.wasmManifest, _ = wasm.LoadManifestFS(built, "_assets/wasm-manifest.json")
+				handler = serve.Site(built, handler)
+			}
+		}
+	}
+	if cfg.Cache.Enabled {
+		handler = cache.FromConfig(cfg.Cache).Handler(handler)
+	}
+	return telemetry.Handler(cfg.Telemetry, handler), nil
+}
+
+func (s *site) handleRequest(w http.ResponseWriter, r *http.Request) {
+	route, params := s.rt.Match(r.URL.Path)
+	if route == nil {
+		http.NotFound(w, r)
+		return
+	}
+	telemetry.SetRoute(r, route.Pattern)
+
+	mwCtx := middleware.NewContext(w, r)
+	mwCtx.Params = params
+	if s.opts.Locals != nil {
+		for k, v := range s.opts.Locals(r) {
+			mwCtx.Locals[k] = v
+		}
+	}
+
+	chain, err := s.middlewareFor(route)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if chain.Len() == 0 {
+		if route.IsEndpoint {
+			s.handleEndpoint(route.Pattern, mwCtx)
+			return
+		}
+		s.handlePage(route, mwCtx)
+		return
+	}
+
+	if err := chain.Execute(mwCtx, func(ctx *middleware.Context) error {
+		if route.IsEndpoint {
+			s.handleEndpoint(route.Pattern, ctx)
+		} else {
+			s.handlePage(route, ctx)
+		}
+		return nil
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// checkHandlers fails when the project has Go files that opts provides no
+// handlers for. Production mode does not compile Go, so without this an
+// endpoint would answer 404 and a _middleware.go, such as an access check,
+// would be skipped.
+func (s *site) checkHandlers() error {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, route := range s.rt.Routes {
+		if _, ok := s.opts.Endpoints[route.Pattern]; route.IsEndpoint && !ok {
+			missing = append(missing, "endpoint "+route.Pattern)
+		}
+		if _, ok := s.opts.Actions[route.Pattern]; route.ActionsFile != "" && !ok {
+			missing = append(missing, "actions for "+route.Pattern)
+		}
+		for _, file := range route.Middleware {
+			rel := s.middlewareDir(file)
+			if _, ok := s.opts.RouteMiddleware[rel]; !ok && !seen[rel] {
+				seen[rel] = true
+				missing = append(missing, "middleware for "+rel)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("production mode does not compile Go; pass these in Options: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// middlewareDir is the RouteMiddleware key of a _middleware.go file.
+func (s *site) middlewareDir(file string) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(path.Dir(file), s.rt.PagesDir), "/")
+	if rel == "" {
+		rel = "."
+	}
+	return rel
+}
+
+// middlewareFor composes the host's middleware, the configured built-ins,
+// the route's directory middleware and its auth guard, outermost first.
+func (s *site) middlewareFor(route *router.Route) (*middleware.Chain, error) {
+	chain := middleware.NewChain().Use(s.middleware...)
+	for _, file := range route.Middleware {
+		chain.Use(s.opts.RouteMiddleware[s.middlewareDir(file)]...)
+	}
+
+	rule, err := auth.FileRuleFS(s.fsys, route.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		chain.Use(auth.Guard(*rule))
+	}
+	return chain, nil
+}
+
+func (s *site) handleEndpoint(pattern string, mwCtx *middleware.Context) {
+	endpoint, ok := s.opts.Endpoints[pattern]
+	if !ok {
+		http.Error(mwCtx.Response, "Endpoint not found", http.StatusNotFound)
+		return
+	}
+
+	ctx, span := telemetry.Start(mwCtx.Request.Context(), "endpoint", slog.String("http.route", pattern))
+	defer span.End()
+	ctx = endpoints.WithWebSocketOrigins(ctx, s.cfg.Server.WebSocketOrigins)
+
+	if err := endpoints.HandleEndpoint(endpoint, mwCtx.Response, mwCtx.Request.WithContext(ctx), mwCtx.Params, mwCtx.Locals); err != nil {
+		span.RecordError(err)
+		endpoints.WriteError(mwCtx.Response, err)
+	}
+}
+
+func (s *site) handlePage(route *router.Route, mwCtx *middleware.Context) {
+	filePath := route.FilePath
+
+	actionSet, hasActions := s.opts.Actions[route.Pattern]
+	actionResult, handled := actions.Handle(actionSet, mwCtx.Response, mwCtx.Request, mwCtx.Params, mwCtx.Locals)
+	if handled {
+		return
+	}
+
+	parsed, err := s.comp.Load(filePath)
+	if err != nil {
+		http.Error(mwCtx.Response, fmt.Sprintf("Parse error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	imports := make([]compiler.Import, len(parsed.Imports))
+	for i, imp := range parsed.Imports {
+		imports[i] = compiler.Import{
+			Path:        imp.Path,
+			Alias:       imp.Alias,
+			IsComponent: imp.IsComponent,
+		}
+	}
+	renderReq := s.comp.NewRequest(filePath, imports)
+
+	ctx := executor.NewContext()
+
+	reqCtx := ssr.NewRequestContext(mwCtx.Request, mwCtx.Params)
+	ctx.SetRequest(reqCtx)
+	ctx.SetLocals(mwCtx.Locals)
+
+	ctx.SetParams(mwCtx.Params)
+	ctx.SetSession(mwCtx.Session(), mwCtx.Cookies())
+	ctx.SetCSRFToken(builtin.CSRFToken(mwCtx.Request))
+	ctx.SetCache(cache.PolicyFrom(mwCtx.Request))
+
+	for k, v := range mwCtx.Params {
+		ctx.Set(k, v)
+	}
+
+	if hasActions {
+		actions.Expose(ctx, actionSet.Names(), actionResult)
+	}
+
+	if parsed.Frontmatter != "" {
+		_, span := telemetry.Start(mwCtx.Request.Context(), "frontmatter", slog.String("http.route", route.Pattern))
+		err := ctx.Execute(parsed.Frontmatter)
+		span.RecordError(err)
+		span.End()
+		if err != nil {
+			http.Error(mwCtx.Response, fmt.Sprintf("Execution error: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	response := ctx.GetResponse()
+	response.ApplyHeaders(mwCtx.Response.Header())
+
+	if ctx.ShouldRedirect {
+		http.Redirect(mwCtx.Response, mwCtx.Request, ctx.RedirectURL, ctx.RedirectStatus)
+		return
+	}
+
+	if ctx.NotFound {
+		http.NotFound(mwCtx.Response, mwCtx.Request)
+		return
+	}
+
+	if mwCtx.Response.Header().Get("Content-Type") == "" {
+		mwCtx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	status := http.StatusOK
+	if actionResult != nil {
+		status = actionResult.Status
+	}
+
+	renderCtx, renderDone := telemetry.Render(mwCtx.Request.Context(), route.Pattern)
+	renderReq.Context = renderCtx
+
+	if s.cfg.Output.Streaming || compiler.HasIslands(parsed.Template) {
+		mwCtx.Response.WriteHeader(response.StatusOr(status))
+		actionsClient := actions.StreamingClient()
+		err := renderReq.Stream(mwCtx.Response, parsed.Template, ctx, compiler.StreamOptions{
+			Transform: func(chunk string) string {
+				chunk = s.injectPageAssets(chunk, filePath, parsed.Styles)
+				if hasActions {
+					chunk = actionsClient(chunk)
+				}
+				return chunk
+			},
+		})
+		renderDone(err)
+		if err != nil {
+			telemetry.Logger(renderCtx).Error("stream failed", "route", route.Pattern, "error", err)
+		}
+		return
+	}
+
+	processedTemplate := renderReq.ProcessComponentTags(parsed.Template, ctx)
+
+	engine := template.NewEngine(ctx)
+	engine.Page = true
+	rendered, err := engine.Render(processedTemplate, nil)
+	renderDone(err)
+	if err != nil {
+		http.Error(mwCtx.Response, fmt.Sprintf("Render error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	styles := append(append([]parser.Style{}, parsed.Styles...), renderReq.Styles...)
+	rendered = s.injectPageAssets(rendered, filePath, styles)
+
+	if hasActions {
+		rendered = actions.InjectClient(rendered)
+	}
+
+	mwCtx.Response.WriteHeader(response.StatusOr(status))
+	mwCtx.Response.Write([]byte(rendered))
+}
+
+// injectPageAssets adds styles before </head> and the page's WASM and JS
+// scripts, as recorded in the build's manifest, before </body>.
+func (s *site) injectPageAssets(rendered, filePath string, styles []parser.Style) string {
+	if len(styles) > 0 && strings.Contains(rendered, "</head>") {
+		var styleContent string
+		for _, style := range styles {
+			styleContent += style.Content + "\n"
+		}
+		styleTag := "<style>" + styleContent + "</style>"
+		rendered = strings.Replace(rendered, "</head>", styleTag+"\n</head>", 1)
+	}
+
+	if s.wasmManifest == nil || !strings.Contains(rendered, "</body>") {
+		return rendered
+	}
+
+	// The manifest is keyed by path within the source directory.
+	pageAssets, ok := s.wasmManifest.Assets[strings.TrimPrefix(filePath, s.srcDir+"/")]
+	if ok && len(pageAssets.WasmModules) > 0 {
+		wasmExecTag := "<script src=\"/wasm_exec.js\"></script>"
+		rendered = strings.Replace(rendered, "</body>", wasmExecTag+"\n</body>", 1)
+
+		for _, mod := range pageAssets.WasmModules {
+			loaderTag := fmt.Sprintf("<script src=\"%s\"></script>", mod.LoaderPath)
+			rendered = strings.Replace(rendered, "</body>", loaderTag+"\n</body>", 1)
+		}
+	}
+
+	for _, jsPath := range pageAssets.JSScripts {
+		jsTag := fmt.Sprintf("<script type=\"module\" src=\"%s\"></script>", jsPath)
+		rendered = strings.Replace(rendered, "</body>", jsTag+"\n</body>", 1)
+	}
+
+	return rendered
+}